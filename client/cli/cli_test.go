@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"treds/client"
+)
+
+func TestArgumentHint(t *testing.T) {
+	commands := map[string]client.CommandInfo{
+		"ZRANGELEXKVS": {Name: "ZRANGELEXKVS", Args: "key offset count withscore min max", Kind: "read"},
+		"MSET":         {Name: "MSET", Args: "key value [key value ...]", Kind: "write"},
+		"DBSIZE":       {Name: "DBSIZE", Kind: "read"},
+	}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "zrangelexkvs", expected: " key offset count withscore min max"},
+		{line: "ZRANGELEXKVS board ", expected: "offset count withscore min max"},
+		{line: "ZRANGELEXKVS board 0", expected: ""},
+		{line: "ZRANGELEXKVS board 0 10 true a z ", expected: ""},
+		{line: "MSET a 1 ", expected: "[key value ...]"},
+		{line: "DBSIZE ", expected: ""},
+		{line: "UNKNOWN ", expected: ""},
+	}
+
+	for _, tt := range tests {
+		if got := argumentHint(tt.line, commands); got != tt.expected {
+			t.Errorf("argumentHint(%q) expected %q, got %q", tt.line, tt.expected, got)
+		}
+	}
+}
+
+func TestCompleter(t *testing.T) {
+	c := &completer{names: []string{"ZRANGELEXKEYS", "ZRANGELEXKVS", "ZREM"}}
+
+	candidates, length := c.Do([]rune("zrangelexk"), 10)
+	if length != 10 || len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates replacing 10 runes, got %d replacing %d", len(candidates), length)
+	}
+	if string(candidates[0]) != "eys " || string(candidates[1]) != "vs " {
+		t.Fatalf("expected lower case suffixes, got %q and %q", string(candidates[0]), string(candidates[1]))
+	}
+
+	candidates, _ = c.Do([]rune("ZREM key"), 8)
+	if len(candidates) != 0 {
+		t.Fatalf("expected no candidates for arguments, got %d", len(candidates))
+	}
+}
+
+func TestFormatReply(t *testing.T) {
+	reply := []interface{}{"a", int64(1), nil, []interface{}{"x", 2.5}}
+	expected := "1) \"a\"\n2) (integer) 1\n3) (nil)\n4) 1) \"x\"\n   2) (double) 2.5"
+	if got := formatReply(reply, false); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	documents := []interface{}{`{"_id":"1","age":20}`}
+	expected = "1) {\n     \"_id\": \"1\",\n     \"age\": 20\n   }"
+	if got := formatReply(documents, true); got != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"treds/client"
+)
+
+// completer completes command names in the first word of the line
+type completer struct {
+	names []string
+}
+
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	typed := string(line[:pos])
+	if strings.ContainsAny(typed, " \t") {
+		return nil, 0
+	}
+	upper := strings.ToUpper(typed)
+	candidates := make([][]rune, 0)
+	for _, name := range c.names {
+		if !strings.HasPrefix(name, upper) {
+			continue
+		}
+		suffix := name[len(typed):]
+		// Keep the case the user started typing in
+		if typed != "" && typed == strings.ToLower(typed) {
+			suffix = strings.ToLower(suffix)
+		}
+		candidates = append(candidates, []rune(suffix+" "))
+	}
+	return candidates, len([]rune(typed))
+}
+
+// hinter paints the arguments still to be typed after the cursor, in grey
+type hinter struct {
+	commands map[string]client.CommandInfo
+}
+
+func (h *hinter) Paint(line []rune, pos int) []rune {
+	if pos != len(line) {
+		return line
+	}
+	hint := argumentHint(string(line), h.commands)
+	if hint == "" {
+		return line
+	}
+	// Move the cursor back over the hint so typing continues where the user left off
+	painted := fmt.Sprintf("\033[90m%s\033[0m\033[%dD", hint, len([]rune(hint)))
+	return append(line, []rune(painted)...)
+}
+
+// argumentHint returns the part of the argument hint of the command on line that has not been typed yet
+func argumentHint(line string, commands map[string]client.CommandInfo) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	info, ok := commands[strings.ToUpper(fields[0])]
+	if !ok || info.Args == "" {
+		return ""
+	}
+	typed := len(fields) - 1
+	separator := ""
+	if !strings.HasSuffix(line, " ") {
+		// Only hint inside an argument right after the command name
+		if typed > 0 {
+			return ""
+		}
+		separator = " "
+	}
+
+	tokens := splitHint(info.Args)
+	if typed >= len(tokens) {
+		// Keep offering a trailing repeatable group such as [key value ...]
+		last := tokens[len(tokens)-1]
+		if strings.HasSuffix(last, "...]") {
+			return separator + last
+		}
+		return ""
+	}
+	return separator + strings.Join(tokens[typed:], " ")
+}
+
+// splitHint splits an argument hint on spaces, keeping bracketed optional groups together
+func splitHint(args string) []string {
+	tokens := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, ch := range args {
+		switch ch {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ' ':
+			if depth == 0 {
+				if i > start {
+					tokens = append(tokens, args[start:i])
+				}
+				start = i + 1
+			}
+		}
+	}
+	if start < len(args) {
+		tokens = append(tokens, args[start:])
+	}
+	return tokens
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"treds/client"
+)
+
+// jsonCommands reply with JSON documents that are printed indented instead of quoted
+var jsonCommands = map[string]bool{
+	"DQUERY":   true,
+	"DEXPLAIN": true,
+}
+
+// formatReply renders a reply the way redis-cli does, nested arrays are numbered and indented
+func formatReply(reply interface{}, prettyJSON bool) string {
+	var b strings.Builder
+	writeReply(&b, reply, prettyJSON)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeReply(b *strings.Builder, reply interface{}, prettyJSON bool) {
+	switch v := reply.(type) {
+	case nil:
+		b.WriteString("(nil)\n")
+	case client.Error:
+		b.WriteString("(error) " + string(v) + "\n")
+	case string:
+		if prettyJSON {
+			var indented bytes.Buffer
+			if json.Indent(&indented, []byte(v), "", "  ") == nil {
+				b.WriteString(indented.String() + "\n")
+				return
+			}
+		}
+		b.WriteString(strconv.Quote(v) + "\n")
+	case int64:
+		b.WriteString(fmt.Sprintf("(integer) %d\n", v))
+	case float64:
+		b.WriteString("(double) " + strconv.FormatFloat(v, 'f', -1, 64) + "\n")
+	case bool:
+		b.WriteString(fmt.Sprintf("(%t)\n", v))
+	case client.Push:
+		writeArray(b, v, prettyJSON)
+	case []interface{}:
+		writeArray(b, v, prettyJSON)
+	default:
+		b.WriteString(fmt.Sprintf("%v\n", v))
+	}
+}
+
+func writeArray(b *strings.Builder, elements []interface{}, prettyJSON bool) {
+	if len(elements) == 0 {
+		b.WriteString("(empty array)\n")
+		return
+	}
+	width := len(strconv.Itoa(len(elements)))
+	for i, element := range elements {
+		prefix := fmt.Sprintf("%*d) ", width, i+1)
+		var nested strings.Builder
+		writeReply(&nested, element, prettyJSON)
+		lines := strings.Split(strings.TrimSuffix(nested.String(), "\n"), "\n")
+		for j, line := range lines {
+			if j == 0 {
+				b.WriteString(prefix)
+			} else {
+				b.WriteString(strings.Repeat(" ", len(prefix)))
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Command treds-cli is an interactive shell for Treds.
+//
+// It reads the command list from the server for completion and argument hints, prints DQUERY and DEXPLAIN
+// results as indented JSON and follows the Raft leader when a write is typed on a follower.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"treds/client"
+	"treds/resp"
+)
+
+const historyFileName = ".treds_cli_history"
+
+type cli struct {
+	addr     string
+	protocol int
+	user     string
+	password string
+	tls      *tls.Config
+	client   *client.Client
+	info     client.ServerInfo
+	commands map[string]client.CommandInfo
+	names    []string
+}
+
+func main() {
+	host := flag.String("h", "127.0.0.1", "Server hostname")
+	port := flag.Int("p", 7997, "Server port")
+	protocol := flag.Int("protocol", 3, "RESP protocol version, 2 or 3")
+	user := flag.String("user", "", "Username to AUTH with, the default user when empty")
+	password := flag.String("a", os.Getenv("TREDSCLI_AUTH"), "Password to AUTH with, read from TREDSCLI_AUTH when not given")
+	useTLS := flag.Bool("tls", false, "Connect over TLS")
+	caCert := flag.String("cacert", "", "CA certificate to verify the server with, the system roots when empty")
+	cert := flag.String("cert", "", "Client certificate for servers that require one")
+	key := flag.String("key", "", "Private key of the client certificate")
+	flag.Parse()
+
+	cl := &cli{protocol: *protocol, user: *user, password: *password}
+	if *useTLS {
+		config, err := tlsConfig(*caCert, *cert, *key)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		cl.tls = config
+	}
+	if err := cl.connect(net.JoinHostPort(*host, strconv.Itoa(*port))); err != nil {
+		fmt.Println("Could not connect to Treds at", cl.addr+":", err)
+		os.Exit(1)
+	}
+	defer cl.client.Close()
+
+	// Run a single command given on the command line, like redis-cli does
+	if flag.NArg() > 0 {
+		if !cl.execute(flag.Args()) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := cl.repl(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// connect switches to the server at addr and loads its command metadata
+func (cl *cli) connect(addr string) error {
+	cl.addr = addr
+	c := client.New(client.Options{Addr: addr, PoolSize: 1, Protocol: cl.protocol, Username: cl.user, Password: cl.password, TLSConfig: cl.tls})
+	ctx := context.Background()
+	info, err := c.Hello(ctx)
+	if err != nil {
+		_ = c.Close()
+		return err
+	}
+	infos, err := c.Commands(ctx)
+	if err != nil {
+		_ = c.Close()
+		return err
+	}
+	if cl.client != nil {
+		_ = cl.client.Close()
+	}
+	cl.client = c
+	cl.info = info
+	cl.commands = make(map[string]client.CommandInfo, len(infos))
+	cl.names = make([]string, 0, len(infos))
+	for _, commandInfo := range infos {
+		cl.commands[commandInfo.Name] = commandInfo
+		cl.names = append(cl.names, commandInfo.Name)
+	}
+	return nil
+}
+
+// tlsConfig loads the CA and client certificate files, each of them is optional
+func tlsConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (cl *cli) repl() error {
+	historyFile := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyFile = filepath.Join(home, historyFileName)
+	}
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          cl.prompt(),
+		HistoryFile:     historyFile,
+		AutoComplete:    &completer{names: cl.names},
+		Painter:         &hinter{commands: cl.commands},
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.EqualFold(line, "exit") || strings.EqualFold(line, "quit") {
+			return nil
+		}
+
+		// The line is split with the same quoting rules the server uses for inline commands
+		command, args, err := resp.Decode(line)
+		if err != nil {
+			fmt.Println("(error)", err)
+			continue
+		}
+		cl.execute(append([]string{command}, args...))
+
+		// The connection may have moved to the leader
+		rl.SetPrompt(cl.prompt())
+		rl.Config.AutoComplete = &completer{names: cl.names}
+		rl.Config.Painter = &hinter{commands: cl.commands}
+	}
+}
+
+func (cl *cli) prompt() string {
+	return cl.addr + "> "
+}
+
+// execute runs one command and prints its reply, it reports whether the command succeeded
+func (cl *cli) execute(args []string) bool {
+	name := strings.ToUpper(args[0])
+	cl.followLeader(name)
+
+	// Ctrl-C cancels the command in flight instead of killing the shell
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch name {
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		return cl.subscribe(ctx, name, args[1:])
+	}
+
+	commandArgs := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		commandArgs = append(commandArgs, arg)
+	}
+	reply, err := cl.client.Do(ctx, commandArgs...)
+	// The client followed a MOVED reply of a follower started with -redirect, the shell moves with it
+	if addr := cl.client.Addr(); addr != cl.addr {
+		if errConnect := cl.connect(addr); errConnect != nil {
+			// The client still sends its commands to the leader, only the shell has not refreshed what it knows of it
+			fmt.Println("Could not refresh the leader at", addr+":", errConnect)
+		} else {
+			fmt.Println("-> Moved to the leader at", addr)
+		}
+	}
+	if err != nil {
+		var replyErr client.Error
+		if errors.As(err, &replyErr) {
+			fmt.Println(formatReply(replyErr, false))
+		} else {
+			fmt.Println("(error)", err)
+		}
+		return false
+	}
+	fmt.Println(formatReply(reply, jsonCommands[name]))
+	return true
+}
+
+// followLeader reconnects to the leader before a write typed on a follower
+func (cl *cli) followLeader(name string) {
+	if cl.commands[name].Kind != "write" || cl.info.Role == "master" {
+		return
+	}
+	leader := cl.info.Leader
+	if leader == "" || leader == cl.addr {
+		return
+	}
+	previous := cl.addr
+	if err := cl.connect(leader); err != nil {
+		// Stay where we are, the follower forwards the write to the leader itself
+		cl.addr = previous
+		fmt.Println("Could not follow the leader at", leader+":", err)
+		return
+	}
+	fmt.Println("-> Following the leader at", leader)
+}
+
+// subscribe prints messages until Ctrl-C is pressed
+func (cl *cli) subscribe(ctx context.Context, name string, channels []string) bool {
+	var ps *client.PubSub
+	var err error
+	if name == "PSUBSCRIBE" {
+		ps, err = cl.client.PSubscribe(ctx, channels...)
+	} else {
+		ps, err = cl.client.Subscribe(ctx, channels...)
+	}
+	if err != nil {
+		fmt.Println("(error)", err)
+		return false
+	}
+	defer ps.Close()
+
+	fmt.Println("Reading messages... (press Ctrl-C to quit)")
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case message, ok := <-ps.Channel():
+			if !ok {
+				if err = ps.Err(); err != nil {
+					fmt.Println("(error)", err)
+					return false
+				}
+				return true
+			}
+			reply := []interface{}{"message", message.Channel, message.Payload}
+			if message.Pattern != "" {
+				reply = []interface{}{"pmessage", message.Pattern, message.Channel, message.Payload}
+			}
+			fmt.Println(formatReply(reply, false))
+		}
+	}
+}
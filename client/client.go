@@ -0,0 +1,146 @@
+// Package client is a Go client for Treds.
+//
+// A Client is safe for concurrent use. It keeps a pool of connections, negotiates RESP3 with HELLO when
+// the server supports it, and exposes typed methods for the key value, sorted map, list, set, hash,
+// document, vector and pub/sub commands. Every method takes a context, cancelling it aborts the
+// in-flight request and discards the connection it was using.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"time"
+)
+
+// Options configures a Client
+type Options struct {
+	// Addr is the host:port of a Treds server, defaults to localhost:7997
+	Addr string
+	// PoolSize is the maximum number of open connections, defaults to 10
+	PoolSize int
+	// DialTimeout bounds establishing a new connection, defaults to 5 seconds
+	DialTimeout time.Duration
+	// IdleTimeout closes pooled connections unused for longer than this, zero keeps them forever
+	IdleTimeout time.Duration
+	// Protocol is the RESP version to negotiate, 2 or 3, defaults to 3
+	Protocol int
+	// Username and Password are sent with AUTH on every new connection when Password is set.
+	// An empty Username authenticates as the default user.
+	Username string
+	Password string
+	// TLSConfig enables TLS when set, ServerName defaults to the host of Addr
+	TLSConfig *tls.Config
+	// ReadConsistency is sent with CONSISTENCY on every new connection when set, e.g. "LINEARIZABLE" or "STALE MAXLAG 500".
+	// Reads are stale, made on the node the client is connected to, by default.
+	ReadConsistency string
+}
+
+func (o *Options) init() {
+	if o.Addr == "" {
+		o.Addr = "localhost:7997"
+	}
+	if o.PoolSize <= 0 {
+		o.PoolSize = 10
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 5 * time.Second
+	}
+	if o.Protocol != 2 {
+		o.Protocol = 3
+	}
+}
+
+// Client is a pooled connection to a Treds server
+type Client struct {
+	opts Options
+	pool *pool
+}
+
+// New creates a Client, connections are dialed lazily on first use
+func New(opts Options) *Client {
+	opts.init()
+	return &Client{
+		opts: opts,
+		pool: newPool(&opts),
+	}
+}
+
+// Close closes every idle connection, connections in use are closed when they are returned
+func (c *Client) Close() error {
+	return c.pool.close()
+}
+
+// Do sends a raw command and returns the decoded reply.
+// Error replies from the server are returned as an Error. A MOVED reply from a follower started with -redirect
+// moves the client to the leader it names and the command is sent again there, once.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	replies, err := c.doPipeline(ctx, [][]interface{}{args})
+	if err != nil {
+		return nil, err
+	}
+	if addr, moved := movedTo(replies[0]); moved {
+		c.pool.moveTo(addr)
+		if replies, err = c.doPipeline(ctx, [][]interface{}{args}); err != nil {
+			return nil, err
+		}
+	}
+	if replyErr, isErr := replies[0].(Error); isErr {
+		return nil, replyErr
+	}
+	return replies[0], nil
+}
+
+// Addr returns the address of the server the client sends its commands to, it changes when a MOVED reply is followed
+func (c *Client) Addr() string {
+	return c.pool.address()
+}
+
+// movedTo returns the address a MOVED error reply points to
+func movedTo(reply interface{}) (string, bool) {
+	replyErr, isErr := reply.(Error)
+	if !isErr {
+		return "", false
+	}
+	fields := strings.Fields(string(replyErr))
+	if len(fields) != 2 || fields[0] != "MOVED" {
+		return "", false
+	}
+	return fields[1], true
+}
+
+func (c *Client) doPipeline(ctx context.Context, cmds [][]interface{}) ([]interface{}, error) {
+	cn, err := c.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := cn.pipeline(ctx, cmds)
+	c.pool.put(cn, err != nil)
+	if err != nil {
+		return nil, err
+	}
+	// RESP2 servers send missing values as the string (nil)
+	if cn.protocol == 2 {
+		for i, reply := range replies {
+			replies[i] = normalizeNil(reply)
+		}
+	}
+	return replies, nil
+}
+
+func normalizeNil(reply interface{}) interface{} {
+	switch v := reply.(type) {
+	case string:
+		if v == nilString {
+			return nil
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = normalizeNil(element)
+		}
+	}
+	return reply
+}
+
+// nilString is what the server sends for a missing value over RESP2
+const nilString = "(nil)"
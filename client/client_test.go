@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"treds/resp"
+)
+
+// fakeServer answers every command with the reply returned by handler, nil replies are never sent
+func fakeServer(t *testing.T, handler func(command string, args []string) string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFake(netConn, handler)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveFake(netConn net.Conn, handler func(command string, args []string) string) {
+	defer netConn.Close()
+	buf := make([]byte, 0)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := netConn.Read(chunk)
+		if err != nil {
+			return
+		}
+		buf = append(buf, chunk[:n]...)
+		for {
+			length, err := resp.FrameLength(buf)
+			if err != nil {
+				break
+			}
+			command, args, err := resp.Decode(string(buf[:length]))
+			buf = buf[length:]
+			if err != nil {
+				return
+			}
+			if reply := handler(strings.ToUpper(command), args); reply != "" {
+				_, _ = netConn.Write([]byte(reply))
+			}
+		}
+	}
+}
+
+func TestClientTypedReplies(t *testing.T) {
+	addr := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeError("unknown command")
+		case "GET":
+			if args[0] == "missing" {
+				return resp.EncodeBulkString("(nil)")
+			}
+			return resp.EncodeBulkString("value with spaces")
+		case "SCANKVS":
+			return resp.EncodeStringArray([]string{"user:1", "a", "user:2", "b", "123"})
+		case "ZRANGESCOREKVS":
+			return resp.EncodeStringArray([]string{"1", "k1", "v1", "2.5", "k2", "v2"})
+		case "VSEARCH":
+			return resp.Encode2DStringArrayRESP([][]string{{"id1", "1", "2"}})
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	c := New(Options{Addr: addr})
+	defer c.Close()
+	ctx := context.Background()
+
+	value, err := c.Get(ctx, "key")
+	if err != nil || value != "value with spaces" {
+		t.Fatalf("expected value with spaces, got %q, %v", value, err)
+	}
+	_, err = c.Get(ctx, "missing")
+	if !errors.Is(err, ErrNil) {
+		t.Fatalf("expected ErrNil, got %v", err)
+	}
+
+	pairs, cursor, err := c.ScanKVS(ctx, "0", "user:", 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedPairs := []KeyValue{{Key: "user:1", Value: "a"}, {Key: "user:2", Value: "b"}}
+	if !reflect.DeepEqual(pairs, expectedPairs) || cursor != "123" {
+		t.Fatalf("expected %v with cursor 123, got %v with cursor %s", expectedPairs, pairs, cursor)
+	}
+
+	members, err := c.ZRangeScoreKVS(ctx, "board", ScoreRange{Min: 0, Max: 10, WithScore: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedMembers := []ZMember{{Score: 1, Key: "k1", Value: "v1"}, {Score: 2.5, Key: "k2", Value: "v2"}}
+	if !reflect.DeepEqual(members, expectedMembers) {
+		t.Fatalf("expected %v, got %v", expectedMembers, members)
+	}
+
+	results, err := c.VSearch(ctx, "vec", []float64{1, 2}, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedResults := []VectorResult{{ID: "id1", Vector: []float64{1, 2}}}
+	if !reflect.DeepEqual(results, expectedResults) {
+		t.Fatalf("expected %v, got %v", expectedResults, results)
+	}
+
+	_, err = c.Do(ctx, "NOPE")
+	var replyErr Error
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("expected an Error reply, got %v", err)
+	}
+}
+
+func TestVSearchResp3(t *testing.T) {
+	addr := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "VSEARCH":
+			row := resp.EncodeStringArrayRESP([]string{
+				resp.EncodeBulkString("id1"),
+				resp.EncodeDouble(0.5),
+				resp.EncodeStringArrayRESP([]string{resp.EncodeDouble(1), resp.EncodeDouble(2)}),
+			})
+			return resp.EncodeStringArrayRESP([]string{row})
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	c := New(Options{Addr: addr})
+	defer c.Close()
+
+	results, err := c.VSearch(context.Background(), "vec", []float64{1, 2}, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedResults := []VectorResult{{ID: "id1", Distance: 0.5, Vector: []float64{1, 2}}}
+	if !reflect.DeepEqual(results, expectedResults) {
+		t.Fatalf("expected %v, got %v", expectedResults, results)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	addr := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "SET":
+			return resp.EncodeSimpleString("OK")
+		case "GET":
+			return resp.EncodeNull()
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	c := New(Options{Addr: addr, PoolSize: 1})
+	defer c.Close()
+
+	p := c.Pipeline()
+	set := p.Do("SET", "k", "v")
+	get := p.Do("GET", "k")
+	bad := p.Do("BAD")
+	_, err := p.Exec(context.Background())
+	if err == nil {
+		t.Fatalf("expected the error of the failed command")
+	}
+	if s, err := set.String(); err != nil || s != "OK" {
+		t.Fatalf("expected OK, got %q, %v", s, err)
+	}
+	if _, err := get.String(); !errors.Is(err, ErrNil) {
+		t.Fatalf("expected ErrNil, got %v", err)
+	}
+	if bad.Err() == nil {
+		t.Fatalf("expected an error for BAD")
+	}
+	if p.Len() != 0 {
+		t.Fatalf("expected the pipeline to be emptied, got %d commands", p.Len())
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	addr := fakeServer(t, func(command string, args []string) string {
+		if command == "HELLO" {
+			return resp.EncodeError("unknown command")
+		}
+		// Never answer so the client has to give up on its own
+		return ""
+	})
+	c := New(Options{Addr: addr, PoolSize: 1})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := c.Do(ctx, "BLOCK")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// The connection is discarded so the single pool slot is free again
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.Do(ctx, "BLOCK")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	addr := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "PSUBSCRIBE":
+			return resp.EncodePush([]interface{}{"psubscribe", args[0], 1}) +
+				resp.EncodePush([]interface{}{"pmessage", args[0], args[0] + ":eu", "hello"})
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	c := New(Options{Addr: addr})
+	defer c.Close()
+
+	ps, err := c.PSubscribe(context.Background(), "billing")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer ps.Close()
+
+	select {
+	case message := <-ps.Channel():
+		expected := &Message{Pattern: "billing", Channel: "billing:eu", Payload: "hello"}
+		if !reflect.DeepEqual(message, expected) {
+			t.Fatalf("expected %v, got %v", expected, message)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for a message")
+	}
+}
+
+func TestAuth(t *testing.T) {
+	addr := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "AUTH":
+			if len(args) == 2 && args[0] == "billing" && args[1] == "secret" {
+				return resp.EncodeSimpleString("OK")
+			}
+			return resp.EncodeError("WRONGPASS invalid username-password pair or user is disabled")
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "PING":
+			return resp.EncodeSimpleString("PONG")
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	ctx := context.Background()
+
+	c := New(Options{Addr: addr, Username: "billing", Password: "secret"})
+	defer c.Close()
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	wrong := New(Options{Addr: addr, Username: "billing", Password: "wrong"})
+	defer wrong.Close()
+	var replyErr Error
+	if err := wrong.Ping(ctx); !errors.As(err, &replyErr) || !strings.HasPrefix(string(replyErr), "WRONGPASS") {
+		t.Fatalf("expected a WRONGPASS error, got %v", err)
+	}
+}
+
+func TestReadConsistency(t *testing.T) {
+	var consistency []string
+	addr := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "CONSISTENCY":
+			consistency = args
+			return resp.EncodeSimpleString("OK")
+		case "GET":
+			return resp.EncodeBulkString("value")
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	c := New(Options{Addr: addr, ReadConsistency: "stale maxlag 500"})
+	defer c.Close()
+
+	if _, err := c.Get(context.Background(), "key"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(consistency, []string{"stale", "maxlag", "500"}) {
+		t.Fatalf("expected CONSISTENCY to be sent on connect, got %v", consistency)
+	}
+}
+
+func TestMovedRedirect(t *testing.T) {
+	leader := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "SET":
+			return resp.EncodeSimpleString("OK")
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	follower := fakeServer(t, func(command string, args []string) string {
+		switch command {
+		case "HELLO":
+			return resp.EncodeStringMap([]string{"proto", "3"})
+		case "SET":
+			return resp.EncodeError("MOVED " + leader)
+		}
+		return resp.EncodeError("unexpected " + command)
+	})
+	c := New(Options{Addr: follower})
+	defer c.Close()
+
+	if err := c.Set(context.Background(), "key", "value"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.Addr() != leader {
+		t.Fatalf("expected the client to move to %s, got %s", leader, c.Addr())
+	}
+}
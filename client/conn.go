@@ -0,0 +1,262 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when a command is run on a closed client
+var ErrClosed = errors.New("treds: client is closed")
+
+// conn is a single connection to the server with its buffered reader and writer
+type conn struct {
+	addr     string
+	netConn  net.Conn
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	protocol int
+	usedAt   time.Time
+}
+
+func dial(ctx context.Context, opts *Options, addr string) (*conn, error) {
+	var netConn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: opts.DialTimeout}
+	if opts.TLSConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: opts.TLSConfig}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{
+		addr:     addr,
+		netConn:  netConn,
+		reader:   bufio.NewReader(netConn),
+		writer:   bufio.NewWriter(netConn),
+		protocol: 2,
+		usedAt:   time.Now(),
+	}
+	if opts.Password != "" {
+		args := []interface{}{"AUTH", opts.Password}
+		if opts.Username != "" {
+			args = []interface{}{"AUTH", opts.Username, opts.Password}
+		}
+		reply, err := cn.roundTrip(ctx, args)
+		if err == nil {
+			if replyErr, isErr := reply.(Error); isErr {
+				err = replyErr
+			}
+		}
+		if err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	if opts.Protocol == 3 {
+		// Fall back to RESP2 when the server does not know HELLO or RESP3
+		reply, err := cn.roundTrip(ctx, []interface{}{"HELLO", "3"})
+		if err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+		if _, isErr := reply.(Error); !isErr {
+			cn.protocol = 3
+		}
+	}
+	if opts.ReadConsistency != "" {
+		args := []interface{}{"CONSISTENCY"}
+		for _, field := range strings.Fields(opts.ReadConsistency) {
+			args = append(args, field)
+		}
+		reply, err := cn.roundTrip(ctx, args)
+		if err == nil {
+			if replyErr, isErr := reply.(Error); isErr {
+				err = replyErr
+			}
+		}
+		if err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// withContext runs fn with the connection deadline tied to ctx.
+// Cancelling ctx moves the deadline into the past so blocked reads and writes return at once.
+func (cn *conn) withContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	if err := cn.netConn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = cn.netConn.SetDeadline(time.Unix(1, 0))
+	})
+	err := fn()
+	stop()
+	if err != nil && hasDeadline && !time.Now().Before(deadline) {
+		// The connection deadline can expire just before the timer of ctx fires
+		<-ctx.Done()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// pipeline writes every command and then reads one reply per command
+func (cn *conn) pipeline(ctx context.Context, cmds [][]interface{}) ([]interface{}, error) {
+	replies := make([]interface{}, 0, len(cmds))
+	err := cn.withContext(ctx, func() error {
+		for _, args := range cmds {
+			if err := writeCommand(cn.writer, args); err != nil {
+				return err
+			}
+		}
+		if err := cn.writer.Flush(); err != nil {
+			return err
+		}
+		for len(replies) < len(cmds) {
+			reply, err := readReply(cn.reader)
+			if err != nil {
+				return err
+			}
+			// Pushes are never the answer to a command on a pooled connection
+			if _, isPush := reply.(Push); isPush {
+				continue
+			}
+			replies = append(replies, reply)
+		}
+		return nil
+	})
+	return replies, err
+}
+
+func (cn *conn) roundTrip(ctx context.Context, args []interface{}) (interface{}, error) {
+	replies, err := cn.pipeline(ctx, [][]interface{}{args})
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+func (cn *conn) close() error {
+	return cn.netConn.Close()
+}
+
+// pool keeps up to size connections open, idle connections are reused in LIFO order
+type pool struct {
+	opts   *Options
+	tokens chan struct{}
+
+	mu sync.Mutex
+	// addr is the server new connections are dialed to, Addr until a MOVED reply moves the pool
+	addr   string
+	idle   []*conn
+	closed bool
+}
+
+func newPool(opts *Options) *pool {
+	return &pool{
+		opts:   opts,
+		tokens: make(chan struct{}, opts.PoolSize),
+		addr:   opts.Addr,
+	}
+}
+
+// address returns the server new connections are dialed to
+func (p *pool) address() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.addr
+}
+
+// moveTo dials the new connections to addr and closes the idle ones, connections in use are closed when they are returned
+func (p *pool) moveTo(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.addr == addr {
+		return
+	}
+	p.addr = addr
+	for _, cn := range p.idle {
+		_ = cn.close()
+	}
+	p.idle = nil
+}
+
+// get returns an idle connection or dials a new one, waiting while the pool is exhausted
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case p.tokens <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.tokens
+		return nil, ErrClosed
+	}
+	for len(p.idle) > 0 {
+		cn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.opts.IdleTimeout > 0 && time.Since(cn.usedAt) > p.opts.IdleTimeout {
+			_ = cn.close()
+			continue
+		}
+		p.mu.Unlock()
+		return cn, nil
+	}
+	addr := p.addr
+	p.mu.Unlock()
+
+	cn, err := dial(ctx, p.opts, addr)
+	if err != nil {
+		<-p.tokens
+		return nil, fmt.Errorf("treds: dial %s: %w", addr, err)
+	}
+	return cn, nil
+}
+
+// put hands a connection back to the pool, broken connections are closed instead of reused
+func (p *pool) put(cn *conn, broken bool) {
+	defer func() { <-p.tokens }()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if broken || p.closed || cn.addr != p.addr {
+		_ = cn.close()
+		return
+	}
+	cn.usedAt = time.Now()
+	p.idle = append(p.idle, cn)
+}
+
+func (p *pool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var err error
+	for _, cn := range p.idle {
+		if closeErr := cn.close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	p.idle = nil
+	return err
+}
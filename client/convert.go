@@ -0,0 +1,130 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func toString(reply interface{}) (string, error) {
+	switch v := reply.(type) {
+	case nil:
+		return "", ErrNil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return formatFloat(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("treds: unexpected reply type %T for a string", reply)
+	}
+}
+
+func toInt(reply interface{}) (int64, error) {
+	switch v := reply.(type) {
+	case nil:
+		return 0, ErrNil
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("treds: unexpected reply type %T for an integer", reply)
+	}
+}
+
+func toFloat(reply interface{}) (float64, error) {
+	switch v := reply.(type) {
+	case nil:
+		return 0, ErrNil
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		if v == "" {
+			return 0, ErrNil
+		}
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("treds: unexpected reply type %T for a double", reply)
+	}
+}
+
+func toBool(reply interface{}) (bool, error) {
+	switch v := reply.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case string:
+		return strconv.ParseBool(v)
+	default:
+		return false, fmt.Errorf("treds: unexpected reply type %T for a boolean", reply)
+	}
+}
+
+// toStrings converts an array reply, null elements become empty strings
+func toStrings(reply interface{}) ([]string, error) {
+	if reply == nil {
+		return []string{}, nil
+	}
+	elements, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("treds: unexpected reply type %T for an array", reply)
+	}
+	result := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if element == nil {
+			result = append(result, "")
+			continue
+		}
+		s, err := toString(element)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// toStringMap converts a RESP3 map or a flattened RESP2 field value array
+func toStringMap(reply interface{}) (map[string]string, error) {
+	pairs, err := toStrings(reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("treds: odd number of elements in a map reply")
+	}
+	result := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		result[pairs[i]] = pairs[i+1]
+	}
+	return result, nil
+}
+
+// splitCursor separates the trailing cursor from the items of a scan reply
+func splitCursor(reply interface{}) ([]string, string, error) {
+	items, err := toStrings(reply)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items) == 0 {
+		return items, "0", nil
+	}
+	return items[:len(items)-1], items[len(items)-1], nil
+}
+
+func toOK(reply interface{}) error {
+	s, err := toString(reply)
+	if err != nil {
+		return err
+	}
+	if s != "OK" {
+		return fmt.Errorf("treds: unexpected reply %q", s)
+	}
+	return nil
+}
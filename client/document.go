@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Index describes a collection index over one or more fields, Type is "normal" or "unique"
+type Index struct {
+	Fields []string `json:"fields"`
+	Type   string   `json:"type,omitempty"`
+}
+
+// Query selects documents of a collection, it is sent to the server as JSON
+type Query struct {
+	Filters []QueryFilter `json:"filters,omitempty"`
+	Sort    []Sort        `json:"sort,omitempty"`
+	Limit   int           `json:"limit,omitempty"`
+	Offset  int           `json:"offset,omitempty"`
+}
+
+// QueryFilter compares Field against Value with Operator, for example "$gt", "$lt" or "$eq"
+type QueryFilter struct {
+	Field      string        `json:"field,omitempty"`
+	Operator   string        `json:"operator,omitempty"`
+	Value      interface{}   `json:"value,omitempty"`
+	SubFilters []QueryFilter `json:"subFilters,omitempty"`
+	Logical    string        `json:"logical,omitempty"`
+}
+
+// Sort orders query results by Field, Order is "asc" or "desc"
+type Sort struct {
+	Field string `json:"field"`
+	Order string `json:"order,omitempty"`
+}
+
+// DCreate creates a collection. The schema maps field names to their type and bounds,
+// for example {"age": {"type": "float", "min": 18}}, and may be nil.
+func (c *Client) DCreate(ctx context.Context, collection string, schema map[string]interface{}, indexes []Index) error {
+	schemaJSON := ""
+	if schema != nil {
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return err
+		}
+		schemaJSON = string(data)
+	}
+	indexJSON := ""
+	if len(indexes) > 0 {
+		data, err := json.Marshal(indexes)
+		if err != nil {
+			return err
+		}
+		indexJSON = string(data)
+	}
+	reply, err := c.Do(ctx, "DCREATE", collection, schemaJSON, indexJSON)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// DDrop drops a collection with all its documents
+func (c *Client) DDrop(ctx context.Context, collection string) error {
+	reply, err := c.Do(ctx, "DDROP", collection)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// DInsert marshals document to JSON, inserts it and returns the generated document id
+func (c *Client) DInsert(ctx context.Context, collection string, document interface{}) (string, error) {
+	data, err := marshalDocument(document)
+	if err != nil {
+		return "", err
+	}
+	reply, err := c.Do(ctx, "DINSERT", collection, data)
+	if err != nil {
+		return "", err
+	}
+	return toString(reply)
+}
+
+// DQuery returns the JSON of every matching document, the generated id is in the "_id" field
+func (c *Client) DQuery(ctx context.Context, collection string, query Query) ([]json.RawMessage, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := c.doStrings(ctx, "DQUERY", collection, data)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]json.RawMessage, 0, len(documents))
+	for _, document := range documents {
+		result = append(result, json.RawMessage(document))
+	}
+	return result, nil
+}
+
+// DExplain returns the plan the server would use for query, as JSON
+func (c *Client) DExplain(ctx context.Context, collection string, query Query) (json.RawMessage, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.Do(ctx, "DEXPLAIN", collection, data)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := toString(reply)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(plan), nil
+}
+
+// marshalDocument passes strings, byte slices and raw JSON through and marshals anything else
+func marshalDocument(document interface{}) ([]byte, error) {
+	switch v := document.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	default:
+		return json.Marshal(document)
+	}
+}
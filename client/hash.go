@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+)
+
+// KeysH returns hash keys matching regex, cursors work the same way as in ScanKeys
+func (c *Client) KeysH(ctx context.Context, cursor, regex string, count int) ([]string, string, error) {
+	reply, err := c.Do(ctx, scanArgs("KEYSH", cursor, regex, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitCursor(reply)
+}
+
+// HSet sets the fields of the hash at key
+func (c *Client) HSet(ctx context.Context, key string, fields map[string]string) error {
+	args := make([]interface{}, 0, 2+2*len(fields))
+	args = append(args, "HSET", key)
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// HGet returns the value of field in the hash at key, ErrNil if it is not set
+func (c *Client) HGet(ctx context.Context, key, field string) (string, error) {
+	reply, err := c.Do(ctx, "HGET", key, field)
+	if err != nil {
+		return "", err
+	}
+	return toString(reply)
+}
+
+// HGetAll returns every field and value of the hash at key
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	reply, err := c.Do(ctx, "HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	return toStringMap(reply)
+}
+
+// HLen returns the number of fields in the hash at key
+func (c *Client) HLen(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "HLEN", key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// HDel deletes fields from the hash at key
+func (c *Client) HDel(ctx context.Context, key string, fields ...string) error {
+	return c.doOK(ctx, "HDEL", key, fields)
+}
+
+// HExists reports whether field is set in the hash at key
+func (c *Client) HExists(ctx context.Context, key, field string) (bool, error) {
+	reply, err := c.Do(ctx, "HEXISTS", key, field)
+	if err != nil {
+		return false, err
+	}
+	return toBool(reply)
+}
+
+// HKeys returns every field of the hash at key
+func (c *Client) HKeys(ctx context.Context, key string) ([]string, error) {
+	return c.doStrings(ctx, "HKEYS", key)
+}
+
+// HVals returns every value of the hash at key
+func (c *Client) HVals(ctx context.Context, key string) ([]string, error) {
+	return c.doStrings(ctx, "HVALS", key)
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KeyValue is a key with its value
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Ping checks that the server is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	reply, err := c.Do(ctx, "PING")
+	if err != nil {
+		return err
+	}
+	s, err := toString(reply)
+	if err != nil {
+		return err
+	}
+	if s != "PONG" {
+		return fmt.Errorf("treds: unexpected reply %q", s)
+	}
+	return nil
+}
+
+// Get returns the value of key, ErrNil if the key does not exist
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return "", err
+	}
+	return toString(reply)
+}
+
+// Set stores value at key
+func (c *Client) Set(ctx context.Context, key, value string) error {
+	reply, err := c.Do(ctx, "SET", key, value)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// Del deletes key
+func (c *Client) Del(ctx context.Context, key string) error {
+	reply, err := c.Do(ctx, "DEL", key)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// MSet stores every key value pair
+func (c *Client) MSet(ctx context.Context, pairs ...KeyValue) error {
+	args := make([]interface{}, 0, 1+2*len(pairs))
+	args = append(args, "MSET")
+	for _, pair := range pairs {
+		args = append(args, pair.Key, pair.Value)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// MGet returns the values of the keys that exist, missing keys are left out of the map
+func (c *Client) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	args := make([]interface{}, 0, 1+len(keys))
+	args = append(args, "MGET")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != len(keys) {
+		return nil, fmt.Errorf("treds: unexpected MGET reply %v", reply)
+	}
+	result := make(map[string]string, len(keys))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		s, err := toString(value)
+		if err != nil {
+			return nil, err
+		}
+		result[keys[i]] = s
+	}
+	return result, nil
+}
+
+// DelPrefix deletes every key starting with prefix and returns how many were deleted
+func (c *Client) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	reply, err := c.Do(ctx, "DELPREFIX", prefix)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// LongestPrefix returns the key value pair whose key is the longest prefix of s, ErrNil if there is none
+func (c *Client) LongestPrefix(ctx context.Context, s string) (KeyValue, error) {
+	reply, err := c.Do(ctx, "LNGPREFIX", s)
+	if err != nil {
+		return KeyValue{}, err
+	}
+	pair, err := toStrings(reply)
+	if err != nil {
+		return KeyValue{}, err
+	}
+	if len(pair) < 2 {
+		return KeyValue{}, ErrNil
+	}
+	return KeyValue{Key: pair[0], Value: pair[1]}, nil
+}
+
+// DBSize returns the number of keys in the key value store
+func (c *Client) DBSize(ctx context.Context) (int64, error) {
+	reply, err := c.Do(ctx, "DBSIZE")
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// Expire deletes key after ttl, rounded down to whole seconds
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	reply, err := c.Do(ctx, "EXPIRE", key, int64(ttl/time.Second))
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// TTL returns the seconds left before key expires, -1 if it has no expiry and -2 if it does not exist
+func (c *Client) TTL(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "TTL", key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// FlushAll deletes every key in every store
+func (c *Client) FlushAll(ctx context.Context) error {
+	reply, err := c.Do(ctx, "FLUSHALL")
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// ScanKeys returns up to count keys starting with prefix, in lex order, after cursor.
+// Start with cursor "0" and pass the returned cursor to the next call, a returned cursor of "0" means the scan is over.
+// A count of zero or less returns every matching key.
+func (c *Client) ScanKeys(ctx context.Context, cursor, prefix string, count int) ([]string, string, error) {
+	reply, err := c.Do(ctx, scanArgs("SCANKEYS", cursor, prefix, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitCursor(reply)
+}
+
+// ScanKVS is ScanKeys returning the values along with the keys
+func (c *Client) ScanKVS(ctx context.Context, cursor, prefix string, count int) ([]KeyValue, string, error) {
+	reply, err := c.Do(ctx, scanArgs("SCANKVS", cursor, prefix, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitKVCursor(reply)
+}
+
+// Keys returns up to count keys matching the regular expression regex, in lex order, after cursor.
+// Cursors work the same way as in ScanKeys.
+func (c *Client) Keys(ctx context.Context, cursor, regex string, count int) ([]string, string, error) {
+	reply, err := c.Do(ctx, scanArgs("KEYS", cursor, regex, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitCursor(reply)
+}
+
+// KVS is Keys returning the values along with the keys
+func (c *Client) KVS(ctx context.Context, cursor, regex string, count int) ([]KeyValue, string, error) {
+	reply, err := c.Do(ctx, scanArgs("KVS", cursor, regex, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitKVCursor(reply)
+}
+
+func scanArgs(command, cursor, pattern string, count int) []interface{} {
+	args := []interface{}{command, cursor, pattern}
+	if count > 0 {
+		args = append(args, count)
+	}
+	return args
+}
+
+func splitKVCursor(reply interface{}) ([]KeyValue, string, error) {
+	items, cursor, err := splitCursor(reply)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items)%2 != 0 {
+		return nil, "", fmt.Errorf("treds: odd number of elements in a key value reply")
+	}
+	pairs := make([]KeyValue, 0, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		pairs = append(pairs, KeyValue{Key: items[i], Value: items[i+1]})
+	}
+	return pairs, cursor, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+)
+
+// KeysL returns list keys matching regex, cursors work the same way as in ScanKeys
+func (c *Client) KeysL(ctx context.Context, cursor, regex string, count int) ([]string, string, error) {
+	reply, err := c.Do(ctx, scanArgs("KEYSL", cursor, regex, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitCursor(reply)
+}
+
+// LPush adds elements to the left of the list at key
+func (c *Client) LPush(ctx context.Context, key string, elements ...string) error {
+	return c.doOK(ctx, "LPUSH", key, elements)
+}
+
+// RPush adds elements to the right of the list at key
+func (c *Client) RPush(ctx context.Context, key string, elements ...string) error {
+	return c.doOK(ctx, "RPUSH", key, elements)
+}
+
+// LPop removes and returns count elements from the left of the list at key
+func (c *Client) LPop(ctx context.Context, key string, count int) ([]string, error) {
+	return c.doStrings(ctx, "LPOP", key, count)
+}
+
+// RPop removes and returns count elements from the right of the list at key
+func (c *Client) RPop(ctx context.Context, key string, count int) ([]string, error) {
+	return c.doStrings(ctx, "RPOP", key, count)
+}
+
+// LRem removes the element at index of the list at key
+func (c *Client) LRem(ctx context.Context, key string, index int) error {
+	reply, err := c.Do(ctx, "LREM", key, index)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// LSet replaces the element at index of the list at key
+func (c *Client) LSet(ctx context.Context, key string, index int, element string) error {
+	reply, err := c.Do(ctx, "LSET", key, index, element)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// LRange returns the elements from start to stop inclusive of the list at key
+func (c *Client) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	return c.doStrings(ctx, "LRANGE", key, start, stop)
+}
+
+// LLen returns the length of the list at key
+func (c *Client) LLen(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "LLEN", key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// LIndex returns the element at index of the list at key
+func (c *Client) LIndex(ctx context.Context, key string, index int) (string, error) {
+	reply, err := c.Do(ctx, "LINDEX", key, index)
+	if err != nil {
+		return "", err
+	}
+	return toString(reply)
+}
+
+// doOK runs command key values... and expects an OK reply
+func (c *Client) doOK(ctx context.Context, command, key string, values []string) error {
+	args := make([]interface{}, 0, 2+len(values))
+	args = append(args, command, key)
+	for _, value := range values {
+		args = append(args, value)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// doStrings runs a command that replies with a list of strings
+func (c *Client) doStrings(ctx context.Context, args ...interface{}) ([]string, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return toStrings(reply)
+}
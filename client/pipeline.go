@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+)
+
+// Cmd is a command queued on a Pipeline, its reply is available once the pipeline is executed
+type Cmd struct {
+	args []interface{}
+	val  interface{}
+	err  error
+}
+
+// Args returns the command and its arguments
+func (cmd *Cmd) Args() []interface{} {
+	return cmd.args
+}
+
+// Result returns the decoded reply, error replies are returned as an Error
+func (cmd *Cmd) Result() (interface{}, error) {
+	return cmd.val, cmd.err
+}
+
+// Err returns the error of the command, if any
+func (cmd *Cmd) Err() error {
+	return cmd.err
+}
+
+// String returns the reply as a string, ErrNil for a null reply
+func (cmd *Cmd) String() (string, error) {
+	if cmd.err != nil {
+		return "", cmd.err
+	}
+	return toString(cmd.val)
+}
+
+// Int returns the reply as an integer
+func (cmd *Cmd) Int() (int64, error) {
+	if cmd.err != nil {
+		return 0, cmd.err
+	}
+	return toInt(cmd.val)
+}
+
+// Float returns the reply as a double
+func (cmd *Cmd) Float() (float64, error) {
+	if cmd.err != nil {
+		return 0, cmd.err
+	}
+	return toFloat(cmd.val)
+}
+
+// Strings returns the reply as a list of strings
+func (cmd *Cmd) Strings() ([]string, error) {
+	if cmd.err != nil {
+		return nil, cmd.err
+	}
+	return toStrings(cmd.val)
+}
+
+// Pipeline queues commands and sends them in a single round trip on one connection.
+// A Pipeline is not safe for concurrent use.
+type Pipeline struct {
+	client *Client
+	cmds   []*Cmd
+}
+
+// Pipeline returns an empty pipeline that runs on c
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// Do queues a raw command
+func (p *Pipeline) Do(args ...interface{}) *Cmd {
+	cmd := &Cmd{args: args}
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Len returns the number of queued commands
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends every queued command and fills in their replies, the queue is emptied either way.
+// The returned error is the first failed command's error, or the network error that aborted the pipeline.
+func (p *Pipeline) Exec(ctx context.Context) ([]*Cmd, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return cmds, nil
+	}
+
+	args := make([][]interface{}, 0, len(cmds))
+	for _, cmd := range cmds {
+		args = append(args, cmd.args)
+	}
+	replies, err := p.client.doPipeline(ctx, args)
+	if err != nil {
+		for _, cmd := range cmds {
+			cmd.err = err
+		}
+		return cmds, err
+	}
+
+	var firstErr error
+	for i, cmd := range cmds {
+		if replyErr, isErr := replies[i].(Error); isErr {
+			cmd.err = replyErr
+			if firstErr == nil {
+				firstErr = replyErr
+			}
+			continue
+		}
+		cmd.val = replies[i]
+	}
+	return cmds, firstErr
+}
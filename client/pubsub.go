@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Message is a message delivered to a PubSub.
+// Pattern is the prefix passed to PSUBSCRIBE for messages sent with PPUBLISH and empty otherwise.
+type Message struct {
+	Channel string
+	Pattern string
+	Payload string
+}
+
+// Publish sends message to subscribers of channel and returns how many received it
+func (c *Client) Publish(ctx context.Context, channel, message string) (int64, error) {
+	reply, err := c.Do(ctx, "PUBLISH", channel, message)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// PPublish sends message to every channel whose name is a prefix of channel and returns how many subscribers received it
+func (c *Client) PPublish(ctx context.Context, channel, message string) (int64, error) {
+	reply, err := c.Do(ctx, "PPUBLISH", channel, message)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// PubSubChannels returns the active channels starting with prefix
+func (c *Client) PubSubChannels(ctx context.Context, prefix string) ([]string, error) {
+	return c.doStrings(ctx, "PUBSUBCHANNELS", prefix)
+}
+
+// Subscribe opens a dedicated connection subscribed to channels
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return c.newPubSub(ctx, "SUBSCRIBE", channels)
+}
+
+// PSubscribe opens a dedicated connection that receives PPUBLISH messages sent to prefixes of channels
+func (c *Client) PSubscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return c.newPubSub(ctx, "PSUBSCRIBE", channels)
+}
+
+// PubSub is a connection in subscribe mode, it is not taken from the pool.
+// Messages are delivered on the channel returned by Channel until Close is called or the connection fails.
+type PubSub struct {
+	cn       *conn
+	messages chan *Message
+
+	mu  sync.Mutex
+	err error
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *Client) newPubSub(ctx context.Context, command string, channels []string) (*PubSub, error) {
+	addr := c.pool.address()
+	cn, err := dial(ctx, &c.opts, addr)
+	if err != nil {
+		return nil, fmt.Errorf("treds: dial %s: %w", addr, err)
+	}
+	// Wait for the subscription to be confirmed so no message published after this call returns is missed
+	err = cn.withContext(ctx, func() error {
+		if err := writeCommand(cn.writer, keysArgs(command, channels)); err != nil {
+			return err
+		}
+		if err := cn.writer.Flush(); err != nil {
+			return err
+		}
+		reply, err := readReply(cn.reader)
+		if err != nil {
+			return err
+		}
+		if replyErr, isErr := reply.(Error); isErr {
+			return replyErr
+		}
+		return nil
+	})
+	if err == nil {
+		// Clear the deadline ctx may have set, the subscription outlives the call
+		err = cn.netConn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		_ = cn.close()
+		return nil, err
+	}
+	ps := &PubSub{
+		cn:       cn,
+		messages: make(chan *Message, 100),
+		done:     make(chan struct{}),
+	}
+	go ps.receive()
+	return ps, nil
+}
+
+// Channel returns the channel messages are delivered on, it is closed when the PubSub stops
+func (ps *PubSub) Channel() <-chan *Message {
+	return ps.messages
+}
+
+// Err returns the error that stopped the PubSub, nil after Close
+func (ps *PubSub) Err() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.err
+}
+
+// Subscribe adds channels to the subscription
+func (ps *PubSub) Subscribe(channels ...string) error {
+	return ps.send("SUBSCRIBE", channels)
+}
+
+// PSubscribe adds channel prefixes to the subscription
+func (ps *PubSub) PSubscribe(channels ...string) error {
+	return ps.send("PSUBSCRIBE", channels)
+}
+
+// Unsubscribe removes channels from the subscription
+func (ps *PubSub) Unsubscribe(channels ...string) error {
+	return ps.send("UNSUBSCRIBE", channels)
+}
+
+// PUnsubscribe removes channel prefixes from the subscription
+func (ps *PubSub) PUnsubscribe(channels ...string) error {
+	return ps.send("PUNSUBSCRIBE", channels)
+}
+
+// Close closes the connection and the message channel
+func (ps *PubSub) Close() error {
+	var err error
+	ps.closeOnce.Do(func() {
+		close(ps.done)
+		err = ps.cn.close()
+	})
+	return err
+}
+
+// send writes a command, its confirmation is consumed by receive
+func (ps *PubSub) send(command string, channels []string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if err := writeCommand(ps.cn.writer, keysArgs(command, channels)); err != nil {
+		return err
+	}
+	return ps.cn.writer.Flush()
+}
+
+func (ps *PubSub) receive() {
+	defer close(ps.messages)
+	for {
+		reply, err := readReply(ps.cn.reader)
+		if err != nil {
+			select {
+			case <-ps.done:
+			default:
+				ps.mu.Lock()
+				ps.err = err
+				ps.mu.Unlock()
+			}
+			return
+		}
+		message := toMessage(reply)
+		if message == nil {
+			continue
+		}
+		select {
+		case ps.messages <- message:
+		case <-ps.done:
+			return
+		}
+	}
+}
+
+// toMessage converts a message or pmessage delivery, subscription confirmations and other replies return nil
+func toMessage(reply interface{}) *Message {
+	var fields []interface{}
+	switch v := reply.(type) {
+	case Push:
+		fields = v
+	case []interface{}:
+		fields = v
+	default:
+		return nil
+	}
+	items, err := toStrings(fields)
+	if err != nil || len(items) < 3 {
+		return nil
+	}
+	switch items[0] {
+	case "message":
+		return &Message{Channel: items[len(items)-2], Payload: items[len(items)-1]}
+	case "pmessage":
+		if len(items) < 4 {
+			return nil
+		}
+		return &Message{Pattern: items[1], Channel: items[2], Payload: items[3]}
+	default:
+		return nil
+	}
+}
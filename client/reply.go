@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"treds/resp"
+)
+
+// ErrNil is returned when the server replies with a null, for example a GET on a missing key
+var ErrNil = errors.New("treds: nil")
+
+// Error is an error reply sent by the server. The connection stays usable after it.
+type Error = resp.ErrorReply
+
+// Push is an out of band RESP3 push message such as a pub/sub delivery
+type Push = resp.Push
+
+// readReply reads a single reply, see resp.ReadReply for the Go types replies are decoded to
+func readReply(r *bufio.Reader) (interface{}, error) {
+	return resp.ReadReply(r)
+}
+
+// writeCommand encodes args as a RESP array of bulk strings
+func writeCommand(w *bufio.Writer, args []interface{}) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		value := formatArg(arg)
+		if _, err := fmt.Fprintf(w, "$%d\r\n", len(value)); err != nil {
+			return err
+		}
+		if _, err := w.WriteString(value); err != nil {
+			return err
+		}
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return formatFloat(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// ServerInfo is the reply to HELLO
+type ServerInfo struct {
+	Server string
+	Proto  int64
+	ID     string
+	Mode   string
+	// Role is "master" on the Raft leader and "replica" on followers
+	Role string
+	// Leader is the client address of the current leader, empty while there is none
+	Leader string
+}
+
+// CommandInfo describes a command registered on the server
+type CommandInfo struct {
+	Name string
+	// Args is the argument hint, for example "key offset count withscore min max"
+	Args string
+	// Kind is "read", "write" or "server"
+	Kind string
+}
+
+// Hello returns the server information without changing the protocol of the connection
+func (c *Client) Hello(ctx context.Context) (ServerInfo, error) {
+	reply, err := c.Do(ctx, "HELLO")
+	if err != nil {
+		return ServerInfo{}, err
+	}
+	fields, ok := reply.([]interface{})
+	if !ok || len(fields)%2 != 0 {
+		return ServerInfo{}, fmt.Errorf("treds: unexpected HELLO reply %v", reply)
+	}
+	var info ServerInfo
+	for i := 0; i < len(fields); i += 2 {
+		name, _ := fields[i].(string)
+		switch name {
+		case "proto":
+			info.Proto, _ = toInt(fields[i+1])
+		case "server":
+			info.Server, _ = toString(fields[i+1])
+		case "id":
+			info.ID, _ = toString(fields[i+1])
+		case "mode":
+			info.Mode, _ = toString(fields[i+1])
+		case "role":
+			info.Role, _ = toString(fields[i+1])
+		case "leader":
+			info.Leader, _ = toString(fields[i+1])
+		}
+	}
+	return info, nil
+}
+
+// Commands returns every command registered on the server
+func (c *Client) Commands(ctx context.Context) ([]CommandInfo, error) {
+	reply, err := c.Do(ctx, "COMMAND")
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("treds: unexpected COMMAND reply %v", reply)
+	}
+	infos := make([]CommandInfo, 0, len(rows))
+	for _, row := range rows {
+		fields, err := toStrings(row)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("treds: unexpected COMMAND entry %v", fields)
+		}
+		infos = append(infos, CommandInfo{Name: fields[0], Args: fields[1], Kind: fields[2]})
+	}
+	return infos, nil
+}
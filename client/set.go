@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+)
+
+// KeysS returns set keys matching regex, cursors work the same way as in ScanKeys
+func (c *Client) KeysS(ctx context.Context, cursor, regex string, count int) ([]string, string, error) {
+	reply, err := c.Do(ctx, scanArgs("KEYSS", cursor, regex, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitCursor(reply)
+}
+
+// SAdd adds members to the set at key
+func (c *Client) SAdd(ctx context.Context, key string, members ...string) error {
+	return c.doOK(ctx, "SADD", key, members)
+}
+
+// SRem removes members from the set at key
+func (c *Client) SRem(ctx context.Context, key string, members ...string) error {
+	return c.doOK(ctx, "SREM", key, members)
+}
+
+// SMembers returns every member of the set at key
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
+	return c.doStrings(ctx, "SMEMBERS", key)
+}
+
+// SIsMember reports whether member is in the set at key
+func (c *Client) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	reply, err := c.Do(ctx, "SISMEMBER", key, member)
+	if err != nil {
+		return false, err
+	}
+	return toBool(reply)
+}
+
+// SCard returns the size of the set at key
+func (c *Client) SCard(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "SCARD", key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// SUnion returns the union of the sets at keys
+func (c *Client) SUnion(ctx context.Context, keys ...string) ([]string, error) {
+	return c.doStrings(ctx, keysArgs("SUNION", keys)...)
+}
+
+// SInter returns the intersection of the sets at keys
+func (c *Client) SInter(ctx context.Context, keys ...string) ([]string, error) {
+	return c.doStrings(ctx, keysArgs("SINTER", keys)...)
+}
+
+// SDiff returns the members of the first set that are in none of the others
+func (c *Client) SDiff(ctx context.Context, keys ...string) ([]string, error) {
+	return c.doStrings(ctx, keysArgs("SDIFF", keys)...)
+}
+
+func keysArgs(command string, keys []string) []interface{} {
+	args := make([]interface{}, 0, 1+len(keys))
+	args = append(args, command)
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	return args
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ZMember is a member of a sorted map, Value is empty for the *Keys range commands
+// and Score is zero unless the range asked for scores
+type ZMember struct {
+	Score float64
+	Key   string
+	Value string
+}
+
+// LexRange selects sorted map members whose keys are between Min and Max inclusive, in lex order.
+// Offset members are skipped and at most Count are returned, a Count of zero or less returns every match.
+type LexRange struct {
+	Min       string
+	Max       string
+	Offset    int
+	Count     int
+	WithScore bool
+}
+
+// ScoreRange selects sorted map members whose scores are between Min and Max inclusive, in score order.
+// Offset members are skipped and at most Count are returned, a Count of zero or less returns every match.
+type ScoreRange struct {
+	Min       float64
+	Max       float64
+	Offset    int
+	Count     int
+	WithScore bool
+}
+
+// ZAdd adds members to the sorted map at key, replacing the score and value of existing member keys
+func (c *Client) ZAdd(ctx context.Context, key string, members ...ZMember) error {
+	args := make([]interface{}, 0, 2+3*len(members))
+	args = append(args, "ZADD", key)
+	for _, member := range members {
+		args = append(args, member.Score, member.Key, member.Value)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// ZRem removes members from the sorted map at key
+func (c *Client) ZRem(ctx context.Context, key string, members ...string) error {
+	args := make([]interface{}, 0, 2+len(members))
+	args = append(args, "ZREM", key)
+	for _, member := range members {
+		args = append(args, member)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// ZCard returns the number of members in the sorted map at key
+func (c *Client) ZCard(ctx context.Context, key string) (int64, error) {
+	reply, err := c.Do(ctx, "ZCARD", key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// ZScore returns the score of member in the sorted map at key, ErrNil if it is not a member
+func (c *Client) ZScore(ctx context.Context, key, member string) (float64, error) {
+	reply, err := c.Do(ctx, "ZSCORE", key, member)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat(reply)
+}
+
+// KeysZ returns sorted map keys matching regex, cursors work the same way as in ScanKeys
+func (c *Client) KeysZ(ctx context.Context, cursor, regex string, count int) ([]string, string, error) {
+	reply, err := c.Do(ctx, scanArgs("KEYSZ", cursor, regex, count)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return splitCursor(reply)
+}
+
+// ZRangeLexKeys returns the member keys in r in lex order
+func (c *Client) ZRangeLexKeys(ctx context.Context, key string, r LexRange) ([]ZMember, error) {
+	return c.zRangeLex(ctx, "ZRANGELEXKEYS", key, r, false)
+}
+
+// ZRangeLexKVS returns the members in r in lex order
+func (c *Client) ZRangeLexKVS(ctx context.Context, key string, r LexRange) ([]ZMember, error) {
+	return c.zRangeLex(ctx, "ZRANGELEXKVS", key, r, true)
+}
+
+// ZRevRangeLexKeys returns the member keys in r in reverse lex order
+func (c *Client) ZRevRangeLexKeys(ctx context.Context, key string, r LexRange) ([]ZMember, error) {
+	return c.zRangeLex(ctx, "ZREVRANGELEXKEYS", key, r, false)
+}
+
+// ZRevRangeLexKVS returns the members in r in reverse lex order
+func (c *Client) ZRevRangeLexKVS(ctx context.Context, key string, r LexRange) ([]ZMember, error) {
+	return c.zRangeLex(ctx, "ZREVRANGELEXKVS", key, r, true)
+}
+
+// ZRangeScoreKeys returns the member keys in r in score order
+func (c *Client) ZRangeScoreKeys(ctx context.Context, key string, r ScoreRange) ([]ZMember, error) {
+	return c.zRangeScore(ctx, "ZRANGESCOREKEYS", key, r, false)
+}
+
+// ZRangeScoreKVS returns the members in r in score order
+func (c *Client) ZRangeScoreKVS(ctx context.Context, key string, r ScoreRange) ([]ZMember, error) {
+	return c.zRangeScore(ctx, "ZRANGESCOREKVS", key, r, true)
+}
+
+// ZRevRangeScoreKeys returns the member keys in r in reverse score order
+func (c *Client) ZRevRangeScoreKeys(ctx context.Context, key string, r ScoreRange) ([]ZMember, error) {
+	return c.zRangeScore(ctx, "ZREVRANGESCOREKEYS", key, r, false)
+}
+
+// ZRevRangeScoreKVS returns the members in r in reverse score order
+func (c *Client) ZRevRangeScoreKVS(ctx context.Context, key string, r ScoreRange) ([]ZMember, error) {
+	return c.zRangeScore(ctx, "ZREVRANGESCOREKVS", key, r, true)
+}
+
+func (c *Client) zRangeLex(ctx context.Context, command, key string, r LexRange, withValue bool) ([]ZMember, error) {
+	reply, err := c.Do(ctx, command, key, r.Offset, rangeCount(r.Count), r.WithScore, r.Min, r.Max)
+	if err != nil {
+		return nil, err
+	}
+	return toZMembers(reply, r.WithScore, withValue)
+}
+
+func (c *Client) zRangeScore(ctx context.Context, command, key string, r ScoreRange, withValue bool) ([]ZMember, error) {
+	reply, err := c.Do(ctx, command, key, r.Min, r.Max, r.Offset, rangeCount(r.Count), r.WithScore)
+	if err != nil {
+		return nil, err
+	}
+	return toZMembers(reply, r.WithScore, withValue)
+}
+
+func rangeCount(count int) int64 {
+	if count <= 0 {
+		return math.MaxInt64
+	}
+	return int64(count)
+}
+
+// toZMembers groups a flat range reply into members, each row is [score] key [value]
+func toZMembers(reply interface{}, withScore, withValue bool) ([]ZMember, error) {
+	items, err := toStrings(reply)
+	if err != nil {
+		return nil, err
+	}
+	width := 1
+	if withScore {
+		width++
+	}
+	if withValue {
+		width++
+	}
+	if len(items)%width != 0 {
+		return nil, fmt.Errorf("treds: range reply has %d elements, expected a multiple of %d", len(items), width)
+	}
+	members := make([]ZMember, 0, len(items)/width)
+	for i := 0; i < len(items); i += width {
+		row := items[i : i+width]
+		var member ZMember
+		if withScore {
+			member.Score, err = strconv.ParseFloat(row[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			row = row[1:]
+		}
+		member.Key = row[0]
+		if withValue {
+			member.Value = row[1]
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
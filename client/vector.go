@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// VectorResult is a neighbour found by VSearch
+type VectorResult struct {
+	ID string
+	// Distance to the searched vector, only sent over RESP3
+	Distance float64
+	Vector   []float64
+}
+
+// VCreate creates an HNSW vector index with the given graph parameters
+func (c *Client) VCreate(ctx context.Context, name string, maxNeighbors int, levelFactor float64, efSearch int) error {
+	reply, err := c.Do(ctx, "VCREATE", name, maxNeighbors, levelFactor, efSearch)
+	if err != nil {
+		return err
+	}
+	return toOK(reply)
+}
+
+// VInsert adds vector to the index and returns its generated id
+func (c *Client) VInsert(ctx context.Context, name string, vector []float64) (string, error) {
+	args := make([]interface{}, 0, 2+len(vector))
+	args = append(args, "VINSERT", name)
+	for _, component := range vector {
+		args = append(args, component)
+	}
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return toString(reply)
+}
+
+// VSearch returns the k nearest neighbours of vector, closest first
+func (c *Client) VSearch(ctx context.Context, name string, vector []float64, k int) ([]VectorResult, error) {
+	args := make([]interface{}, 0, 3+len(vector))
+	args = append(args, "VSEARCH", name)
+	for _, component := range vector {
+		args = append(args, component)
+	}
+	args = append(args, k)
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := reply.([]interface{})
+	if !ok && reply != nil {
+		return nil, fmt.Errorf("treds: unexpected reply type %T for VSEARCH", reply)
+	}
+	results := make([]VectorResult, 0, len(rows))
+	for _, row := range rows {
+		// RESP2 rows are the id and then the vector components,
+		// RESP3 rows are the id, the distance and the array of the vector components
+		fields, ok := row.([]interface{})
+		if !ok || len(fields) < 1 {
+			return nil, fmt.Errorf("treds: unexpected VSEARCH row %v", row)
+		}
+		var result VectorResult
+		if result.ID, err = toString(fields[0]); err != nil {
+			return nil, err
+		}
+		components := fields[1:]
+		if len(fields) == 3 {
+			if nested, isArray := fields[2].([]interface{}); isArray {
+				if result.Distance, err = toFloat(fields[1]); err != nil {
+					return nil, err
+				}
+				components = nested
+			}
+		}
+		result.Vector = make([]float64, 0, len(components))
+		for _, field := range components {
+			component, err := toFloat(field)
+			if err != nil {
+				return nil, err
+			}
+			result.Vector = append(result.Vector, component)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// VDelete removes the vector with id from the index and reports whether it existed
+func (c *Client) VDelete(ctx context.Context, name, id string) (bool, error) {
+	reply, err := c.Do(ctx, "VDELETE", name, id)
+	if err != nil {
+		return false, err
+	}
+	status, err := toString(reply)
+	if err != nil {
+		return false, err
+	}
+	return status == "OK", nil
+}
@@ -12,9 +12,11 @@ const DCreateCollection = "DCREATE"
 func RegisterDCreateCollection(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DCreateCollection,
+		Args:     "collectionname schemajson indexjson",
 		Validate: validateDCreateCollection(),
 		Execute:  executeDCreateCollection(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -12,9 +12,11 @@ const DDropCollection = "DDROP"
 func RegisterDDropCollection(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DDropCollection,
+		Args:     "collectionname",
 		Validate: validateDDropCollection(),
 		Execute:  executeDDropCollection(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -12,9 +12,11 @@ const DeleteCommand = "DEL"
 func RegisterDeleteCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DeleteCommand,
+		Args:     "key",
 		Validate: validateDel(),
 		Execute:  executeDel(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
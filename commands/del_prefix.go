@@ -12,9 +12,11 @@ const DeletePrefixCommand = "DELPREFIX"
 func RegisterDeletePrefixCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DeletePrefixCommand,
+		Args:     "prefix",
 		Validate: validateDeletePrefix(),
 		Execute:  executeDeletePrefix(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -12,8 +12,10 @@ const DExplain = "DEXPLAIN"
 func RegisterDExplainCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DExplain,
+		Args:     "collectionname json",
 		Validate: validateDExplainCommand(),
 		Execute:  executeDExplainCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
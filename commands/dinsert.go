@@ -2,25 +2,33 @@ package commands
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/google/uuid"
 	"treds/resp"
 	"treds/store"
 )
 
 const DInsert = "DINSERT"
 
+// documentIdOption carries the id generated by the leader in the Raft log
+const documentIdOption = "ID"
+
 func RegisterDInsertCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DInsert,
+		Args:     "collectionname json",
 		Validate: validateDInsertCommand(),
 		Execute:  executeDInsertCommand(),
+		Prepare:  prepareDInsertCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
 func validateDInsertCommand() ValidationHook {
 	return func(args []string) error {
-		if len(args) < 2 {
+		if len(args) != 2 {
 			return fmt.Errorf("expected 2 argument, got %d", len(args))
 		}
 
@@ -28,12 +36,28 @@ func validateDInsertCommand() ValidationHook {
 	}
 }
 
+func prepareDInsertCommand() PrepareHook {
+	return func(args []string, _ store.Store) ([]string, error) {
+		return append(args, documentIdOption, uuid.New().String()), nil
+	}
+}
+
 func executeDInsertCommand() ExecutionHook {
 	return func(args []string, store store.Store) string {
-		res, err := store.DInsert(args)
+		storeArgs := args[:2]
+		// Entries written before DINSERT was prepared on the leader do not carry the id, the store generates one
+		if len(args) == 4 && strings.EqualFold(args[2], documentIdOption) {
+			storeArgs = []string{args[0], args[1], args[3]}
+		}
+		res, err := store.DInsert(storeArgs)
 		if err != nil {
 			return resp.EncodeError(err.Error())
 		}
 		return resp.EncodeBulkString(res)
 	}
 }
+
+// DInsertArgs returns the command of a log entry that inserts data into collection with the given id, like the ones DINSERT is prepared into
+func DInsertArgs(collection, data, id string) []string {
+	return []string{DInsert, collection, data, documentIdOption, id}
+}
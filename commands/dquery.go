@@ -12,8 +12,10 @@ const DQuery = "DQUERY"
 func RegisterDQueryCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     DQuery,
+		Args:     "collectionname json",
 		Validate: validateDQueryCommand(),
 		Execute:  executeDQueryCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"treds/resp"
@@ -11,25 +12,34 @@ import (
 
 const ExpireCommand = "EXPIRE"
 
+// expireAtOption carries the time the leader received EXPIRE, in unix milliseconds, in the Raft log
+const expireAtOption = "AT"
+
 func RegisterExpireCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ExpireCommand,
+		Args:     "key seconds",
 		Validate: validateExpireCommand(),
 		Execute:  executeExpireCommand(),
+		Prepare:  prepareExpireCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
 func validateExpireCommand() ValidationHook {
 	return func(args []string) error {
 		if len(args) != 2 {
-			_, err := strconv.Atoi(args[1])
-			if err != nil {
-				return err
-			}
-			return fmt.Errorf("expected 1 argument, got %d", len(args))
+			return fmt.Errorf("expected 2 argument, got %d", len(args))
 		}
-		return nil
+		_, err := strconv.Atoi(args[1])
+		return err
+	}
+}
+
+func prepareExpireCommand() PrepareHook {
+	return func(args []string, _ store.Store) ([]string, error) {
+		return append(args, expireAtOption, strconv.FormatInt(time.Now().UnixMilli(), 10)), nil
 	}
 }
 
@@ -38,6 +48,14 @@ func executeExpireCommand() ExecutionHook {
 		key := args[0]
 		seconds, _ := strconv.Atoi(args[1])
 		now := time.Now()
+		// Entries written before EXPIRE was prepared on the leader do not carry the time
+		if len(args) == 4 && strings.EqualFold(args[2], expireAtOption) {
+			millis, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil {
+				return resp.EncodeError(err.Error())
+			}
+			now = time.UnixMilli(millis)
+		}
 		expiryTime := now.Add(time.Duration(seconds) * time.Second)
 		err := store.Expire(key, expiryTime)
 		if err != nil {
@@ -46,3 +64,8 @@ func executeExpireCommand() ExecutionHook {
 		return resp.EncodeSimpleString("OK")
 	}
 }
+
+// ExpireAtArgs returns the command of a log entry that expires key at the given time, like the ones EXPIRE is prepared into
+func ExpireAtArgs(key string, at time.Time) []string {
+	return []string{ExpireCommand, key, "0", expireAtOption, strconv.FormatInt(at.UnixMilli(), 10)}
+}
@@ -13,6 +13,8 @@ func RegisterFlushAllCommand(r CommandRegistry) {
 		Validate: validateDBSize(),
 		Execute:  executeFlushAll(),
 		IsWrite:  true,
+		Category: CategoryAdmin,
+		Keys:     AllKeys,
 	})
 }
 
@@ -11,9 +11,12 @@ const GetCommand = "GET"
 
 func RegisterGetCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     GetCommand,
-		Validate: validateGet(),
-		Execute:  executeGet(),
+		Name:         GetCommand,
+		Args:         "key",
+		Validate:     validateGet(),
+		Execute:      executeGet(),
+		Resp3Execute: executeGetResp3(),
+		Keys:         KeyAt(0),
 	})
 }
 
@@ -36,3 +39,16 @@ func executeGet() ExecutionHook {
 		return resp.EncodeBulkString(res)
 	}
 }
+
+func executeGetResp3() ExecutionHook {
+	return func(args []string, s store.Store) string {
+		res, err := s.Get(args[0])
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		if res == store.NilResp {
+			return resp.EncodeNull()
+		}
+		return resp.EncodeBulkString(res)
+	}
+}
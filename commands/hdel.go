@@ -12,9 +12,11 @@ const HDelCommand = "HDEL"
 func RegisterHDelCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     HDelCommand,
+		Args:     "key field [field ...]",
 		Validate: validateHDelCommand(),
 		Execute:  executeHDelCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
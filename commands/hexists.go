@@ -12,8 +12,10 @@ const HExistsCommand = "HEXISTS"
 func RegisterHExistsCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     HExistsCommand,
+		Args:     "key field",
 		Validate: validateHExistsCommand(),
 		Execute:  executeHExistsCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
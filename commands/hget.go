@@ -11,9 +11,12 @@ const HGetCommand = "HGET"
 
 func RegisterHGetCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     HGetCommand,
-		Validate: validateHGetCommand(),
-		Execute:  executeHGetCommand(),
+		Name:         HGetCommand,
+		Args:         "key field",
+		Validate:     validateHGetCommand(),
+		Execute:      executeHGetCommand(),
+		Resp3Execute: executeHGetCommandResp3(),
+		Keys:         KeyAt(0),
 	})
 }
 
@@ -37,3 +40,17 @@ func executeHGetCommand() ExecutionHook {
 		return resp.EncodeBulkString(res)
 	}
 }
+
+func executeHGetCommandResp3() ExecutionHook {
+	return func(args []string, s store.Store) string {
+		key := args[0]
+		res, err := s.HGet(key, args[1])
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		if res == store.NilResp {
+			return resp.EncodeNull()
+		}
+		return resp.EncodeBulkString(res)
+	}
+}
@@ -11,9 +11,12 @@ const HGetAllCommand = "HGETALL"
 
 func RegisterHGetAllCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     HGetAllCommand,
-		Validate: validateHGetAllCommand(),
-		Execute:  executeHGetAllCommand(),
+		Name:         HGetAllCommand,
+		Args:         "key",
+		Validate:     validateHGetAllCommand(),
+		Execute:      executeHGetAllCommand(),
+		Resp3Execute: executeHGetAllCommandResp3(),
+		Keys:         KeyAt(0),
 	})
 }
 
@@ -37,3 +40,14 @@ func executeHGetAllCommand() ExecutionHook {
 		return resp.EncodeStringArray(res)
 	}
 }
+
+func executeHGetAllCommandResp3() ExecutionHook {
+	return func(args []string, store store.Store) string {
+		key := args[0]
+		res, err := store.HGetAll(key)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		return resp.EncodeStringMap(res)
+	}
+}
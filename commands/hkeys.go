@@ -12,8 +12,10 @@ const HKeysCommand = "HKEYS"
 func RegisterHKeysCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     HKeysCommand,
+		Args:     "key",
 		Validate: validateHKeysCommand(),
 		Execute:  executeHKeysCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -12,8 +12,10 @@ const HLenCommand = "HLEN"
 func RegisterHLenCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     HLenCommand,
+		Args:     "key",
 		Validate: validateHLenCommand(),
 		Execute:  executeHLenCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
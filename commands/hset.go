@@ -12,9 +12,11 @@ const HSetCommand = "HSET"
 func RegisterHSetCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     HSetCommand,
+		Args:     "key field value [field value ...]",
 		Validate: validateHSetCommand(),
 		Execute:  executeHSetCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
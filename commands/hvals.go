@@ -12,8 +12,10 @@ const HValsCommand = "HVALS"
 func RegisterHValsCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     HValsCommand,
+		Args:     "key",
 		Validate: validateHValsCommand(),
 		Execute:  executeHValsCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -15,8 +15,10 @@ const KeysCommand = "KEYS"
 func RegisterKeysCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     KeysCommand,
+		Args:     "cursor regex [count]",
 		Validate: validateKeys(),
 		Execute:  executeKeys(),
+		Keys:     AllKeys,
 	})
 }
 
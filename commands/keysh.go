@@ -15,8 +15,10 @@ const KeysHCommand = "KEYSH"
 func RegisterKeysHCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     KeysHCommand,
+		Args:     "cursor regex [count]",
 		Validate: validateKeysH(),
 		Execute:  executeKeysH(),
+		Keys:     AllKeys,
 	})
 }
 
@@ -15,8 +15,10 @@ const KeysLCommand = "KEYSL"
 func RegisterKeysLCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     KeysLCommand,
+		Args:     "cursor regex [count]",
 		Validate: validateKeysL(),
 		Execute:  executeKeysL(),
+		Keys:     AllKeys,
 	})
 }
 
@@ -15,8 +15,10 @@ const KeysSCommand = "KEYSS"
 func RegisterKeysSCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     KeysSCommand,
+		Args:     "cursor regex [count]",
 		Validate: validateKeysS(),
 		Execute:  executeKeysS(),
+		Keys:     AllKeys,
 	})
 }
 
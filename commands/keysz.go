@@ -15,8 +15,10 @@ const KeysZCommand = "KEYSZ"
 func RegisterKeysZCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     KeysZCommand,
+		Args:     "cursor regex [count]",
 		Validate: validateKeysZ(),
 		Execute:  executeKeysZ(),
+		Keys:     AllKeys,
 	})
 }
 
@@ -15,8 +15,10 @@ const KVSCommand = "KVS"
 func RegisterKVSCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     KVSCommand,
+		Args:     "cursor regex [count]",
 		Validate: validateKVS(),
 		Execute:  executeKVS(),
+		Keys:     AllKeys,
 	})
 }
 
@@ -12,8 +12,10 @@ const LIndexCommand = "LINDEX"
 func RegisterLIndexCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LIndexCommand,
+		Args:     "key index",
 		Validate: validateLIndexCommand(),
 		Execute:  executeLIndexCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -12,8 +12,10 @@ const LLenCommand = "LLEN"
 func RegisterLLenCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LLenCommand,
+		Args:     "key",
 		Validate: validateLLenCommand(),
 		Execute:  executeLLenCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -10,8 +10,10 @@ const LongestPrefixCommand = "LNGPREFIX"
 func RegisterLongestPrefixCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LongestPrefixCommand,
+		Args:     "string",
 		Validate: validateDeletePrefix(),
 		Execute:  executeLongestPrefixCommand(),
+		Keys:     AllKeys,
 	})
 }
 
@@ -13,9 +13,11 @@ const LPopCommand = "LPOP"
 func RegisterLPopCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LPopCommand,
+		Args:     "key count",
 		Validate: validateLPopCommand(),
 		Execute:  executeLPopCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -12,9 +12,11 @@ const LPushCommand = "LPUSH"
 func RegisterLPushCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LPushCommand,
+		Args:     "key element [element ...]",
 		Validate: validateLPushCommand(),
 		Execute:  executeLPushCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -13,8 +13,10 @@ const LRangeCommand = "LRANGE"
 func RegisterLRangeCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LRangeCommand,
+		Args:     "key start stop",
 		Validate: validateLRangeCommand(),
 		Execute:  executeLRangeCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
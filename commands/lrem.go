@@ -13,9 +13,11 @@ const LRemCommand = "LREM"
 func RegisterLRemCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LRemCommand,
+		Args:     "key index",
 		Validate: validateLRemCommand(),
 		Execute:  executeLRemCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -14,9 +14,11 @@ const LSetCommand = "LSET"
 func RegisterLSetCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     LSetCommand,
+		Args:     "key index element",
 		Validate: validateLSetCommand(),
 		Execute:  executeLSetCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -11,9 +11,12 @@ const MGetCommand = "MGET"
 
 func RegisterMGetCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     MGetCommand,
-		Validate: validateMGet(),
-		Execute:  executeMGet(),
+		Name:         MGetCommand,
+		Args:         "key [key ...]",
+		Validate:     validateMGet(),
+		Execute:      executeMGet(),
+		Resp3Execute: executeMGetResp3(),
+		Keys:         KeysFrom(0, 1),
 	})
 }
 
@@ -35,3 +38,21 @@ func executeMGet() ExecutionHook {
 		return resp.EncodeStringArray(res)
 	}
 }
+
+func executeMGetResp3() ExecutionHook {
+	return func(args []string, s store.Store) string {
+		res, err := s.MGet(args)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		values := make([]string, 0, len(res))
+		for _, value := range res {
+			if value == store.NilResp {
+				values = append(values, resp.EncodeNull())
+				continue
+			}
+			values = append(values, resp.EncodeBulkString(value))
+		}
+		return resp.EncodeStringArrayRESP(values)
+	}
+}
@@ -2,11 +2,14 @@ package commands
 
 import (
 	"errors"
+	"io"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"treds/store"
 )
 
 // MockStore is a mock implementation of the store interface for testing.
@@ -361,11 +364,11 @@ func (rs *MockStore) LongestPrefix(key string) ([]string, error) {
 	return nil, nil
 }
 
-func (rs *MockStore) Snapshot() ([]byte, error) {
+func (rs *MockStore) Snapshot() (*store.PointInTimeSnapshot, error) {
 	return nil, nil
 }
 
-func (rs *MockStore) Restore(data []byte) error {
+func (rs *MockStore) Restore(r io.Reader) error {
 	return nil
 }
 
@@ -393,11 +396,15 @@ func (rs *MockStore) VCreate(args []string) error {
 	return nil
 }
 
+func (rs *MockStore) VNextNode(vectorName string) (string, int, error) {
+	return "", 0, nil
+}
+
 func (rs *MockStore) VInsert(args []string) (string, error) {
 	return "", nil
 }
 
-func (rs *MockStore) VSearch(args []string) ([][]string, error) {
+func (rs *MockStore) VSearch(args []string) ([]store.VectorSearchResult, error) {
 	return nil, nil
 }
 
@@ -12,9 +12,11 @@ const MSETCommand = "MSET"
 func RegisterMSetCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     MSETCommand,
+		Args:     "key value [key value ...]",
 		Validate: validateMSet(),
 		Execute:  executeMSet(),
 		IsWrite:  true,
+		Keys:     KeysFrom(0, 2),
 	})
 }
 
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"treds/store"
@@ -10,6 +11,7 @@ import (
 type CommandRegistry interface {
 	Add(*CommandRegistration) error
 	Retrieve(string) (*CommandRegistration, error)
+	List() []*CommandRegistration
 }
 
 type commandRegistry struct {
@@ -19,11 +21,96 @@ type commandRegistry struct {
 type ValidationHook func(args []string) error
 type ExecutionHook func(args []string, store store.Store) string
 
+// ProtocolExecutionHook runs a command once and returns its reply encoded for RESP2 and for RESP3
+type ProtocolExecutionHook func(args []string, store store.Store) (resp2 string, resp3 string)
+
+// PrepareHook returns the arguments a write is appended to the Raft log with, it runs on the leader after Validate
+// and no command is applied or read meanwhile
+type PrepareHook func(args []string, store store.Store) ([]string, error)
+
 type CommandRegistration struct {
-	Name     string
+	Name string
+	// Args describes the arguments after the command name, it is served by COMMAND for client hints
+	Args     string
 	Validate ValidationHook
 	Execute  ExecutionHook
-	IsWrite  bool
+	// Resp3Execute is used instead of Execute for connections that switched to RESP3 with HELLO.
+	// It is only needed when the reply has a native RESP3 type, like a map, set, double or null.
+	// Writes are applied without knowing the protocol of the client, they set ProtocolExecute instead.
+	Resp3Execute ExecutionHook
+	// ProtocolExecute is used instead of Execute when a write whose reply has native RESP3 types is applied,
+	// the node serving the client picks the encoding of its protocol
+	ProtocolExecute ProtocolExecutionHook
+	IsWrite         bool
+	// Prepare resolves the values a write depends on besides its arguments, like generated ids, random draws or the clock.
+	// Execute runs on every replica and on every replay of the log, so it must only use what Prepare put in the arguments.
+	Prepare PrepareHook
+	// Category overrides the read or write ACL category implied by IsWrite
+	Category string
+	// Keys locates the keys in the arguments for ACL checks, nil for commands that do not touch keys
+	Keys *KeySpec
+}
+
+// ACL categories, a user is only allowed the commands of the categories it is granted
+const (
+	CategoryRead   = "read"
+	CategoryWrite  = "write"
+	CategoryAdmin  = "admin"
+	CategoryPubSub = "pubsub"
+	// CategoryConnection holds commands that only change the state of the connection, every user can run them
+	CategoryConnection = "connection"
+)
+
+// ACLCategory returns the ACL category of the command
+func (r *CommandRegistration) ACLCategory() string {
+	if r.Category != "" {
+		return r.Category
+	}
+	if r.IsWrite {
+		return CategoryWrite
+	}
+	return CategoryRead
+}
+
+// KeySpec tells which arguments of a command are keys, from First to Last moving by Step.
+// A negative Last counts from the end of the arguments, -1 is the last argument.
+// Prefix arguments, like the one of SCANKEYS, are keys as well since every key they reach starts with them.
+type KeySpec struct {
+	First int
+	Last  int
+	Step  int
+	// All is set for commands that can reach any key, like the regex scans, only users allowed every key can run them
+	All bool
+}
+
+// AllKeys is the KeySpec of commands that can reach any key
+var AllKeys = &KeySpec{All: true}
+
+// KeyAt is the KeySpec of a command with a single key at index
+func KeyAt(index int) *KeySpec {
+	return &KeySpec{First: index, Last: index, Step: 1}
+}
+
+// KeysFrom is the KeySpec of a command whose arguments from index to the end are keys, step apart
+func KeysFrom(index, step int) *KeySpec {
+	return &KeySpec{First: index, Last: -1, Step: step}
+}
+
+// Keys returns the keys found in args, missing arguments are left for the validation of the command
+func (k *KeySpec) Keys(args []string) []string {
+	last := k.Last
+	if last < 0 {
+		last = len(args) + last
+	}
+	step := k.Step
+	if step <= 0 {
+		step = 1
+	}
+	keys := make([]string, 0)
+	for i := k.First; i <= last && i < len(args); i += step {
+		keys = append(keys, args[i])
+	}
+	return keys
 }
 
 func NewRegistry() CommandRegistry {
@@ -48,3 +135,15 @@ func (c *commandRegistry) Retrieve(name string) (*CommandRegistration, error) {
 
 	return c.commands[strings.ToUpper(name)], nil
 }
+
+// List returns every registered command sorted by name
+func (c *commandRegistry) List() []*CommandRegistration {
+	registrations := make([]*CommandRegistration, 0, len(c.commands))
+	for _, reg := range c.commands {
+		registrations = append(registrations, reg)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
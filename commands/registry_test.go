@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestKeySpecKeys tests that KeySpec finds the keys in the arguments of a command.
+func TestKeySpecKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     *KeySpec
+		args     []string
+		expected []string
+	}{
+		{"single key", KeyAt(0), []string{"key", "value"}, []string{"key"}},
+		{"scan prefix", KeyAt(1), []string{"0", "billing:", "10"}, []string{"billing:"}},
+		{"every argument", KeysFrom(0, 1), []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"key value pairs", KeysFrom(0, 2), []string{"a", "1", "b", "2"}, []string{"a", "b"}},
+		{"missing arguments", KeyAt(1), []string{"0"}, []string{}},
+		{"no arguments", KeysFrom(0, 1), []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := tt.spec.Keys(tt.args)
+			if !reflect.DeepEqual(keys, tt.expected) {
+				t.Errorf("expected keys %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
+
+// TestACLCategory tests the ACL category derived from a registration.
+func TestACLCategory(t *testing.T) {
+	registry := NewRegistry()
+	RegisterCommands(registry)
+
+	expected := map[string]string{
+		GetCommand: CategoryRead,
+		SetCommand: CategoryWrite,
+		FlushAll:   CategoryAdmin,
+	}
+	for name, category := range expected {
+		reg, err := registry.Retrieve(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reg.ACLCategory() != category {
+			t.Errorf("expected %s to be in category %s, got %s", name, category, reg.ACLCategory())
+		}
+	}
+}
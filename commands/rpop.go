@@ -12,9 +12,11 @@ const RPopCommand = "RPOP"
 func RegisterRPopCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     RPopCommand,
+		Args:     "key count",
 		Validate: validateLPopCommand(),
 		Execute:  executeRPopCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -10,9 +10,11 @@ const RPushCommand = "RPUSH"
 func RegisterRPushCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     RPushCommand,
+		Args:     "key element [element ...]",
 		Validate: validateLPushCommand(),
 		Execute:  executeRPushCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
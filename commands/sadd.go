@@ -12,9 +12,11 @@ const SAddCommand = "SADD"
 func RegisterSAddCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     SAddCommand,
+		Args:     "key member [member ...]",
 		Validate: validateSAddCommand(),
 		Execute:  executeSAddCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
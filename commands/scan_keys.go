@@ -14,8 +14,10 @@ const PrefixScanKeysCommand = "SCANKEYS"
 func RegisterScanKeysCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     PrefixScanKeysCommand,
+		Args:     "cursor prefix [count]",
 		Validate: validatePrefixScanKeys(),
 		Execute:  executePrefixScanKeys(),
+		Keys:     KeyAt(1),
 	})
 }
 
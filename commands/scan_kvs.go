@@ -14,8 +14,10 @@ const PrefixScanCommand = "SCANKVS"
 func RegisterScanKVSCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     PrefixScanCommand,
+		Args:     "cursor prefix [count]",
 		Validate: validatePrefixScan(),
 		Execute:  executePrefixScan(),
+		Keys:     KeyAt(1),
 	})
 }
 
@@ -12,8 +12,10 @@ const SCardCommand = "SCARD"
 func RegisterSCardCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     SCardCommand,
+		Args:     "key",
 		Validate: validateSCardCommand(),
 		Execute:  executeSCardCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
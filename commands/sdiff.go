@@ -11,9 +11,12 @@ const SDiffCommand = "SDIFF"
 
 func RegisterSDiffCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     SDiffCommand,
-		Validate: validateSDiffCommand(),
-		Execute:  executeSDiffCommand(),
+		Name:         SDiffCommand,
+		Args:         "key [key ...]",
+		Validate:     validateSDiffCommand(),
+		Execute:      executeSDiffCommand(),
+		Resp3Execute: executeSDiffCommandResp3(),
+		Keys:         KeysFrom(0, 1),
 	})
 }
 
@@ -36,3 +39,13 @@ func executeSDiffCommand() ExecutionHook {
 		return resp.EncodeStringArray(res)
 	}
 }
+
+func executeSDiffCommandResp3() ExecutionHook {
+	return func(args []string, store store.Store) string {
+		res, err := store.SDiff(args)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		return resp.EncodeStringSet(res)
+	}
+}
@@ -14,9 +14,11 @@ const SetCommand = "SET"
 func RegisterSetCommand(r CommandRegistry) {
 	err := r.Add(&CommandRegistration{
 		Name:     SetCommand,
+		Args:     "key value",
 		Validate: validateSet(),
 		Execute:  executeSet(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 	if err != nil {
 		log.Fatal(err)
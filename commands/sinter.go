@@ -11,9 +11,12 @@ const SInterCommand = "SINTER"
 
 func RegisterSInterCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     SInterCommand,
-		Validate: validateSInterCommand(),
-		Execute:  executeSInterCommand(),
+		Name:         SInterCommand,
+		Args:         "key [key ...]",
+		Validate:     validateSInterCommand(),
+		Execute:      executeSInterCommand(),
+		Resp3Execute: executeSInterCommandResp3(),
+		Keys:         KeysFrom(0, 1),
 	})
 }
 
@@ -36,3 +39,13 @@ func executeSInterCommand() ExecutionHook {
 		return resp.EncodeStringArray(res)
 	}
 }
+
+func executeSInterCommandResp3() ExecutionHook {
+	return func(args []string, store store.Store) string {
+		res, err := store.SInter(args)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		return resp.EncodeStringSet(res)
+	}
+}
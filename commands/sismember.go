@@ -13,8 +13,10 @@ const SIsMember = "SISMEMBER"
 func RegisterSIsMemberCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     SIsMember,
+		Args:     "key member",
 		Validate: validateSIsMemberCommand(),
 		Execute:  executeSIsMemberCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
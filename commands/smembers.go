@@ -11,9 +11,12 @@ const SMembersCommand = "SMEMBERS"
 
 func RegisterSMembersCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     SMembersCommand,
-		Validate: validateSMembersCommand(),
-		Execute:  executeSMembersCommand(),
+		Name:         SMembersCommand,
+		Args:         "key",
+		Validate:     validateSMembersCommand(),
+		Execute:      executeSMembersCommand(),
+		Resp3Execute: executeSMembersCommandResp3(),
+		Keys:         KeyAt(0),
 	})
 }
 
@@ -37,3 +40,14 @@ func executeSMembersCommand() ExecutionHook {
 		return resp.EncodeStringArray(res)
 	}
 }
+
+func executeSMembersCommandResp3() ExecutionHook {
+	return func(args []string, store store.Store) string {
+		key := args[0]
+		res, err := store.SMembers(key)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		return resp.EncodeStringSet(res)
+	}
+}
@@ -10,9 +10,11 @@ const SRemCommand = "SREM"
 func RegisterSRemCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     SRemCommand,
+		Args:     "key member [member ...]",
 		Validate: validateSAddCommand(),
 		Execute:  executeSRemCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -11,9 +11,12 @@ const SUnionCommand = "SUNION"
 
 func RegisterSUnionCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     SUnionCommand,
-		Validate: validateSUnionCommand(),
-		Execute:  executeSUnionCommand(),
+		Name:         SUnionCommand,
+		Args:         "key [key ...]",
+		Validate:     validateSUnionCommand(),
+		Execute:      executeSUnionCommand(),
+		Resp3Execute: executeSUnionCommandResp3(),
+		Keys:         KeysFrom(0, 1),
 	})
 }
 
@@ -36,3 +39,13 @@ func executeSUnionCommand() ExecutionHook {
 		return resp.EncodeStringArray(res)
 	}
 }
+
+func executeSUnionCommandResp3() ExecutionHook {
+	return func(args []string, store store.Store) string {
+		res, err := store.SUnion(args)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		return resp.EncodeStringSet(res)
+	}
+}
@@ -12,8 +12,10 @@ const TTLCommand = "TTL"
 func RegisterTtlCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     TTLCommand,
+		Args:     "key",
 		Validate: validateTtlCommand(),
 		Execute:  executeTtlCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
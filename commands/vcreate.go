@@ -10,9 +10,11 @@ const VCreate = "VCREATE"
 func RegisterVCreate(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     VCreate,
+		Args:     "vectorname [maxNeighbor] [levelFactor] [efSearch]",
 		Validate: validateVCreate(),
 		Execute:  executeVCreate(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
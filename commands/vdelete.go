@@ -12,9 +12,11 @@ const VDelete = "VDELETE"
 func RegisterVDelete(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     VDelete,
+		Args:     "vectorname id",
 		Validate: validateVDelete(),
 		Execute:  executeVDelete(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
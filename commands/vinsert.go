@@ -2,6 +2,8 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"treds/resp"
 	"treds/store"
@@ -9,12 +11,21 @@ import (
 
 const VInsert = "VINSERT"
 
+// Options carrying the node id and level drawn by the leader in the Raft log
+const (
+	vectorIdOption    = "ID"
+	vectorLevelOption = "LEVEL"
+)
+
 func RegisterVInsert(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     VInsert,
+		Args:     "vectorname float [float...]",
 		Validate: validateVInsert(),
 		Execute:  executeVInsert(),
+		Prepare:  prepareVInsert(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -23,16 +34,53 @@ func validateVInsert() ValidationHook {
 		if len(args) < 2 {
 			return fmt.Errorf("expected minimum 2 argument, got %d", len(args))
 		}
+		for _, data := range args[1:] {
+			if _, err := strconv.ParseFloat(data, 64); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 }
 
+func prepareVInsert() PrepareHook {
+	return func(args []string, store store.Store) ([]string, error) {
+		id, level, err := store.VNextNode(args[0])
+		if err != nil {
+			return nil, err
+		}
+		prepared := []string{args[0], vectorIdOption, id, vectorLevelOption, strconv.Itoa(level)}
+		return append(prepared, args[1:]...), nil
+	}
+}
+
 func executeVInsert() ExecutionHook {
 	return func(args []string, store store.Store) string {
-		id, err := store.VInsert(args)
+		var storeArgs []string
+		if len(args) > 5 && strings.EqualFold(args[1], vectorIdOption) && strings.EqualFold(args[3], vectorLevelOption) {
+			storeArgs = append([]string{args[0], args[2], args[4]}, args[5:]...)
+		} else {
+			// Entries written before VINSERT was prepared on the leader draw the id and level here
+			id, level, err := store.VNextNode(args[0])
+			if err != nil {
+				return resp.EncodeError(err.Error())
+			}
+			storeArgs = append([]string{args[0], id, strconv.Itoa(level)}, args[1:]...)
+		}
+		id, err := store.VInsert(storeArgs)
 		if err != nil {
 			return resp.EncodeError(err.Error())
 		}
 		return resp.EncodeSimpleString(id)
 	}
 }
+
+// VInsertArgs returns the command of a log entry that inserts vector into the vector store with the given id and level,
+// like the ones VINSERT is prepared into
+func VInsertArgs(vectorName, id string, level int, vector []float64) []string {
+	args := []string{VInsert, vectorName, vectorIdOption, id, vectorLevelOption, strconv.Itoa(level)}
+	for _, value := range vector {
+		args = append(args, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	return args
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
 
 	"treds/resp"
 	"treds/store"
@@ -11,10 +12,15 @@ const VSearch = "VSEARCH"
 
 func RegisterVSearch(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     VSearch,
-		Validate: validateVSearch(),
-		Execute:  executeVSearch(),
-		IsWrite:  true,
+		Name:            VSearch,
+		Args:            "vectorname float [float...] k",
+		Validate:        validateVSearch(),
+		Execute:         executeVSearch(),
+		ProtocolExecute: executeVSearchProtocols(),
+		IsWrite:         true,
+		// Searching does not change the vector store, users allowed to read can search
+		Category: CategoryRead,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -33,6 +39,49 @@ func executeVSearch() ExecutionHook {
 		if err != nil {
 			return resp.EncodeError(err.Error())
 		}
-		return resp.Encode2DStringArrayRESP(result)
+		return encodeVSearch(result)
 	}
 }
+
+// executeVSearchProtocols searches once and encodes the result for both protocols
+func executeVSearchProtocols() ProtocolExecutionHook {
+	return func(args []string, store store.Store) (string, string) {
+		result, err := store.VSearch(args)
+		if err != nil {
+			return resp.EncodeError(err.Error()), resp.EncodeError(err.Error())
+		}
+		return encodeVSearch(result), encodeVSearchResp3(result)
+	}
+}
+
+// encodeVSearch encodes every row as the vector id followed by the vector components
+func encodeVSearch(result []store.VectorSearchResult) string {
+	rows := make([][]string, 0, len(result))
+	for _, found := range result {
+		row := make([]string, 0, 1+len(found.Vector))
+		row = append(row, found.ID)
+		for _, value := range found.Vector {
+			row = append(row, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		rows = append(rows, row)
+	}
+	return resp.Encode2DStringArrayRESP(rows)
+}
+
+// encodeVSearchResp3 encodes every row as the vector id, its distance to the searched vector and the array
+// of the vector components, the distance and the components are doubles
+func encodeVSearchResp3(result []store.VectorSearchResult) string {
+	rows := make([]string, 0, len(result))
+	for _, found := range result {
+		components := make([]string, 0, len(found.Vector))
+		for _, value := range found.Vector {
+			components = append(components, resp.EncodeDouble(value))
+		}
+		rows = append(rows, resp.EncodeStringArrayRESP([]string{
+			resp.EncodeBulkString(found.ID),
+			resp.EncodeDouble(found.Distance),
+			resp.EncodeStringArrayRESP(components),
+		}))
+	}
+	return resp.EncodeStringArrayRESP(rows)
+}
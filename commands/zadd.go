@@ -12,9 +12,11 @@ const ZAddCommand = "ZADD"
 func RegisterZAddCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZAddCommand,
+		Args:     "key score member_key member_value [score member_key member_value ...]",
 		Validate: validateZAddCommand(),
 		Execute:  executeZAddCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
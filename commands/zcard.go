@@ -12,8 +12,10 @@ const ZCardCommand = "ZCARD"
 func RegisterZCardCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZCardCommand,
+		Args:     "key",
 		Validate: validateZCard(),
 		Execute:  executeZCardCommand(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -13,8 +13,10 @@ const ZRANGELEXKEYS = "ZRANGELEXKEYS"
 func RegisterZRangeLexKeysCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZRANGELEXKEYS,
+		Args:     "key offset count withscore min max",
 		Validate: validateZRangeLex(),
 		Execute:  executeZRangeLexKeys(),
+		Keys:     KeyAt(0),
 	})
 }
 
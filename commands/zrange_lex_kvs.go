@@ -14,8 +14,10 @@ const ZRANGELEXKVS = "ZRANGELEXKVS"
 func RegisterZRangeLexCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZRANGELEXKVS,
+		Args:     "key offset count withscore min max",
 		Validate: validateZRangeLex(),
 		Execute:  executeZRangeLex(),
+		Keys:     KeyAt(0),
 	})
 }
 
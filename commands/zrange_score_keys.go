@@ -14,8 +14,10 @@ const ZRANGESCOREKEYS = "ZRANGESCOREKEYS"
 func RegisterZRangeScoreCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZRANGESCOREKEYS,
+		Args:     "key min max offset count withscore",
 		Validate: validateZRangeScore(),
 		Execute:  executeZRangeScoreKeys(),
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -13,8 +13,10 @@ const ZRANGESCOREKVS = "ZRANGESCOREKVS"
 func RegisterZRangeScoreKVSCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZRANGESCOREKVS,
+		Args:     "key min max offset count withscore",
 		Validate: validateZRangeScore(),
 		Execute:  executeZRangeScoreKVS(),
+		Keys:     KeyAt(0),
 	})
 }
 
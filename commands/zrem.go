@@ -12,9 +12,11 @@ const ZRemCommand = "ZREM"
 func RegisterZRemCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZRemCommand,
+		Args:     "key member [member ...]",
 		Validate: validateZRem(),
 		Execute:  executeZRemCommand(),
 		IsWrite:  true,
+		Keys:     KeyAt(0),
 	})
 }
 
@@ -13,8 +13,10 @@ const ZREVRANGELEXKEYS = "ZREVRANGELEXKEYS"
 func RegisterZRevRangeLexKeysCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZREVRANGELEXKEYS,
+		Args:     "key offset count withscore min max",
 		Validate: validateZRangeLex(),
 		Execute:  executeZRevRangeLexKeys(),
+		Keys:     KeyAt(0),
 	})
 }
 
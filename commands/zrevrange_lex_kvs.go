@@ -13,8 +13,10 @@ const ZREVRANGELEXKVS = "ZREVRANGELEXKVS"
 func RegisterZRevRangeLexKVSCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZREVRANGELEXKVS,
+		Args:     "key offset count withscore min max",
 		Validate: validateZRangeLex(),
 		Execute:  executeZRevRangeLexKVS(),
+		Keys:     KeyAt(0),
 	})
 }
 
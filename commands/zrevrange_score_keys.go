@@ -13,8 +13,10 @@ const ZREVRANGESCOREKEYS = "ZREVRANGESCOREKEYS"
 func RegisterZRevRangeScoreCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZREVRANGESCOREKEYS,
+		Args:     "key min max offset count withscore",
 		Validate: validateZRangeScore(),
 		Execute:  executeZRevRangeScoreKeys(),
+		Keys:     KeyAt(0),
 	})
 }
 
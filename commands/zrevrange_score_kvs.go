@@ -13,8 +13,10 @@ const ZREVRANGESCOREKVS = "ZREVRANGESCOREKVS"
 func RegisterZRevRangeScoreKVSCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
 		Name:     ZREVRANGESCOREKVS,
+		Args:     "key min max offset count withscore",
 		Validate: validateZRangeScore(),
 		Execute:  executeZRevRangeScoreKVS(),
+		Keys:     KeyAt(0),
 	})
 }
 
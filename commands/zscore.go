@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
 
 	"treds/resp"
 	"treds/store"
@@ -11,9 +12,12 @@ const ZScoreCommand = "ZSCORE"
 
 func RegisterZScoreCommand(r CommandRegistry) {
 	r.Add(&CommandRegistration{
-		Name:     ZScoreCommand,
-		Validate: validateZScore(),
-		Execute:  executeZScoreCommand(),
+		Name:         ZScoreCommand,
+		Args:         "key member",
+		Validate:     validateZScore(),
+		Execute:      executeZScoreCommand(),
+		Resp3Execute: executeZScoreCommandResp3(),
+		Keys:         KeyAt(0),
 	})
 }
 
@@ -35,3 +39,20 @@ func executeZScoreCommand() ExecutionHook {
 		return resp.EncodeBulkString(res)
 	}
 }
+
+func executeZScoreCommandResp3() ExecutionHook {
+	return func(args []string, store store.Store) string {
+		res, err := store.ZScore(args)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		if res == "" {
+			return resp.EncodeNull()
+		}
+		score, err := strconv.ParseFloat(res, 64)
+		if err != nil {
+			return resp.EncodeError(err.Error())
+		}
+		return resp.EncodeDouble(score)
+	}
+}
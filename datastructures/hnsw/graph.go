@@ -92,13 +92,48 @@ func (h *HNSW) randomLevel() int {
 	return mx
 }
 
+// Clone returns a copy of the graph that can be changed without changing h. The vectors of the nodes are never changed
+// and are shared, the copy draws from the same random number generator.
+func (h *HNSW) Clone() *HNSW {
+	clone := *h
+	// Every layer of a node holds the same node pointer, so does the copy
+	nodes := make(map[string]*Node)
+	clone.Layers = make([]*GraphLayer, 0, len(h.Layers))
+	for _, layer := range h.Layers {
+		clonedLayer := &GraphLayer{Nodes: make(map[string]*Node, len(layer.Nodes))}
+		for id, node := range layer.Nodes {
+			clonedNode, ok := nodes[id]
+			if !ok {
+				clonedNode = &Node{ID: node.ID, Layer: node.Layer, Neighbors: maps.Clone(node.Neighbors), Value: node.Value}
+				nodes[id] = clonedNode
+			}
+			clonedLayer.Nodes[id] = clonedNode
+		}
+		clone.Layers = append(clone.Layers, clonedLayer)
+	}
+	if h.EntryPoint != nil {
+		clone.EntryPoint = nodes[h.EntryPoint.ID]
+	}
+	return &clone
+}
+
+// NextNode draws the id and the level of a new node without inserting it.
+// Replicated graphs draw them once and pass them to InsertNode, so every copy of the graph ends up with the same shape.
+func (h *HNSW) NextNode() (string, int) {
+	return h.generateID(), h.randomLevel()
+}
+
 // Insert adds a new element `vector` into the HNSW graph.
 func (h *HNSW) Insert(vector Vector) string {
-	level := h.randomLevel()
+	id, level := h.NextNode()
+	return h.InsertNode(id, level, vector)
+}
 
+// InsertNode adds `vector` into the graph with the given id, its highest layer is `level`.
+func (h *HNSW) InsertNode(id string, level int, vector Vector) string {
 	// Create the new node
 	node := &Node{
-		ID:        h.generateID(),
+		ID:        id,
 		Value:     vector,
 		Neighbors: make(map[string]float64),
 		Layer:     level, // "highest" layer for the node
@@ -253,8 +288,8 @@ func (h *HNSW) searchLayer(entryPoint *Node, target Vector, ef int, layer int) [
 			continue
 		}
 
-		// Check neighbors
-		for neighborID := range currentNode.Neighbors {
+		// Check neighbors, in order of their ids so that ties are broken the same way on every replica
+		for _, neighborID := range sortedIDs(currentNode.Neighbors) {
 			if visited[neighborID] {
 				continue
 			}
@@ -304,7 +339,7 @@ func (h *HNSW) selectNeighborsHeuristic(q *Node, candidates []string, M int) []s
 
 	// Sort ascending by distance
 	sort.Slice(candidateDistances, func(i, j int) bool {
-		return candidateDistances[i].Distance < candidateDistances[j].Distance
+		return closer(candidateDistances[i], candidateDistances[j])
 	})
 
 	// Take top M
@@ -353,7 +388,7 @@ func (h *HNSW) Topography() []int {
 // isolateNode, replenishNode, delete, etc. can remain if you need them for other operations:
 
 func (h *HNSW) isolateNode(node *Node, layer int) {
-	for neighborID := range node.Neighbors {
+	for _, neighborID := range sortedIDs(node.Neighbors) {
 		neighbor := h.Layers[layer].Nodes[neighborID]
 		if neighbor == nil {
 			continue
@@ -405,7 +440,7 @@ func (h *HNSW) selectNeighborsNormal(q *Node, candidateIDs []string, M int) []st
 		})
 	}
 	sort.Slice(candidateDistances, func(i, j int) bool {
-		return candidateDistances[i].Distance < candidateDistances[j].Distance
+		return closer(candidateDistances[i], candidateDistances[j])
 	})
 
 	selected := make([]string, 0, M)
@@ -415,17 +450,38 @@ func (h *HNSW) selectNeighborsNormal(q *Node, candidateIDs []string, M int) []st
 	return selected
 }
 
-// updateEntryPoint re-selects the top-layer node, used after deletes.
+// updateEntryPoint re-selects the top-layer node with the smallest id, used after deletes.
 func (h *HNSW) updateEntryPoint() {
 	for layer := len(h.Layers) - 1; layer >= 0; layer-- {
-		for _, node := range h.Layers[layer].Nodes {
-			h.EntryPoint = node
+		var entryPoint *Node
+		for id, node := range h.Layers[layer].Nodes {
+			if entryPoint == nil || id < entryPoint.ID {
+				entryPoint = node
+			}
+		}
+		if entryPoint != nil {
+			h.EntryPoint = entryPoint
 			return
 		}
 	}
 	h.EntryPoint = nil
 }
 
+// closer orders candidates by distance and then by id, so the order does not depend on map iteration
+func closer(a, b SearchCandidate) bool {
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	return a.NodeID < b.NodeID
+}
+
+// sortedIDs returns the ids of a neighbor map in ascending order
+func sortedIDs(neighbors map[string]float64) []string {
+	ids := maps.Keys(neighbors)
+	sort.Strings(ids)
+	return ids
+}
+
 // small helper
 func min(a, b int) int {
 	if a < b {
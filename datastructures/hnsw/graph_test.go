@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
 	"treds/datastructures/hnsw"
 )
 
@@ -119,3 +120,65 @@ func TestGraphInsertSearch(t *testing.T) {
 		values,
 	)
 }
+
+func TestGraphInsertNodeReplicates(t *testing.T) {
+	leader := hnsw.NewHNSW(4, 0.5, 8, EuclideanDistance)
+	follower := hnsw.NewHNSW(4, 0.5, 8, EuclideanDistance)
+
+	// The follower is only given the ids and levels drawn by the leader, like a replica applying the Raft log
+	ids := make([]string, 0)
+	for i := 0; i < 64; i++ {
+		vector := Vector{float64(i % 8), float64(i / 8)}
+		id, level := leader.NextNode()
+		leader.InsertNode(id, level, vector)
+		follower.InsertNode(id, level, vector)
+		ids = append(ids, id)
+	}
+	for i := 0; i < len(ids); i += 5 {
+		require.Equal(t, leader.Delete(ids[i]), follower.Delete(ids[i]))
+	}
+
+	require.Equal(t, leader.Topography(), follower.Topography())
+	require.Equal(t, leader.EntryPoint.ID, follower.EntryPoint.ID)
+	for layer := range leader.Layers {
+		for id, node := range leader.Layers[layer].Nodes {
+			require.Equal(t, node.Neighbors, follower.Layers[layer].Nodes[id].Neighbors)
+		}
+	}
+}
+
+func TestHNSWClone(t *testing.T) {
+	h := hnsw.NewHNSW(4, 0.5, 8, EuclideanDistance)
+	ids := make([]string, 0)
+	for i := 0; i < 32; i++ {
+		ids = append(ids, h.Insert(Vector{float64(i % 8), float64(i / 8)}))
+	}
+	topography := h.Topography()
+	neighbors := make(map[string]map[string]float64)
+	for _, node := range h.Layers[0].Nodes {
+		neighbors[node.ID] = maps.Clone(node.Neighbors)
+	}
+	results := h.Search(Vector{0, 0}, 4)
+
+	clone := h.Clone()
+	require.Equal(t, topography, clone.Topography())
+	require.Equal(t, h.EntryPoint.ID, clone.EntryPoint.ID)
+	for layer := range clone.Layers {
+		for id, node := range clone.Layers[layer].Nodes {
+			require.Same(t, clone.Layers[0].Nodes[id], node, "every layer of the clone should hold the same node")
+		}
+	}
+
+	// Changing the clone leaves the graph as it was
+	for i := 0; i < len(ids); i += 3 {
+		clone.Delete(ids[i])
+	}
+	for i := 0; i < 16; i++ {
+		clone.Insert(Vector{float64(i) + 0.5, 0.5})
+	}
+	require.Equal(t, topography, h.Topography())
+	for _, node := range h.Layers[0].Nodes {
+		require.Equal(t, neighbors[node.ID], node.Neighbors)
+	}
+	require.Equal(t, results, h.Search(Vector{0, 0}, 4))
+}
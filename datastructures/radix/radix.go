@@ -74,6 +74,23 @@ func (t *Txn) trackChannelsAndCount(n *Node) int {
 	return leaves
 }
 
+// writeNode returns a copy of n that the transaction can modify. Nodes reachable from a committed Tree are never
+// modified, so a Tree stays a point-in-time view of its keys while newer trees are derived from it.
+// The leaves are shared, so only their next and previous links can change under an older Tree.
+func (t *Txn) writeNode(n *Node) *Node {
+	nc := &Node{
+		leaf:    n.leaf,
+		minLeaf: n.minLeaf,
+		maxLeaf: n.maxLeaf,
+		prefix:  n.prefix,
+	}
+	if len(n.edges) != 0 {
+		nc.edges = make([]edge, len(n.edges))
+		copy(nc.edges, n.edges)
+	}
+	return nc
+}
+
 // mergeChild is called to collapse the given node with its child. This is only
 // called when the given node is not a leaf and has a single edge.
 func (t *Txn) mergeChild(n *Node) {
@@ -106,16 +123,18 @@ func (t *Txn) insert(n *Node, k, search []byte, v interface{}) (*Node, interface
 			didUpdate = true
 		}
 
-		n.leaf = &LeafNode{
+		nc := t.writeNode(n)
+		nc.leaf = &LeafNode{
 			key: k,
 			val: v,
 		}
-		n.computeLinks()
-		return n, oldVal, didUpdate
+		nc.computeLinks()
+		return nc, oldVal, didUpdate
 	}
 
 	// Look for the edge
 	idx, child := n.getEdge(search[0])
+	n = t.writeNode(n)
 
 	// No edge, create one
 	if child == nil {
@@ -160,11 +179,12 @@ func (t *Txn) insert(n *Node, k, search []byte, v interface{}) (*Node, interface
 	})
 
 	// Restore the existing child node
+	modChild := t.writeNode(child)
 	splitNode.addEdge(edge{
-		label: child.prefix[commonPrefix],
-		node:  child,
+		label: modChild.prefix[commonPrefix],
+		node:  modChild,
 	})
-	child.prefix = child.prefix[commonPrefix:]
+	modChild.prefix = modChild.prefix[commonPrefix:]
 
 	// Create a new leaf node
 	leaf := &LeafNode{
@@ -212,15 +232,17 @@ func (t *Txn) delete(parent, n *Node, search []byte) (*Node, *LeafNode) {
 		oldLeaf := n.leaf
 
 		// Remove the leaf node
-		n.leaf = nil
-		n.minLeaf = nil
-		n.maxLeaf = nil
+		nc := t.writeNode(n)
+		nc.leaf = nil
+		nc.minLeaf = nil
+		nc.maxLeaf = nil
 
 		// Check if this node should be merged
-		if n != t.root && len(n.edges) == 1 {
-			t.mergeChild(n)
+		if parent != nil && len(nc.edges) == 1 {
+			t.mergeChild(nc)
 		}
-		return n, oldLeaf
+		nc.computeLinks()
+		return nc, oldLeaf
 	}
 
 	// Look for an edge
@@ -238,16 +260,17 @@ func (t *Txn) delete(parent, n *Node, search []byte) (*Node, *LeafNode) {
 	}
 
 	// Delete the edge if the node has no edges
+	nc := t.writeNode(n)
 	if newChild.leaf == nil && len(newChild.edges) == 0 {
-		n.delEdge(label)
-		if n != t.root && len(n.edges) == 1 && !n.isLeaf() {
-			t.mergeChild(n)
+		nc.delEdge(label)
+		if parent != nil && len(nc.edges) == 1 && !nc.isLeaf() {
+			t.mergeChild(nc)
 		}
 	} else {
-		n.edges[idx].node = newChild
+		nc.edges[idx].node = newChild
 	}
-	n.computeLinks()
-	return n, leaf
+	nc.computeLinks()
+	return nc, leaf
 }
 
 // delete does a recursive deletion
@@ -255,12 +278,11 @@ func (t *Txn) deletePrefix(n *Node, search []byte) (*Node, int) {
 	// Check for key exhaustion
 	if len(search) == 0 {
 		delSize := t.trackChannelsAndCount(n)
-		if n.isLeaf() {
-			n.leaf = nil
-		}
-		n.edges = nil
-		n.computeLinks()
-		return n, delSize
+		nc := t.writeNode(n)
+		nc.leaf = nil
+		nc.edges = nil
+		nc.computeLinks()
+		return nc, delSize
 	}
 
 	// Look for an edge
@@ -283,16 +305,17 @@ func (t *Txn) deletePrefix(n *Node, search []byte) (*Node, int) {
 		return nil, 0
 	}
 	// Delete the edge if the node has no edges
+	nc := t.writeNode(n)
 	if newChild.leaf == nil && len(newChild.edges) == 0 {
-		n.delEdge(label)
-		if n != t.root && len(n.edges) == 1 && !n.isLeaf() {
-			t.mergeChild(n)
+		nc.delEdge(label)
+		if n != t.root && len(nc.edges) == 1 && !nc.isLeaf() {
+			t.mergeChild(nc)
 		}
 	} else {
-		n.edges[idx].node = newChild
+		nc.edges[idx].node = newChild
 	}
-	n.computeLinks()
-	return n, numDeletions
+	nc.computeLinks()
+	return nc, numDeletions
 }
 
 // Insert is used to add or update a given key. The return provides
@@ -312,9 +335,9 @@ func (t *Txn) Insert(k []byte, v interface{}) (interface{}, bool) {
 // and a bool indicating if the key was set.
 func (t *Txn) Delete(k []byte) (interface{}, bool) {
 	newRoot, leaf := t.delete(nil, t.root, k)
-	t.root = newRoot
-	if t.root == nil {
-		t.root = &Node{}
+	// A nil root means the key was not found and the tree is unchanged
+	if newRoot != nil {
+		t.root = newRoot
 	}
 	if leaf != nil {
 		t.size--
@@ -327,12 +350,12 @@ func (t *Txn) Delete(k []byte) (interface{}, bool) {
 // This will delete all nodes under that prefix
 func (t *Txn) DeletePrefix(prefix []byte) (bool, int) {
 	newRoot, numDeletions := t.deletePrefix(t.root, prefix)
-	t.root = newRoot
-	t.size = t.size - numDeletions
-	if t.root == nil {
-		t.root = &Node{}
+	// A nil root means no key has the prefix and the tree is unchanged
+	if newRoot != nil {
+		t.root = newRoot
 	}
-	return true, numDeletions
+	t.size = t.size - numDeletions
+	return numDeletions > 0, numDeletions
 }
 
 // Root returns the current root of the radix tree within this
@@ -0,0 +1,71 @@
+package radix
+
+import (
+	"reflect"
+	"testing"
+)
+
+func walkKeys(t *Tree) []string {
+	keys := make([]string, 0)
+	t.Root().Walk(func(k []byte, _ interface{}) bool {
+		keys = append(keys, string(k))
+		return false
+	})
+	return keys
+}
+
+func leafKeys(t *Tree) []string {
+	keys := make([]string, 0)
+	leaf, found := t.Root().MinimumLeaf()
+	for found && leaf != nil {
+		keys = append(keys, string(leaf.Key()))
+		leaf = leaf.GetNextLeaf()
+	}
+	return keys
+}
+
+func TestTreePointInTime(t *testing.T) {
+	tree := New()
+	for _, key := range []string{"user:1", "user:2", "user:10", "team:1"} {
+		tree, _, _ = tree.Insert([]byte(key), key)
+	}
+	old := tree
+
+	tree, _, _ = tree.Insert([]byte("user:3"), "user:3")
+	tree, _, _ = tree.Insert([]byte("user:1"), "updated")
+	tree, _, _ = tree.Delete([]byte("user:10"))
+	tree, _, _ = tree.DeletePrefix([]byte("team:"))
+
+	expectedOld := []string{"team:1", "user:1", "user:10", "user:2"}
+	if keys := walkKeys(old); !reflect.DeepEqual(keys, expectedOld) {
+		t.Fatalf("expected the old tree to keep %v, got %v", expectedOld, keys)
+	}
+	if value, _ := old.Get([]byte("user:1")); value != "user:1" {
+		t.Fatalf("expected the old tree to keep the old value, got %v", value)
+	}
+
+	expected := []string{"user:1", "user:2", "user:3"}
+	if keys := walkKeys(tree); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+	if keys := leafKeys(tree); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected the leaves to be linked as %v, got %v", expected, keys)
+	}
+}
+
+func TestTreeDeleteMissing(t *testing.T) {
+	tree := New()
+	tree, _, _ = tree.Insert([]byte("a"), 1)
+
+	tree, _, deleted := tree.Delete([]byte("b"))
+	if deleted {
+		t.Fatalf("expected a missing key not to be deleted")
+	}
+	tree, matched, _ := tree.DeletePrefix([]byte("c"))
+	if matched {
+		t.Fatalf("expected a missing prefix not to match")
+	}
+	if _, found := tree.Get([]byte("a")); !found || tree.Len() != 1 {
+		t.Fatalf("expected the tree to be unchanged, got %v", walkKeys(tree))
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -12,13 +14,14 @@ import (
 	"time"
 
 	"treds/server"
+	"treds/store"
 
-	"github.com/panjf2000/gnet/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const DefaultPort = "7997"
 const DefaultBind = "localhost"
-const DefaultAdvertise = "localhost"
 const DefaultSegmentSize = 200
 
 func parseServers(input string) []server.BootStrapServer {
@@ -52,13 +55,75 @@ func parseServers(input string) []server.BootStrapServer {
 	return servers
 }
 
+// envOr returns the environment variable name, or fallback when it is not set
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envInt returns the environment variable name as an integer, or fallback when it is not set
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("%s must be an integer, got %s", name, value)
+	}
+	return parsed
+}
+
+func reloadTLSOnHangup(reloaders ...*server.TLSReloader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		for _, reloader := range reloaders {
+			if reloader == nil {
+				continue
+			}
+			if err := reloader.Reload(); err != nil {
+				fmt.Println("Error occurred reloading TLS certificates", err)
+			}
+		}
+	}
+}
+
 func main() {
 	serverId := flag.String("id", "", "Server Id - must be a uuid, if not given a new one will be generated")
 	portFlag := flag.String("port", DefaultPort, "Port at which server will listen")
 	segmentSize := flag.Int("segmentSize", DefaultSegmentSize, "Segment size")
 	bindAddr := flag.String("bind", DefaultBind, "Bind Address")
-	advertiseAddr := flag.String("advertise", DefaultAdvertise, "Advertise Address")
+	advertiseAddr := flag.String("advertise", os.Getenv("TREDS_ADVERTISE"), "Host the other nodes reach the Raft port at, the bind address or the first private IP when empty")
+	clientAdvertise := flag.String("clientAdvertise", os.Getenv("TREDS_CLIENT_ADVERTISE"), "host:port the clients and the other nodes reach the client port at, the advertised host and port when empty")
+	redirect := flag.Bool("redirect", false, "Reply -MOVED with the client address of the leader instead of forwarding commands to it")
+	raftPort := flag.Int("raftPort", envInt("TREDS_RAFT_PORT", server.DefaultRaftPort), "Port of the Raft transport")
+	dataDir := flag.String("dataDir", envOr("TREDS_DATA_DIR", server.DefaultDataDir), "Directory of the Raft log and snapshots")
+	serverIdFile := flag.String("serverIdFile", os.Getenv("TREDS_SERVER_ID_FILE"), "File the server id is kept in, server-id next to dataDir when empty")
 	applyTimeout := flag.Duration("raftApplyTimeout", 1*time.Second, "Raft Apply Timeout")
+	httpAddr := flag.String("httpAddr", "", "Address for the HTTP/JSON gateway, e.g. 'localhost:8080', disabled when empty")
+	grpcAddr := flag.String("grpcAddr", "", "Address for the gRPC service, e.g. 'localhost:7998', disabled when empty")
+	aclFile := flag.String("aclFile", "", "JSON file with the users allowed to connect, authentication is disabled when empty")
+	tlsCert := flag.String("tlsCert", "", "Certificate for TLS on the client port and the gateways, TLS is disabled when empty")
+	tlsKey := flag.String("tlsKey", "", "Private key of tlsCert")
+	tlsCA := flag.String("tlsCA", "", "CA that client certificates must be signed by, client certificates are not required when empty")
+	raftTLSCert := flag.String("raftTLSCert", "", "Certificate for mutual TLS between Raft nodes, plaintext TCP when empty")
+	raftTLSKey := flag.String("raftTLSKey", "", "Private key of raftTLSCert")
+	raftTLSCA := flag.String("raftTLSCA", "", "CA that the certificates of the other Raft nodes must be signed by")
+	snapshotCompression := flag.String("snapshotCompression", "none", "Compression of the snapshot chunks, none, snappy or zstd")
+	snapshotRetain := flag.Int("snapshotRetain", 3, "Number of Raft snapshots kept in the data directory")
+	backupDir := flag.String("backupDir", server.DefaultBackupDir, "Directory BACKUP and the scheduled backups write their archives to")
+	backupInterval := flag.Duration("backupInterval", 0, "Interval between backups taken by the leader, e.g. '1h', disabled when 0")
+	backupRetainCount := flag.Int("backupRetainCount", 0, "Number of backups kept in backupDir, all of them when 0")
+	backupRetainAge := flag.Duration("backupRetainAge", 0, "Age after which backups are removed from backupDir, e.g. '168h', never when 0")
+	join := flag.Bool("join", false, "Start a new node without bootstrapping a cluster, it is added with CLUSTER JOIN on the leader")
+	replica := flag.Bool("replica", false, "Start a read-only replica that never counts toward the quorum, it is added with CLUSTER JOIN id address NONVOTER on the leader")
+	standalone := flag.Bool("standalone", false, "Run a single node without Raft, writes are applied straight to the store and nothing is written to dataDir")
+	commandLog := flag.String("commandLog", os.Getenv("TREDS_COMMAND_LOG"), "Append-only log a standalone node keeps its writes in and replays at startup, writes are only kept in memory when empty")
+	commandLogSync := flag.Duration("commandLogSync", time.Second, "Interval the command log is fsynced at, e.g. '100ms', after every write when 0")
+	readOnly := flag.Bool("readOnly", false, "Make a replica reject writes instead of forwarding them to the leader")
 	servers := flag.String("servers", "", "Comma-separated list of servers in the format id:host:port (e.g., 'uuid1:127.0.0.1:8080,uuid2:192.168.1.1:9090')")
 
 	flag.Parse()
@@ -84,18 +149,114 @@ func main() {
 		panic(err)
 	}
 
-	tredsServer, err := server.New(portInt, *segmentSize, *bindAddr, *advertiseAddr, *serverId, *applyTimeout, serverList)
+	var clientTLS, raftTLS *server.TLSReloader
+	if *tlsCert != "" {
+		clientTLS, err = server.NewTLSReloader(server.TLSFiles{CertFile: *tlsCert, KeyFile: *tlsKey, CAFile: *tlsCA})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *raftTLSCert != "" {
+		raftTLS, err = server.NewTLSReloader(server.TLSFiles{CertFile: *raftTLSCert, KeyFile: *raftTLSKey, CAFile: *raftTLSCA})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// Certificates are read again on SIGHUP, so they can be rotated without a restart
+	go reloadTLSOnHangup(clientTLS, raftTLS)
+
+	compression, err := store.ParseSnapshotCompression(*snapshotCompression)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Fatal(gnet.Run(
-		tredsServer,
-		"tcp://0.0.0.0:"+strconv.Itoa(tredsServer.Port),
-		// Single Event loop
-		gnet.WithMulticore(false),
-		gnet.WithReusePort(false),
-		gnet.WithTCPKeepAlive(300*time.Second),
-	))
+	tredsServer, err := server.New(server.Config{
+		Port:                portInt,
+		SegmentSize:         *segmentSize,
+		BindAddr:            *bindAddr,
+		AdvertiseAddr:       *advertiseAddr,
+		ClientAdvertiseAddr: *clientAdvertise,
+		Redirect:            *redirect,
+		RaftPort:            *raftPort,
+		DataDir:             *dataDir,
+		ServerIdFile:        *serverIdFile,
+		ServerId:            *serverId,
+		ApplyTimeout:        *applyTimeout,
+		Servers:             serverList,
+		Join:                *join,
+		Replica:             *replica,
+		ReadOnly:            *readOnly,
+		Standalone:          *standalone,
+		CommandLog:          *commandLog,
+		CommandLogSync:      *commandLogSync,
+		RaftTLS:             raftTLS,
+		SnapshotCompression: compression,
+		SnapshotRetain:      *snapshotRetain,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tredsServer.SetBackupConfig(server.BackupConfig{
+		Dir:         *backupDir,
+		Interval:    *backupInterval,
+		RetainCount: *backupRetainCount,
+		RetainAge:   *backupRetainAge,
+	})
+	go tredsServer.RunBackups()
+
+	// The Raft node leaves its log consistent when the process is stopped
+	go func() {
+		<-sigs
+		if errShutdown := tredsServer.Shutdown(); errShutdown != nil {
+			fmt.Println("Error occurred shutting down", errShutdown)
+		}
+		os.Exit(0)
+	}()
+
+	if *aclFile != "" {
+		acl, errACL := server.LoadACL(*aclFile)
+		if errACL != nil {
+			log.Fatal(errACL)
+		}
+		tredsServer.SetACL(acl)
+	}
+
+	if *httpAddr != "" {
+		go func() {
+			if clientTLS == nil {
+				log.Fatal(http.ListenAndServe(*httpAddr, server.NewHTTPGateway(tredsServer)))
+			}
+			httpServer := &http.Server{
+				Addr:      *httpAddr,
+				Handler:   server.NewHTTPGateway(tredsServer),
+				TLSConfig: clientTLS.ServerConfig(),
+			}
+			log.Fatal(httpServer.ListenAndServeTLS("", ""))
+		}()
+	}
+
+	if *grpcAddr != "" {
+		listener, errListen := net.Listen("tcp", *grpcAddr)
+		if errListen != nil {
+			log.Fatal(errListen)
+		}
+		var opts []grpc.ServerOption
+		if clientTLS != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(clientTLS.ServerConfig())))
+		}
+		go func() {
+			log.Fatal(server.NewGRPCServer(tredsServer, opts...).Serve(listener))
+		}()
+	}
+
+	clientAddr := "0.0.0.0:" + strconv.Itoa(tredsServer.Port)
+	if clientTLS != nil {
+		tredsServer.SetClientTLS(clientTLS)
+		// gnet has no TLS support, TLS is terminated on the client port and the connections are relayed to the event loop
+		log.Fatal(tredsServer.ServeTLS(clientAddr))
+	}
+
+	log.Fatal(tredsServer.Serve(clientAddr))
 
 }
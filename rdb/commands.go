@@ -0,0 +1,53 @@
+package rdb
+
+import (
+	"strconv"
+	"time"
+
+	"treds/commands"
+)
+
+// ElementsPerCommand is the largest number of elements in a command, large values are split in several commands
+// so the Raft log entries stay small
+const ElementsPerCommand = 1000
+
+// Commands returns the Treds commands that write the entry, with the command name first.
+// Keys that already expired have no commands, like Redis skips them when it loads a dump.
+func (e *Entry) Commands() [][]string {
+	if !e.ExpireAt.IsZero() && !e.ExpireAt.After(time.Now()) {
+		return nil
+	}
+	var result [][]string
+	switch e.Type {
+	case TypeString:
+		result = append(result, []string{commands.SetCommand, e.Key, e.Value})
+	case TypeList:
+		result = appendSplit(result, commands.RPushCommand, e.Key, e.Values, 1)
+	case TypeSet:
+		result = appendSplit(result, commands.SAddCommand, e.Key, e.Values, 1)
+	case TypeHash:
+		result = appendSplit(result, commands.HSetCommand, e.Key, e.Fields, 2)
+	case TypeSortedSet:
+		// Members of a Redis sorted set have no value
+		args := make([]string, 0, 3*len(e.Members))
+		for _, member := range e.Members {
+			args = append(args, strconv.FormatFloat(member.Score, 'g', -1, 64), member.Member, "")
+		}
+		result = appendSplit(result, commands.ZAddCommand, e.Key, args, 3)
+	}
+	if !e.ExpireAt.IsZero() {
+		result = append(result, commands.ExpireAtArgs(e.Key, e.ExpireAt))
+	}
+	return result
+}
+
+// appendSplit appends name key args... split in commands of at most ElementsPerCommand elements of per arguments
+func appendSplit(result [][]string, name, key string, args []string, per int) [][]string {
+	for start := 0; start < len(args); start += ElementsPerCommand * per {
+		end := min(start+ElementsPerCommand*per, len(args))
+		command := make([]string, 0, 2+end-start)
+		command = append(command, name, key)
+		result = append(result, append(command, args[start:end]...))
+	}
+	return result
+}
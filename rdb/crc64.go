@@ -0,0 +1,28 @@
+package rdb
+
+// The CRC-64 of Redis uses the Jones polynomial, reflected, with no initial or final inversion.
+// hash/crc64 inverts the CRC before and after every update, so it cannot compute it.
+const crc64JonesReflected = 0x95AC9329AC4BC9B5
+
+var crc64Table = func() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		crc := uint64(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ crc64JonesReflected
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func crc64Update(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crc64Table[byte(crc)^b] ^ crc>>8
+	}
+	return crc
+}
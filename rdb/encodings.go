@@ -0,0 +1,326 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errTruncated = errors.New("truncated encoding")
+
+// parseZiplist returns the elements of a ziplist, the encoding of small lists, hashes and sorted sets up to Redis 6.2
+func parseZiplist(buf []byte) ([]string, error) {
+	// zlbytes, zltail and zllen
+	const headerSize = 10
+	if len(buf) < headerSize+1 {
+		return nil, fmt.Errorf("ziplist: %w", errTruncated)
+	}
+	values := make([]string, 0, binary.LittleEndian.Uint16(buf[8:10]))
+	pos := headerSize
+	for {
+		if pos >= len(buf) {
+			return nil, fmt.Errorf("ziplist: %w", errTruncated)
+		}
+		if buf[pos] == 0xFF {
+			return values, nil
+		}
+		// The length of the previous entry
+		if buf[pos] == 0xFE {
+			pos += 5
+		} else {
+			pos++
+		}
+		if pos >= len(buf) {
+			return nil, fmt.Errorf("ziplist: %w", errTruncated)
+		}
+
+		encoding := buf[pos]
+		var length, header int
+		var value string
+		switch {
+		case encoding>>6 == 0:
+			header, length = 1, int(encoding&0x3f)
+		case encoding>>6 == 1:
+			if pos+2 > len(buf) {
+				return nil, fmt.Errorf("ziplist: %w", errTruncated)
+			}
+			header, length = 2, int(encoding&0x3f)<<8|int(buf[pos+1])
+		case encoding == 0x80:
+			if pos+5 > len(buf) {
+				return nil, fmt.Errorf("ziplist: %w", errTruncated)
+			}
+			header, length = 5, int(binary.BigEndian.Uint32(buf[pos+1:pos+5]))
+		default:
+			integer, size, err := ziplistInteger(encoding, buf[pos+1:])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, strconv.FormatInt(integer, 10))
+			pos += 1 + size
+			continue
+		}
+		start := pos + header
+		if start+length > len(buf) {
+			return nil, fmt.Errorf("ziplist: %w", errTruncated)
+		}
+		value = string(buf[start : start+length])
+		values = append(values, value)
+		pos = start + length
+	}
+}
+
+// ziplistInteger decodes an integer entry and returns it with the number of bytes after the encoding
+func ziplistInteger(encoding byte, buf []byte) (int64, int, error) {
+	var size int
+	switch encoding {
+	case 0xC0:
+		size = 2
+	case 0xD0:
+		size = 4
+	case 0xE0:
+		size = 8
+	case 0xF0:
+		size = 3
+	case 0xFE:
+		size = 1
+	default:
+		// 1111xxxx holds the value 0 to 12 in xxxx minus 1
+		if encoding>>4 == 0xF && encoding&0x0F >= 1 && encoding&0x0F <= 13 {
+			return int64(encoding&0x0F) - 1, 0, nil
+		}
+		return 0, 0, fmt.Errorf("ziplist: invalid encoding %#x", encoding)
+	}
+	if len(buf) < size {
+		return 0, 0, fmt.Errorf("ziplist: %w", errTruncated)
+	}
+	switch size {
+	case 1:
+		return int64(int8(buf[0])), size, nil
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(buf))), size, nil
+	case 3:
+		return int64(int32(uint32(buf[0])<<8|uint32(buf[1])<<16|uint32(buf[2])<<24) >> 8), size, nil
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(buf))), size, nil
+	}
+	return int64(binary.LittleEndian.Uint64(buf)), size, nil
+}
+
+// parseListpack returns the elements of a listpack, the encoding of small lists, sets, hashes and sorted sets since Redis 7.0
+func parseListpack(buf []byte) ([]string, error) {
+	// Total bytes and number of elements
+	const headerSize = 6
+	if len(buf) < headerSize+1 {
+		return nil, fmt.Errorf("listpack: %w", errTruncated)
+	}
+	values := make([]string, 0, binary.LittleEndian.Uint16(buf[4:6]))
+	pos := headerSize
+	for {
+		if pos >= len(buf) {
+			return nil, fmt.Errorf("listpack: %w", errTruncated)
+		}
+		encoding := buf[pos]
+		if encoding == 0xFF {
+			return values, nil
+		}
+
+		var header, length int
+		var integer int64
+		isString := true
+		switch {
+		case encoding>>7 == 0:
+			header, integer, isString = 1, int64(encoding&0x7f), false
+		case encoding>>6 == 2:
+			header, length = 1, int(encoding&0x3f)
+		case encoding>>5 == 6:
+			if pos+2 > len(buf) {
+				return nil, fmt.Errorf("listpack: %w", errTruncated)
+			}
+			header, isString = 2, false
+			integer = int64(uint64(encoding&0x1f)<<8 | uint64(buf[pos+1]))
+			// Sign extends the 13 bit integer
+			integer = integer << 51 >> 51
+		case encoding>>4 == 0xE:
+			if pos+2 > len(buf) {
+				return nil, fmt.Errorf("listpack: %w", errTruncated)
+			}
+			header, length = 2, int(encoding&0x0f)<<8|int(buf[pos+1])
+		case encoding == 0xF0:
+			if pos+5 > len(buf) {
+				return nil, fmt.Errorf("listpack: %w", errTruncated)
+			}
+			header, length = 5, int(binary.LittleEndian.Uint32(buf[pos+1:pos+5]))
+		case encoding >= 0xF1 && encoding <= 0xF4:
+			size := map[byte]int{0xF1: 2, 0xF2: 3, 0xF3: 4, 0xF4: 8}[encoding]
+			if pos+1+size > len(buf) {
+				return nil, fmt.Errorf("listpack: %w", errTruncated)
+			}
+			header, isString = 1+size, false
+			integer = listpackInteger(buf[pos+1 : pos+1+size])
+		default:
+			return nil, fmt.Errorf("listpack: invalid encoding %#x", encoding)
+		}
+
+		entrySize := header + length
+		if pos+entrySize > len(buf) {
+			return nil, fmt.Errorf("listpack: %w", errTruncated)
+		}
+		if isString {
+			values = append(values, string(buf[pos+header:pos+entrySize]))
+		} else {
+			values = append(values, strconv.FormatInt(integer, 10))
+		}
+		// Every entry ends with its size, used to walk the listpack backwards
+		pos += entrySize + backlenSize(entrySize)
+	}
+}
+
+// listpackInteger decodes a little endian signed integer of 2, 3, 4 or 8 bytes
+func listpackInteger(buf []byte) int64 {
+	var value uint64
+	for i := len(buf) - 1; i >= 0; i-- {
+		value = value<<8 | uint64(buf[i])
+	}
+	shift := 64 - 8*len(buf)
+	return int64(value<<shift) >> shift
+}
+
+func backlenSize(entrySize int) int {
+	switch {
+	case entrySize < 1<<7:
+		return 1
+	case entrySize < 1<<14:
+		return 2
+	case entrySize < 1<<21:
+		return 3
+	case entrySize < 1<<28:
+		return 4
+	}
+	return 5
+}
+
+// parseIntset returns the members of an intset, the encoding of small sets of integers
+func parseIntset(buf []byte) ([]string, error) {
+	if len(buf) < 8 {
+		return nil, fmt.Errorf("intset: %w", errTruncated)
+	}
+	size := int(binary.LittleEndian.Uint32(buf[0:4]))
+	length := int(binary.LittleEndian.Uint32(buf[4:8]))
+	if size != 2 && size != 4 && size != 8 {
+		return nil, fmt.Errorf("intset: invalid integer size %d", size)
+	}
+	if len(buf) < 8+size*length {
+		return nil, fmt.Errorf("intset: %w", errTruncated)
+	}
+	values := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		values = append(values, strconv.FormatInt(listpackInteger(buf[8+i*size:8+(i+1)*size]), 10))
+	}
+	return values, nil
+}
+
+// parseZipmap returns the fields and values of a zipmap, the encoding of small hashes before Redis 2.6
+func parseZipmap(buf []byte) ([]string, error) {
+	if len(buf) < 2 {
+		return nil, fmt.Errorf("zipmap: %w", errTruncated)
+	}
+	values := make([]string, 0)
+	pos := 1
+	readLength := func() (int, error) {
+		if pos >= len(buf) {
+			return 0, fmt.Errorf("zipmap: %w", errTruncated)
+		}
+		switch first := buf[pos]; {
+		case first < 254:
+			pos++
+			return int(first), nil
+		case first == 254:
+			if pos+5 > len(buf) {
+				return 0, fmt.Errorf("zipmap: %w", errTruncated)
+			}
+			length := int(binary.LittleEndian.Uint32(buf[pos+1 : pos+5]))
+			pos += 5
+			return length, nil
+		}
+		return -1, nil
+	}
+	for {
+		keyLength, err := readLength()
+		if err != nil {
+			return nil, err
+		}
+		// 255 ends the zipmap
+		if keyLength < 0 {
+			return values, nil
+		}
+		if pos+keyLength > len(buf) {
+			return nil, fmt.Errorf("zipmap: %w", errTruncated)
+		}
+		key := string(buf[pos : pos+keyLength])
+		pos += keyLength
+
+		valueLength, err := readLength()
+		if err != nil {
+			return nil, err
+		}
+		if valueLength < 0 || pos >= len(buf) {
+			return nil, fmt.Errorf("zipmap: %w", errTruncated)
+		}
+		// Values are followed by unused bytes, their number is before the value
+		free := int(buf[pos])
+		pos++
+		if pos+valueLength+free > len(buf) {
+			return nil, fmt.Errorf("zipmap: %w", errTruncated)
+		}
+		values = append(values, key, string(buf[pos:pos+valueLength]))
+		pos += valueLength + free
+	}
+}
+
+// lzfDecompress decompresses the LZF compressed strings of a dump
+func lzfDecompress(in []byte, length int) ([]byte, error) {
+	// The length is not verified until the end, the output grows past the input as it is decompressed
+	out := make([]byte, 0, min(length, 2*len(in)))
+	for pos := 0; pos < len(in); {
+		if len(out) > length {
+			return nil, fmt.Errorf("lzf: expected %d bytes, got more", length)
+		}
+		ctrl := int(in[pos])
+		pos++
+		// A run of ctrl+1 literal bytes
+		if ctrl < 1<<5 {
+			if pos+ctrl+1 > len(in) {
+				return nil, fmt.Errorf("lzf: %w", errTruncated)
+			}
+			out = append(out, in[pos:pos+ctrl+1]...)
+			pos += ctrl + 1
+			continue
+		}
+		// A back reference to bytes already decompressed
+		refLength := ctrl >> 5
+		if refLength == 7 {
+			if pos >= len(in) {
+				return nil, fmt.Errorf("lzf: %w", errTruncated)
+			}
+			refLength += int(in[pos])
+			pos++
+		}
+		if pos >= len(in) {
+			return nil, fmt.Errorf("lzf: %w", errTruncated)
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[pos]) - 1
+		pos++
+		if ref < 0 {
+			return nil, fmt.Errorf("lzf: invalid back reference")
+		}
+		// The reference can overlap the bytes it produces, so it is copied a byte at a time
+		for i := 0; i < refLength+2; i++ {
+			out = append(out, out[ref+i])
+		}
+	}
+	if len(out) != length {
+		return nil, fmt.Errorf("lzf: expected %d bytes, got %d", length, len(out))
+	}
+	return out, nil
+}
@@ -0,0 +1,550 @@
+// Package rdb parses Redis RDB dumps into the keys of the stores Treds has.
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MaxVersion is the newest RDB version that can be parsed, the one of Redis 7.4
+const MaxVersion = 12
+
+const (
+	opcodeSlotInfo      = 0xF4
+	opcodeFunctionPreGA = 0xF5
+	opcodeFunction2     = 0xF6
+	opcodeFreq          = 0xF7
+	opcodeIdle          = 0xF8
+	opcodeModuleAux     = 0xF9
+	opcodeAux           = 0xFA
+	opcodeResizeDB      = 0xFB
+	opcodeExpireTimeMs  = 0xFC
+	opcodeExpireTime    = 0xFD
+	opcodeSelectDB      = 0xFE
+	opcodeEOF           = 0xFF
+)
+
+const (
+	typeString            = 0
+	typeList              = 1
+	typeSet               = 2
+	typeZSet              = 3
+	typeHash              = 4
+	typeZSet2             = 5
+	typeHashZipmap        = 9
+	typeListZiplist       = 10
+	typeSetIntset         = 11
+	typeZSetZiplist       = 12
+	typeHashZiplist       = 13
+	typeListQuicklist     = 14
+	typeHashListpack      = 16
+	typeZSetListpack      = 17
+	typeListQuicklist2    = 18
+	typeSetListpack       = 20
+	quicklistNodePlain    = 1
+	quicklistNodePacked   = 2
+	encodingInt8          = 0
+	encodingInt16         = 1
+	encodingInt32         = 2
+	encodingLZF           = 3
+	length32Bit           = 0x80
+	length64Bit           = 0x81
+	scoreNaN              = 253
+	scorePositiveInfinity = 254
+	scoreNegativeInfinity = 255
+)
+
+// maxStringLength bounds the strings of a dump, like proto-max-bulk-len bounds them in Redis,
+// so a corrupted length is an error rather than an allocation of that many bytes
+const maxStringLength = 512 * 1024 * 1024
+
+// Type is the store a key goes into
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeList
+	TypeSet
+	TypeSortedSet
+	TypeHash
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeSet:
+		return "set"
+	case TypeSortedSet:
+		return "zset"
+	case TypeHash:
+		return "hash"
+	}
+	return "unknown"
+}
+
+// SortedSetMember is a member of a sorted set with its score
+type SortedSetMember struct {
+	Member string
+	Score  float64
+}
+
+// Entry is a key of the dump with its value
+type Entry struct {
+	DB   int
+	Key  string
+	Type Type
+	// ExpireAt is the zero time for keys without a TTL
+	ExpireAt time.Time
+	// Value is the value of a string
+	Value string
+	// Values are the elements of a list in order, or the members of a set
+	Values []string
+	// Members are the members of a sorted set
+	Members []SortedSetMember
+	// Fields are the fields and values of a hash, one after the other
+	Fields []string
+}
+
+// ErrUnsupportedType is returned for keys of a type Treds has no store for, like streams and module types
+var ErrUnsupportedType = errors.New("unsupported RDB type")
+
+// Parse reads an RDB dump from r and calls fn with every key, in the order they are in the dump.
+// The checksum at the end of the dump is verified when it was written.
+func Parse(r io.Reader, fn func(*Entry) error) error {
+	p := &parser{r: bufio.NewReader(r)}
+	p.r = &checksumReader{r: p.r}
+	return p.parse(fn)
+}
+
+type parser struct {
+	r       io.Reader
+	version int
+	buf     [8]byte
+}
+
+// checksumReader computes the CRC-64 of everything read, which the dump ends with
+type checksumReader struct {
+	r   io.Reader
+	crc uint64
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.crc = crc64Update(c.crc, p[:n])
+	return n, err
+}
+
+func (p *parser) parse(fn func(*Entry) error) error {
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return fmt.Errorf("reading the RDB header: %v", err)
+	}
+	if string(header[:5]) != "REDIS" {
+		return fmt.Errorf("not an RDB file")
+	}
+	version, err := strconv.Atoi(string(header[5:]))
+	if err != nil {
+		return fmt.Errorf("invalid RDB version %q", header[5:])
+	}
+	if version < 1 || version > MaxVersion {
+		return fmt.Errorf("RDB version %d is not supported, the newest supported version is %d", version, MaxVersion)
+	}
+	p.version = version
+
+	db := 0
+	var expireAt time.Time
+	for {
+		opcode, errOpcode := p.readByte()
+		if errOpcode != nil {
+			return errOpcode
+		}
+		switch opcode {
+		case opcodeEOF:
+			return p.verifyChecksum()
+		case opcodeSelectDB:
+			selected, errLength := p.readLength()
+			if errLength != nil {
+				return errLength
+			}
+			db = int(selected)
+		case opcodeResizeDB:
+			if _, err = p.readLength(); err != nil {
+				return err
+			}
+			if _, err = p.readLength(); err != nil {
+				return err
+			}
+		case opcodeSlotInfo:
+			for i := 0; i < 3; i++ {
+				if _, err = p.readLength(); err != nil {
+					return err
+				}
+			}
+		case opcodeAux:
+			if _, err = p.readString(); err != nil {
+				return err
+			}
+			if _, err = p.readString(); err != nil {
+				return err
+			}
+		case opcodeFunction2:
+			// Functions are not keys, they are skipped
+			if _, err = p.readString(); err != nil {
+				return err
+			}
+		case opcodeFunctionPreGA, opcodeModuleAux:
+			return fmt.Errorf("%w: module and pre-release function data, opcode %#x", ErrUnsupportedType, opcode)
+		case opcodeExpireTime:
+			buf := make([]byte, 4)
+			if _, err = io.ReadFull(p.r, buf); err != nil {
+				return err
+			}
+			expireAt = time.Unix(int64(binary.LittleEndian.Uint32(buf)), 0)
+		case opcodeExpireTimeMs:
+			buf := make([]byte, 8)
+			if _, err = io.ReadFull(p.r, buf); err != nil {
+				return err
+			}
+			expireAt = time.UnixMilli(int64(binary.LittleEndian.Uint64(buf)))
+		case opcodeIdle:
+			if _, err = p.readLength(); err != nil {
+				return err
+			}
+		case opcodeFreq:
+			if _, err = p.readByte(); err != nil {
+				return err
+			}
+		default:
+			key, errKey := p.readString()
+			if errKey != nil {
+				return errKey
+			}
+			entry := &Entry{DB: db, Key: key, ExpireAt: expireAt}
+			if err = p.readValue(opcode, entry); err != nil {
+				return fmt.Errorf("reading key %s: %w", key, err)
+			}
+			expireAt = time.Time{}
+			if err = fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *parser) verifyChecksum() error {
+	if p.version < 5 {
+		return nil
+	}
+	expected := p.r.(*checksumReader).crc
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(p.r, buf); err != nil {
+		return fmt.Errorf("reading the RDB checksum: %v", err)
+	}
+	checksum := binary.LittleEndian.Uint64(buf)
+	// Dumps written with rdbchecksum disabled have a zero checksum
+	if checksum != 0 && checksum != expected {
+		return fmt.Errorf("RDB checksum %x does not match the content %x", checksum, expected)
+	}
+	return nil
+}
+
+func (p *parser) readValue(valueType byte, entry *Entry) error {
+	var err error
+	switch valueType {
+	case typeString:
+		entry.Type = TypeString
+		entry.Value, err = p.readString()
+	case typeList, typeSet:
+		entry.Type = TypeList
+		if valueType == typeSet {
+			entry.Type = TypeSet
+		}
+		entry.Values, err = p.readStrings(1)
+	case typeHash:
+		entry.Type = TypeHash
+		entry.Fields, err = p.readStrings(2)
+	case typeZSet, typeZSet2:
+		entry.Type = TypeSortedSet
+		entry.Members, err = p.readSortedSet(valueType == typeZSet2)
+	case typeHashZipmap:
+		entry.Type = TypeHash
+		entry.Fields, err = p.readEncoded(parseZipmap)
+	case typeListZiplist:
+		entry.Type = TypeList
+		entry.Values, err = p.readEncoded(parseZiplist)
+	case typeSetIntset:
+		entry.Type = TypeSet
+		entry.Values, err = p.readEncoded(parseIntset)
+	case typeSetListpack:
+		entry.Type = TypeSet
+		entry.Values, err = p.readEncoded(parseListpack)
+	case typeHashZiplist:
+		entry.Type = TypeHash
+		entry.Fields, err = p.readEncoded(parseZiplist)
+	case typeHashListpack:
+		entry.Type = TypeHash
+		entry.Fields, err = p.readEncoded(parseListpack)
+	case typeZSetZiplist, typeZSetListpack:
+		entry.Type = TypeSortedSet
+		parse := parseZiplist
+		if valueType == typeZSetListpack {
+			parse = parseListpack
+		}
+		var pairs []string
+		if pairs, err = p.readEncoded(parse); err == nil {
+			entry.Members, err = sortedSetMembers(pairs)
+		}
+	case typeListQuicklist, typeListQuicklist2:
+		entry.Type = TypeList
+		entry.Values, err = p.readQuicklist(valueType == typeListQuicklist2)
+	default:
+		return fmt.Errorf("%w %d", ErrUnsupportedType, valueType)
+	}
+	return err
+}
+
+// readStrings reads a length followed by length times per strings
+func (p *parser) readStrings(per int) ([]string, error) {
+	length, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	// The capacity is bounded, the length of a corrupted dump could be anything
+	values := make([]string, 0, min(length*uint64(per), 1024))
+	for i := uint64(0); i < length*uint64(per); i++ {
+		value, errValue := p.readString()
+		if errValue != nil {
+			return nil, errValue
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (p *parser) readSortedSet(binaryScores bool) ([]SortedSetMember, error) {
+	length, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]SortedSetMember, 0, min(length, 1024))
+	for i := uint64(0); i < length; i++ {
+		member, errMember := p.readString()
+		if errMember != nil {
+			return nil, errMember
+		}
+		var score float64
+		if binaryScores {
+			buf := make([]byte, 8)
+			if _, err = io.ReadFull(p.r, buf); err != nil {
+				return nil, err
+			}
+			score = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+		} else if score, err = p.readStringScore(); err != nil {
+			return nil, err
+		}
+		members = append(members, SortedSetMember{Member: member, Score: score})
+	}
+	return members, nil
+}
+
+// readStringScore reads a score of the first sorted set encoding, it is written as text
+func (p *parser) readStringScore() (float64, error) {
+	length, err := p.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch length {
+	case scoreNaN:
+		return math.NaN(), nil
+	case scorePositiveInfinity:
+		return math.Inf(1), nil
+	case scoreNegativeInfinity:
+		return math.Inf(-1), nil
+	}
+	buf, err := p.readBytes(uint64(length))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(buf), 64)
+}
+
+func (p *parser) readQuicklist(packedNodes bool) ([]string, error) {
+	nodes, err := p.readLength()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0)
+	for i := uint64(0); i < nodes; i++ {
+		container := uint64(quicklistNodePacked)
+		if packedNodes {
+			if container, err = p.readLength(); err != nil {
+				return nil, err
+			}
+		}
+		blob, errBlob := p.readString()
+		if errBlob != nil {
+			return nil, errBlob
+		}
+		// Plain nodes hold a single large element as it is
+		if container == quicklistNodePlain {
+			values = append(values, blob)
+			continue
+		}
+		parse := parseZiplist
+		if packedNodes {
+			parse = parseListpack
+		}
+		nodeValues, errParse := parse([]byte(blob))
+		if errParse != nil {
+			return nil, errParse
+		}
+		values = append(values, nodeValues...)
+	}
+	return values, nil
+}
+
+// readEncoded reads a string holding a compact encoding of a whole value and parses it
+func (p *parser) readEncoded(parse func([]byte) ([]string, error)) ([]string, error) {
+	blob, err := p.readString()
+	if err != nil {
+		return nil, err
+	}
+	return parse([]byte(blob))
+}
+
+func sortedSetMembers(pairs []string) ([]SortedSetMember, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("sorted set with a member without a score")
+	}
+	members := make([]SortedSetMember, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		score, err := strconv.ParseFloat(pairs[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, SortedSetMember{Member: pairs[i], Score: score})
+	}
+	return members, nil
+}
+
+// readBytes reads a string of length bytes. The buffer grows with the bytes read rather than the length,
+// so a length past the end of a corrupted dump fails once the dump ends.
+func (p *parser) readBytes(length uint64) ([]byte, error) {
+	if length > maxStringLength {
+		return nil, fmt.Errorf("string of %d bytes is longer than the maximum of %d bytes", length, maxStringLength)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, p.r, int64(length)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *parser) readByte() (byte, error) {
+	if _, err := io.ReadFull(p.r, p.buf[:1]); err != nil {
+		return 0, err
+	}
+	return p.buf[0], nil
+}
+
+// readLengthOrEncoding reads a length, or the encoding of a string when encoded is true
+func (p *parser) readLengthOrEncoding() (uint64, bool, error) {
+	first, err := p.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch first >> 6 {
+	case 0:
+		return uint64(first & 0x3f), false, nil
+	case 1:
+		next, errNext := p.readByte()
+		if errNext != nil {
+			return 0, false, errNext
+		}
+		return uint64(first&0x3f)<<8 | uint64(next), false, nil
+	case 2:
+		switch first {
+		case length32Bit:
+			buf := make([]byte, 4)
+			if _, err = io.ReadFull(p.r, buf); err != nil {
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(buf)), false, nil
+		case length64Bit:
+			buf := make([]byte, 8)
+			if _, err = io.ReadFull(p.r, buf); err != nil {
+				return 0, false, err
+			}
+			return binary.BigEndian.Uint64(buf), false, nil
+		}
+		return 0, false, fmt.Errorf("invalid length encoding %#x", first)
+	}
+	return uint64(first & 0x3f), true, nil
+}
+
+func (p *parser) readLength() (uint64, error) {
+	length, encoded, err := p.readLengthOrEncoding()
+	if err != nil {
+		return 0, err
+	}
+	if encoded {
+		return 0, fmt.Errorf("expected a length, got a string encoding")
+	}
+	return length, nil
+}
+
+func (p *parser) readString() (string, error) {
+	length, encoded, err := p.readLengthOrEncoding()
+	if err != nil {
+		return "", err
+	}
+	if !encoded {
+		buf, errRead := p.readBytes(length)
+		return string(buf), errRead
+	}
+	switch length {
+	case encodingInt8:
+		value, errValue := p.readByte()
+		return strconv.Itoa(int(int8(value))), errValue
+	case encodingInt16:
+		buf := make([]byte, 2)
+		_, err = io.ReadFull(p.r, buf)
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(buf)))), err
+	case encodingInt32:
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(p.r, buf)
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(buf)))), err
+	case encodingLZF:
+		compressedLength, errLength := p.readLength()
+		if errLength != nil {
+			return "", errLength
+		}
+		length, errLength = p.readLength()
+		if errLength != nil {
+			return "", errLength
+		}
+		compressed, errRead := p.readBytes(compressedLength)
+		if errRead != nil {
+			return "", errRead
+		}
+		if length > maxStringLength {
+			return "", fmt.Errorf("lzf: string of %d bytes is longer than the maximum of %d bytes", length, maxStringLength)
+		}
+		decompressed, errLZF := lzfDecompress(compressed, int(length))
+		return string(decompressed), errLZF
+	}
+	return "", fmt.Errorf("invalid string encoding %d", length)
+}
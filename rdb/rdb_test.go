@@ -0,0 +1,285 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dump builds an RDB file the way Redis writes it
+type dump struct {
+	bytes.Buffer
+}
+
+func newDump(version string) *dump {
+	d := &dump{}
+	d.WriteString("REDIS" + version)
+	d.aux("redis-ver", "7.2.4")
+	d.WriteByte(opcodeSelectDB)
+	d.length(0)
+	d.WriteByte(opcodeResizeDB)
+	d.length(10)
+	d.length(1)
+	return d
+}
+
+func (d *dump) length(n int) {
+	switch {
+	case n < 1<<6:
+		d.WriteByte(byte(n))
+	case n < 1<<14:
+		d.WriteByte(byte(n>>8) | 0x40)
+		d.WriteByte(byte(n))
+	default:
+		d.WriteByte(length32Bit)
+		_ = binary.Write(d, binary.BigEndian, uint32(n))
+	}
+}
+
+func (d *dump) str(s string) {
+	d.length(len(s))
+	d.WriteString(s)
+}
+
+func (d *dump) aux(key, value string) {
+	d.WriteByte(opcodeAux)
+	d.str(key)
+	d.str(value)
+}
+
+func (d *dump) key(valueType byte, key string) {
+	d.WriteByte(valueType)
+	d.str(key)
+}
+
+// end writes the EOF opcode and the checksum of the dump
+func (d *dump) end() []byte {
+	d.WriteByte(opcodeEOF)
+	_ = binary.Write(d, binary.LittleEndian, crc64Update(0, d.Bytes()))
+	return d.Bytes()
+}
+
+func listpack(entries ...[]byte) string {
+	var body []byte
+	for _, entry := range entries {
+		body = append(body, entry...)
+		body = append(body, byte(len(entry)))
+	}
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(6+len(body)+1))
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(entries)))
+	buf = append(buf, body...)
+	return string(append(buf, 0xFF))
+}
+
+func lpString(s string) []byte {
+	return append([]byte{0x80 | byte(len(s))}, s...)
+}
+
+func lpInt13(v int) []byte {
+	u := uint16(v) & 0x1fff
+	return []byte{0xC0 | byte(u>>8), byte(u)}
+}
+
+func ziplist(entries ...[]byte) string {
+	var body []byte
+	for _, entry := range entries {
+		// The length of the previous entry is not needed to read the ziplist forwards
+		body = append(body, 0)
+		body = append(body, entry...)
+	}
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(10+len(body)+1))
+	buf = binary.LittleEndian.AppendUint32(buf, 0)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(entries)))
+	buf = append(buf, body...)
+	return string(append(buf, 0xFF))
+}
+
+func parseAll(t *testing.T, data []byte) map[string]*Entry {
+	entries := make(map[string]*Entry)
+	err := Parse(bytes.NewReader(data), func(entry *Entry) error {
+		entries[entry.Key] = entry
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return entries
+}
+
+func TestParse(t *testing.T) {
+	expireAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	d := newDump("0011")
+
+	d.WriteByte(opcodeExpireTimeMs)
+	_ = binary.Write(d, binary.LittleEndian, uint64(expireAt.UnixMilli()))
+	d.key(typeString, "plain")
+	d.str("value")
+
+	d.key(typeString, "int")
+	d.WriteByte(0xC0 | encodingInt16)
+	_ = binary.Write(d, binary.LittleEndian, int16(-1234))
+
+	// "a" followed by a back reference repeating it 9 times
+	d.key(typeString, "lzf")
+	d.WriteByte(0xC0 | encodingLZF)
+	d.length(5)
+	d.length(10)
+	d.Write([]byte{0x00, 'a', 0xE0, 0x00, 0x00})
+
+	d.key(typeListQuicklist2, "list")
+	d.length(2)
+	d.length(quicklistNodePacked)
+	d.str(listpack(lpString("x"), []byte{7}, lpInt13(-5)))
+	d.length(quicklistNodePlain)
+	d.str("large")
+
+	d.key(typeSetIntset, "intset")
+	intset := binary.LittleEndian.AppendUint32(nil, 2)
+	intset = binary.LittleEndian.AppendUint32(intset, 2)
+	intset = binary.LittleEndian.AppendUint16(intset, uint16(0xFFFF))
+	intset = binary.LittleEndian.AppendUint16(intset, 300)
+	d.str(string(intset))
+
+	d.key(typeSetListpack, "set")
+	d.str(listpack(lpString("m1"), lpString("m2")))
+
+	d.key(typeZSet2, "zset")
+	d.length(2)
+	d.str("alice")
+	_ = binary.Write(d, binary.LittleEndian, math.Float64bits(1.5))
+	d.str("bob")
+	_ = binary.Write(d, binary.LittleEndian, math.Float64bits(math.Inf(1)))
+
+	d.key(typeZSetListpack, "zsetlp")
+	d.str(listpack(lpString("carol"), []byte{3}))
+
+	d.key(typeHashListpack, "hash")
+	d.str(listpack(lpString("f1"), lpString("v1"), lpString("f2"), []byte{42}))
+
+	d.key(typeListZiplist, "ziplist")
+	d.str(ziplist([]byte{0x01, 'z'}, []byte{0xF1 + 5}, []byte{0xC0, 0x39, 0x30}, []byte{0xF0, 0xFF, 0xFF, 0xFF}))
+
+	d.key(typeHash, "oldhash")
+	d.length(1)
+	d.str("field")
+	d.str("value")
+
+	entries := parseAll(t, d.end())
+
+	if entry := entries["plain"]; entry.Type != TypeString || entry.Value != "value" || !entry.ExpireAt.Equal(expireAt) {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if entry := entries["int"]; !entry.ExpireAt.IsZero() || entry.Value != "-1234" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if value := entries["lzf"].Value; value != strings.Repeat("a", 10) {
+		t.Fatalf("expected 10 a, got %q", value)
+	}
+	expected := map[string][]string{
+		"list":    {"x", "7", "-5", "large"},
+		"intset":  {"-1", "300"},
+		"set":     {"m1", "m2"},
+		"ziplist": {"z", "5", "12345", "-1"},
+	}
+	for key, values := range expected {
+		if !reflect.DeepEqual(entries[key].Values, values) {
+			t.Fatalf("expected %s to be %v, got %v", key, values, entries[key].Values)
+		}
+	}
+	if fields := entries["hash"].Fields; !reflect.DeepEqual(fields, []string{"f1", "v1", "f2", "42"}) {
+		t.Fatalf("unexpected hash fields %v", fields)
+	}
+	if fields := entries["oldhash"].Fields; !reflect.DeepEqual(fields, []string{"field", "value"}) {
+		t.Fatalf("unexpected hash fields %v", fields)
+	}
+	expectedMembers := []SortedSetMember{{Member: "alice", Score: 1.5}, {Member: "bob", Score: math.Inf(1)}}
+	if members := entries["zset"].Members; !reflect.DeepEqual(members, expectedMembers) {
+		t.Fatalf("expected %v, got %v", expectedMembers, members)
+	}
+	if members := entries["zsetlp"].Members; !reflect.DeepEqual(members, []SortedSetMember{{Member: "carol", Score: 3}}) {
+		t.Fatalf("unexpected members %v", members)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	if crc := crc64Update(0, []byte("123456789")); crc != 0xe9c6d914c4b8d9ca {
+		t.Fatalf("expected the CRC-64 of Redis, got %x", crc)
+	}
+
+	d := newDump("0011")
+	d.key(typeString, "key")
+	d.str("value")
+	data := d.end()
+	data[len(data)-1] ^= 0xFF
+	if err := Parse(bytes.NewReader(data), func(*Entry) error { return nil }); err == nil {
+		t.Fatalf("expected error for a wrong checksum")
+	}
+
+	if err := Parse(strings.NewReader("REDIS0013"), func(*Entry) error { return nil }); err == nil {
+		t.Fatalf("expected error for an unsupported version")
+	}
+}
+
+func TestParseCorruptedLengths(t *testing.T) {
+	// The 21 byte dump of a string claiming 2^63-1 bytes, and a string length past the end of the dump
+	huge := []byte("REDIS0011\x00\x01k\x81\x7f\xff\xff\xff\xff\xff\xff\xff")
+	truncated := []byte("REDIS0011\x00\x01k\x80\x10\x00\x00\x00value")
+	// A score written as text longer than the rest of the dump
+	score := []byte("REDIS0011\x03\x01z\x01\x06alice\xfa1")
+	// LZF strings with a compressed or a decompressed length past the maximum, and an output longer than its length
+	lzfCompressed := []byte("REDIS0011\x00\x01k\xc3\x81\x7f\xff\xff\xff\xff\xff\xff\xff\x05")
+	lzfDecompressed := []byte("REDIS0011\x00\x01k\xc3\x02\x81\x7f\xff\xff\xff\xff\xff\xff\xff\x00a")
+	lzfLonger := []byte("REDIS0011\x00\x01k\xc3\x05\x01\x00a\xe0\xff\x00")
+	for _, tt := range []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{name: "huge string", data: huge, expected: "string of 9223372036854775807 bytes is longer than the maximum"},
+		{name: "truncated string", data: truncated, expected: "unexpected EOF"},
+		{name: "truncated score", data: score, expected: "unexpected EOF"},
+		{name: "huge lzf input", data: lzfCompressed, expected: "string of 9223372036854775807 bytes is longer than the maximum"},
+		{name: "huge lzf output", data: lzfDecompressed, expected: "lzf: string of 9223372036854775807 bytes is longer than the maximum"},
+		{name: "lzf output past its size", data: lzfLonger, expected: "lzf: expected 1 bytes, got 265"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Parse(bytes.NewReader(tt.data), func(*Entry) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestEntryCommands(t *testing.T) {
+	values := make([]string, ElementsPerCommand+1)
+	for i := range values {
+		values[i] = "v"
+	}
+	expireAt := time.Now().Add(time.Hour)
+	entry := &Entry{Key: "list", Type: TypeList, Values: values, ExpireAt: expireAt}
+	commands := entry.Commands()
+	if len(commands) != 3 {
+		t.Fatalf("expected 2 RPUSH and an EXPIRE, got %d commands", len(commands))
+	}
+	if commands[0][0] != "RPUSH" || len(commands[0]) != ElementsPerCommand+2 || len(commands[1]) != 3 {
+		t.Fatalf("unexpected split of the list %v %v", commands[0][:3], commands[1])
+	}
+	if commands[2][0] != "EXPIRE" {
+		t.Fatalf("expected EXPIRE, got %v", commands[2])
+	}
+
+	entry = &Entry{Key: "zset", Type: TypeSortedSet, Members: []SortedSetMember{{Member: "alice", Score: 1.5}}}
+	if commands = entry.Commands(); !reflect.DeepEqual(commands, [][]string{{"ZADD", "zset", "1.5", "alice", ""}}) {
+		t.Fatalf("unexpected commands %v", commands)
+	}
+
+	entry = &Entry{Key: "old", Type: TypeString, Value: "v", ExpireAt: time.Now().Add(-time.Second)}
+	if commands = entry.Commands(); commands != nil {
+		t.Fatalf("expected no commands for an expired key, got %v", commands)
+	}
+}
@@ -1,20 +1,178 @@
 package resp
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
-// Decode parses a RESP command string and returns the command and arguments
+// MaxBulkLength is the largest bulk string length accepted from a client
+const MaxBulkLength = 512 * 1024 * 1024 // 512 MB
+
+// MaxInlineLength is the largest inline command accepted from a client
+const MaxInlineLength = 64 * 1024 // 64 KB
+
+// typePrefixes are the first bytes of every RESP2 and RESP3 type, anything else starts an inline command
+const typePrefixes = "+-:$*_,#(!=~>%"
+
+// ErrIncompleteFrame is returned when the input ends before a whole RESP frame has been received
+var ErrIncompleteFrame = errors.New("incomplete RESP frame")
+
+var crlf = []byte("\r\n")
+
+// MaxNestingDepth is the deepest nesting of aggregates accepted from a client
+const MaxNestingDepth = 32
+
+// MaxAggregateLength is the largest number of elements of an aggregate accepted from a client
+const MaxAggregateLength = 1024 * 1024
+
+// FrameLength returns the number of bytes used by the first complete RESP frame in buf.
+// ErrIncompleteFrame is returned when more bytes are needed to complete the frame.
+func FrameLength(buf []byte) (int, error) {
+	var scanner FrameScanner
+	return scanner.Scan(buf)
+}
+
+// FrameScanner finds the frames of a stream, a frame received over several reads is only scanned once
+// because every call resumes where the previous one stopped.
+type FrameScanner struct {
+	// Start of the element being scanned
+	pos int
+	// Where the search for the end of the line of the element resumes
+	lineSearch int
+	// Elements left in the aggregates the element is nested in, the innermost last
+	pending []int
+}
+
+// Scan returns the number of bytes used by the frame at the start of buf, or ErrIncompleteFrame when more bytes are needed.
+// Until it returns something else buf has to start with the bytes passed to the previous call, the scanner starts
+// over with the next frame after that.
+func (s *FrameScanner) Scan(buf []byte) (int, error) {
+	var n int
+	var err error
+	if len(buf) > 0 && strings.IndexByte(typePrefixes, buf[0]) < 0 {
+		n, err = s.inlineFrameEnd(buf)
+	} else {
+		n, err = s.frameEnd(buf)
+	}
+	if !errors.Is(err, ErrIncompleteFrame) {
+		s.pos = 0
+		s.lineSearch = 0
+		s.pending = s.pending[:0]
+	}
+	return n, err
+}
+
+// inlineFrameEnd returns the offset just past the newline ending an inline command
+func (s *FrameScanner) inlineFrameEnd(buf []byte) (int, error) {
+	lineEnd := bytes.IndexByte(buf[s.lineSearch:], '\n')
+	if lineEnd < 0 {
+		if len(buf) > MaxInlineLength {
+			return 0, fmt.Errorf("too big inline request")
+		}
+		s.lineSearch = len(buf)
+		return 0, ErrIncompleteFrame
+	}
+	lineEnd += s.lineSearch
+	if lineEnd > MaxInlineLength {
+		return 0, fmt.Errorf("too big inline request")
+	}
+	return lineEnd + 1, nil
+}
+
+// frameEnd returns the offset just past the frame, the elements of aggregates are scanned in turn
+func (s *FrameScanner) frameEnd(buf []byte) (int, error) {
+	for {
+		if s.pos >= len(buf) {
+			return 0, ErrIncompleteFrame
+		}
+		lineEnd := bytes.Index(buf[s.lineSearch:], crlf)
+		if lineEnd < 0 {
+			// The CR of the CRLF may be the last byte received
+			s.lineSearch = max(s.pos, len(buf)-1)
+			return 0, ErrIncompleteFrame
+		}
+		lineEnd += s.lineSearch
+		s.lineSearch = lineEnd
+		header := string(buf[s.pos+1 : lineEnd])
+		next := lineEnd + 2
+
+		switch buf[s.pos] {
+		case '+', '-', ':', '_', ',', '#', '(':
+		case '$', '!', '=':
+			bulkLength, err := strconv.Atoi(header)
+			if err != nil {
+				return 0, fmt.Errorf("invalid bulk string length: %v", err)
+			}
+			if bulkLength > MaxBulkLength {
+				return 0, fmt.Errorf("invalid bulk string length: %d exceeds maximum", bulkLength)
+			}
+			// Null bulk string
+			if bulkLength < 0 {
+				break
+			}
+			if len(buf) < next+bulkLength+2 {
+				return 0, ErrIncompleteFrame
+			}
+			if !bytes.Equal(buf[next+bulkLength:next+bulkLength+2], crlf) {
+				return 0, fmt.Errorf("bulk string length mismatch")
+			}
+			next += bulkLength + 2
+		case '*', '~', '>', '%':
+			arrayLength, err := strconv.Atoi(header)
+			if err != nil {
+				return 0, fmt.Errorf("invalid array length: %v", err)
+			}
+			if arrayLength > MaxAggregateLength {
+				return 0, fmt.Errorf("invalid array length: %d exceeds maximum", arrayLength)
+			}
+			// Maps hold a key and a value for every entry
+			if buf[s.pos] == '%' {
+				arrayLength *= 2
+			}
+			// Null and empty aggregates end with their header
+			if arrayLength <= 0 {
+				break
+			}
+			if len(s.pending) == MaxNestingDepth {
+				return 0, fmt.Errorf("aggregates nested deeper than %d", MaxNestingDepth)
+			}
+			s.pending = append(s.pending, arrayLength)
+			s.pos, s.lineSearch = next, next
+			continue
+		default:
+			return 0, fmt.Errorf("invalid RESP input: unknown type prefix '%c'", buf[s.pos])
+		}
+
+		// The element ends at next, so do the aggregates whose last element it is
+		s.pos, s.lineSearch = next, next
+		for len(s.pending) > 0 {
+			s.pending[len(s.pending)-1]--
+			if s.pending[len(s.pending)-1] > 0 {
+				break
+			}
+			s.pending = s.pending[:len(s.pending)-1]
+		}
+		if len(s.pending) == 0 {
+			return next, nil
+		}
+	}
+}
+
+// Decode parses a RESP command string and returns the command and arguments.
+// Bulk strings are read by their declared length so arguments may contain any bytes, including CRLF.
 func Decode(respInput string) (string, []string, error) {
-	lines := strings.Split(respInput, "\r\n")
-	if len(lines) < 1 || lines[0][0] != '*' {
-		return "", nil, fmt.Errorf("invalid RESP input: missing array prefix '*'")
+	if len(respInput) == 0 {
+		return "", nil, fmt.Errorf("invalid RESP input: empty command")
+	}
+	if respInput[0] != '*' {
+		return decodeInline(respInput)
 	}
 
 	// Parse the array length
-	arrayLength, err := strconv.Atoi(lines[0][1:])
+	arrayLength, pos, err := readLength(respInput, 1)
 	if err != nil {
 		return "", nil, fmt.Errorf("invalid array length: %v", err)
 	}
@@ -25,30 +183,29 @@ func Decode(respInput string) (string, []string, error) {
 
 	// Parse the bulk strings
 	args := make([]string, 0, arrayLength)
-	i := 1
-	for len(args) < arrayLength && i < len(lines) {
-		if len(lines[i]) > 0 && lines[i][0] == '$' {
-			// Parse the bulk string length
-			bulkLength, err := strconv.Atoi(lines[i][1:])
-			if err != nil || bulkLength < 0 {
-				return "", nil, fmt.Errorf("invalid bulk string length: %v", err)
-			}
+	for len(args) < arrayLength {
+		if pos >= len(respInput) {
+			return "", nil, fmt.Errorf("mismatch between declared and parsed array length")
+		}
+		if respInput[pos] != '$' {
+			return "", nil, fmt.Errorf("expected bulk string prefix '$', found: %q", respInput[pos])
+		}
 
-			// Ensure the bulk string value exists
-			if i+1 >= len(lines) || len(lines[i+1]) != bulkLength {
-				return "", nil, fmt.Errorf("bulk string length mismatch")
-			}
+		// Parse the bulk string length
+		bulkLength, start, err := readLength(respInput, pos+1)
+		if err != nil || bulkLength < 0 {
+			return "", nil, fmt.Errorf("invalid bulk string length: %v", err)
+		}
 
-			// Append the value to args
-			args = append(args, lines[i+1])
-			i += 2 // Move to the next bulk string header
-		} else {
-			return "", nil, fmt.Errorf("expected bulk string prefix '$', found: %s", lines[i])
+		// Ensure the bulk string value exists and is terminated where its length says it is
+		end := start + bulkLength
+		if end+2 > len(respInput) || respInput[end:end+2] != "\r\n" {
+			return "", nil, fmt.Errorf("bulk string length mismatch")
 		}
-	}
 
-	if len(args) != arrayLength {
-		return "", nil, fmt.Errorf("mismatch between declared and parsed array length")
+		// Append the value to args
+		args = append(args, respInput[start:end])
+		pos = end + 2 // Move to the next bulk string header
 	}
 
 	// The first argument is the command (e.g., "SET")
@@ -57,3 +214,124 @@ func Decode(respInput string) (string, []string, error) {
 
 	return command, arguments, nil
 }
+
+// readLength parses the integer running from pos up to the next CRLF and returns it with the offset after the CRLF
+func readLength(input string, pos int) (int, int, error) {
+	lineEnd := strings.Index(input[pos:], "\r\n")
+	if lineEnd < 0 {
+		return 0, 0, fmt.Errorf("missing CRLF terminator")
+	}
+	length, err := strconv.Atoi(input[pos : pos+lineEnd])
+	if err != nil {
+		return 0, 0, err
+	}
+	return length, pos + lineEnd + 2, nil
+}
+
+// decodeInline parses a telnet style command, a single line of arguments separated by spaces.
+// Arguments can be quoted the way Redis allows, double quotes support escape sequences and single quotes do not.
+func decodeInline(input string) (string, []string, error) {
+	line := strings.TrimSuffix(strings.TrimSuffix(input, "\n"), "\r")
+	args, err := splitInlineArgs(line)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("invalid RESP input: empty command")
+	}
+	return args[0], args[1:], nil
+}
+
+func splitInlineArgs(line string) ([]string, error) {
+	args := make([]string, 0)
+	i := 0
+	for {
+		// Skip the blanks between arguments
+		for i < len(line) && isInlineSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var current strings.Builder
+		inDoubleQuotes := false
+		inSingleQuotes := false
+		done := false
+		for !done {
+			switch {
+			case inDoubleQuotes:
+				if i >= len(line) {
+					return nil, fmt.Errorf("unbalanced quotes in request")
+				}
+				if line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHexDigit(line[i+2]) && isHexDigit(line[i+3]) {
+					value, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+					current.WriteByte(byte(value))
+					i += 3
+				} else if line[i] == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						current.WriteByte('\n')
+					case 'r':
+						current.WriteByte('\r')
+					case 't':
+						current.WriteByte('\t')
+					case 'b':
+						current.WriteByte('\b')
+					case 'a':
+						current.WriteByte('\a')
+					default:
+						current.WriteByte(line[i])
+					}
+				} else if line[i] == '"' {
+					// The closing quote must be followed by a blank or the end of the line
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return nil, fmt.Errorf("unbalanced quotes in request")
+					}
+					done = true
+				} else {
+					current.WriteByte(line[i])
+				}
+			case inSingleQuotes:
+				if i >= len(line) {
+					return nil, fmt.Errorf("unbalanced quotes in request")
+				}
+				if line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					current.WriteByte('\'')
+				} else if line[i] == '\'' {
+					if i+1 < len(line) && !isInlineSpace(line[i+1]) {
+						return nil, fmt.Errorf("unbalanced quotes in request")
+					}
+					done = true
+				} else {
+					current.WriteByte(line[i])
+				}
+			default:
+				if i >= len(line) || isInlineSpace(line[i]) {
+					done = true
+					continue
+				}
+				switch line[i] {
+				case '"':
+					inDoubleQuotes = true
+				case '\'':
+					inSingleQuotes = true
+				default:
+					current.WriteByte(line[i])
+				}
+			}
+			i++
+		}
+		args = append(args, current.String())
+	}
+}
+
+func isInlineSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,177 @@
+package resp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFrameLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    int
+		expectedErr error
+	}{
+		{"single command", "*1\r\n$4\r\nPING\r\n", 14, nil},
+		{"pipelined commands", "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$1\r\na\r\n", 14, nil},
+		{"partial header", "*2\r\n$3\r\nGET\r\n$1", 0, ErrIncompleteFrame},
+		{"partial bulk string", "*2\r\n$3\r\nGET\r\n$5\r\nab", 0, ErrIncompleteFrame},
+		{"missing trailing crlf", "*1\r\n$4\r\nPING", 0, ErrIncompleteFrame},
+		{"nested reply", "*2\r\n*1\r\n:1\r\n$-1\r\n+OK\r\n", 17, nil},
+		{"simple string", "+OK\r\n", 5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := FrameLength([]byte(tt.input))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if n != tt.expected {
+				t.Errorf("expected length %d, got %d", tt.expected, n)
+			}
+		})
+	}
+}
+
+func TestFrameLengthInvalid(t *testing.T) {
+	inputs := []string{
+		"*1\r\n$4\r\nPINGX\r\n",
+		"*x\r\n",
+		"$x\r\n",
+	}
+	for _, input := range inputs {
+		_, err := FrameLength([]byte(input))
+		if err == nil || errors.Is(err, ErrIncompleteFrame) {
+			t.Errorf("expected protocol error for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestFrameLengthLimits(t *testing.T) {
+	nested := strings.Repeat("*1\r\n", MaxNestingDepth) + ":1\r\n"
+	if n, err := FrameLength([]byte(nested)); err != nil || n != len(nested) {
+		t.Fatalf("expected frame of length %d, got %d (%v)", len(nested), n, err)
+	}
+	if _, err := FrameLength([]byte("*1\r\n" + nested)); err == nil || errors.Is(err, ErrIncompleteFrame) {
+		t.Errorf("expected an error for aggregates nested too deep, got %v", err)
+	}
+	if _, err := FrameLength([]byte("*" + strconv.Itoa(MaxAggregateLength+1) + "\r\n")); err == nil || errors.Is(err, ErrIncompleteFrame) {
+		t.Errorf("expected an error for too many elements, got %v", err)
+	}
+}
+
+func TestFrameScannerResumes(t *testing.T) {
+	inputs := []string{
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$10\r\nab\r\ncd\r\nef\r\n",
+		"*2\r\n*1\r\n:1\r\n$-1\r\n",
+		"%1\r\n+key\r\n*0\r\n",
+		"SET key value\r\n",
+	}
+	for _, input := range inputs {
+		// Every read adds a byte, the scanner is given the whole frame received so far
+		var scanner FrameScanner
+		for received := 1; received < len(input); received++ {
+			if _, err := scanner.Scan([]byte(input[:received])); !errors.Is(err, ErrIncompleteFrame) {
+				t.Fatalf("expected incomplete frame for %q, got %v", input[:received], err)
+			}
+		}
+		if n, err := scanner.Scan([]byte(input + "*1\r\n")); err != nil || n != len(input) {
+			t.Fatalf("expected frame of length %d for %q, got %d (%v)", len(input), input, n, err)
+		}
+		// The scanner starts over with the next frame
+		if n, err := scanner.Scan([]byte("*1\r\n$4\r\nPING\r\n")); err != nil || n != 14 {
+			t.Fatalf("expected frame of length 14, got %d (%v)", n, err)
+		}
+	}
+}
+
+func TestDecodeBinarySafe(t *testing.T) {
+	value := "line1\r\nline2\x00\xff"
+	input := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n" + EncodeBulkString(value)
+
+	command, args, err := Decode(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if command != "SET" {
+		t.Errorf("expected command SET, got %s", command)
+	}
+	if len(args) != 2 || args[0] != "key" || args[1] != value {
+		t.Errorf("expected args [key %q], got %q", value, args)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"*2\r\n$3\r\nGET\r\n",
+		"*1\r\n$5\r\nGET\r\n",
+		"*1\r\n:3\r\n",
+	}
+	for _, input := range inputs {
+		if _, _, err := Decode(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestDecodeInline(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedCommand string
+		expectedArgs    []string
+	}{
+		{"ping", "PING\r\n", "PING", []string{}},
+		{"newline only", "SET a b\n", "SET", []string{"a", "b"}},
+		{"extra blanks", "  SET   a\tb  \r\n", "SET", []string{"a", "b"}},
+		{"double quotes", "SET a \"hello world\"\r\n", "SET", []string{"a", "hello world"}},
+		{"escapes", "SET a \"x\\r\\ny\\x41\\\"\"\r\n", "SET", []string{"a", "x\r\nyA\""}},
+		{"single quotes", "SET a 'it\\'s \\n'\r\n", "SET", []string{"a", "it's \\n"}},
+		{"empty quoted", "SET a \"\"\r\n", "SET", []string{"a", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := FrameLength([]byte(tt.input))
+			if err != nil || n != len(tt.input) {
+				t.Fatalf("expected frame of length %d, got %d (%v)", len(tt.input), n, err)
+			}
+			command, args, err := Decode(tt.input)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if command != tt.expectedCommand {
+				t.Errorf("expected command %s, got %s", tt.expectedCommand, command)
+			}
+			if len(args) != len(tt.expectedArgs) {
+				t.Fatalf("expected args %q, got %q", tt.expectedArgs, args)
+			}
+			for i := range args {
+				if args[i] != tt.expectedArgs[i] {
+					t.Errorf("expected args %q, got %q", tt.expectedArgs, args)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeInlineInvalid(t *testing.T) {
+	inputs := []string{
+		"SET a \"unterminated\r\n",
+		"SET a 'unterminated\r\n",
+		"SET a \"closed\"early\r\n",
+	}
+	for _, input := range inputs {
+		if _, _, err := Decode(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+
+	if _, err := FrameLength([]byte("PING")); !errors.Is(err, ErrIncompleteFrame) {
+		t.Errorf("expected incomplete frame, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package resp
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"strconv"
 )
 
@@ -109,3 +110,74 @@ func EncodeMap(m map[string]string) string {
 	}
 	return buffer.String()
 }
+
+// Protocol versions a connection can negotiate with HELLO
+const (
+	Protocol2 = 2
+	Protocol3 = 3
+)
+
+// EncodeNull encodes a RESP3 null
+func EncodeNull() string {
+	return "_\r\n"
+}
+
+// EncodeDouble encodes a RESP3 double
+func EncodeDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ",inf\r\n"
+	case math.IsInf(f, -1):
+		return ",-inf\r\n"
+	case math.IsNaN(f):
+		return ",nan\r\n"
+	}
+	return "," + strconv.FormatFloat(f, 'f', -1, 64) + "\r\n"
+}
+
+// EncodeBoolean encodes a RESP3 boolean
+func EncodeBoolean(b bool) string {
+	if b {
+		return "#t\r\n"
+	}
+	return "#f\r\n"
+}
+
+// EncodeStringMap encodes alternating field and value strings as a RESP3 map
+func EncodeStringMap(fieldValues []string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("%%%d\r\n", len(fieldValues)/2))
+	for _, s := range fieldValues {
+		buffer.WriteString(EncodeBulkString(s))
+	}
+	return buffer.String()
+}
+
+// EncodeMapRESP encodes alternating already encoded keys and values as a RESP3 map
+func EncodeMapRESP(pairs []string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("%%%d\r\n", len(pairs)/2))
+	for _, s := range pairs {
+		buffer.WriteString(s)
+	}
+	return buffer.String()
+}
+
+// EncodeStringSet encodes an array of strings as a RESP3 set
+func EncodeStringSet(members []string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("~%d\r\n", len(members)))
+	for _, s := range members {
+		buffer.WriteString(EncodeBulkString(s))
+	}
+	return buffer.String()
+}
+
+// EncodePush encodes out of band data such as pub/sub messages as a RESP3 push
+func EncodePush(elements []interface{}) string {
+	array := EncodeArray(elements)
+	if elements == nil {
+		return array
+	}
+	return ">" + array[1:]
+}
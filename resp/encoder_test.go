@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodeResp3(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoded  string
+		expected string
+	}{
+		{"null", EncodeNull(), "_\r\n"},
+		{"double", EncodeDouble(1.5), ",1.5\r\n"},
+		{"infinite double", EncodeDouble(math.Inf(-1)), ",-inf\r\n"},
+		{"boolean", EncodeBoolean(true), "#t\r\n"},
+		{"map", EncodeStringMap([]string{"f", "v"}), "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{"set", EncodeStringSet([]string{"a"}), "~1\r\n$1\r\na\r\n"},
+		{"push", EncodePush([]interface{}{"message", 1}), ">2\r\n$7\r\nmessage\r\n:1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.encoded != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, tt.encoded)
+			}
+			n, err := FrameLength([]byte(tt.encoded))
+			if err != nil || n != len(tt.encoded) {
+				t.Errorf("expected a single frame of length %d, got %d (%v)", len(tt.encoded), n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrorReply is an error reply read by ReadReply
+type ErrorReply string
+
+func (e ErrorReply) Error() string {
+	return string(e)
+}
+
+// Push is an out of band RESP3 push message such as a pub/sub delivery
+type Push []interface{}
+
+// ReadReply reads a single reply from r.
+// Simple, bulk and verbatim strings are returned as string, integers as int64, doubles as float64,
+// booleans as bool, nulls as nil, arrays, sets and maps (flattened to key, value, ...) as []interface{}
+// and pushes as Push. Error replies are returned as an ErrorReply value, not as the error result.
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty reply line")
+	}
+
+	payload := line[1:]
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-', '!':
+		if line[0] == '!' {
+			blob, err := readBulk(r, payload)
+			if err != nil {
+				return nil, err
+			}
+			return ErrorReply(blob.(string)), nil
+		}
+		return ErrorReply(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '(':
+		// Big numbers are kept as their decimal text
+		return payload, nil
+	case '_':
+		return nil, nil
+	case '#':
+		return payload == "t", nil
+	case ',':
+		// ParseFloat also accepts the inf and -inf spellings of RESP3
+		return strconv.ParseFloat(payload, 64)
+	case '$':
+		return readBulk(r, payload)
+	case '=':
+		blob, err := readBulk(r, payload)
+		if err != nil || blob == nil {
+			return blob, err
+		}
+		// Verbatim strings start with a three letter format and a colon
+		text := blob.(string)
+		if len(text) >= 4 && text[3] == ':' {
+			text = text[4:]
+		}
+		return text, nil
+	case '*', '~', '>', '%':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregate length %q", payload)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		if line[0] == '%' {
+			count *= 2
+		}
+		elements := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			element, err := ReadReply(r)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		if line[0] == '>' {
+			return Push(elements), nil
+		}
+		return elements, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", line[0])
+	}
+}
+
+// ReadFrame reads a single reply from r and returns it as it was received, the elements of aggregates included.
+// Replies are relayed with it without being decoded and encoded again.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	return appendFrame(nil, r)
+}
+
+func appendFrame(frame []byte, r *bufio.Reader) ([]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty reply line")
+	}
+	frame = append(append(frame, line...), '\r', '\n')
+
+	payload := line[1:]
+	switch line[0] {
+	case '+', '-', ':', '(', '_', '#', ',':
+		return frame, nil
+	case '$', '!', '=':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", payload)
+		}
+		if length < 0 {
+			return frame, nil
+		}
+		start := len(frame)
+		frame = append(frame, make([]byte, length+2)...)
+		if _, err = io.ReadFull(r, frame[start:]); err != nil {
+			return nil, err
+		}
+		if frame[len(frame)-2] != '\r' || frame[len(frame)-1] != '\n' {
+			return nil, fmt.Errorf("bulk string is not terminated by CRLF")
+		}
+		return frame, nil
+	case '*', '~', '>', '%':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregate length %q", payload)
+		}
+		if line[0] == '%' {
+			count *= 2
+		}
+		for i := 0; i < count; i++ {
+			if frame, err = appendFrame(frame, r); err != nil {
+				return nil, err
+			}
+		}
+		return frame, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("reply line is not terminated by CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+func readBulk(r *bufio.Reader, header string) (interface{}, error) {
+	length, err := strconv.Atoi(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bulk length %q", header)
+	}
+	if length < 0 {
+		return nil, nil
+	}
+	buf := make([]byte, length+2)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	if buf[length] != '\r' || buf[length+1] != '\n' {
+		return nil, fmt.Errorf("bulk string is not terminated by CRLF")
+	}
+	return string(buf[:length]), nil
+}
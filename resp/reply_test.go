@@ -0,0 +1,69 @@
+package resp
+
+import (
+	"bufio"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{name: "simple string", input: "+OK\r\n", expected: "OK"},
+		{name: "error", input: "-ERR boom\r\n", expected: ErrorReply("ERR boom")},
+		{name: "integer", input: ":42\r\n", expected: int64(42)},
+		{name: "bulk string with CRLF", input: "$4\r\na\r\nb\r\n", expected: "a\r\nb"},
+		{name: "null bulk string", input: "$-1\r\n", expected: nil},
+		{name: "null", input: "_\r\n", expected: nil},
+		{name: "double", input: ",1.5\r\n", expected: 1.5},
+		{name: "infinite double", input: ",-inf\r\n", expected: math.Inf(-1)},
+		{name: "boolean", input: "#t\r\n", expected: true},
+		{name: "verbatim string", input: "=9\r\ntxt:hello\r\n", expected: "hello"},
+		{name: "array", input: "*2\r\n$1\r\na\r\n:1\r\n", expected: []interface{}{"a", int64(1)}},
+		{name: "map", input: "%1\r\n+k\r\n+v\r\n", expected: []interface{}{"k", "v"}},
+		{name: "set", input: "~1\r\n+a\r\n", expected: []interface{}{"a"}},
+		{name: "push", input: ">2\r\n+message\r\n+hi\r\n", expected: Push{"message", "hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := ReadReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(reply, tt.expected) {
+				t.Fatalf("expected %#v, got %#v", tt.expected, reply)
+			}
+		})
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	replies := []string{
+		"+OK\r\n",
+		"$4\r\na\r\nb\r\n",
+		"$-1\r\n",
+		"*2\r\n*2\r\n$1\r\na\r\n:1\r\n*-1\r\n",
+		"%1\r\n+k\r\n~1\r\n,1.5\r\n",
+		"-ERR boom\r\n",
+	}
+	reader := bufio.NewReader(strings.NewReader(strings.Join(replies, "")))
+	for _, expected := range replies {
+		frame, err := ReadFrame(reader)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(frame) != expected {
+			t.Fatalf("expected %q, got %q", expected, frame)
+		}
+	}
+
+	if _, err := ReadFrame(bufio.NewReader(strings.NewReader("*2\r\n+a\r\n"))); err == nil {
+		t.Fatalf("expected error for a truncated array")
+	}
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"treds/commands"
+)
+
+// ErrNoAuth is returned for commands sent before authenticating when there is no default user
+var ErrNoAuth = errors.New("NOAUTH Authentication required")
+
+// ErrWrongPass is returned by AUTH for an unknown user or a wrong password
+var ErrWrongPass = errors.New("WRONGPASS invalid username-password pair or user is disabled")
+
+// PermissionError is returned when a user runs a command, or touches a key or channel, it is not allowed to
+type PermissionError struct {
+	msg string
+}
+
+func (e *PermissionError) Error() string {
+	return "NOPERM " + e.msg
+}
+
+func permissionErrorf(format string, args ...interface{}) error {
+	return &PermissionError{msg: fmt.Sprintf(format, args...)}
+}
+
+// DefaultUserName is the user a connection runs as before it sends AUTH, when such a user is defined
+const DefaultUserName = "default"
+
+// ACLUser is a user defined in the ACL file.
+// Keys and Channels are patterns, "billing:*" allows everything starting with "billing:", "*" allows everything
+// and a pattern without a trailing "*" allows that exact name.
+type ACLUser struct {
+	Name       string   `json:"name"`
+	Password   string   `json:"password"`
+	Categories []string `json:"categories"`
+	Keys       []string `json:"keys"`
+	Channels   []string `json:"channels"`
+}
+
+// ACLConfig is the content of the ACL file
+type ACLConfig struct {
+	Users []*ACLUser `json:"users"`
+	// ClusterUser is the user followers authenticate as when they forward commands to the leader,
+	// commands are checked against the user of the client on the follower before they are forwarded
+	ClusterUser string `json:"clusterUser"`
+}
+
+// ACL holds the users that can connect to the server, every node of a cluster needs the same ACL file
+type ACL struct {
+	users       map[string]*ACLUser
+	clusterUser *ACLUser
+}
+
+// LoadACL reads the users from a JSON ACL file
+func LoadACL(path string) (*ACL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config ACLConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid ACL file %s: %v", path, err)
+	}
+	return NewACL(config)
+}
+
+func NewACL(config ACLConfig) (*ACL, error) {
+	acl := &ACL{users: make(map[string]*ACLUser)}
+	for _, user := range config.Users {
+		if user.Name == "" {
+			return nil, fmt.Errorf("ACL user without a name")
+		}
+		if _, ok := acl.users[user.Name]; ok {
+			return nil, fmt.Errorf("ACL user %s is defined twice", user.Name)
+		}
+		for _, category := range user.Categories {
+			switch category {
+			case commands.CategoryRead, commands.CategoryWrite, commands.CategoryAdmin, commands.CategoryPubSub:
+			default:
+				return nil, fmt.Errorf("ACL user %s has unknown category %s", user.Name, category)
+			}
+		}
+		acl.users[user.Name] = user
+	}
+	if config.ClusterUser != "" {
+		clusterUser, ok := acl.users[config.ClusterUser]
+		if !ok {
+			return nil, fmt.Errorf("ACL cluster user %s is not defined", config.ClusterUser)
+		}
+		acl.clusterUser = clusterUser
+	}
+	return acl, nil
+}
+
+// DefaultUser returns the user of connections that did not authenticate, nil when they are not allowed anything
+func (a *ACL) DefaultUser() *ACLUser {
+	return a.users[DefaultUserName]
+}
+
+// ClusterUser returns the user nodes authenticate as with each other, nil when it is not configured
+func (a *ACL) ClusterUser() *ACLUser {
+	return a.clusterUser
+}
+
+// Authenticate returns the user matching the credentials
+func (a *ACL) Authenticate(name, password string) (*ACLUser, error) {
+	user, ok := a.users[name]
+	if !ok || subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return nil, ErrWrongPass
+	}
+	return user, nil
+}
+
+func (u *ACLUser) allowsCategory(category string) bool {
+	for _, allowed := range u.Categories {
+		if allowed == category {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *ACLUser) allowsAllKeys() bool {
+	return matchesAll(u.Keys)
+}
+
+func (u *ACLUser) allowsKey(key string) bool {
+	return matchesPattern(u.Keys, key)
+}
+
+func (u *ACLUser) allowsChannel(channel string) bool {
+	return matchesPattern(u.Channels, channel)
+}
+
+func matchesAll(patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkACL returns an error when user may not run the command with args.
+// It is a no-op when no ACL is loaded, a nil user is a connection that has not authenticated.
+func (ts *Server) checkACL(user *ACLUser, command string, args []string) error {
+	if ts.acl == nil {
+		return nil
+	}
+	name := strings.ToUpper(command)
+	var category string
+	var keys, channels *commands.KeySpec
+	if ts.isServerCommand(name) {
+		reg, err := ts.tredsServerCommandRegistry.Retrieve(name)
+		if err != nil {
+			return err
+		}
+		if reg.NoAuth {
+			return nil
+		}
+		category = reg.Category
+		channels = reg.Channels
+	} else {
+		reg, err := ts.tredsCommandRegistry.Retrieve(name)
+		if err != nil {
+			// Unknown commands fail on their own
+			return nil
+		}
+		category = reg.ACLCategory()
+		keys = reg.Keys
+	}
+
+	if user == nil {
+		return ErrNoAuth
+	}
+	if category != commands.CategoryConnection && !user.allowsCategory(category) {
+		return permissionErrorf("User %s has no permissions to run the '%s' command", user.Name, strings.ToLower(name))
+	}
+	if keys != nil {
+		if keys.All && !user.allowsAllKeys() {
+			return permissionErrorf("User %s has no permissions to run the '%s' command on every key", user.Name, strings.ToLower(name))
+		}
+		for _, key := range keys.Keys(args) {
+			if !user.allowsKey(key) {
+				return permissionErrorf("No permissions to access the '%s' key", key)
+			}
+		}
+	}
+	if channels != nil {
+		for _, channel := range channels.Keys(args) {
+			if !user.allowsChannel(channel) {
+				return permissionErrorf("No permissions to access the '%s' channel", channel)
+			}
+		}
+	}
+	return nil
+}
+
+// gatewayUser returns the user of an HTTP or gRPC request from its basic auth credentials.
+// Requests without credentials run as the default user, they get ErrNoAuth from checkACL when there is none.
+func (ts *Server) gatewayUser(name, password string, hasCredentials bool) (*ACLUser, error) {
+	if ts.acl == nil {
+		return nil, nil
+	}
+	if !hasCredentials {
+		return ts.acl.DefaultUser(), nil
+	}
+	return ts.acl.Authenticate(name, password)
+}
+
+// parseBasicAuth parses the value of an "Authorization: Basic" header
+func parseBasicAuth(header string) (string, string, bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	name, password, ok := strings.Cut(string(decoded), ":")
+	return name, password, ok
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckACL(t *testing.T) {
+	s, err := New(Config{Standalone: true, DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	acl, err := NewACL(ACLConfig{Users: []*ACLUser{
+		{Name: "reader", Password: "secret", Categories: []string{"read"}, Keys: []string{"public:*", "config"}},
+		{Name: "writer", Password: "secret", Categories: []string{"read", "write"}, Keys: []string{"*"}},
+		{Name: "subscriber", Password: "secret", Categories: []string{"pubsub"}, Channels: []string{"news:*", "alerts"}},
+		{Name: "admin", Password: "secret", Categories: []string{"admin"}},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s.SetACL(acl)
+	user := func(name string) *ACLUser {
+		return acl.users[name]
+	}
+
+	tests := []struct {
+		name     string
+		user     *ACLUser
+		command  string
+		args     []string
+		expected error
+	}{
+		{name: "read allowed", user: user("reader"), command: "get", args: []string{"public:1"}},
+		{name: "exact key allowed", user: user("reader"), command: "GET", args: []string{"config"}},
+		{name: "exact key is not a prefix", user: user("reader"), command: "GET", args: []string{"configs"}, expected: &PermissionError{}},
+		{name: "key outside the prefix", user: user("reader"), command: "GET", args: []string{"private:1"}, expected: &PermissionError{}},
+		{name: "every key of MGET is checked", user: user("reader"), command: "MGET", args: []string{"public:1", "private:1"}, expected: &PermissionError{}},
+		{name: "every key of MSET is checked", user: user("writer"), command: "MSET", args: []string{"a", "1", "b", "2"}},
+		{name: "write denied", user: user("reader"), command: "SET", args: []string{"public:1", "v"}, expected: &PermissionError{}},
+		{name: "write allowed", user: user("writer"), command: "SET", args: []string{"private:1", "v"}},
+		{name: "all keys need the * pattern", user: user("reader"), command: "KEYS", args: []string{".*"}, expected: &PermissionError{}},
+		{name: "all keys allowed", user: user("writer"), command: "KEYS", args: []string{".*"}},
+		{name: "admin denied", user: user("writer"), command: "SNAPSHOT", expected: &PermissionError{}},
+		{name: "admin allowed", user: user("admin"), command: "SNAPSHOT"},
+		{name: "channel allowed", user: user("subscriber"), command: "PUBLISH", args: []string{"news:sport", "goal"}},
+		{name: "exact channel allowed", user: user("subscriber"), command: "SUBSCRIBE", args: []string{"alerts", "news:tech"}},
+		{name: "channel denied", user: user("subscriber"), command: "SUBSCRIBE", args: []string{"news:tech", "private"}, expected: &PermissionError{}},
+		{name: "pubsub denied", user: user("writer"), command: "PUBLISH", args: []string{"news:sport", "goal"}, expected: &PermissionError{}},
+		{name: "connection commands need no category", user: user("subscriber"), command: "MULTI"},
+		{name: "not authenticated", command: "GET", args: []string{"public:1"}, expected: ErrNoAuth},
+		{name: "connection commands need authenticating", command: "MULTI", expected: ErrNoAuth},
+		{name: "AUTH before authenticating", command: "AUTH", args: []string{"reader", "secret"}},
+		{name: "unknown commands fail on their own", command: "NOPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkACL(tt.user, tt.command, tt.args)
+			switch expected := tt.expected.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			case *PermissionError:
+				if !errors.As(err, &expected) {
+					t.Fatalf("expected a permission error, got %v", err)
+				}
+			default:
+				if !errors.Is(err, expected) {
+					t.Fatalf("expected %v, got %v", expected, err)
+				}
+			}
+		})
+	}
+
+	s.SetACL(nil)
+	if err = s.checkACL(nil, "SET", []string{"key", "value"}); err != nil {
+		t.Fatalf("expected no error without an ACL, got %v", err)
+	}
+}
+
+func TestNewACL(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   ACLConfig
+		expected string
+	}{
+		{name: "valid", config: ACLConfig{Users: []*ACLUser{{Name: "alice", Categories: []string{"read"}}}, ClusterUser: "alice"}},
+		{name: "no name", config: ACLConfig{Users: []*ACLUser{{Password: "secret"}}}, expected: "ACL user without a name"},
+		{name: "defined twice", config: ACLConfig{Users: []*ACLUser{{Name: "alice"}, {Name: "alice"}}}, expected: "ACL user alice is defined twice"},
+		{name: "unknown category", config: ACLConfig{Users: []*ACLUser{{Name: "alice", Categories: []string{"everything"}}}}, expected: "ACL user alice has unknown category everything"},
+		{name: "unknown cluster user", config: ACLConfig{ClusterUser: "node"}, expected: "ACL cluster user node is not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewACL(tt.config)
+			if tt.expected == "" && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+				t.Fatalf("expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	acl, err := NewACL(ACLConfig{Users: []*ACLUser{
+		{Name: "alice", Password: "secret", Categories: []string{"read", "write"}, Keys: []string{"*"}},
+		{Name: DefaultUserName, Password: "shared", Categories: []string{"read"}, Keys: []string{"*"}},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, addr := startStandalone(t, "", "", func(s *Server) {
+		s.SetACL(acl)
+	})
+	defer s.Shutdown()
+
+	rc := dialRaw(t, addr)
+	// The default user runs the commands sent before AUTH
+	if reply := rc.do("LLEN", "list"); reply != ":0\r\n" {
+		t.Fatalf("expected 0, got %q", reply)
+	}
+	if reply := rc.do("SET", "key", "value"); !strings.HasPrefix(reply, "-NOPERM") {
+		t.Fatalf("expected NOPERM, got %q", reply)
+	}
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"AUTH", "alice", "wrong"}, expected: "-WRONGPASS"},
+		{args: []string{"AUTH", "nobody", "secret"}, expected: "-WRONGPASS"},
+		{args: []string{"AUTH", "wrong"}, expected: "-WRONGPASS"},
+		{args: []string{"AUTH"}, expected: "-invalid number of arguments"},
+		{args: []string{"AUTH", "alice", "secret"}, expected: "+OK"},
+		{args: []string{"SET", "key", "value"}, expected: "+OK"},
+		// A password alone authenticates as the default user
+		{args: []string{"AUTH", "shared"}, expected: "+OK"},
+		{args: []string{"SET", "key", "value"}, expected: "-NOPERM"},
+	} {
+		if reply := rc.do(tt.args...); !strings.HasPrefix(reply, tt.expected) {
+			t.Fatalf("%v: expected %q, got %q", tt.args, tt.expected, reply)
+		}
+	}
+
+	// Without a default user nothing but AUTH runs before authenticating
+	acl, err = NewACL(ACLConfig{Users: []*ACLUser{acl.users["alice"]}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, addr = startStandalone(t, "", "", func(s *Server) {
+		s.SetACL(acl)
+	})
+	defer s.Shutdown()
+	rc = dialRaw(t, addr)
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"GET", "key"}, expected: "-NOAUTH"},
+		{args: []string{"PING"}, expected: "-NOAUTH"},
+		{args: []string{"AUTH", "alice", "wrong"}, expected: "-WRONGPASS"},
+		{args: []string{"GET", "key"}, expected: "-NOAUTH"},
+		{args: []string{"AUTH", "alice", "secret"}, expected: "+OK"},
+		{args: []string{"LLEN", "list"}, expected: ":0"},
+	} {
+		if reply := rc.do(tt.args...); !strings.HasPrefix(reply, tt.expected) {
+			t.Fatalf("%v: expected %q, got %q", tt.args, tt.expected, reply)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+)
+
+const AuthCommandName = "AUTH"
+
+func RegisterAuthCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     AuthCommandName,
+		Args:     "[username] password",
+		Execute:  executeAuth(),
+		Category: commands.CategoryConnection,
+		NoAuth:   true,
+	})
+}
+
+func executeAuth() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// The user belongs to this connection, so AUTH is never forwarded to the leader
+		if ts.GetACL() == nil {
+			ts.RespondErr(c, fmt.Errorf("AUTH called without any users configured"))
+			return gnet.None
+		}
+
+		// A password alone authenticates as the default user, like Redis does
+		name := DefaultUserName
+		var password string
+		switch len(args) {
+		case 1:
+			password = args[0]
+		case 2:
+			name, password = args[0], args[1]
+		default:
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+
+		user, err := ts.GetACL().Authenticate(name, password)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		ts.SetConnectionUser(c.RemoteAddr().String(), user)
+
+		_, errConn := c.Write([]byte(resp.EncodeSimpleString("OK")))
+		if errConn != nil {
+			ts.RespondErr(c, errConn)
+		}
+		return gnet.None
+	}
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+)
+
+const BackupCommandName = "BACKUP"
+
+// DefaultBackupDir is where backups are written when no directory is configured, next to the data directory
+const DefaultBackupDir = "backups"
+
+// Backup archives are named after the time they were taken, so their names sort in the order they were written
+const (
+	backupPrefix     = "backup-"
+	backupSuffix     = ".tar"
+	backupTimeLayout = "20060102T150405.000Z"
+)
+
+// BackupConfig is where backups are written and how long they are kept
+type BackupConfig struct {
+	Dir string
+	// Interval between scheduled backups, backups are only taken with BACKUP when it is 0
+	Interval time.Duration
+	// Number of backups kept, all of them are kept when it is 0
+	RetainCount int
+	// Age after which backups are removed, they are kept whatever their age when it is 0
+	RetainAge time.Duration
+}
+
+func RegisterBackupCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:      BackupCommandName,
+		Execute:   executeBackup(),
+		Category:  commands.CategoryAdmin,
+		NeedsRaft: true,
+	})
+}
+
+func executeBackup() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		// Process this command on leader, the archive is written on the leader like the scheduled backups
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// If request is forwarded we just send back the answer from the leader to the client
+		// and stop processing
+		if forwarded {
+			_, errConn := c.Write([]byte(rspFwd))
+			if errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		// The backup is written in the background and its path is written once it is done
+		ts.deferReply(c, func() string {
+			path, errBackup := ts.Backup()
+			if errBackup != nil {
+				fmt.Println("Error occurred writing backup", errBackup)
+				return resp.EncodeError(errBackup.Error())
+			}
+			return resp.EncodeBulkString(path)
+		})
+		return gnet.None
+	}
+}
+
+// Backup takes a Raft snapshot, archives it in the backup directory, removes the backups past the retention
+// and returns the path of the archive. The latest snapshot is archived when nothing was written since it was taken.
+func (ts *Server) Backup() (string, error) {
+	meta, state, err := ts.latestSnapshot()
+	if err != nil {
+		return "", err
+	}
+	defer state.Close()
+
+	if err = os.MkdirAll(ts.backup.Dir, 0755); err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	path := filepath.Join(ts.backup.Dir, backupPrefix+now.Format(backupTimeLayout)+backupSuffix)
+	if err = writeBackupArchive(path, meta, state); err != nil {
+		return "", err
+	}
+	if err = pruneBackups(ts.backup, now); err != nil {
+		return path, fmt.Errorf("backup %s written, removing old backups failed: %v", path, err)
+	}
+	return path, nil
+}
+
+// latestSnapshot takes a Raft snapshot and opens it, or opens the latest snapshot when there is nothing new to snapshot
+func (ts *Server) latestSnapshot() (*raft.SnapshotMeta, io.ReadCloser, error) {
+	future := ts.raft.Snapshot()
+	err := future.Error()
+	if err == nil {
+		return future.Open()
+	}
+	if !errors.Is(err, raft.ErrNothingNewToSnapshot) {
+		return nil, nil, err
+	}
+	snapshots, err := ts.snapshotStore.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, nil, fmt.Errorf("nothing to back up, no write was applied yet")
+	}
+	return ts.snapshotStore.Open(snapshots[0].ID)
+}
+
+// RunBackups takes a backup every interval of the config while the node is the leader, so a cluster writes a single
+// series of backups, until the server is shut down.
+// It returns at once when the interval is 0 or the node is standalone, backups are Raft snapshots.
+func (ts *Server) RunBackups() {
+	if ts.backup.Interval <= 0 || ts.standalone {
+		return
+	}
+	ticker := time.NewTicker(ts.backup.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ts.done:
+			return
+		case <-ticker.C:
+		}
+		if ts.raft.State() != raft.Leader {
+			continue
+		}
+		path, err := ts.Backup()
+		if err != nil {
+			fmt.Println("Error occurred writing scheduled backup", err)
+			continue
+		}
+		fmt.Println("Backup written to", path)
+	}
+}
+
+// writeBackupArchive writes a tar archive holding meta.json and state.bin, the files of a snapshot folder.
+// The archive is written next to path and renamed once complete, so path never holds a partial backup.
+func writeBackupArchive(path string, meta *raft.SnapshotMeta, state io.Reader) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	defer file.Close()
+
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	archive := tar.NewWriter(file)
+	modTime := time.Now()
+	if err = archive.WriteHeader(&tar.Header{Name: "meta.json", Mode: 0644, Size: int64(len(metaData)), ModTime: modTime}); err != nil {
+		return err
+	}
+	if _, err = archive.Write(metaData); err != nil {
+		return err
+	}
+	if err = archive.WriteHeader(&tar.Header{Name: "state.bin", Mode: 0644, Size: meta.Size, ModTime: modTime}); err != nil {
+		return err
+	}
+	if _, err = io.CopyN(archive, state, meta.Size); err != nil {
+		return err
+	}
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// openBackupArchive returns the metadata and the state of a backup archive, the state is read from the archive file
+func openBackupArchive(path string) (*raft.SnapshotMeta, io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	archive := tar.NewReader(file)
+	var meta *raft.SnapshotMeta
+	for {
+		header, errNext := archive.Next()
+		if errors.Is(errNext, io.EOF) {
+			_ = file.Close()
+			return nil, nil, fmt.Errorf("%s is not a backup archive, state.bin is missing", path)
+		}
+		if errNext != nil {
+			_ = file.Close()
+			return nil, nil, fmt.Errorf("reading backup archive %s: %v", path, errNext)
+		}
+		switch header.Name {
+		case "meta.json":
+			if err = json.NewDecoder(archive).Decode(&meta); err != nil {
+				_ = file.Close()
+				return nil, nil, fmt.Errorf("reading meta.json of %s: %v", path, err)
+			}
+		case "state.bin":
+			if meta == nil {
+				_ = file.Close()
+				return nil, nil, fmt.Errorf("%s is not a backup archive, meta.json is missing", path)
+			}
+			// The size of the state in the archive is checked against the metadata like the size of state.bin in a folder
+			if header.Size != meta.Size {
+				_ = file.Close()
+				return nil, nil, fmt.Errorf("%w: state.bin has %d bytes, meta.json expects %d", store.ErrSnapshotCorrupted, header.Size, meta.Size)
+			}
+			return meta, struct {
+				io.Reader
+				io.Closer
+			}{archive, file}, nil
+		}
+	}
+}
+
+// pruneBackups removes the backups past the count and age of the retention, the latest backup is always kept
+func pruneBackups(config BackupConfig, now time.Time) error {
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			backups = append(backups, name)
+		}
+	}
+	// Newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, name := range backups {
+		if i == 0 {
+			continue
+		}
+		expired := config.RetainCount > 0 && i >= config.RetainCount
+		if config.RetainAge > 0 {
+			takenAt, errParse := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+			expired = expired || (errParse == nil && now.Sub(takenAt) > config.RetainAge)
+		}
+		if !expired {
+			continue
+		}
+		if err = os.Remove(filepath.Join(config.Dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"treds/store"
+)
+
+// startNode serves a single node Raft cluster with its data under dir and returns its client address once it leads,
+// setup runs before the node starts serving
+func startNode(t *testing.T, dir string, setup ...func(s *Server)) (*Server, string) {
+	t.Helper()
+	ports, err := freePorts(2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, err := New(Config{
+		Port:         ports[0],
+		BindAddr:     "127.0.0.1",
+		RaftPort:     ports[1],
+		DataDir:      filepath.Join(dir, DefaultDataDir),
+		ServerIdFile: filepath.Join(dir, "server-id"),
+		SegmentSize:  1024 * 1024,
+		ApplyTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, setupServer := range setup {
+		setupServer(s)
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0]))
+	go func() {
+		_ = s.Serve(addr)
+	}()
+	if err = waitForListener(addr, 10*time.Second); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for s.raft.State() != raft.Leader {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the node to be elected")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return s, addr
+}
+
+func TestBackupPipelined(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backups")
+	s, addr := startNode(t, dir, func(s *Server) {
+		s.SetBackupConfig(BackupConfig{Dir: backupDir})
+	})
+	defer s.Shutdown()
+
+	rc := dialRaw(t, addr)
+	// The commands after BACKUP wait for its reply, which is written once the archive is written
+	replies := rc.pipeline([]string{"SET", "key", "value"}, []string{"BACKUP"}, []string{"PING"})
+	if replies[0] != "+OK\r\n" || !strings.Contains(replies[1], backupDir) || replies[2] != "+PONG\r\n" {
+		t.Fatalf("expected OK, the path of the backup and PONG, got %q", replies)
+	}
+	entries, err := os.ReadDir(backupDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected a backup, got %v, %v", entries, err)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	s, addr := startNode(t, dir, func(s *Server) {
+		s.SetBackupConfig(BackupConfig{Dir: filepath.Join(dir, "backups")})
+	})
+	defer s.Shutdown()
+
+	do(t, addr, "SET", "key", "value")
+	do(t, addr, "RPUSH", "list", "a", "b")
+	path, ok := do(t, addr, "BACKUP").(string)
+	if !ok {
+		t.Fatalf("expected the path of the backup")
+	}
+	meta, state, err := openBackupArchive(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if errVerify := store.VerifySnapshot(state); errVerify != nil {
+		t.Fatalf("expected the archived state to verify, got %v", errVerify)
+	}
+	_ = state.Close()
+	if meta.Index == 0 || meta.Size == 0 {
+		t.Fatalf("expected the metadata of the snapshot, got %+v", meta)
+	}
+
+	do(t, addr, "SET", "key", "changed")
+	do(t, addr, "DEL", "list")
+	if got := do(t, addr, "RESTORE", path); got != "OK" {
+		t.Fatalf("expected OK, got %v", got)
+	}
+	if got := do(t, addr, "GET", "key"); got != "value" {
+		t.Fatalf("expected value, got %v", got)
+	}
+	if got := do(t, addr, "LRANGE", "list", "0", "-1"); !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Fatalf("expected the list to be restored, got %v", got)
+	}
+}
+
+func TestBackupArchive(t *testing.T) {
+	tredsStore := store.NewTredsStore()
+	_ = tredsStore.Set("key", "value")
+	view, err := tredsStore.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var state bytes.Buffer
+	if err = view.Persist(&state, store.CompressionNone); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	dir := t.TempDir()
+	meta := &raft.SnapshotMeta{Version: raft.SnapshotVersionMax, ID: "2-10-1", Index: 10, Term: 2, Size: int64(state.Len())}
+	path := filepath.Join(dir, "backup.tar")
+	if err = writeBackupArchive(path, meta, bytes.NewReader(state.Bytes())); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the temporary archive to be renamed, got %v", err)
+	}
+
+	openedMeta, opened, err := openBackupArchive(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, err := io.ReadAll(opened)
+	_ = opened.Close()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(openedMeta, meta) || !bytes.Equal(data, state.Bytes()) {
+		t.Fatalf("expected the archive to hold %+v and the state, got %+v and %d bytes", meta, openedMeta, len(data))
+	}
+
+	// An archive cut in state.bin opens, the state fails to verify
+	archive, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	truncated := filepath.Join(dir, "truncated.tar")
+	// state.bin is padded to blocks of 512 bytes and followed by two empty blocks
+	stateStart := len(archive) - 1024 - (int(meta.Size)+511)/512*512
+	if err = os.WriteFile(truncated, archive[:stateStart+int(meta.Size)/2], 0644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = verifySnapshotPath(truncated); err == nil {
+		t.Fatalf("expected a truncated archive to fail")
+	}
+
+	// The size of state.bin must be the size of the metadata
+	mismatched := filepath.Join(dir, "mismatched.tar")
+	writeTar(t, mismatched, map[string][]byte{
+		"meta.json": []byte(`{"Version":1,"ID":"2-10-1","Index":10,"Term":2,"Size":100}`),
+		"state.bin": state.Bytes(),
+	})
+	if _, _, err = openBackupArchive(mismatched); !errors.Is(err, store.ErrSnapshotCorrupted) {
+		t.Fatalf("expected ErrSnapshotCorrupted, got %v", err)
+	}
+
+	missing := filepath.Join(dir, "missing.tar")
+	writeTar(t, missing, map[string][]byte{"meta.json": []byte(`{"Size":1}`)})
+	if _, _, err = openBackupArchive(missing); err == nil || !strings.Contains(err.Error(), "state.bin is missing") {
+		t.Fatalf("expected state.bin to be missing, got %v", err)
+	}
+}
+
+// writeTar writes an archive with the files in the order of their names
+func writeTar(t *testing.T, path string, files map[string][]byte) {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	for _, name := range names {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name]))}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := archive.Write(files[name]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRunBackupsShutdown(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := startNode(t, dir, func(s *Server) {
+		s.SetBackupConfig(BackupConfig{Dir: filepath.Join(dir, "backups"), Interval: time.Hour})
+	})
+	stopped := make(chan struct{})
+	go func() {
+		s.RunBackups()
+		close(stopped)
+	}()
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the scheduled backups to stop with the server")
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	// The ages of the backups, newest first
+	ages := []time.Duration{0, 30 * time.Minute, 2 * time.Hour, 3 * time.Hour}
+	backup := func(age time.Duration) string {
+		return backupPrefix + now.Add(-age).Format(backupTimeLayout) + backupSuffix
+	}
+
+	tests := []struct {
+		name     string
+		config   BackupConfig
+		ages     []time.Duration
+		expected []time.Duration
+	}{
+		{name: "no retention", ages: ages, expected: ages},
+		{name: "count", config: BackupConfig{RetainCount: 2}, ages: ages, expected: ages[:2]},
+		{name: "age", config: BackupConfig{RetainAge: time.Hour}, ages: ages, expected: ages[:2]},
+		{name: "count and age", config: BackupConfig{RetainCount: 1, RetainAge: time.Hour}, ages: ages, expected: ages[:1]},
+		{name: "newest kept past the age", config: BackupConfig{RetainAge: time.Hour}, ages: ages[2:], expected: ages[2:3]},
+		{name: "newest kept past the count", config: BackupConfig{RetainCount: 1}, ages: ages, expected: ages[:1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Dir = t.TempDir()
+			// Files that are not backups are left alone
+			for _, name := range append([]string{"notes.txt", backupPrefix + "partial" + backupSuffix + ".tmp"}, backupNames(tt.ages, backup)...) {
+				if err := os.WriteFile(filepath.Join(tt.config.Dir, name), nil, 0644); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+			if err := pruneBackups(tt.config, now); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			entries, err := os.ReadDir(tt.config.Dir)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			var remaining []string
+			for _, entry := range entries {
+				if strings.HasSuffix(entry.Name(), backupSuffix) {
+					remaining = append(remaining, entry.Name())
+				}
+			}
+			sort.Sort(sort.Reverse(sort.StringSlice(remaining)))
+			if expected := backupNames(tt.expected, backup); !reflect.DeepEqual(remaining, expected) {
+				t.Fatalf("expected %v, got %v", expected, remaining)
+			}
+			if len(entries) != len(remaining)+2 {
+				t.Fatalf("expected the other files to be kept, got %v", entries)
+			}
+		})
+	}
+}
+
+func backupNames(ages []time.Duration, backup func(time.Duration) string) []string {
+	names := make([]string, 0, len(ages))
+	for _, age := range ages {
+		names = append(names, backup(age))
+	}
+	return names
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+)
+
+const ClusterCommandName = "CLUSTER"
+
+// The subcommands of CLUSTER
+const (
+	clusterJoin           = "JOIN"
+	clusterLeave          = "LEAVE"
+	clusterMembers        = "MEMBERS"
+	clusterTransferLeader = "TRANSFER-LEADER"
+)
+
+func RegisterClusterCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:      ClusterCommandName,
+		Args:      "JOIN id address [VOTER|NONVOTER] | LEAVE id | MEMBERS | TRANSFER-LEADER [id]",
+		Execute:   executeCluster(),
+		Category:  commands.CategoryAdmin,
+		NeedsRaft: true,
+	})
+}
+
+func executeCluster() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		if len(args) == 0 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+		subcommand := strings.ToUpper(args[0])
+		args = args[1:]
+
+		// Members are listed from the configuration this node knows, so they can be listed while there is no leader
+		if subcommand == clusterMembers {
+			if len(args) != 0 {
+				ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+				return gnet.None
+			}
+			members, errMembers := ts.clusterMembers()
+			if errMembers != nil {
+				ts.RespondErr(c, errMembers)
+				return gnet.None
+			}
+			if _, errConn := c.Write([]byte(resp.Encode2DStringArrayRESP(members))); errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		change, err := parseClusterChange(subcommand, args)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// Process this command on leader, only the leader changes the configuration of the cluster
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// If request is forwarded we just send back the answer from the leader to the client
+		// and stop processing
+		if forwarded {
+			_, errConn := c.Write([]byte(rspFwd))
+			if errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		future, err := ts.changeCluster(change)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		// The change is committed in the background and the reply is written once it is done
+		ts.deferReply(c, func() string {
+			if errFuture := future.Error(); errFuture != nil {
+				return resp.EncodeError(errFuture.Error())
+			}
+			return resp.EncodeSimpleString("OK")
+		})
+		return gnet.None
+	}
+}
+
+// clusterChange is a change of the configuration of the cluster asked with CLUSTER
+type clusterChange struct {
+	subcommand string
+	id         raft.ServerID
+	address    raft.ServerAddress
+	suffrage   raft.ServerSuffrage
+}
+
+func parseClusterChange(subcommand string, args []string) (*clusterChange, error) {
+	change := &clusterChange{subcommand: subcommand, suffrage: raft.Voter}
+	switch subcommand {
+	case clusterJoin:
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("invalid number of arguments")
+		}
+		host, port, err := net.SplitHostPort(args[1])
+		if _, errPort := strconv.Atoi(port); err != nil || host == "" || errPort != nil {
+			return nil, fmt.Errorf("invalid address %s, expected the host:port of the Raft transport of the node", args[1])
+		}
+		change.address = raft.ServerAddress(args[1])
+		if len(args) == 3 {
+			switch strings.ToUpper(args[2]) {
+			case "VOTER":
+			case "NONVOTER":
+				change.suffrage = raft.Nonvoter
+			default:
+				return nil, fmt.Errorf("expected VOTER or NONVOTER, got %s", args[2])
+			}
+		}
+	case clusterLeave:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("invalid number of arguments")
+		}
+	case clusterTransferLeader:
+		if len(args) > 1 {
+			return nil, fmt.Errorf("invalid number of arguments")
+		}
+		if len(args) == 0 {
+			return change, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s, expected JOIN, LEAVE, MEMBERS or TRANSFER-LEADER", subcommand)
+	}
+	// Server ids are uuids, like the ids nodes are started with
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid server id %s, it must be a uuid", args[0])
+	}
+	change.id = raft.ServerID(id.String())
+	return change, nil
+}
+
+// changeCluster starts the change of the configuration on the leader, the returned future is done once it is committed
+func (ts *Server) changeCluster(change *clusterChange) (raft.Future, error) {
+	timeout := ts.GetRaftApplyTimeout()
+	switch change.subcommand {
+	case clusterJoin:
+		if change.suffrage == raft.Nonvoter {
+			// Raft keeps a voter a voter when it is added as a nonvoter, it has to be demoted
+			server, found, err := ts.clusterServer(change.id)
+			if err != nil {
+				return nil, err
+			}
+			if found && server.Suffrage == raft.Voter {
+				return ts.raft.DemoteVoter(change.id, 0, timeout), nil
+			}
+			return ts.raft.AddNonvoter(change.id, change.address, 0, timeout), nil
+		}
+		return ts.raft.AddVoter(change.id, change.address, 0, timeout), nil
+	case clusterLeave:
+		if _, found, err := ts.clusterServer(change.id); err != nil || !found {
+			if err == nil {
+				err = fmt.Errorf("server %s is not a member of the cluster", change.id)
+			}
+			return nil, err
+		}
+		return ts.raft.RemoveServer(change.id, 0, timeout), nil
+	}
+
+	// Leadership is transferred to a voter raft picks when no id is given
+	if change.id == "" {
+		return ts.raft.LeadershipTransfer(), nil
+	}
+	if change.id == ts.id {
+		return nil, fmt.Errorf("server %s is already the leader", change.id)
+	}
+	server, found, err := ts.clusterServer(change.id)
+	if err != nil {
+		return nil, err
+	}
+	if !found || server.Suffrage != raft.Voter {
+		return nil, fmt.Errorf("server %s is not a voter of the cluster", change.id)
+	}
+	return ts.raft.LeadershipTransferToServer(server.ID, server.Address), nil
+}
+
+// clusterServer returns the server of the configuration with the id
+func (ts *Server) clusterServer(id raft.ServerID) (raft.Server, bool, error) {
+	future := ts.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return raft.Server{}, false, err
+	}
+	for _, server := range future.Configuration().Servers {
+		if server.ID == id {
+			return server, true, nil
+		}
+	}
+	return raft.Server{}, false, nil
+}
+
+// clusterMembers returns the id, Raft address, suffrage and role of every server of the configuration
+func (ts *Server) clusterMembers() ([][]string, error) {
+	future := ts.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	_, leaderId := ts.raft.LeaderWithID()
+	members := make([][]string, 0, len(future.Configuration().Servers))
+	for _, server := range future.Configuration().Servers {
+		role := "follower"
+		if server.ID == leaderId {
+			role = "leader"
+		}
+		members = append(members, []string{string(server.ID), string(server.Address), strings.ToLower(server.Suffrage.String()), role})
+	}
+	return members, nil
+}
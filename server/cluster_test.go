@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"treds/client"
+)
+
+// doErr runs a command on addr
+func doErr(addr string, args ...interface{}) (interface{}, error) {
+	c := client.New(client.Options{Addr: addr})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.Do(ctx, args...)
+}
+
+// do runs a command on addr and fails the test when it returns an error
+func do(t *testing.T, addr string, args ...interface{}) interface{} {
+	t.Helper()
+	reply, err := doErr(addr, args...)
+	if err != nil {
+		t.Fatalf("%v: expected no error, got %v", args, err)
+	}
+	return reply
+}
+
+// members returns the servers CLUSTER MEMBERS lists on addr by id
+func members(t *testing.T, addr string) map[string][]interface{} {
+	t.Helper()
+	byId := make(map[string][]interface{})
+	for _, member := range do(t, addr, "CLUSTER", "MEMBERS").([]interface{}) {
+		fields := member.([]interface{})
+		byId[fields[0].(string)] = fields
+	}
+	return byId
+}
+
+func TestClusterMembership(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	leaderAddr := cluster.ClientAddrs[leader]
+	do(t, leaderAddr, "SET", "key", "value")
+
+	node, err := cluster.AddNode()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = doErr(leaderAddr, "CLUSTER", "JOIN", "not-a-uuid", cluster.RaftAddrs[node]); err == nil {
+		t.Fatalf("expected error for an invalid server id")
+	}
+	do(t, leaderAddr, "CLUSTER", "JOIN", cluster.IDs[node], cluster.RaftAddrs[node], "NONVOTER")
+	// The log is replicated to the new node
+	waitForValue(t, cluster.ClientAddrs[node], "key", "value")
+
+	joined := members(t, leaderAddr)
+	if len(joined) != 4 {
+		t.Fatalf("expected 4 members, got %v", joined)
+	}
+	if fields := joined[cluster.IDs[node]]; fields[1] != cluster.RaftAddrs[node] || fields[2] != "nonvoter" || fields[3] != "follower" {
+		t.Fatalf("unexpected member %v", fields)
+	}
+	if fields := joined[cluster.IDs[leader]]; fields[2] != "voter" || fields[3] != "leader" {
+		t.Fatalf("unexpected member %v", fields)
+	}
+
+	// A nonvoter cannot lead, once promoted it takes the leadership over
+	if _, err = doErr(leaderAddr, "CLUSTER", "TRANSFER-LEADER", cluster.IDs[node]); err == nil {
+		t.Fatalf("expected error transferring the leadership to a nonvoter")
+	}
+	do(t, leaderAddr, "CLUSTER", "JOIN", cluster.IDs[node], cluster.RaftAddrs[node], "VOTER")
+	do(t, leaderAddr, "CLUSTER", "TRANSFER-LEADER", cluster.IDs[node])
+	if newLeader, errLeader := cluster.WaitForLeader(10 * time.Second); errLeader != nil || newLeader != node {
+		t.Fatalf("expected node %d to lead, got %d, %v", node, newLeader, errLeader)
+	}
+
+	// The old leader is removed from the configuration by the new one
+	do(t, cluster.ClientAddrs[node], "CLUSTER", "LEAVE", cluster.IDs[leader])
+	left := members(t, cluster.ClientAddrs[node])
+	if _, found := left[cluster.IDs[leader]]; found || len(left) != 3 {
+		t.Fatalf("expected 3 members without the old leader, got %v", left)
+	}
+	do(t, cluster.ClientAddrs[node], "SET", "key", "other")
+	waitForValue(t, cluster.ClientAddrs[(leader+1)%3], "key", "other")
+}
+
+func TestClusterPipelined(t *testing.T) {
+	s, addr := startNode(t, t.TempDir())
+	defer s.Shutdown()
+
+	// The commands after a change wait for its reply, which is written once the configuration is committed
+	id := uuid.New().String()
+	rc := dialRaw(t, addr)
+	replies := rc.pipeline(
+		[]string{"CLUSTER", "JOIN", id, "127.0.0.1:1", "NONVOTER"}, []string{"CLUSTER", "MEMBERS"},
+		[]string{"CLUSTER", "LEAVE", id}, []string{"CLUSTER", "MEMBERS"},
+	)
+	if replies[0] != "+OK\r\n" || !strings.Contains(replies[1], id) || replies[2] != "+OK\r\n" || strings.Contains(replies[3], id) {
+		t.Fatalf("expected %s to join and leave in order, got %q", id, replies)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+)
+
+const CommandCommandName = "COMMAND"
+
+// Kinds of commands reported by COMMAND
+const (
+	CommandKindRead   = "read"
+	CommandKindWrite  = "write"
+	CommandKindServer = "server"
+)
+
+func RegisterCommandCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     CommandCommandName,
+		Args:     "[COUNT | INFO name [name ...]]",
+		Execute:  executeCommandCommand(),
+		Category: commands.CategoryConnection,
+		NoAuth:   true,
+	})
+}
+
+// executeCommandCommand describes the registered commands, every entry is the name, the argument hint and the kind.
+// The registries are the same on every node so COMMAND is never forwarded to the leader.
+func executeCommandCommand() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		entries := make(map[string][]string)
+		names := make([]string, 0)
+		for _, reg := range ts.GetCommandRegistry().List() {
+			kind := CommandKindRead
+			if reg.IsWrite {
+				kind = CommandKindWrite
+			}
+			entries[reg.Name] = []string{reg.Name, reg.Args, kind}
+			names = append(names, reg.Name)
+		}
+		for _, reg := range ts.GetServerCommandRegistry().List() {
+			entries[reg.Name] = []string{reg.Name, reg.Args, CommandKindServer}
+			names = append(names, reg.Name)
+		}
+
+		var res string
+		switch {
+		case len(args) == 0:
+			rows := make([]string, 0, len(names))
+			for _, name := range names {
+				rows = append(rows, resp.EncodeStringArray(entries[name]))
+			}
+			res = resp.EncodeStringArrayRESP(rows)
+		case strings.ToUpper(args[0]) == "COUNT":
+			res = resp.EncodeInteger(len(names))
+		case strings.ToUpper(args[0]) == "INFO":
+			rows := make([]string, 0, len(args)-1)
+			for _, name := range args[1:] {
+				// Unknown commands get an empty entry so replies line up with the requested names
+				rows = append(rows, resp.EncodeStringArray(entries[strings.ToUpper(name)]))
+			}
+			res = resp.EncodeStringArrayRESP(rows)
+		default:
+			ts.RespondErr(c, fmt.Errorf("unknown subcommand '%s'", args[0]))
+			return gnet.None
+		}
+
+		_, errConn := c.Write([]byte(res))
+		if errConn != nil {
+			ts.RespondErr(c, errConn)
+		}
+		return gnet.None
+	}
+}
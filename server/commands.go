@@ -3,6 +3,12 @@ package server
 func RegisterCommands(r ServerCommandRegistry) {
 	RegisterSnapshotCommand(r)
 	RegisterRestoreCommand(r)
+	RegisterBackupCommand(r)
+	RegisterRecoverCommand(r)
+	RegisterClusterCommand(r)
+	RegisterLoadRDBCommand(r)
+	RegisterExportCommand(r)
+	RegisterImportCommand(r)
 	RegisterMultiCommand(r)
 	RegisterExecCommand(r)
 	RegisterDiscardCommand(r)
@@ -13,4 +19,10 @@ func RegisterCommands(r ServerCommandRegistry) {
 	RegisterPUnsubscribeCommand(r)
 	RegisterUnsubscribeCommand(r)
 	RegisterPubSubChannels(r)
+	RegisterHelloCommand(r)
+	RegisterAuthCommand(r)
+	RegisterCommandCommand(r)
+	RegisterConsistencyCommand(r)
+	RegisterReadCommand(r)
+	RegisterReplicationCommand(r)
 }
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+)
+
+const ConsistencyCommandName = "CONSISTENCY"
+const ReadCommandName = "READ"
+
+// The levels of consistency reads are made with
+const (
+	// ConsistencyStale reads the local store of the node, it may be behind the leader
+	ConsistencyStale = "STALE"
+	// ConsistencyLeader reads the store of the leader, a leader that lost the leadership without knowing it yet may be behind
+	ConsistencyLeader = "LEADER"
+	// ConsistencyLinearizable reads the store of the leader once it has checked it is still the leader
+	// and applied every write committed before the read
+	ConsistencyLinearizable = "LINEARIZABLE"
+)
+
+// ReadConsistency is the consistency a read is made with, the zero value is a stale read without a bound on the lag
+type ReadConsistency struct {
+	Level string
+	// MaxLag fails stale reads on a follower that has not heard from the leader for longer, there is no bound when it is 0
+	MaxLag time.Duration
+}
+
+func (rc ReadConsistency) String() string {
+	if rc.Level == "" {
+		return ConsistencyStale
+	}
+	if rc.MaxLag > 0 {
+		return rc.Level + " MAXLAG " + strconv.FormatInt(rc.MaxLag.Milliseconds(), 10)
+	}
+	return rc.Level
+}
+
+// args returns the arguments ParseReadConsistency parses back into rc
+func (rc ReadConsistency) args() []string {
+	return strings.Fields(rc.String())
+}
+
+// ParseReadConsistency parses LINEARIZABLE, LEADER or STALE [MAXLAG milliseconds] at the start of args
+// and returns the arguments after it
+func ParseReadConsistency(args []string) (ReadConsistency, []string, error) {
+	if len(args) == 0 {
+		return ReadConsistency{}, nil, fmt.Errorf("invalid number of arguments")
+	}
+	rc := ReadConsistency{Level: strings.ToUpper(args[0])}
+	switch rc.Level {
+	case ConsistencyLinearizable, ConsistencyLeader:
+		return rc, args[1:], nil
+	case ConsistencyStale:
+	default:
+		return ReadConsistency{}, nil, fmt.Errorf("expected LINEARIZABLE, LEADER or STALE, got %s", args[0])
+	}
+	if len(args) < 2 || strings.ToUpper(args[1]) != "MAXLAG" {
+		return rc, args[1:], nil
+	}
+	if len(args) < 3 {
+		return ReadConsistency{}, nil, fmt.Errorf("MAXLAG needs the lag in milliseconds")
+	}
+	millis, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || millis <= 0 {
+		return ReadConsistency{}, nil, fmt.Errorf("invalid max lag %s, expected a positive number of milliseconds", args[2])
+	}
+	rc.MaxLag = time.Duration(millis) * time.Millisecond
+	return rc, args[3:], nil
+}
+
+func RegisterConsistencyCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     ConsistencyCommandName,
+		Args:     "[LINEARIZABLE | LEADER | STALE [MAXLAG milliseconds]]",
+		Execute:  executeConsistency(),
+		Category: commands.CategoryConnection,
+	})
+}
+
+func RegisterReadCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     ReadCommandName,
+		Args:     "LINEARIZABLE | LEADER | STALE [MAXLAG milliseconds] command [arg ...]",
+		Execute:  executeReadWith(),
+		Category: commands.CategoryRead,
+	})
+}
+
+func executeConsistency() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// The consistency belongs to this connection, so CONSISTENCY is never forwarded to the leader
+		ra := c.RemoteAddr().String()
+		if len(args) == 0 {
+			if _, errConn := c.Write([]byte(resp.EncodeBulkString(ts.GetConnectionConsistency(ra).String()))); errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+		consistency, rest, err := ParseReadConsistency(args)
+		if err == nil && len(rest) != 0 {
+			err = fmt.Errorf("invalid number of arguments")
+		}
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		ts.SetConnectionConsistency(ra, consistency)
+		if _, errConn := c.Write([]byte(resp.EncodeSimpleString("OK"))); errConn != nil {
+			fmt.Println("Error occurred writing to connection", errConn)
+		}
+		return gnet.None
+	}
+}
+
+// executeReadWith runs a single read with the given consistency, whatever the consistency of the connection
+func executeReadWith() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		consistency, rest, err := ParseReadConsistency(args)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		if len(rest) == 0 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		commandReg, err := ts.tredsCommandRegistry.Retrieve(strings.ToUpper(rest[0]))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		if commandReg.IsWrite {
+			ts.RespondErr(c, fmt.Errorf("%s only runs read commands", ReadCommandName))
+			return gnet.None
+		}
+		// READ is allowed to users with the read category, the command it runs is checked on its own
+		ra := c.RemoteAddr().String()
+		if err = ts.checkACL(ts.GetConnectionUser(ra), rest[0], rest[1:]); err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		if err = commandReg.Validate(rest[1:]); err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		res, err := ts.executeConsistentRead(commandReg, rest[1:], ts.GetConnectionProtocol(ra), consistency)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		if _, errConn := c.Write([]byte(res)); errConn != nil {
+			fmt.Println("Error occurred writing to connection", errConn)
+		}
+		return gnet.None
+	}
+}
+
+func (ts *Server) GetConnectionConsistency(ra string) ReadConsistency {
+	return ts.connectionConsistency[ra]
+}
+
+func (ts *Server) SetConnectionConsistency(ra string, consistency ReadConsistency) {
+	ts.connectionConsistency[ra] = consistency
+}
+
+// executeConsistentRead runs a validated read command with the consistency and returns the RESP encoded reply.
+// Reads that need the leader are forwarded to it with READ, so it makes them with the same consistency.
+// The error is an UnavailableError when the read cannot be made with the consistency right now.
+func (ts *Server) executeConsistentRead(commandReg *commands.CommandRegistration, args []string, protocol int, consistency ReadConsistency) (string, error) {
+	switch consistency.Level {
+	case ConsistencyLeader, ConsistencyLinearizable:
+		if !ts.isLeader() {
+			addr, _ := ts.raft.LeaderWithID()
+			if addr == "" {
+				return "", &UnavailableError{Err: fmt.Errorf("there is no leader to make the %s read", strings.ToLower(consistency.Level))}
+			}
+			readArgs := append(append(append([]string{ReadCommandName}, consistency.args()...), commandReg.Name), args...)
+			// Reads are forwarded by read-only replicas too, the leader encodes the reply for the protocol of the client
+			forwarded, rspFwd, err := ts.forwardRequest([]byte(resp.EncodeStringArray(readArgs)), protocol, ts.redirect)
+			if err != nil {
+				return "", &UnavailableError{Err: err}
+			}
+			if forwarded {
+				return rspFwd, nil
+			}
+		}
+		if consistency.Level == ConsistencyLinearizable {
+			if err := ts.readBarrier(); err != nil {
+				return "", &UnavailableError{Err: err}
+			}
+		}
+	default:
+		if err := ts.checkLag(consistency.MaxLag); err != nil {
+			return "", &UnavailableError{Err: err}
+		}
+	}
+	return ts.executeRead(commandReg, args, protocol), nil
+}
+
+// readBarrier returns once this node has confirmed with a quorum that it is still the leader and has applied
+// every entry of its log. The log of a leader holds the no-op entry appended when it was elected,
+// so the writes committed by the earlier leaders are applied too. Like writes, it holds the event loop meanwhile.
+func (ts *Server) readBarrier() error {
+	// A standalone node applies every write before it replies to it
+	if ts.standalone {
+		return nil
+	}
+	readIndex := ts.raft.LastIndex()
+	if err := ts.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(ts.raftApplyTimeout)
+	for ts.raft.AppliedIndex() < readIndex {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for index %d to be applied", readIndex)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
+
+// checkLag fails when this node is a follower that has not heard from the leader for longer than maxLag
+func (ts *Server) checkLag(maxLag time.Duration) error {
+	if maxLag <= 0 || ts.isLeader() {
+		return nil
+	}
+	lastContact := ts.raft.LastContact()
+	if lastContact.IsZero() {
+		return fmt.Errorf("this node has not heard from a leader, the read would exceed the max lag of %s", maxLag)
+	}
+	if lag := time.Since(lastContact); lag > maxLag {
+		return fmt.Errorf("this node last heard from the leader %s ago, more than the max lag of %s", lag.Round(time.Millisecond), maxLag)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"treds/client"
+)
+
+func TestReadConsistency(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	follower := (leader + 1) % 3
+
+	// Writes are read back at once on the leader
+	c := client.New(client.Options{Addr: cluster.ClientAddrs[leader], ReadConsistency: "LINEARIZABLE"})
+	defer c.Close()
+	for _, value := range []string{"1", "2", "3"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err = c.Set(ctx, "key", value); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		got, errGet := c.Get(ctx, "key")
+		cancel()
+		if errGet != nil || got != value {
+			t.Fatalf("expected %s, got %q, %v", value, got, errGet)
+		}
+	}
+	waitForValue(t, cluster.ClientAddrs[follower], "key", "3")
+	if reply := do(t, cluster.ClientAddrs[follower], "READ", "STALE", "MAXLAG", "5000", "GET", "key"); reply != "3" {
+		t.Fatalf("expected 3, got %v", reply)
+	}
+	if _, err = doErr(cluster.ClientAddrs[follower], "READ", "LINEARIZABLE", "SET", "key", "4"); err == nil {
+		t.Fatalf("expected error for a write")
+	}
+
+	// Reads forwarded to the leader are encoded for the protocol of the client
+	waitForLeaderAddress(t, cluster, follower, leader)
+	rc := dialRaw(t, cluster.ClientAddrs[follower])
+	rc.do("HELLO", "3")
+	for _, level := range []string{"LEADER", "LINEARIZABLE"} {
+		if reply := rc.do("READ", level, "GET", "missing"); reply != "_\r\n" {
+			t.Fatalf("expected a null for a %s read, got %q", level, reply)
+		}
+		if reply := rc.do("READ", level, "GET", "key"); reply != "$1\r\n3\r\n" {
+			t.Fatalf("expected 3 for a %s read, got %q", level, reply)
+		}
+	}
+
+	// Without a leader, only reads that accept any lag are made
+	for i := range cluster.Servers {
+		if i != follower {
+			if err = cluster.Stop(i); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+	if reply := do(t, cluster.ClientAddrs[follower], "READ", "STALE", "GET", "key"); reply != "3" {
+		t.Fatalf("expected 3, got %v", reply)
+	}
+	if _, err = doErr(cluster.ClientAddrs[follower], "READ", "STALE", "MAXLAG", "200", "GET", "key"); err == nil || !strings.Contains(err.Error(), "max lag") {
+		t.Fatalf("expected the max lag to be exceeded, got %v", err)
+	}
+	if _, err = doErr(cluster.ClientAddrs[follower], "READ", "LINEARIZABLE", "GET", "key"); err == nil {
+		t.Fatalf("expected error without a leader")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
 )
 
@@ -11,15 +12,16 @@ const DiscardCommandName = "DISCARD"
 
 func RegisterDiscardCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    DiscardCommandName,
-		Execute: executeDiscard(),
+		Name:     DiscardCommandName,
+		Execute:  executeDiscard(),
+		Category: commands.CategoryConnection,
 	})
 }
 
 func executeDiscard() ExecutionHook {
 	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
 		// Execute this command at leader
-		forwarded, rspFwd, err := ts.ForwardRequest(([]byte)(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
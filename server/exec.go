@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
 )
 
@@ -12,15 +13,16 @@ const ExecCommandName = "EXEC"
 
 func RegisterExecCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    ExecCommandName,
-		Execute: executeExec(),
+		Name:     ExecCommandName,
+		Execute:  executeExec(),
+		Category: commands.CategoryConnection,
 	})
 }
 
 func executeExec() ExecutionHook {
 	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
 		//Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -61,7 +63,13 @@ func executeExec() ExecutionHook {
 				continue
 			}
 
-			future := ts.GetRaft().Apply([]byte(transactionCommand), ts.GetRaftApplyTimeout())
+			entry, errPrepare := ts.logEntry(commandReg, transactionCommand, storedArgs)
+			if errPrepare != nil {
+				replies = append(replies, errPrepare.Error())
+				continue
+			}
+
+			future := ts.apply(entry)
 
 			if err := future.Error(); err != nil {
 				ts.RespondErr(c, err)
@@ -73,6 +81,8 @@ func executeExec() ExecutionHook {
 			case error:
 				errResp := rsp.(error)
 				replies = append(replies, errResp.Error())
+			case protocolReplies:
+				replies = append(replies, rsp.(protocolReplies).reply(ts.GetConnectionProtocol(c.RemoteAddr().String())))
 			default:
 				replies = append(replies, rsp.(string))
 			}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+)
+
+const ExportCommandName = "EXPORT"
+
+func RegisterExportCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     ExportCommandName,
+		Args:     "path [prefix]",
+		Execute:  executeExport(),
+		Category: commands.CategoryAdmin,
+	})
+}
+
+func executeExport() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		if len(args) != 1 && len(args) != 2 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+
+		// Process this command on leader, the file is written on the leader like IMPORT reads it there
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// If request is forwarded we just send back the answer from the leader to the client
+		// and stop processing
+		if forwarded {
+			_, errConn := c.Write([]byte(rspFwd))
+			if errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		prefix := ""
+		if len(args) == 2 {
+			prefix = args[1]
+		}
+		// The view is taken by the FSM so no log is applied while it is copied
+		view, err := ts.fsm.view()
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// The view is written in the background and the number of keys is written once it is done
+		ts.deferReply(c, func() string {
+			exported, errExport := ExportFile(args[0], view, prefix)
+			if errExport != nil {
+				fmt.Println("Error occurred exporting", errExport)
+				return resp.EncodeError(errExport.Error())
+			}
+			return resp.EncodeInteger(exported)
+		})
+		return gnet.None
+	}
+}
+
+// ExportFile writes the keys of view starting with prefix to path as JSON Lines and returns the number of keys written.
+// The export is written next to path and renamed once complete, so path never holds a partial export.
+func ExportFile(path string, view *store.PointInTimeSnapshot, prefix string) (int, error) {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpPath)
+	defer file.Close()
+	exported, err := view.Export(file, prefix)
+	if err != nil {
+		return 0, err
+	}
+	if err = file.Sync(); err != nil {
+		return 0, err
+	}
+	if err = file.Close(); err != nil {
+		return 0, err
+	}
+	return exported, os.Rename(tmpPath, path)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportImportPipelined(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	s, addr := startStandalone(t, "", "")
+	defer s.Shutdown()
+
+	rc := dialRaw(t, addr)
+	// The commands after EXPORT and IMPORT wait for their reply, which is written once the file is done
+	replies := rc.pipeline(
+		[]string{"SET", "key", "value"}, []string{"RPUSH", "list", "a", "b c"},
+		[]string{"EXPORT", path}, []string{"FLUSHALL"},
+		[]string{"IMPORT", path}, []string{"GET", "key"}, []string{"LRANGE", "list", "0", "-1"},
+	)
+	expected := []string{
+		"+OK\r\n", "+OK\r\n",
+		":2\r\n", "+OK\r\n",
+		":2\r\n", "$5\r\nvalue\r\n", "*2\r\n$1\r\na\r\n$3\r\nb c\r\n",
+	}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Fatalf("expected %q, got %q", expected, replies)
+	}
+}
+
+func TestExportImportBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	s, addr := startStandalone(t, "", "")
+	defer s.Shutdown()
+
+	// The bytes are not UTF-8, JSON strings cannot hold them as they are. Keys, members and fields are printable,
+	// the values are binary safe.
+	binary := "\b\x96\x01\xff\xfe"
+	rc := dialRaw(t, addr)
+	for _, args := range [][]string{
+		{"SET", "key", binary},
+		{"SET", "text", "value"},
+		{"ZADD", "board", "1.5", "bob", binary, "2", "alice", "a"},
+		{"RPUSH", "list", "a", binary},
+		{"SADD", "set", binary},
+		{"HSET", "hash", "binary", binary, "field", "value"},
+	} {
+		if reply := rc.do(args...); reply[0] == '-' {
+			t.Fatalf("%v: expected no error, got %q", args, reply)
+		}
+	}
+	reads := [][]string{
+		{"GET", "key"}, {"GET", "text"},
+		{"ZRANGESCORE", "board", "0", "10", "0", "10", "1"},
+		{"LRANGE", "list", "0", "-1"},
+		{"SMEMBERS", "set"},
+		{"HGET", "hash", "binary"}, {"HGET", "hash", "field"},
+	}
+	expected := rc.pipeline(reads...)
+
+	if reply := rc.do("EXPORT", path); reply != ":6\r\n" {
+		t.Fatalf("expected 6 keys, got %q", reply)
+	}
+	rc.do("FLUSHALL")
+	if reply := rc.do("IMPORT", path); reply != ":6\r\n" {
+		t.Fatalf("expected 6 keys, got %q", reply)
+	}
+	if replies := rc.pipeline(reads...); !reflect.DeepEqual(replies, expected) {
+		t.Fatalf("expected %q, got %q", expected, replies)
+	}
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"treds/resp"
+)
+
+// forwardDialTimeout bounds connecting to the leader
+const forwardDialTimeout = 5 * time.Second
+
+// clientAddrKey is the key of the metadata of the cluster the client address of a node is published at
+func clientAddrKey(id raft.ServerID) string {
+	return "client-addr/" + string(id)
+}
+
+// publishClientAddress publishes the client address of this node in the metadata of the cluster
+// while it leads and the published address is not its own, until Shutdown
+func (ts *Server) publishClientAddress() {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ts.done:
+			return
+		case <-ticker.C:
+		}
+		if ts.raft.State() != raft.Leader {
+			continue
+		}
+		key := clientAddrKey(ts.id)
+		if addr, ok := ts.fsm.Metadata(key); ok && addr == ts.clientAddr {
+			continue
+		}
+		entry := resp.EncodeStringArray([]string{metadataCommandName, key, ts.clientAddr})
+		if err := ts.raft.Apply([]byte(entry), ts.raftApplyTimeout).Error(); err != nil {
+			fmt.Println("Error occurred publishing the client address", err)
+		}
+	}
+}
+
+// LeaderAddress returns the client address of the current leader, empty when there is no known leader
+// or the leader has not published its address yet
+func (ts *Server) LeaderAddress() string {
+	if ts.standalone {
+		return ts.clientAddr
+	}
+	_, leaderId := ts.raft.LeaderWithID()
+	if leaderId == "" {
+		return ""
+	}
+	if leaderId == ts.id {
+		return ts.clientAddr
+	}
+	addr, _ := ts.fsm.Metadata(clientAddrKey(leaderId))
+	return addr
+}
+
+// ForwardRequest sends a command to the leader and returns its reply encoded for the protocol of the client,
+// it returns false when this node is the leader.
+// In redirect mode the reply is a -MOVED error with the client address of the leader instead,
+// on a read-only replica it is a -READONLY error.
+func (ts *Server) ForwardRequest(data []byte, protocol int) (bool, string, error) {
+	if ts.readOnly && ts.raft.State() != raft.Leader {
+		return true, resp.EncodeError(readOnlyError), nil
+	}
+	return ts.forwardRequest(data, protocol, ts.redirect)
+}
+
+// forwardRequest is ForwardRequest for the commands a read-only replica still sends to the leader,
+// the leader encodes the reply for the protocol
+func (ts *Server) forwardRequest(data []byte, protocol int, redirect bool) (bool, string, error) {
+	if ts.standalone {
+		return false, "", nil
+	}
+	leader, leaderId := ts.raft.LeaderWithID()
+	if ts.id == leaderId {
+		return false, "", nil
+	}
+	if leaderId == "" {
+		return false, "", fmt.Errorf("there is no leader to forward the command to")
+	}
+	addr := ts.LeaderAddress()
+	if addr == "" {
+		return false, "", fmt.Errorf("the client address of the leader %s is not known yet", leader)
+	}
+	if redirect {
+		return true, resp.EncodeError("MOVED " + addr), nil
+	}
+
+	// Commands that change the state of the connection they run on would change the shared channel for everyone,
+	// they run on a connection of their own
+	command, _, err := parseCommand(string(data))
+	if err != nil {
+		return false, "", err
+	}
+	if connectionStateCommands[strings.ToUpper(command)] {
+		reply, errForward := ts.forwardOnce(addr, protocol, data)
+		if errForward != nil {
+			return false, "", errForward
+		}
+		return true, string(reply), nil
+	}
+
+	channel, err := ts.forwardChannel(addr, protocol)
+	if err != nil {
+		return false, "", err
+	}
+	reply, sent, err := channel.do(data)
+	if err != nil && !sent {
+		// The leader may have closed the channel since it was last used, nothing was sent so it is retried once
+		if channel, err = ts.forwardChannel(addr, protocol); err != nil {
+			return false, "", err
+		}
+		reply, _, err = channel.do(data)
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("forwarding to the leader %s: %v", addr, err)
+	}
+	return true, string(reply), nil
+}
+
+// connectionStateCommands are the commands whose effect outlives their reply on the connection they run on
+var connectionStateCommands = map[string]bool{
+	MultiCommandName:        true,
+	ExecCommandName:         true,
+	DiscardCommandName:      true,
+	SubscribeCommandName:    true,
+	PSubscribeCommandName:   true,
+	UnsubscribeCommandName:  true,
+	PUnsubscribeCommandName: true,
+}
+
+// forwardChannel returns the channel of the protocol to the leader at addr,
+// it is dialed when there is none or the leader changed
+func (ts *Server) forwardChannel(addr string, protocol int) (*forwardChannel, error) {
+	ts.forwardLock.Lock()
+	defer ts.forwardLock.Unlock()
+	channel := ts.forward[protocol]
+	if channel != nil && channel.addr == addr && !channel.isClosed() {
+		return channel, nil
+	}
+	if channel != nil {
+		channel.close(fmt.Errorf("the leader moved to %s", addr))
+		delete(ts.forward, protocol)
+	}
+	conn, reader, err := ts.dialLeader(addr, protocol)
+	if err != nil {
+		return nil, err
+	}
+	if ts.forward == nil {
+		ts.forward = make(map[int]*forwardChannel)
+	}
+	channel = newForwardChannel(addr, conn, reader)
+	ts.forward[protocol] = channel
+	return channel, nil
+}
+
+// closeForward closes the channels to the leader, the commands waiting for their reply fail
+func (ts *Server) closeForward() {
+	ts.forwardLock.Lock()
+	defer ts.forwardLock.Unlock()
+	for protocol, channel := range ts.forward {
+		channel.close(fmt.Errorf("the server is shutting down"))
+		delete(ts.forward, protocol)
+	}
+}
+
+// forwardOnce sends a command to the leader on a connection of its own with the protocol and returns the first reply
+func (ts *Server) forwardOnce(addr string, protocol int, data []byte) ([]byte, error) {
+	conn, reader, err := ts.dialLeader(addr, protocol)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err = conn.Write(data); err != nil {
+		return nil, fmt.Errorf("forwarding to the leader %s: %v", addr, err)
+	}
+	reply, err := resp.ReadFrame(reader)
+	if err != nil {
+		return nil, fmt.Errorf("forwarding to the leader %s: %v", addr, err)
+	}
+	return reply, nil
+}
+
+// dialLeader connects to the client port of the leader, with TLS when the client port uses it,
+// authenticates as the cluster user when an ACL is loaded and switches the connection to the protocol
+func (ts *Server) dialLeader(addr string, protocol int) (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", addr, forwardDialTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to the leader %s: %v", addr, err)
+	}
+	if ts.clientTLS != nil {
+		host, _, _ := net.SplitHostPort(addr)
+		conn = tls.Client(conn, ts.clientTLS.ClientConfig(host))
+	}
+	reader := bufio.NewReader(conn)
+	if err = ts.authenticateForward(conn, reader); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if protocol == resp.Protocol3 {
+		if err = forwardHello(conn, reader); err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+	}
+	return conn, reader, nil
+}
+
+// forwardHello switches a connection to the leader to RESP3
+func forwardHello(conn net.Conn, reader *bufio.Reader) error {
+	if _, err := conn.Write([]byte(resp.EncodeStringArray([]string{HelloCommandName, "3"}))); err != nil {
+		return err
+	}
+	reply, err := resp.ReadReply(reader)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(resp.ErrorReply); ok {
+		return fmt.Errorf("switching the connection to the leader to RESP3: %v", replyErr)
+	}
+	return nil
+}
+
+// authenticateForward authenticates a connection to the leader as the cluster user when an ACL is loaded
+func (ts *Server) authenticateForward(conn net.Conn, reader *bufio.Reader) error {
+	if ts.acl == nil || ts.acl.ClusterUser() == nil {
+		return nil
+	}
+	user := ts.acl.ClusterUser()
+	if _, err := conn.Write([]byte(resp.EncodeStringArray([]string{AuthCommandName, user.Name, user.Password}))); err != nil {
+		return err
+	}
+	reply, err := resp.ReadReply(reader)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(resp.ErrorReply); ok {
+		return fmt.Errorf("authenticating with the leader: %v", replyErr)
+	}
+	return nil
+}
+
+// forwardChannel is a connection to the leader the commands of every client of this node are forwarded on.
+// Commands are pipelined: the leader replies in the order they were written, so every reply read
+// is handed to the oldest command still waiting for one.
+type forwardChannel struct {
+	addr string
+	conn net.Conn
+
+	// Held while a command is written and queued, so commands are queued in the order they are written
+	writeLock sync.Mutex
+	pending   chan chan []byte
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	err       error
+}
+
+func newForwardChannel(addr string, conn net.Conn, reader *bufio.Reader) *forwardChannel {
+	fc := &forwardChannel{
+		addr:    addr,
+		conn:    conn,
+		pending: make(chan chan []byte, 1024),
+		closed:  make(chan struct{}),
+	}
+	go fc.readReplies(reader)
+	return fc
+}
+
+// do writes a command and waits for its reply, sent tells whether the command may have reached the leader
+func (fc *forwardChannel) do(data []byte) ([]byte, bool, error) {
+	reply := make(chan []byte, 1)
+	fc.writeLock.Lock()
+	if fc.isClosed() {
+		fc.writeLock.Unlock()
+		return nil, false, fc.err
+	}
+	if _, err := fc.conn.Write(data); err != nil {
+		fc.writeLock.Unlock()
+		fc.close(err)
+		return nil, false, err
+	}
+	select {
+	case fc.pending <- reply:
+	case <-fc.closed:
+	}
+	fc.writeLock.Unlock()
+
+	select {
+	case frame := <-reply:
+		return frame, true, nil
+	case <-fc.closed:
+		// The reply may have been handed over just before the channel was closed
+		select {
+		case frame := <-reply:
+			return frame, true, nil
+		default:
+			return nil, true, fc.err
+		}
+	}
+}
+
+// readReplies reads the replies of the leader until the channel fails or is closed
+func (fc *forwardChannel) readReplies(reader *bufio.Reader) {
+	for {
+		frame, err := resp.ReadFrame(reader)
+		if err != nil {
+			fc.close(err)
+			return
+		}
+		select {
+		case waiting := <-fc.pending:
+			waiting <- frame
+		case <-fc.closed:
+			return
+		}
+	}
+}
+
+func (fc *forwardChannel) isClosed() bool {
+	select {
+	case <-fc.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the connection with the reason the commands waiting for their reply fail with
+func (fc *forwardChannel) close(err error) {
+	fc.closeOnce.Do(func() {
+		if err == nil {
+			err = errors.New("the channel to the leader is closed")
+		}
+		fc.err = err
+		close(fc.closed)
+		_ = fc.conn.Close()
+	})
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"treds/client"
+	"treds/resp"
+)
+
+// waitForLeaderAddress waits until the node at index knows the client address the leader published
+func waitForLeaderAddress(t *testing.T, cluster *LocalCluster, index, leader int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for cluster.Servers[index].LeaderAddress() != cluster.ClientAddrs[leader] {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected node %d to know the leader at %s, got %q", index, cluster.ClientAddrs[leader], cluster.Servers[index].LeaderAddress())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestForwardToLeader(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	follower := (leader + 1) % 3
+	followerAddr := cluster.ClientAddrs[follower]
+	// The nodes share a host, the follower only reaches the leader at the address it published
+	waitForLeaderAddress(t, cluster, follower, leader)
+
+	do(t, followerAddr, "SET", "key", "value")
+	waitForValue(t, cluster.ClientAddrs[leader], "key", "value")
+
+	// Replies spanning several lines are relayed whole
+	do(t, followerAddr, "RPUSH", "list", "a", "b c", "d")
+	got := do(t, followerAddr, "READ", "LINEARIZABLE", "LRANGE", "list", "0", "-1")
+	if !reflect.DeepEqual(got, []interface{}{"a", "b c", "d"}) {
+		t.Fatalf("expected the whole list, got %v", got)
+	}
+
+	// Forwarded writes are encoded for the protocol of the client, RESP2 and RESP3 clients of the follower are not mixed up
+	do(t, followerAddr, "VCREATE", "vec", "6", "0.5", "100")
+	id, _ := do(t, followerAddr, "VINSERT", "vec", "1.5", "2").(string)
+	rc := dialRaw(t, followerAddr)
+	rc.do("HELLO", "3")
+	expectedResp3 := resp.EncodeStringArrayRESP([]string{resp.EncodeStringArrayRESP([]string{
+		resp.EncodeBulkString(id),
+		resp.EncodeDouble(0.5),
+		resp.EncodeStringArrayRESP([]string{resp.EncodeDouble(1.5), resp.EncodeDouble(2)}),
+	})})
+	if reply := rc.do("VSEARCH", "vec", "1", "2", "1"); reply != expectedResp3 {
+		t.Fatalf("expected %q, got %q", expectedResp3, reply)
+	}
+	expectedResp2 := resp.Encode2DStringArrayRESP([][]string{{id, "1.5", "2"}})
+	if reply := dialRaw(t, followerAddr).do("VSEARCH", "vec", "1", "2", "1"); reply != expectedResp2 {
+		t.Fatalf("expected %q, got %q", expectedResp2, reply)
+	}
+
+	// The writes of every client of the follower share its channel to the leader
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, errDo := doErr(followerAddr, "SET", fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i)); errDo != nil {
+				errs <- errDo
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for errDo := range errs {
+		t.Fatalf("expected no error, got %v", errDo)
+	}
+	for i := 0; i < 20; i++ {
+		if got = do(t, cluster.ClientAddrs[leader], "GET", fmt.Sprintf("key%d", i)); got != fmt.Sprintf("value%d", i) {
+			t.Fatalf("expected value%d, got %v", i, got)
+		}
+	}
+}
+
+func TestRedirectToLeader(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	follower := (leader + 1) % 3
+	waitForLeaderAddress(t, cluster, follower, leader)
+	cluster.Servers[follower].redirect = true
+
+	// The client follows redirects, the reply is read off the wire
+	conn, err := net.Dial("tcp", cluster.ClientAddrs[follower])
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(resp.EncodeStringArray([]string{"SET", "key", "value"}))); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	reply, err := resp.ReadReply(bufio.NewReader(conn))
+	if err != nil || reply != resp.ErrorReply("MOVED "+cluster.ClientAddrs[leader]) {
+		t.Fatalf("expected MOVED %s, got %v, %v", cluster.ClientAddrs[leader], reply, err)
+	}
+
+	// The client follows the redirect and stays on the leader
+	c := client.New(client.Options{Addr: cluster.ClientAddrs[follower]})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = c.Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.Addr() != cluster.ClientAddrs[leader] {
+		t.Fatalf("expected the client to move to %s, got %s", cluster.ClientAddrs[leader], c.Addr())
+	}
+	waitForValue(t, cluster.ClientAddrs[leader], "key", "value")
+}
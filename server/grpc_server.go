@@ -0,0 +1,534 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+
+	"treds/client"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+	kvstore "treds/store/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultScanPageSize is the number of pairs fetched per SCANKVS call by the streaming ScanKVS RPC
+const DefaultScanPageSize = 1000
+
+// GRPCService implements the Treds gRPC service on top of the store command registry.
+// Store RPCs run through the same path as RESP commands, writes are forwarded to the leader or applied through Raft.
+// Subscriptions belong to the RESP event loop of the leader, so publish and subscribe RPCs reach it over a RESP connection.
+type GRPCService struct {
+	kvstore.UnimplementedTredsServer
+	server *Server
+
+	mu         sync.Mutex
+	leader     *client.Client
+	leaderAddr string
+}
+
+// NewGRPCServer returns a gRPC server with the Treds service registered, it is started with Serve on a listener.
+// When an ACL is loaded calls authenticate with basic auth credentials in the authorization metadata.
+func NewGRPCServer(ts *Server, opts ...grpc.ServerOption) *grpc.Server {
+	service := &GRPCService{server: ts}
+	grpcServer := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(service.authenticateUnary),
+		grpc.ChainStreamInterceptor(service.authenticateStream),
+	}, opts...)...)
+	kvstore.RegisterTredsServer(grpcServer, service)
+	return grpcServer
+}
+
+// authenticate stores the user of the call in its context
+func (s *GRPCService) authenticate(ctx context.Context) (context.Context, error) {
+	var name, password string
+	hasCredentials := false
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			name, password, hasCredentials = parseBasicAuth(values[0])
+		}
+	}
+	user, err := s.server.gatewayUser(name, password, hasCredentials)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return context.WithValue(ctx, aclUserKey{}, user), nil
+}
+
+func (s *GRPCService) authenticateUnary(ctx context.Context, request interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (s *GRPCService) authenticateStream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream carries the context holding the user of the call
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authenticatedStream) Context() context.Context {
+	return a.ctx
+}
+
+// run executes a store command as the user authenticated by the interceptors
+func (s *GRPCService) run(ctx context.Context, args ...string) (interface{}, error) {
+	user, _ := ctx.Value(aclUserKey{}).(*ACLUser)
+	value, err := s.server.runStoreCommand(user, args...)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return value, nil
+}
+
+// grpcError maps ACL errors to their gRPC codes, error replies to InvalidArgument and commands the cluster
+// could not run to Unavailable, anything else failed inside the server
+func grpcError(err error) error {
+	if errors.Is(err, ErrNoAuth) || errors.Is(err, ErrWrongPass) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	var permissionErr *PermissionError
+	if errors.As(err, &permissionErr) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	var replyErr resp.ErrorReply
+	if errors.As(err, &replyErr) {
+		return status.Error(codes.InvalidArgument, replyErr.Error())
+	}
+	var unavailableErr *UnavailableError
+	if errors.As(err, &unavailableErr) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (s *GRPCService) Get(ctx context.Context, request *kvstore.GetRequest) (*kvstore.GetResponse, error) {
+	value, err := s.run(ctx, commands.GetCommand, request.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &kvstore.GetResponse{}, nil
+	}
+	return &kvstore.GetResponse{Found: true, Value: []byte(fmt.Sprint(value))}, nil
+}
+
+func (s *GRPCService) Set(ctx context.Context, request *kvstore.SetRequest) (*kvstore.SetResponse, error) {
+	if _, err := s.run(ctx, commands.SetCommand, request.GetKey(), string(request.GetValue())); err != nil {
+		return nil, err
+	}
+	return &kvstore.SetResponse{}, nil
+}
+
+func (s *GRPCService) Delete(ctx context.Context, request *kvstore.DeleteRequest) (*kvstore.DeleteResponse, error) {
+	if _, err := s.run(ctx, commands.DeleteCommand, request.GetKey()); err != nil {
+		return nil, err
+	}
+	return &kvstore.DeleteResponse{}, nil
+}
+
+// ScanKVS calls SCANKVS page by page so a large prefix never has to be held in memory at once
+func (s *GRPCService) ScanKVS(request *kvstore.ScanKVSRequest, stream grpc.ServerStreamingServer[kvstore.KeyValue]) error {
+	pageSize := request.GetPageSize()
+	if pageSize <= 0 {
+		pageSize = DefaultScanPageSize
+	}
+	sent := int64(0)
+	cursor := "0"
+	for {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		value, err := s.run(stream.Context(), commands.PrefixScanCommand, cursor, request.GetPrefix(), strconv.FormatInt(pageSize, 10))
+		if err != nil {
+			return err
+		}
+		items := replyStrings(value)
+		if len(items) == 0 {
+			return nil
+		}
+		cursor = items[len(items)-1]
+		items = items[:len(items)-1]
+		for i := 0; i+1 < len(items); i += 2 {
+			if request.GetLimit() > 0 && sent >= request.GetLimit() {
+				return nil
+			}
+			if err = stream.Send(&kvstore.KeyValue{Key: []byte(items[i]), Value: []byte(items[i+1])}); err != nil {
+				return err
+			}
+			sent++
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+func (s *GRPCService) ZAdd(ctx context.Context, request *kvstore.ZAddRequest) (*kvstore.ZAddResponse, error) {
+	args := []string{commands.ZAddCommand, request.GetKey()}
+	for _, member := range request.GetMembers() {
+		args = append(args, formatScore(member.GetScore()), member.GetKey(), string(member.GetValue()))
+	}
+	if _, err := s.run(ctx, args...); err != nil {
+		return nil, err
+	}
+	return &kvstore.ZAddResponse{}, nil
+}
+
+func (s *GRPCService) ZRem(ctx context.Context, request *kvstore.ZRemRequest) (*kvstore.ZRemResponse, error) {
+	if _, err := s.run(ctx, append([]string{commands.ZRemCommand, request.GetKey()}, request.GetMembers()...)...); err != nil {
+		return nil, err
+	}
+	return &kvstore.ZRemResponse{}, nil
+}
+
+func (s *GRPCService) ZScore(ctx context.Context, request *kvstore.ZScoreRequest) (*kvstore.ZScoreResponse, error) {
+	value, err := s.run(ctx, commands.ZScoreCommand, request.GetKey(), request.GetMember())
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &kvstore.ZScoreResponse{}, nil
+	}
+	score, err := replyFloat(value)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &kvstore.ZScoreResponse{Found: true, Score: score}, nil
+}
+
+func (s *GRPCService) ZRangeLex(ctx context.Context, request *kvstore.ZRangeLexRequest) (*kvstore.ZRangeResponse, error) {
+	command := commands.ZRANGELEXKVS
+	if request.GetReverse() {
+		command = commands.ZREVRANGELEXKVS
+	}
+	value, err := s.run(ctx, command, request.GetKey(), strconv.FormatInt(request.GetOffset(), 10),
+		rangeCount(request.GetCount()), "true", request.GetMin(), request.GetMax())
+	if err != nil {
+		return nil, err
+	}
+	return toZRangeResponse(value)
+}
+
+func (s *GRPCService) ZRangeScore(ctx context.Context, request *kvstore.ZRangeScoreRequest) (*kvstore.ZRangeResponse, error) {
+	command := commands.ZRANGESCOREKVS
+	if request.GetReverse() {
+		command = commands.ZREVRANGESCOREKVS
+	}
+	value, err := s.run(ctx, command, request.GetKey(), formatScore(request.GetMin()), formatScore(request.GetMax()),
+		strconv.FormatInt(request.GetOffset(), 10), rangeCount(request.GetCount()), "true")
+	if err != nil {
+		return nil, err
+	}
+	return toZRangeResponse(value)
+}
+
+func (s *GRPCService) DCreate(ctx context.Context, request *kvstore.DCreateRequest) (*kvstore.DCreateResponse, error) {
+	if _, err := s.run(ctx, commands.DCreateCollection, request.GetCollection(), request.GetSchemaJson(), request.GetIndexesJson()); err != nil {
+		return nil, err
+	}
+	return &kvstore.DCreateResponse{}, nil
+}
+
+func (s *GRPCService) DDrop(ctx context.Context, request *kvstore.DDropRequest) (*kvstore.DDropResponse, error) {
+	if _, err := s.run(ctx, commands.DDropCollection, request.GetCollection()); err != nil {
+		return nil, err
+	}
+	return &kvstore.DDropResponse{}, nil
+}
+
+func (s *GRPCService) DInsert(ctx context.Context, request *kvstore.DInsertRequest) (*kvstore.DInsertResponse, error) {
+	value, err := s.run(ctx, commands.DInsert, request.GetCollection(), request.GetDocumentJson())
+	if err != nil {
+		return nil, err
+	}
+	return &kvstore.DInsertResponse{Id: fmt.Sprint(value)}, nil
+}
+
+func (s *GRPCService) DQuery(ctx context.Context, request *kvstore.DQueryRequest) (*kvstore.DQueryResponse, error) {
+	query := request.GetQueryJson()
+	if query == "" {
+		query = "{}"
+	}
+	value, err := s.run(ctx, commands.DQuery, request.GetCollection(), query)
+	if err != nil {
+		return nil, err
+	}
+	return &kvstore.DQueryResponse{DocumentsJson: replyStrings(value)}, nil
+}
+
+func (s *GRPCService) VCreate(ctx context.Context, request *kvstore.VCreateRequest) (*kvstore.VCreateResponse, error) {
+	maxNeighbors := request.GetMaxNeighbors()
+	if maxNeighbors <= 0 {
+		maxNeighbors = store.DefaultMaxNeighbor
+	}
+	levelFactor := request.GetLevelFactor()
+	if levelFactor <= 0 {
+		levelFactor = store.DefaultLevelFactor
+	}
+	efSearch := request.GetEfSearch()
+	if efSearch <= 0 {
+		efSearch = store.DefaultEfSearch
+	}
+	_, err := s.run(ctx, commands.VCreate, request.GetName(), strconv.FormatInt(maxNeighbors, 10),
+		strconv.FormatFloat(levelFactor, 'f', -1, 64), strconv.FormatInt(efSearch, 10))
+	if err != nil {
+		return nil, err
+	}
+	return &kvstore.VCreateResponse{}, nil
+}
+
+func (s *GRPCService) VInsert(ctx context.Context, request *kvstore.VInsertRequest) (*kvstore.VInsertResponse, error) {
+	value, err := s.run(ctx, append([]string{commands.VInsert, request.GetName()}, formatVector(request.GetVector())...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &kvstore.VInsertResponse{Id: fmt.Sprint(value)}, nil
+}
+
+func (s *GRPCService) VSearch(ctx context.Context, request *kvstore.VSearchRequest) (*kvstore.VSearchResponse, error) {
+	args := append([]string{commands.VSearch, request.GetName()}, formatVector(request.GetVector())...)
+	value, err := s.run(ctx, append(args, strconv.FormatInt(request.GetK(), 10))...)
+	if err != nil {
+		return nil, err
+	}
+	rows, _ := value.([]interface{})
+	response := &kvstore.VSearchResponse{Results: make([]*kvstore.VSearchResult, 0, len(rows))}
+	for _, row := range rows {
+		// Every row is the vector id, its distance and the array of the vector components
+		fields, ok := row.([]interface{})
+		if !ok || len(fields) != 3 {
+			return nil, status.Errorf(codes.Internal, "unexpected VSEARCH row %v", row)
+		}
+		components, ok := fields[2].([]interface{})
+		if !ok {
+			return nil, status.Errorf(codes.Internal, "unexpected VSEARCH row %v", row)
+		}
+		distance, errFloat := replyFloat(fields[1])
+		if errFloat != nil {
+			return nil, status.Error(codes.Internal, errFloat.Error())
+		}
+		result := &kvstore.VSearchResult{Id: fmt.Sprint(fields[0]), Distance: distance, Vector: make([]float64, 0, len(components))}
+		for _, field := range components {
+			number, errFloat := replyFloat(field)
+			if errFloat != nil {
+				return nil, status.Error(codes.Internal, errFloat.Error())
+			}
+			result.Vector = append(result.Vector, number)
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+func (s *GRPCService) VDelete(ctx context.Context, request *kvstore.VDeleteRequest) (*kvstore.VDeleteResponse, error) {
+	value, err := s.run(ctx, commands.VDelete, request.GetName(), request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &kvstore.VDeleteResponse{Deleted: value == "OK"}, nil
+}
+
+// checkACL checks a server command against the user of the call, it is run here since the leader only sees this node
+func (s *GRPCService) checkACL(ctx context.Context, command string, args []string) error {
+	user, _ := ctx.Value(aclUserKey{}).(*ACLUser)
+	if err := s.server.checkACL(user, command, args); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// leaderClient returns a RESP client for the current leader, it is replaced when the leadership moves
+func (s *GRPCService) leaderClient() (*client.Client, error) {
+	if s.server.readOnly {
+		return nil, status.Error(codes.FailedPrecondition, readOnlyError)
+	}
+	addr := s.server.LeaderAddress()
+	if addr == "" {
+		return nil, status.Error(codes.Unavailable, "no known leader")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leader != nil && s.leaderAddr == addr {
+		return s.leader, nil
+	}
+	if s.leader != nil {
+		_ = s.leader.Close()
+	}
+	opts := client.Options{Addr: addr}
+	if s.server.clientTLS != nil {
+		host, _, _ := net.SplitHostPort(addr)
+		opts.TLSConfig = s.server.clientTLS.ClientConfig(host)
+	}
+	if acl := s.server.GetACL(); acl != nil && acl.ClusterUser() != nil {
+		// Callers are checked on this node, the leader only has to trust the node
+		opts.Username = acl.ClusterUser().Name
+		opts.Password = acl.ClusterUser().Password
+	}
+	s.leader = client.New(opts)
+	s.leaderAddr = addr
+	return s.leader, nil
+}
+
+func (s *GRPCService) Publish(ctx context.Context, request *kvstore.PublishRequest) (*kvstore.PublishResponse, error) {
+	command := PublishCommandName
+	if request.GetPattern() {
+		command = PPublishCommandName
+	}
+	if err := s.checkACL(ctx, command, []string{request.GetChannel(), request.GetMessage()}); err != nil {
+		return nil, err
+	}
+	c, err := s.leaderClient()
+	if err != nil {
+		return nil, err
+	}
+	var receivers int64
+	if request.GetPattern() {
+		receivers, err = c.PPublish(ctx, request.GetChannel(), request.GetMessage())
+	} else {
+		receivers, err = c.Publish(ctx, request.GetChannel(), request.GetMessage())
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &kvstore.PublishResponse{Receivers: receivers}, nil
+}
+
+// Subscribe holds a subscribed RESP connection to the leader for as long as the stream is open
+func (s *GRPCService) Subscribe(request *kvstore.SubscribeRequest, stream grpc.ServerStreamingServer[kvstore.Message]) error {
+	if len(request.GetChannels()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one channel is required")
+	}
+	ctx := stream.Context()
+	command := SubscribeCommandName
+	if request.GetPattern() {
+		command = PSubscribeCommandName
+	}
+	if err := s.checkACL(ctx, command, request.GetChannels()); err != nil {
+		return err
+	}
+	c, err := s.leaderClient()
+	if err != nil {
+		return err
+	}
+	var ps *client.PubSub
+	if request.GetPattern() {
+		ps, err = c.PSubscribe(ctx, request.GetChannels()...)
+	} else {
+		ps, err = c.Subscribe(ctx, request.GetChannels()...)
+	}
+	if err != nil {
+		return grpcError(err)
+	}
+	defer ps.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-ps.Channel():
+			if !ok {
+				if err = ps.Err(); err != nil {
+					return status.Error(codes.Unavailable, err.Error())
+				}
+				return nil
+			}
+			err = stream.Send(&kvstore.Message{Channel: message.Channel, Pattern: message.Pattern, Payload: message.Payload})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toZRangeResponse(value interface{}) (*kvstore.ZRangeResponse, error) {
+	// Rows are flattened as score, key and value
+	items := replyStrings(value)
+	if len(items)%3 != 0 {
+		return nil, status.Errorf(codes.Internal, "range reply has %d elements, expected a multiple of 3", len(items))
+	}
+	response := &kvstore.ZRangeResponse{Members: make([]*kvstore.ZMember, 0, len(items)/3)}
+	for i := 0; i < len(items); i += 3 {
+		score, err := strconv.ParseFloat(items[i], 64)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		response.Members = append(response.Members, &kvstore.ZMember{Score: score, Key: items[i+1], Value: []byte(items[i+2])})
+	}
+	return response, nil
+}
+
+// replyStrings converts an array reply, null elements become empty strings
+func replyStrings(value interface{}) []string {
+	elements, _ := value.([]interface{})
+	result := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if element == nil {
+			result = append(result, "")
+			continue
+		}
+		result = append(result, fmt.Sprint(element))
+	}
+	return result
+}
+
+func replyFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected reply type %T for a double", value)
+	}
+}
+
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+Inf"
+	case math.IsInf(score, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+func formatVector(vector []float64) []string {
+	components := make([]string, 0, len(vector))
+	for _, component := range vector {
+		components = append(components, strconv.FormatFloat(component, 'f', -1, 64))
+	}
+	return components
+}
+
+// rangeCount maps a count of 0 to every member in the range
+func rangeCount(count int64) string {
+	if count <= 0 {
+		return strconv.FormatInt(math.MaxInt64, 10)
+	}
+	return strconv.FormatInt(count, 10)
+}
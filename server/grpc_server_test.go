@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"treds/resp"
+	kvstore "treds/store/proto"
+)
+
+// startGRPC serves the gRPC service of s over an in-memory listener and returns a client for it
+func startGRPC(t *testing.T, s *Server) kvstore.TredsClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := NewGRPCServer(s)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		grpcServer.Stop()
+	})
+	return kvstore.NewTredsClient(conn)
+}
+
+// withBasicAuth returns a context carrying basic auth credentials in the authorization metadata
+func withBasicAuth(ctx context.Context, name, password string) context.Context {
+	credentials := base64.StdEncoding.EncodeToString([]byte(name + ":" + password))
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+credentials)
+}
+
+func TestGRPCService(t *testing.T) {
+	s, _ := startStandalone(t, "", "")
+	defer s.Shutdown()
+	c := startGRPC(t, s)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Values are bytes, they are stored as they are sent
+	value := []byte("hello \x00\xff world")
+	if _, err := c.Set(ctx, &kvstore.SetRequest{Key: "key", Value: value}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, err := c.Get(ctx, &kvstore.GetRequest{Key: "key"})
+	if err != nil || !got.GetFound() || !reflect.DeepEqual(got.GetValue(), value) {
+		t.Fatalf("expected %q, got %v, %v", value, got, err)
+	}
+	if got, err = c.Get(ctx, &kvstore.GetRequest{Key: "missing"}); err != nil || got.GetFound() {
+		t.Fatalf("expected the key not to be found, got %v, %v", got, err)
+	}
+	if _, err = c.Delete(ctx, &kvstore.DeleteRequest{Key: "key"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, err = c.Get(ctx, &kvstore.GetRequest{Key: "key"}); err != nil || got.GetFound() {
+		t.Fatalf("expected the key to be deleted, got %v, %v", got, err)
+	}
+
+	members := []*kvstore.ZMember{{Score: 1, Key: "alice", Value: []byte("a")}, {Score: 2.5, Key: "bob", Value: []byte("b")}}
+	if _, err = c.ZAdd(ctx, &kvstore.ZAddRequest{Key: "board", Members: members}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	score, err := c.ZScore(ctx, &kvstore.ZScoreRequest{Key: "board", Member: "bob"})
+	if err != nil || !score.GetFound() || score.GetScore() != 2.5 {
+		t.Fatalf("expected a score of 2.5, got %v, %v", score, err)
+	}
+	ranged, err := c.ZRangeScore(ctx, &kvstore.ZRangeScoreRequest{Key: "board", Min: 0, Max: 10, Reverse: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(ranged.GetMembers()) != 2 || ranged.GetMembers()[0].GetKey() != "bob" || ranged.GetMembers()[1].GetKey() != "alice" {
+		t.Fatalf("expected bob and alice, got %v", ranged.GetMembers())
+	}
+
+	// Pairs are streamed page by page until the limit
+	for i := 0; i < 5; i++ {
+		if _, err = c.Set(ctx, &kvstore.SetRequest{Key: fmt.Sprintf("user:%d", i), Value: []byte("v")}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	stream, err := c.ScanKVS(ctx, &kvstore.ScanKVSRequest{Prefix: "user:", PageSize: 2, Limit: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	keys := make([]string, 0)
+	for {
+		pair, errRecv := stream.Recv()
+		if errors.Is(errRecv, io.EOF) {
+			break
+		}
+		if errRecv != nil {
+			t.Fatalf("expected no error, got %v", errRecv)
+		}
+		keys = append(keys, string(pair.GetKey()))
+	}
+	if expected := []string{"user:0", "user:1", "user:2", "user:3"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+
+	if _, err = c.VCreate(ctx, &kvstore.VCreateRequest{Name: "vec"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	inserted, err := c.VInsert(ctx, &kvstore.VInsertRequest{Name: "vec", Vector: []float64{1.5, 2}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	searched, err := c.VSearch(ctx, &kvstore.VSearchRequest{Name: "vec", Vector: []float64{1, 2}, K: 1})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(searched.GetResults()) != 1 || searched.GetResults()[0].GetId() != inserted.GetId() ||
+		searched.GetResults()[0].GetDistance() != 0.5 || !reflect.DeepEqual(searched.GetResults()[0].GetVector(), []float64{1.5, 2}) {
+		t.Fatalf("expected the inserted vector, got %v", searched.GetResults())
+	}
+
+	// Error replies of the commands are invalid arguments
+	_, err = c.ZAdd(ctx, &kvstore.ZAddRequest{Key: "user:0", Members: members})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	_, err = c.VSearch(ctx, &kvstore.VSearchRequest{Name: "missing", Vector: []float64{1}, K: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	// The status of a stream is returned by its first Recv
+	subscription, err := c.Subscribe(ctx, &kvstore.SubscribeRequest{})
+	if err == nil {
+		_, err = subscription.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	acl, err := NewACL(ACLConfig{Users: []*ACLUser{
+		{Name: "reader", Password: "secret", Categories: []string{"read"}, Keys: []string{"public:*"}},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, _ := startStandalone(t, "", "", func(s *Server) {
+		s.SetACL(acl)
+	})
+	defer s.Shutdown()
+	c := startGRPC(t, s)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		key  string
+		code codes.Code
+	}{
+		{name: "no credentials and no default user", ctx: ctx, key: "public:1", code: codes.Unauthenticated},
+		{name: "wrong password", ctx: withBasicAuth(ctx, "reader", "wrong"), key: "public:1", code: codes.Unauthenticated},
+		{name: "unknown user", ctx: withBasicAuth(ctx, "nobody", "secret"), key: "public:1", code: codes.Unauthenticated},
+		{name: "allowed key", ctx: withBasicAuth(ctx, "reader", "secret"), key: "public:1", code: codes.OK},
+		{name: "denied key", ctx: withBasicAuth(ctx, "reader", "secret"), key: "private:1", code: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errGet := c.Get(tt.ctx, &kvstore.GetRequest{Key: tt.key})
+			if status.Code(errGet) != tt.code {
+				t.Fatalf("expected %v, got %v", tt.code, errGet)
+			}
+		})
+	}
+
+	// Writes need the write category
+	_, err = c.Set(withBasicAuth(ctx, "reader", "secret"), &kvstore.SetRequest{Key: "public:1", Value: []byte("v")})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+
+	// Streams are authenticated by the stream interceptor
+	stream, err := c.ScanKVS(ctx, &kvstore.ScanKVSRequest{Prefix: "public:"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{name: "not authenticated", err: ErrNoAuth, code: codes.Unauthenticated},
+		{name: "wrong password", err: ErrWrongPass, code: codes.Unauthenticated},
+		{name: "permission", err: permissionErrorf("denied"), code: codes.PermissionDenied},
+		{name: "error reply", err: resp.ErrorReply("ERR invalid number of arguments"), code: codes.InvalidArgument},
+		{name: "no leader", err: &UnavailableError{Err: fmt.Errorf("there is no leader to forward the command to")}, code: codes.Unavailable},
+		{name: "raft apply", err: &UnavailableError{Err: raft.ErrLeadershipLost}, code: codes.Unavailable},
+		{name: "canceled", err: context.Canceled, code: codes.Canceled},
+		{name: "deadline", err: context.DeadlineExceeded, code: codes.DeadlineExceeded},
+		{name: "internal", err: errors.New("unexpected reply"), code: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(grpcError(tt.err)); code != tt.code {
+				t.Fatalf("expected %v, got %v", tt.code, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+)
+
+const HelloCommandName = "HELLO"
+const ServerName = "treds"
+
+func RegisterHelloCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     HelloCommandName,
+		Args:     "[protover [AUTH username password]]",
+		Execute:  executeHello(),
+		Category: commands.CategoryConnection,
+		NoAuth:   true,
+	})
+}
+
+func executeHello() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// The protocol belongs to this connection, so HELLO is never forwarded to the leader
+		protocol := ts.GetConnectionProtocol(c.RemoteAddr().String())
+		if len(args) >= 1 {
+			version, errVersion := strconv.Atoi(args[0])
+			if errVersion != nil {
+				ts.RespondErr(c, fmt.Errorf("protocol version is not an integer or out of range"))
+				return gnet.None
+			}
+			if version != resp.Protocol2 && version != resp.Protocol3 {
+				ts.RespondErr(c, fmt.Errorf("NOPROTO unsupported protocol version"))
+				return gnet.None
+			}
+			protocol = version
+		}
+
+		// Nothing changes on the connection unless every option is valid and the credentials are right
+		var user *ACLUser
+		for i := 1; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "AUTH":
+				if i+2 >= len(args) {
+					ts.RespondErr(c, fmt.Errorf("syntax error in HELLO option AUTH"))
+					return gnet.None
+				}
+				if ts.GetACL() == nil {
+					ts.RespondErr(c, fmt.Errorf("AUTH called without any users configured"))
+					return gnet.None
+				}
+				var errAuth error
+				if user, errAuth = ts.GetACL().Authenticate(args[i+1], args[i+2]); errAuth != nil {
+					ts.RespondErr(c, errAuth)
+					return gnet.None
+				}
+				i += 2
+			case "SETNAME":
+				ts.RespondErr(c, fmt.Errorf("HELLO option SETNAME is not supported, connections have no name"))
+				return gnet.None
+			default:
+				ts.RespondErr(c, fmt.Errorf("syntax error in HELLO option %s", args[i]))
+				return gnet.None
+			}
+		}
+		if user != nil {
+			ts.SetConnectionUser(c.RemoteAddr().String(), user)
+		}
+		ts.SetConnectionProtocol(c.RemoteAddr().String(), protocol)
+
+		role := "replica"
+		if ts.isLeader() {
+			role = "master"
+		}
+		mode := "cluster"
+		if ts.standalone {
+			mode = "standalone"
+		}
+
+		fields := []string{
+			resp.EncodeBulkString("server"), resp.EncodeBulkString(ServerName),
+			resp.EncodeBulkString("proto"), resp.EncodeInteger(protocol),
+			resp.EncodeBulkString("id"), resp.EncodeBulkString(string(ts.id)),
+			resp.EncodeBulkString("mode"), resp.EncodeBulkString(mode),
+			resp.EncodeBulkString("role"), resp.EncodeBulkString(role),
+			resp.EncodeBulkString("leader"), resp.EncodeBulkString(ts.LeaderAddress()),
+			resp.EncodeBulkString("modules"), resp.EncodeStringArray([]string{}),
+		}
+
+		res := resp.EncodeStringArrayRESP(fields)
+		if protocol == resp.Protocol3 {
+			res = resp.EncodeMapRESP(fields)
+		}
+		_, errConn := c.Write([]byte(res))
+		if errConn != nil {
+			ts.RespondErr(c, errConn)
+		}
+		return gnet.None
+	}
+}
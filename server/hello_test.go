@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"treds/resp"
+)
+
+// rawConn sends commands as RESP arrays and reads the replies as they are on the wire, whatever the protocol
+type rawConn struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialRaw(t *testing.T, addr string) *rawConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return &rawConn{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// do sends a command and returns its raw reply
+func (rc *rawConn) do(args ...string) string {
+	rc.t.Helper()
+	if _, err := rc.conn.Write([]byte(resp.EncodeStringArray(args))); err != nil {
+		rc.t.Fatalf("expected no error, got %v", err)
+	}
+	return rc.read()
+}
+
+// pipeline sends the commands in a single write and returns their raw replies in the order they are read
+func (rc *rawConn) pipeline(commands ...[]string) []string {
+	rc.t.Helper()
+	var data strings.Builder
+	for _, args := range commands {
+		data.WriteString(resp.EncodeStringArray(args))
+	}
+	if _, err := rc.conn.Write([]byte(data.String())); err != nil {
+		rc.t.Fatalf("expected no error, got %v", err)
+	}
+	replies := make([]string, len(commands))
+	for i := range replies {
+		replies[i] = rc.read()
+	}
+	return replies
+}
+
+// read reads the next raw reply
+func (rc *rawConn) read() string {
+	rc.t.Helper()
+	frame, err := resp.ReadFrame(rc.reader)
+	if err != nil {
+		rc.t.Fatalf("expected no error, got %v", err)
+	}
+	return string(frame)
+}
+
+func TestHello(t *testing.T) {
+	acl, err := NewACL(ACLConfig{Users: []*ACLUser{
+		{Name: "alice", Password: "secret", Categories: []string{"read", "write"}, Keys: []string{"*"}},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, addr := startStandalone(t, "", "", func(s *Server) {
+		s.SetACL(acl)
+	})
+	defer s.Shutdown()
+
+	rc := dialRaw(t, addr)
+	if reply := rc.do("GET", "key"); !strings.HasPrefix(reply, "-NOAUTH") {
+		t.Fatalf("expected NOAUTH, got %q", reply)
+	}
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"HELLO", "3", "AUTH", "alice"}, expected: "-syntax error in HELLO option AUTH"},
+		{args: []string{"HELLO", "3", "AUTH", "alice", "wrong"}, expected: "-WRONGPASS"},
+		{args: []string{"HELLO", "3", "SETNAME", "name"}, expected: "-HELLO option SETNAME is not supported"},
+		{args: []string{"HELLO", "3", "AUTH", "alice", "secret", "NOPE"}, expected: "-syntax error in HELLO option NOPE"},
+		{args: []string{"HELLO", "4"}, expected: "-NOPROTO"},
+	} {
+		if reply := rc.do(tt.args...); !strings.HasPrefix(reply, tt.expected) {
+			t.Fatalf("%v: expected %q, got %q", tt.args, tt.expected, reply)
+		}
+	}
+	// A rejected HELLO changes nothing on the connection
+	if reply := rc.do("GET", "key"); !strings.HasPrefix(reply, "-NOAUTH") {
+		t.Fatalf("expected NOAUTH, got %q", reply)
+	}
+
+	if reply := rc.do("HELLO", "3", "AUTH", "alice", "secret"); !strings.HasPrefix(reply, "%") {
+		t.Fatalf("expected a map, got %q", reply)
+	}
+	if reply := rc.do("GET", "key"); reply != "_\r\n" {
+		t.Fatalf("expected a null, got %q", reply)
+	}
+}
+
+func TestVSearchReply(t *testing.T) {
+	s, addr := startStandalone(t, "", "")
+	defer s.Shutdown()
+	rc := dialRaw(t, addr)
+	if reply := rc.do("VCREATE", "vec", "6", "0.5", "100"); reply != "+OK\r\n" {
+		t.Fatalf("expected OK, got %q", reply)
+	}
+	id := rc.do("VINSERT", "vec", "1.5", "2")
+	if !strings.HasPrefix(id, "+") {
+		t.Fatalf("expected an id, got %q", id)
+	}
+	id = strings.TrimSpace(id[1:])
+
+	// RESP2 rows are the id and the components as bulk strings,
+	// RESP3 rows are the id, the distance and the components as doubles
+	expected := resp.Encode2DStringArrayRESP([][]string{{id, "1.5", "2"}})
+	if reply := rc.do("VSEARCH", "vec", "1", "2", "1"); reply != expected {
+		t.Fatalf("expected %q, got %q", expected, reply)
+	}
+	rc.do("HELLO", "3")
+	reply, err := resp.ReadReply(bufio.NewReader(strings.NewReader(rc.do("VSEARCH", "vec", "1", "2", "1"))))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expectedRows := []interface{}{[]interface{}{id, 0.5, []interface{}{1.5, 2.0}}}; !reflect.DeepEqual(reply, expectedRows) {
+		t.Fatalf("expected %v, got %v", expectedRows, reply)
+	}
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"treds/commands"
+	"treds/resp"
+)
+
+// HTTPGateway serves a JSON API on top of the store command registry.
+// Every request is turned into a store command and runs through the same path as RESP commands,
+// writes are forwarded to the leader or applied through Raft and reads are served from the local store.
+type HTTPGateway struct {
+	server *Server
+	mux    *http.ServeMux
+}
+
+func NewHTTPGateway(ts *Server) *HTTPGateway {
+	g := &HTTPGateway{
+		server: ts,
+		mux:    http.NewServeMux(),
+	}
+	g.mux.HandleFunc("GET /kv", g.scanKeys)
+	g.mux.HandleFunc("GET /kv/{key...}", g.getKey)
+	g.mux.HandleFunc("PUT /kv/{key...}", g.setKey)
+	g.mux.HandleFunc("DELETE /kv/{key...}", g.deleteKey)
+	g.mux.HandleFunc("POST /collections/{name}/documents", g.insertDocument)
+	g.mux.HandleFunc("POST /collections/{name}/query", g.queryCollection)
+	g.mux.HandleFunc("POST /vectors/{name}/search", g.searchVectors)
+	g.mux.HandleFunc("POST /commands", g.runCommand)
+	return g
+}
+
+type aclUserKey struct{}
+
+// ServeHTTP authenticates the request with its basic auth credentials when an ACL is loaded
+func (g *HTTPGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, password, hasCredentials := r.BasicAuth()
+	user, err := g.server.gatewayUser(name, password, hasCredentials)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	g.mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), aclUserKey{}, user)))
+}
+
+// run executes a store command as the user of the request
+func (g *HTTPGateway) run(r *http.Request, args ...string) (interface{}, error) {
+	user, _ := r.Context().Value(aclUserKey{}).(*ACLUser)
+	return g.server.runStoreCommand(user, args...)
+}
+
+func (g *HTTPGateway) getKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	value, err := g.run(r, commands.GetCommand, key)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	if value == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("key not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"key": key, "value": value})
+}
+
+func (g *HTTPGateway) setKey(w http.ResponseWriter, r *http.Request) {
+	value, err := io.ReadAll(io.LimitReader(r.Body, resp.MaxBulkLength))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := g.run(r, commands.SetCommand, r.PathValue("key"), string(value))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+func (g *HTTPGateway) deleteKey(w http.ResponseWriter, r *http.Request) {
+	result, err := g.run(r, commands.DeleteCommand, r.PathValue("key"))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+// scanKeys maps GET /kv?prefix=&cursor=&count= onto SCANKVS, the reply carries the cursor for the next page
+func (g *HTTPGateway) scanKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cursor := query.Get("cursor")
+	if cursor == "" {
+		cursor = "0"
+	}
+	args := []string{commands.PrefixScanCommand, cursor, query.Get("prefix")}
+	if count := query.Get("count"); count != "" {
+		if _, err := strconv.Atoi(count); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("count must be an integer"))
+			return
+		}
+		args = append(args, count)
+	}
+	value, err := g.run(r, args...)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	elements, _ := value.([]interface{})
+	items := make([]map[string]interface{}, 0)
+	nextCursor := "0"
+	if len(elements) > 0 {
+		nextCursor = fmt.Sprint(elements[len(elements)-1])
+		elements = elements[:len(elements)-1]
+	}
+	for i := 0; i+1 < len(elements); i += 2 {
+		items = append(items, map[string]interface{}{"key": elements[i], "value": elements[i+1]})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "cursor": nextCursor})
+}
+
+func (g *HTTPGateway) insertDocument(w http.ResponseWriter, r *http.Request) {
+	body, err := readJSONBody(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, err := g.run(r, commands.DInsert, r.PathValue("name"), string(body))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+}
+
+// queryCollection returns the matching documents as JSON objects rather than JSON encoded strings
+func (g *HTTPGateway) queryCollection(w http.ResponseWriter, r *http.Request) {
+	body, err := readJSONBody(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	value, err := g.run(r, commands.DQuery, r.PathValue("name"), string(body))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	elements, _ := value.([]interface{})
+	documents := make([]json.RawMessage, 0, len(elements))
+	for _, element := range elements {
+		documents = append(documents, json.RawMessage(fmt.Sprint(element)))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"documents": documents})
+}
+
+type vectorSearchRequest struct {
+	Vector []float64 `json:"vector"`
+	K      int       `json:"k"`
+}
+
+func (g *HTTPGateway) searchVectors(w http.ResponseWriter, r *http.Request) {
+	var request vectorSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Vector) == 0 || request.K <= 0 {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("vector and a positive k are required"))
+		return
+	}
+	args := append([]string{commands.VSearch, r.PathValue("name")}, formatVector(request.Vector)...)
+	args = append(args, strconv.Itoa(request.K))
+	value, err := g.run(r, args...)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	rows, _ := value.([]interface{})
+	results := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		// Every row is the vector id, its distance and the array of the vector components
+		fields, ok := row.([]interface{})
+		if !ok || len(fields) != 3 {
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"id":       fields[0],
+			"distance": fields[1],
+			"vector":   fields[2],
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+type commandRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// runCommand runs any store command, server commands need a RESP connection and are refused
+func (g *HTTPGateway) runCommand(w http.ResponseWriter, r *http.Request) {
+	var request commandRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Command == "" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("command is required"))
+		return
+	}
+	if g.server.isServerCommand(request.Command) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%s is not available over HTTP", strings.ToUpper(request.Command)))
+		return
+	}
+	value, err := g.run(r, append([]string{request.Command}, request.Args...)...)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": value})
+}
+
+// readJSONBody reads a request body that must hold valid JSON, an empty body is read as an empty object
+func readJSONBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, resp.MaxBulkLength))
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return []byte("{}"), nil
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("request body is not valid JSON")
+	}
+	return body, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, errWrite := w.Write(data)
+	if errWrite != nil {
+		fmt.Println("Error occurred writing HTTP response", errWrite)
+	}
+}
+
+// writeCommandError maps authentication and permission errors to 401 and 403 and error replies of the command to 400.
+// Commands the cluster could not run, with no leader or a failed Raft apply, are 503, anything else failed in the gateway.
+func writeCommandError(w http.ResponseWriter, err error) {
+	var permissionErr *PermissionError
+	var replyErr resp.ErrorReply
+	var unavailableErr *UnavailableError
+	switch {
+	case errors.Is(err, ErrNoAuth) || errors.Is(err, ErrWrongPass):
+		w.Header().Set("WWW-Authenticate", `Basic realm="treds"`)
+		writeJSONError(w, http.StatusUnauthorized, err)
+	case errors.As(err, &permissionErr):
+		writeJSONError(w, http.StatusForbidden, err)
+	case errors.As(err, &replyErr):
+		writeJSONError(w, http.StatusBadRequest, err)
+	case errors.As(err, &unavailableErr):
+		writeJSONError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeJSONError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"treds/resp"
+)
+
+// gatewayRequest runs a request against the gateway and decodes its JSON body
+func gatewayRequest(t *testing.T, g *HTTPGateway, method, target, body string, credentials ...string) (int, map[string]interface{}) {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if len(credentials) == 2 {
+		r.SetBasicAuth(credentials[0], credentials[1])
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s: expected a JSON body, got %q", method, target, w.Body.String())
+	}
+	return w.Code, decoded
+}
+
+func TestHTTPGateway(t *testing.T) {
+	s, _ := startStandalone(t, "", "")
+	defer s.Shutdown()
+	g := NewHTTPGateway(s)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		status   int
+		expected map[string]interface{}
+	}{
+		{name: "set", method: http.MethodPut, target: "/kv/user:1", body: "alice smith", status: http.StatusOK, expected: map[string]interface{}{"result": "OK"}},
+		{name: "get", method: http.MethodGet, target: "/kv/user:1", status: http.StatusOK, expected: map[string]interface{}{"key": "user:1", "value": "alice smith"}},
+		{name: "get missing", method: http.MethodGet, target: "/kv/user:2", status: http.StatusNotFound, expected: map[string]interface{}{"error": "key not found"}},
+		{name: "scan", method: http.MethodGet, target: "/kv?prefix=user:", status: http.StatusOK, expected: map[string]interface{}{
+			"items":  []interface{}{map[string]interface{}{"key": "user:1", "value": "alice smith"}},
+			"cursor": "0",
+		}},
+		{name: "scan with an invalid count", method: http.MethodGet, target: "/kv?count=many", status: http.StatusBadRequest, expected: map[string]interface{}{"error": "count must be an integer"}},
+		{name: "command", method: http.MethodPost, target: "/commands", body: `{"command": "RPUSH", "args": ["list", "a b", "c"]}`, status: http.StatusOK, expected: map[string]interface{}{"result": "OK"}},
+		{name: "command reply", method: http.MethodPost, target: "/commands", body: `{"command": "LRANGE", "args": ["list", "0", "-1"]}`, status: http.StatusOK, expected: map[string]interface{}{"result": []interface{}{"a b", "c"}}},
+		{name: "command error reply", method: http.MethodPost, target: "/commands", body: `{"command": "LRANGE", "args": ["list"]}`, status: http.StatusBadRequest},
+		{name: "server command", method: http.MethodPost, target: "/commands", body: `{"command": "snapshot"}`, status: http.StatusBadRequest, expected: map[string]interface{}{"error": "SNAPSHOT is not available over HTTP"}},
+		{name: "invalid JSON", method: http.MethodPost, target: "/collections/users/query", body: `{"filter"`, status: http.StatusBadRequest, expected: map[string]interface{}{"error": "request body is not valid JSON"}},
+		{name: "vector search without k", method: http.MethodPost, target: "/vectors/vec/search", body: `{"vector": [1]}`, status: http.StatusBadRequest, expected: map[string]interface{}{"error": "vector and a positive k are required"}},
+		{name: "delete", method: http.MethodDelete, target: "/kv/user:1", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := gatewayRequest(t, g, tt.method, tt.target, tt.body)
+			if status != tt.status {
+				t.Fatalf("expected status %d, got %d: %v", tt.status, status, body)
+			}
+			if tt.expected != nil && !reflect.DeepEqual(body, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestHTTPGatewayAuthentication(t *testing.T) {
+	acl, err := NewACL(ACLConfig{Users: []*ACLUser{
+		{Name: "reader", Password: "secret", Categories: []string{"read"}, Keys: []string{"public:*"}},
+	}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, _ := startStandalone(t, "", "", func(s *Server) {
+		s.SetACL(acl)
+	})
+	defer s.Shutdown()
+	g := NewHTTPGateway(s)
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		credentials []string
+		status      int
+	}{
+		{name: "no credentials and no default user", method: http.MethodGet, target: "/kv/public:1", status: http.StatusUnauthorized},
+		{name: "wrong password", method: http.MethodGet, target: "/kv/public:1", credentials: []string{"reader", "wrong"}, status: http.StatusUnauthorized},
+		{name: "allowed key", method: http.MethodGet, target: "/kv/public:1", credentials: []string{"reader", "secret"}, status: http.StatusNotFound},
+		{name: "denied key", method: http.MethodGet, target: "/kv/private:1", credentials: []string{"reader", "secret"}, status: http.StatusForbidden},
+		{name: "denied category", method: http.MethodPut, target: "/kv/public:1", credentials: []string{"reader", "secret"}, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := gatewayRequest(t, g, tt.method, tt.target, "", tt.credentials...)
+			if status != tt.status {
+				t.Fatalf("expected status %d, got %d: %v", tt.status, status, body)
+			}
+		})
+	}
+}
+
+func TestWriteCommandError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "not authenticated", err: ErrNoAuth, status: http.StatusUnauthorized},
+		{name: "wrong password", err: ErrWrongPass, status: http.StatusUnauthorized},
+		{name: "permission", err: permissionErrorf("denied"), status: http.StatusForbidden},
+		{name: "error reply", err: resp.ErrorReply("ERR invalid number of arguments"), status: http.StatusBadRequest},
+		{name: "no leader", err: &UnavailableError{Err: fmt.Errorf("there is no leader to forward the command to")}, status: http.StatusServiceUnavailable},
+		{name: "raft apply", err: &UnavailableError{Err: raft.ErrNotLeader}, status: http.StatusServiceUnavailable},
+		{name: "internal", err: errors.New("unexpected reply"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeCommandError(w, tt.err)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+)
+
+const ImportCommandName = "IMPORT"
+
+// importElementsPerCommand is the largest number of elements in an imported command, large values are split in several commands
+// so the Raft log entries stay small
+const importElementsPerCommand = 1000
+
+func RegisterImportCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     ImportCommandName,
+		Args:     "path",
+		Execute:  executeImport(),
+		Category: commands.CategoryAdmin,
+	})
+}
+
+func executeImport() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		if len(args) != 1 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+
+		// Process this command on leader, the path is read on the leader
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// If request is forwarded we just send back the answer from the leader to the client
+		// and stop processing
+		if forwarded {
+			_, errConn := c.Write([]byte(rspFwd))
+			if errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		// The export is imported in the background and the number of keys is written once it is done
+		ts.deferReply(c, func() string {
+			imported, errImport := ts.importFile(args[0])
+			if errImport != nil {
+				fmt.Println("Error occurred importing", errImport)
+				return resp.EncodeError(errImport.Error())
+			}
+			return resp.EncodeInteger(imported)
+		})
+		return gnet.None
+	}
+}
+
+// importFile applies the records of an export through Raft and returns the number of keys imported
+func (ts *Server) importFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	imported := 0
+	batch := make([][]string, 0, applyBatchSize)
+	err = store.ReadExport(file, func(record *store.ExportRecord) error {
+		recordCommands, errRecord := ImportCommands(record, time.Now())
+		if errRecord != nil {
+			return errRecord
+		}
+		batch = append(batch, recordCommands...)
+		if len(batch) >= applyBatchSize {
+			if errApply := ts.applyBatch(batch); errApply != nil {
+				return errApply
+			}
+			batch = batch[:0]
+		}
+		imported++
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		err = ts.applyBatch(batch)
+	}
+	return imported, err
+}
+
+// ImportCommands returns the commands that write an exported record, with the command name first.
+// A key that already exists is replaced, collections and vector stores are created and must not exist yet.
+// The TTL of the record counts from now. The key and the strings of a base64 record are decoded.
+func ImportCommands(record *store.ExportRecord, now time.Time) ([][]string, error) {
+	key, err := record.Decode(record.Key)
+	if err != nil {
+		return nil, err
+	}
+	// decode decodes the strings of the value in place, the first error is kept
+	decode := func(values ...*string) {
+		for _, value := range values {
+			if err != nil {
+				return
+			}
+			*value, err = record.Decode(*value)
+		}
+	}
+
+	var result [][]string
+	switch record.Type {
+	case store.ExportTypeString:
+		var value string
+		if err = json.Unmarshal(record.Value, &value); err != nil {
+			return nil, err
+		}
+		if decode(&value); err != nil {
+			return nil, err
+		}
+		result = append(result, []string{commands.DeleteCommand, key}, []string{commands.SetCommand, key, value})
+
+	case store.ExportTypeSortedMap:
+		var members []store.ExportSortedMapMember
+		if err = json.Unmarshal(record.Value, &members); err != nil {
+			return nil, err
+		}
+		args := make([]string, 0, 3*len(members))
+		for _, member := range members {
+			decode(&member.Member, &member.Value)
+			args = append(args, strconv.FormatFloat(float64(member.Score), 'g', -1, 64), member.Member, member.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, []string{commands.DeleteCommand, key})
+		result = appendImportSplit(result, commands.ZAddCommand, key, args, 3)
+
+	case store.ExportTypeList, store.ExportTypeSet:
+		var values []string
+		if err = json.Unmarshal(record.Value, &values); err != nil {
+			return nil, err
+		}
+		for i := range values {
+			decode(&values[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := commands.RPushCommand
+		if record.Type == store.ExportTypeSet {
+			name = commands.SAddCommand
+		}
+		result = append(result, []string{commands.DeleteCommand, key})
+		result = appendImportSplit(result, name, key, values, 1)
+
+	case store.ExportTypeHash:
+		var fields map[string]string
+		if err = json.Unmarshal(record.Value, &fields); err != nil {
+			return nil, err
+		}
+		args := make([]string, 0, 2*len(fields))
+		for field, value := range fields {
+			decode(&field, &value)
+			args = append(args, field, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, []string{commands.DeleteCommand, key})
+		result = appendImportSplit(result, commands.HSetCommand, key, args, 2)
+
+	case store.ExportTypeCollection:
+		var collection store.ExportCollection
+		if err = json.Unmarshal(record.Value, &collection); err != nil {
+			return nil, err
+		}
+		indices, errIndices := json.Marshal(collection.Indices)
+		if errIndices != nil {
+			return nil, errIndices
+		}
+		schema := string(collection.Schema)
+		if schema == "null" {
+			schema = ""
+		}
+		result = append(result, []string{commands.DCreateCollection, key, schema, string(indices)})
+		for _, document := range collection.Documents {
+			decode(&document.ID)
+			result = append(result, commands.DInsertArgs(key, string(document.Data), document.ID))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	case store.ExportTypeVector:
+		var vector store.ExportVector
+		if err = json.Unmarshal(record.Value, &vector); err != nil {
+			return nil, err
+		}
+		result = append(result, []string{
+			commands.VCreate,
+			key,
+			strconv.Itoa(vector.MaxNeighbors),
+			strconv.FormatFloat(vector.LayerFactor, 'g', -1, 64),
+			strconv.Itoa(vector.EfSearch),
+		})
+		for _, node := range vector.Nodes {
+			decode(&node.ID)
+			result = append(result, commands.VInsertArgs(key, node.ID, node.Level, node.Vector))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown type %s", record.Type)
+	}
+
+	if record.TTL > 0 {
+		result = append(result, commands.ExpireAtArgs(key, now.Add(time.Duration(record.TTL)*time.Millisecond)))
+	}
+	return result, nil
+}
+
+// appendImportSplit appends name key args... split in commands of at most importElementsPerCommand elements of per arguments
+func appendImportSplit(result [][]string, name, key string, args []string, per int) [][]string {
+	for start := 0; start < len(args); start += importElementsPerCommand * per {
+		end := min(start+importElementsPerCommand*per, len(args))
+		command := make([]string, 0, 2+end-start)
+		command = append(command, name, key)
+		result = append(result, append(command, args[start:end]...))
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/rdb"
+	"treds/resp"
+)
+
+const LoadRDBCommandName = "LOADRDB"
+
+func RegisterLoadRDBCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:     LoadRDBCommandName,
+		Args:     "path",
+		Execute:  executeLoadRDB(),
+		Category: commands.CategoryAdmin,
+	})
+}
+
+func executeLoadRDB() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		if len(args) != 1 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+
+		// Process this command on leader, the path is read on the leader
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// If request is forwarded we just send back the answer from the leader to the client
+		// and stop processing
+		if forwarded {
+			_, errConn := c.Write([]byte(rspFwd))
+			if errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		// The dump is loaded in the background and the number of keys is written once it is done
+		ts.deferReply(c, func() string {
+			loaded, errLoad := ts.loadRDB(args[0])
+			if errLoad != nil {
+				fmt.Println("Error occurred loading RDB", errLoad)
+				return resp.EncodeError(errLoad.Error())
+			}
+			return resp.EncodeInteger(loaded)
+		})
+		return gnet.None
+	}
+}
+
+// loadRDB applies the keys of a Redis dump through Raft and returns the number of keys loaded.
+// Keys of every database of the dump are loaded in the single keyspace of Treds.
+func (ts *Server) loadRDB(path string) (loaded int, err error) {
+	// The dump can come from anywhere, a corruption the parser does not catch fails the command rather than the node
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("loading %s: %v", path, r)
+		}
+	}()
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	batch := make([][]string, 0, applyBatchSize)
+	err = rdb.Parse(file, func(entry *rdb.Entry) error {
+		entryCommands := entry.Commands()
+		if len(entryCommands) == 0 {
+			return nil
+		}
+		batch = append(batch, entryCommands...)
+		if len(batch) >= applyBatchSize {
+			if errApply := ts.applyBatch(batch); errApply != nil {
+				return errApply
+			}
+			batch = batch[:0]
+		}
+		loaded++
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		err = ts.applyBatch(batch)
+	}
+	return loaded, err
+}
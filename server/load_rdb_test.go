@@ -0,0 +1,37 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRDBPipelined(t *testing.T) {
+	dir := t.TempDir()
+	// A dump with the string key "key" and a zero checksum, which is not verified
+	dump := append([]byte("REDIS0011\x00\x03key\x05value\xff"), make([]byte, 8)...)
+	path := filepath.Join(dir, "dump.rdb")
+	if err := os.WriteFile(path, dump, 0644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// A corrupted dump with a string of 2^63-1 bytes
+	corrupted := filepath.Join(dir, "corrupted.rdb")
+	if err := os.WriteFile(corrupted, []byte("REDIS0011\x00\x01k\x81\x7f\xff\xff\xff\xff\xff\xff\xff"), 0644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, addr := startStandalone(t, "", "")
+	defer s.Shutdown()
+
+	// The commands after LOADRDB wait for its reply, which is written once the dump is loaded
+	rc := dialRaw(t, addr)
+	replies := rc.pipeline(
+		[]string{"LOADRDB", path}, []string{"GET", "key"},
+		[]string{"LOADRDB", filepath.Join(dir, "missing.rdb")}, []string{"LOADRDB", corrupted}, []string{"PING"},
+	)
+	for i, expected := range []string{":1\r\n", "$5\r\nvalue\r\n", "-open ", "-reading key k: string of", "+PONG\r\n"} {
+		if !strings.HasPrefix(replies[i], expected) {
+			t.Fatalf("expected reply %d to be %q, got %q", i, expected, replies)
+		}
+	}
+}
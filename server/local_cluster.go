@@ -0,0 +1,172 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+)
+
+// LocalCluster is a cluster of nodes running in one process on the loopback interface, every node has its own
+// client port, Raft port and data directory. It is meant for integration tests.
+type LocalCluster struct {
+	Servers []*Server
+	// ClientAddrs are the host:port the clients of every server connect to, in the order of Servers
+	ClientAddrs []string
+	// RaftAddrs are the host:port of the Raft transport of every server, in the order of Servers
+	RaftAddrs []string
+	// IDs are the server ids, in the order of Servers
+	IDs []string
+	dir string
+}
+
+// StartLocalCluster bootstraps a cluster of size voters with their data under dir and serves their client ports
+func StartLocalCluster(dir string, size int) (*LocalCluster, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("a cluster needs at least one node")
+	}
+	ports, err := freePorts(2 * size)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, size)
+	for i := range ids {
+		ids[i] = uuid.New().String()
+	}
+
+	cluster := &LocalCluster{dir: dir}
+	for i := 0; i < size; i++ {
+		// Every node is bootstrapped with the others, so they all start from the same configuration
+		var servers []BootStrapServer
+		for j := 0; j < size; j++ {
+			if j != i {
+				servers = append(servers, BootStrapServer{ID: ids[j], Host: "127.0.0.1", Port: ports[2*j+1]})
+			}
+		}
+		if _, err = cluster.startNode(ids[i], ports[2*i], ports[2*i+1], Config{Servers: servers}); err != nil {
+			cluster.Shutdown()
+			return nil, err
+		}
+	}
+	return cluster, nil
+}
+
+// AddNode starts a node that is not part of the cluster and returns its index in Servers,
+// it joins once CLUSTER JOIN is run on the leader with its id and RaftAddrs entry
+func (lc *LocalCluster) AddNode() (int, error) {
+	ports, err := freePorts(2)
+	if err != nil {
+		return -1, err
+	}
+	return lc.startNode(uuid.New().String(), ports[0], ports[1], Config{Join: true})
+}
+
+// AddReplica starts a read-only replica and returns its index in Servers,
+// it joins once CLUSTER JOIN is run on the leader with its id, RaftAddrs entry and NONVOTER
+func (lc *LocalCluster) AddReplica(readOnly bool) (int, error) {
+	ports, err := freePorts(2)
+	if err != nil {
+		return -1, err
+	}
+	return lc.startNode(uuid.New().String(), ports[0], ports[1], Config{Replica: true, ReadOnly: readOnly})
+}
+
+// startNode starts a node with the role set in cfg, its ports and directories are filled in
+func (lc *LocalCluster) startNode(id string, clientPort, raftPort int, cfg Config) (int, error) {
+	nodeDir := filepath.Join(lc.dir, "node-"+strconv.Itoa(len(lc.Servers)))
+	cfg.Port = clientPort
+	cfg.SegmentSize = 1024 * 1024
+	cfg.BindAddr = "127.0.0.1"
+	cfg.RaftPort = raftPort
+	cfg.DataDir = filepath.Join(nodeDir, DefaultDataDir)
+	cfg.ServerIdFile = filepath.Join(nodeDir, "server-id")
+	cfg.ServerId = id
+	cfg.ApplyTimeout = time.Second
+	s, err := New(cfg)
+	if err != nil {
+		return -1, err
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(clientPort))
+	lc.Servers = append(lc.Servers, s)
+	lc.ClientAddrs = append(lc.ClientAddrs, addr)
+	lc.RaftAddrs = append(lc.RaftAddrs, net.JoinHostPort("127.0.0.1", strconv.Itoa(raftPort)))
+	lc.IDs = append(lc.IDs, id)
+	go func() {
+		if errServe := s.Serve(addr); errServe != nil {
+			fmt.Println("Error occurred serving", addr, errServe)
+		}
+	}()
+	return len(lc.Servers) - 1, waitForListener(addr, 10*time.Second)
+}
+
+// WaitForLeader waits until a node of the cluster is the leader and returns its index in Servers
+func (lc *LocalCluster) WaitForLeader(timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for i, s := range lc.Servers {
+			if s != nil && s.raft.State() == raft.Leader {
+				return i, nil
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return -1, fmt.Errorf("no leader was elected in %s", timeout)
+}
+
+// Stop shuts the node at index down, it stays in the configuration of the cluster
+func (lc *LocalCluster) Stop(index int) error {
+	s := lc.Servers[index]
+	if s == nil {
+		return nil
+	}
+	lc.Servers[index] = nil
+	return s.Shutdown()
+}
+
+// Shutdown stops every node that is still running
+func (lc *LocalCluster) Shutdown() {
+	for i := range lc.Servers {
+		if err := lc.Stop(i); err != nil {
+			fmt.Println("Error occurred shutting down node", i, err)
+		}
+	}
+}
+
+// freePorts returns n ports of the loopback interface nothing listens on
+func freePorts(n int) ([]int, error) {
+	listeners := make([]net.Listener, 0, n)
+	defer func() {
+		for _, listener := range listeners {
+			_ = listener.Close()
+		}
+	}()
+	ports := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		// The listeners are kept open until every port is picked, so the ports are distinct
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+	}
+	return ports, nil
+}
+
+func waitForListener(addr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is not listening: %v", addr, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"treds/client"
+)
+
+// waitForValue reads key from addr until it holds value
+func waitForValue(t *testing.T, addr, key, value string) {
+	t.Helper()
+	c := client.New(client.Options{Addr: addr})
+	defer c.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		got, err := c.Get(ctx, key)
+		cancel()
+		if err == nil && got == value {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be %s on %s, got %q, %v", key, value, addr, got, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestLocalCluster(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	c := client.New(client.Options{Addr: cluster.ClientAddrs[leader]})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	err = c.Set(ctx, "key", "value")
+	cancel()
+	_ = c.Close()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, addr := range cluster.ClientAddrs {
+		waitForValue(t, addr, "key", "value")
+	}
+
+	// The two nodes left elect a new leader which keeps the write
+	if err = cluster.Stop(leader); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	newLeader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if newLeader == leader {
+		t.Fatalf("expected a new leader")
+	}
+	waitForValue(t, cluster.ClientAddrs[newLeader], "key", "value")
+}
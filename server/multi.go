@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
 )
 
@@ -11,8 +12,9 @@ const MultiCommandName = "MULTI"
 
 func RegisterMultiCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    MultiCommandName,
-		Execute: executeMulti(),
+		Name:     MultiCommandName,
+		Execute:  executeMulti(),
+		Category: commands.CategoryConnection,
 	})
 }
 
@@ -20,7 +22,7 @@ func executeMulti() ExecutionHook {
 	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
 		// Only writes need to be forwarded to leader
 		// Process the command on the leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
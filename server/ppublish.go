@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
 )
 
@@ -13,8 +14,11 @@ const PMessage = "pmessage"
 
 func RegisterPPublishCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    PPublishCommandName,
-		Execute: executePPublishCommand(),
+		Name:     PPublishCommandName,
+		Args:     "channel message",
+		Execute:  executePPublishCommand(),
+		Category: commands.CategoryPubSub,
+		Channels: commands.KeyAt(0),
 	})
 }
 
@@ -32,7 +36,7 @@ func executePPublishCommand() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -66,9 +70,9 @@ func executePPublishCommand() ExecutionHook {
 			}
 			connections := value.(map[string]struct{})
 			for id := range connections {
-				arrayMessage := []string{PMessage, channelPrefix, string(key), message}
+				arrayMessage := []interface{}{PMessage, channelPrefix, string(key), message}
 				conn := ts.GetConnectionFromAddress(id)
-				_, errConn := conn.Write([]byte(resp.EncodeStringArray(arrayMessage)))
+				_, errConn := conn.Write([]byte(ts.EncodeMessage(id, arrayMessage)))
 				if errConn != nil {
 					fmt.Println("Error occurred writing to connection", errConn)
 				}
@@ -5,15 +5,18 @@ import (
 	"strings"
 
 	"github.com/panjf2000/gnet/v2"
-	"treds/resp"
+	"treds/commands"
 )
 
 const PSubscribeCommandName = "PSUBSCRIBE"
 
 func RegisterPSubscribeCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    PSubscribeCommandName,
-		Execute: executePSubscribeCommand(),
+		Name:     PSubscribeCommandName,
+		Args:     "channel [channel ...]",
+		Execute:  executePSubscribeCommand(),
+		Category: commands.CategoryPubSub,
+		Channels: commands.KeysFrom(0, 1),
 	})
 }
 
@@ -31,7 +34,7 @@ func executePSubscribeCommand() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -88,7 +91,7 @@ func executePSubscribeCommand() ExecutionHook {
 			ts.GetConnectionSubscription()[c.RemoteAddr().String()][channel] = struct{}{}
 			response = append(response, indx+1)
 		}
-		_, errConn := c.Write([]byte(resp.EncodeArray(response)))
+		_, errConn := c.Write([]byte(ts.EncodeMessage(c.RemoteAddr().String(), response)))
 		if errConn != nil {
 			ts.RespondErr(c, errConn)
 		}
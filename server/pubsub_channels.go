@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
 )
 
@@ -11,8 +12,11 @@ const PubSubChannelCommandName = "PUBSUBCHANNELS"
 
 func RegisterPubSubChannels(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    PubSubChannelCommandName,
-		Execute: executePubSubChannelsCommand(),
+		Name:     PubSubChannelCommandName,
+		Args:     "[prefix]",
+		Execute:  executePubSubChannelsCommand(),
+		Category: commands.CategoryPubSub,
+		Channels: commands.KeyAt(0),
 	})
 }
 
@@ -25,7 +29,7 @@ func executePubSubChannelsCommand() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -5,15 +5,17 @@ import (
 	"strings"
 
 	"github.com/panjf2000/gnet/v2"
-	"treds/resp"
+	"treds/commands"
 )
 
 const PUnsubscribeCommandName = "PUNSUBSCRIBE"
 
 func RegisterPUnsubscribeCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    PUnsubscribeCommandName,
-		Execute: executePUnsubscribeCommand(),
+		Name:     PUnsubscribeCommandName,
+		Args:     "channel [channel ...]",
+		Execute:  executePUnsubscribeCommand(),
+		Category: commands.CategoryPubSub,
 	})
 }
 
@@ -31,7 +33,7 @@ func executePUnsubscribeCommand() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -84,7 +86,7 @@ func executePUnsubscribeCommand() ExecutionHook {
 			response = append(response, channel)
 			response = append(response, indx+1)
 		}
-		_, errConn := c.Write([]byte(resp.EncodeArray(response)))
+		_, errConn := c.Write([]byte(ts.EncodeMessage(c.RemoteAddr().String(), response)))
 		if errConn != nil {
 			ts.RespondErr(c, errConn)
 		}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+)
+
+const RecoverCommandName = "RECOVER"
+
+func RegisterRecoverCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:      RecoverCommandName,
+		Args:      "INDEX index | TIME timestamp",
+		Execute:   executeRecover(),
+		Category:  commands.CategoryAdmin,
+		NeedsRaft: true,
+	})
+}
+
+// RecoveryTarget is the point a store is recovered at, the Raft index of the last applied entry
+// or, when Index is 0, the time of the last applied entry
+type RecoveryTarget struct {
+	Index uint64
+	Time  time.Time
+}
+
+// ParseRecoveryTarget parses INDEX index or TIME timestamp, the timestamp is RFC 3339 or unix milliseconds
+func ParseRecoveryTarget(kind, value string) (RecoveryTarget, error) {
+	switch strings.ToUpper(kind) {
+	case "INDEX":
+		index, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || index == 0 {
+			return RecoveryTarget{}, fmt.Errorf("invalid index %s", value)
+		}
+		return RecoveryTarget{Index: index}, nil
+	case "TIME":
+		if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return RecoveryTarget{Time: time.UnixMilli(millis)}, nil
+		}
+		at, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return RecoveryTarget{}, fmt.Errorf("invalid time %s, expected RFC 3339 or unix milliseconds", value)
+		}
+		return RecoveryTarget{Time: at}, nil
+	}
+	return RecoveryTarget{}, fmt.Errorf("expected INDEX or TIME, got %s", kind)
+}
+
+func executeRecover() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		if len(args) != 2 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+
+		target, err := ParseRecoveryTarget(args[0], args[1])
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// Process this command on leader, its log and snapshots are replayed
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+
+		// If request is forwarded we just send back the answer from the leader to the client
+		// and stop processing
+		if forwarded {
+			_, errConn := c.Write([]byte(rspFwd))
+			if errConn != nil {
+				fmt.Println("Error occurred writing to connection", errConn)
+			}
+			return gnet.None
+		}
+
+		if _, ok := ts.GetClientTransaction()[c.RemoteAddr().String()]; ok {
+			ts.RespondErr(c, fmt.Errorf("please run this command outside transaction"))
+			return gnet.None
+		}
+
+		// The log is replayed in the background and the recovered index is written once the store is replaced
+		ts.deferReply(c, func() string {
+			index, errRecover := ts.recoverAt(target)
+			if errRecover != nil {
+				fmt.Println("Error occurred recovering", errRecover)
+				return resp.EncodeError(errRecover.Error())
+			}
+			return resp.EncodeInteger(int(index))
+		})
+		return gnet.None
+	}
+}
+
+// recoverAt rebuilds the store at the target from the snapshots and the log of this node
+// and restores it through Raft, like RESTORE, on every node. It returns the index of the last replayed entry.
+func (ts *Server) recoverAt(target RecoveryTarget) (uint64, error) {
+	recovered, index, err := RecoverStore(ts.logStore, ts.snapshotStore, ts.tredsCommandRegistry, target)
+	if err != nil {
+		return 0, err
+	}
+	view, err := recovered.Snapshot()
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.CreateTemp("", "treds-recover-*.bin")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if err = view.Persist(file, ts.fsm.compression); err != nil {
+		return 0, err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	meta := &raft.SnapshotMeta{Version: raft.SnapshotVersionMax, ID: fmt.Sprintf("recover-%d", index), Size: size}
+	if err = ts.raft.Restore(meta, file, 2*time.Minute); err != nil {
+		return 0, err
+	}
+	return index, nil
+}
+
+// RecoverStore rebuilds the store as it was after the entry of the log at the target. The newest snapshot taken
+// at or before the target is restored and the commands of the log after it are applied up to the target.
+// It returns the store and the index of the last applied entry.
+func RecoverStore(logs raft.LogStore, snapshots raft.SnapshotStore, registry commands.CommandRegistry, target RecoveryTarget) (*store.TredsStore, uint64, error) {
+	first, err := logs.FirstIndex()
+	if err != nil {
+		return nil, 0, err
+	}
+	last, err := logs.LastIndex()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	targetIndex := target.Index
+	if targetIndex == 0 {
+		if targetIndex, err = lastIndexAt(logs, first, last, target.Time); err != nil {
+			return nil, 0, err
+		}
+	} else if targetIndex > last {
+		return nil, 0, fmt.Errorf("index %d is past the last index %d of the log", targetIndex, last)
+	}
+
+	metas, err := snapshots.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	recovered := store.NewTredsStore()
+	start := uint64(1)
+	// Snapshots are listed newest first
+	for _, meta := range metas {
+		if meta.Index > targetIndex {
+			continue
+		}
+		_, state, errOpen := snapshots.Open(meta.ID)
+		if errOpen != nil {
+			return nil, 0, errOpen
+		}
+		errRestore := recovered.Restore(state)
+		_ = state.Close()
+		if errRestore != nil {
+			return nil, 0, fmt.Errorf("restoring snapshot %s: %v", meta.ID, errRestore)
+		}
+		start = meta.Index + 1
+		break
+	}
+	if start < first && start <= targetIndex {
+		return nil, 0, fmt.Errorf("the log starts at index %d and no snapshot at or before index %d was kept, recover from a backup", first, targetIndex)
+	}
+
+	// The entries are applied like Raft applies them, commands are the only entries the FSM sees
+	fsm := NewTredsFsm(registry, recovered, store.CompressionNone)
+	for index := start; index <= targetIndex; index++ {
+		var entry raft.Log
+		if err = logs.GetLog(index, &entry); err != nil {
+			return nil, 0, fmt.Errorf("reading log entry %d: %v", index, err)
+		}
+		if entry.Type == raft.LogCommand {
+			fsm.Apply(&entry)
+		}
+	}
+	return recovered, targetIndex, nil
+}
+
+// lastIndexAt returns the index of the last entry of the log appended at or before at.
+// Entries written before Raft recorded the time they were appended are considered older than any time.
+func lastIndexAt(logs raft.LogStore, first, last uint64, at time.Time) (uint64, error) {
+	var found uint64
+	for index := first; index <= last && index > 0; index++ {
+		var entry raft.Log
+		if err := logs.GetLog(index, &entry); err != nil {
+			if errors.Is(err, raft.ErrLogNotFound) {
+				continue
+			}
+			return 0, err
+		}
+		if !entry.AppendedAt.IsZero() && entry.AppendedAt.After(at) {
+			break
+		}
+		found = index
+	}
+	if found == 0 {
+		return 0, fmt.Errorf("no log entry was appended at or before %s, the log starts at index %d", at.Format(time.RFC3339Nano), first)
+	}
+	return found, nil
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+)
+
+func TestRecoverPipelined(t *testing.T) {
+	s, addr := startNode(t, t.TempDir())
+	defer s.Shutdown()
+
+	rc := dialRaw(t, addr)
+	if reply := rc.do("SET", "key", "first"); reply != "+OK\r\n" {
+		t.Fatalf("expected OK, got %q", reply)
+	}
+	index := strconv.FormatUint(s.raft.LastIndex(), 10)
+	if reply := rc.do("SET", "key", "second"); reply != "+OK\r\n" {
+		t.Fatalf("expected OK, got %q", reply)
+	}
+	// The commands after RECOVER wait for its reply, which is written once the recovered store is restored
+	replies := rc.pipeline([]string{"RECOVER", "INDEX", index}, []string{"GET", "key"})
+	expected := []string{":" + index + "\r\n", "$5\r\nfirst\r\n"}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Fatalf("expected %q, got %q", expected, replies)
+	}
+}
+
+// recoveryLog returns a log with the entries from first to 6 and snapshots at index 2 and 5. Entry i sets key to vi
+// and is appended i minutes after base, entry 4 is not a command. The snapshots hold the key of the log and a snapshot key
+// the log never sets, so the snapshot a store was recovered from can be told.
+func recoveryLog(t *testing.T, first uint64, base time.Time) (raft.LogStore, raft.SnapshotStore) {
+	t.Helper()
+	logs := raft.NewInmemStore()
+	for index := first; index <= 6; index++ {
+		entry := &raft.Log{Index: index, Term: 1, Type: raft.LogCommand, AppendedAt: base.Add(time.Duration(index) * time.Minute)}
+		if index == 4 {
+			entry.Type = raft.LogNoop
+		} else {
+			entry.Data = []byte(resp.EncodeStringArray([]string{commands.SetCommand, "key", fmt.Sprintf("v%d", index)}))
+		}
+		if err := logs.StoreLog(entry); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// The in-memory snapshot store keeps a single snapshot
+	snapshots, err := raft.NewFileSnapshotStore(t.TempDir(), 2, io.Discard)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, index := range []uint64{2, 5} {
+		tredsStore := store.NewTredsStore()
+		_ = tredsStore.Set("key", fmt.Sprintf("v%d", index))
+		_ = tredsStore.Set("snapshot", strconv.FormatUint(index, 10))
+		view, errView := tredsStore.Snapshot()
+		if errView != nil {
+			t.Fatalf("expected no error, got %v", errView)
+		}
+		sink, errCreate := snapshots.Create(raft.SnapshotVersionMax, index, 1, raft.Configuration{}, 1, nil)
+		if errCreate != nil {
+			t.Fatalf("expected no error, got %v", errCreate)
+		}
+		if errPersist := view.Persist(sink, store.CompressionNone); errPersist != nil {
+			t.Fatalf("expected no error, got %v", errPersist)
+		}
+		if errClose := sink.Close(); errClose != nil {
+			t.Fatalf("expected no error, got %v", errClose)
+		}
+	}
+	return logs, snapshots
+}
+
+func TestRecoverStore(t *testing.T) {
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	base := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		first    uint64
+		target   RecoveryTarget
+		index    uint64
+		key      string
+		snapshot string
+		err      string
+	}{
+		{name: "index before the snapshots", first: 1, target: RecoveryTarget{Index: 1}, index: 1, key: "v1", snapshot: "(nil)"},
+		{name: "index of a snapshot", first: 1, target: RecoveryTarget{Index: 2}, index: 2, key: "v2", snapshot: "2"},
+		{name: "index after the first snapshot", first: 1, target: RecoveryTarget{Index: 4}, index: 4, key: "v3", snapshot: "2"},
+		{name: "last index", first: 1, target: RecoveryTarget{Index: 6}, index: 6, key: "v6", snapshot: "5"},
+		{name: "index past the log", first: 1, target: RecoveryTarget{Index: 7}, err: "index 7 is past the last index 6 of the log"},
+		{name: "time between entries", first: 1, target: RecoveryTarget{Time: base.Add(4*time.Minute + 30*time.Second)}, index: 4, key: "v3", snapshot: "2"},
+		{name: "time of an entry", first: 1, target: RecoveryTarget{Time: base.Add(5 * time.Minute)}, index: 5, key: "v5", snapshot: "5"},
+		{name: "time after the log", first: 1, target: RecoveryTarget{Time: base.Add(time.Hour)}, index: 6, key: "v6", snapshot: "5"},
+		{name: "time before the log", first: 1, target: RecoveryTarget{Time: base}, err: "no log entry was appended at or before"},
+		{name: "compacted log with a snapshot", first: 4, target: RecoveryTarget{Index: 6}, index: 6, key: "v6", snapshot: "5"},
+		{name: "log compacted past the target", first: 4, target: RecoveryTarget{Index: 4},
+			err: "the log starts at index 4 and no snapshot at or before index 4 was kept, recover from a backup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, snapshots := recoveryLog(t, tt.first, base)
+			recovered, index, err := RecoverStore(logs, snapshots, registry, tt.target)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if index != tt.index {
+				t.Fatalf("expected index %d, got %d", tt.index, index)
+			}
+			if key, _ := recovered.Get("key"); key != tt.key {
+				t.Fatalf("expected key to be %q, got %q", tt.key, key)
+			}
+			if snapshot, _ := recovered.Get("snapshot"); snapshot != tt.snapshot {
+				t.Fatalf("expected the snapshot %q, got %q", tt.snapshot, snapshot)
+			}
+		})
+	}
+}
@@ -2,14 +2,18 @@ package server
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	"treds/commands"
+
 	"github.com/panjf2000/gnet/v2"
 )
 
 type ServerCommandRegistry interface {
 	Add(*ServerCommandRegistration) error
 	Retrieve(string) (*ServerCommandRegistration, error)
+	List() []*ServerCommandRegistration
 }
 
 type CommandRegistry struct {
@@ -19,8 +23,18 @@ type CommandRegistry struct {
 type ExecutionHook func(inp string, server *Server, c gnet.Conn) gnet.Action
 
 type ServerCommandRegistration struct {
-	Name    string
+	Name string
+	// Args describes the arguments after the command name, it is served by COMMAND for client hints
+	Args    string
 	Execute ExecutionHook
+	// Category is the ACL category of the command, one of the commands.Category constants
+	Category string
+	// Channels locates the channels in the arguments for ACL checks
+	Channels *commands.KeySpec
+	// NoAuth commands can run before the connection authenticates
+	NoAuth bool
+	// NeedsRaft commands are not available on a standalone node
+	NeedsRaft bool
 }
 
 func NewRegistry() ServerCommandRegistry {
@@ -45,3 +59,15 @@ func (c *CommandRegistry) Retrieve(name string) (*ServerCommandRegistration, err
 
 	return c.commands[strings.ToUpper(name)], nil
 }
+
+// List returns every registered command sorted by name
+func (c *CommandRegistry) List() []*ServerCommandRegistration {
+	registrations := make([]*ServerCommandRegistration, 0, len(c.commands))
+	for _, reg := range c.commands {
+		registrations = append(registrations, reg)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
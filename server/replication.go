@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
+	"treds/resp"
+)
+
+const ReplicationCommandName = "REPLICATION"
+
+// readOnlyError is the reply of a read-only replica to the commands that run on the leader
+const readOnlyError = "READONLY this node is a read-only replica, run writes on the leader"
+
+func RegisterReplicationCommand(r ServerCommandRegistry) {
+	r.Add(&ServerCommandRegistration{
+		Name:      ReplicationCommandName,
+		Execute:   executeReplication(),
+		Category:  commands.CategoryRead,
+		NeedsRaft: true,
+	})
+}
+
+// executeReplication reports the role of this node and how far it is behind the leader, as known by this node
+func executeReplication() ExecutionHook {
+	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
+		_, args, err := parseCommand(inp)
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		if len(args) != 0 {
+			ts.RespondErr(c, fmt.Errorf("invalid number of arguments"))
+			return gnet.None
+		}
+		fields, err := ts.replicationFields()
+		if err != nil {
+			ts.RespondErr(c, err)
+			return gnet.None
+		}
+		res := resp.EncodeStringArrayRESP(fields)
+		if ts.GetConnectionProtocol(c.RemoteAddr().String()) == resp.Protocol3 {
+			res = resp.EncodeMapRESP(fields)
+		}
+		if _, errConn := c.Write([]byte(res)); errConn != nil {
+			fmt.Println("Error occurred writing to connection", errConn)
+		}
+		return gnet.None
+	}
+}
+
+// replicationFields returns the RESP encoded fields and values REPLICATION replies with.
+// The lag is the time since the leader was last heard from and the committed entries not applied yet,
+// the commit index of a follower is the one the leader sent it last.
+func (ts *Server) replicationFields() ([]string, error) {
+	server, found, err := ts.clusterServer(ts.id)
+	if err != nil {
+		return nil, err
+	}
+	suffrage := "none"
+	if found {
+		suffrage = strings.ToLower(server.Suffrage.String())
+	}
+	role := "follower"
+	lagMillis := 0
+	switch {
+	case ts.raft.State() == raft.Leader:
+		role = "leader"
+	case ts.replica || suffrage == "nonvoter":
+		role = "replica"
+	}
+	if role != "leader" {
+		lagMillis = -1
+		if lastContact := ts.raft.LastContact(); !lastContact.IsZero() {
+			lagMillis = int(time.Since(lastContact).Milliseconds())
+		}
+	}
+	commitIndex, appliedIndex := ts.raft.CommitIndex(), ts.raft.AppliedIndex()
+	lagEntries := 0
+	if commitIndex > appliedIndex {
+		lagEntries = int(commitIndex - appliedIndex)
+	}
+	return []string{
+		resp.EncodeBulkString("id"), resp.EncodeBulkString(string(ts.id)),
+		resp.EncodeBulkString("role"), resp.EncodeBulkString(role),
+		resp.EncodeBulkString("suffrage"), resp.EncodeBulkString(suffrage),
+		resp.EncodeBulkString("read_only"), resp.EncodeBulkString(strconv.FormatBool(ts.readOnly)),
+		resp.EncodeBulkString("leader"), resp.EncodeBulkString(ts.LeaderAddress()),
+		resp.EncodeBulkString("last_log_index"), resp.EncodeInteger(int(ts.raft.LastIndex())),
+		resp.EncodeBulkString("commit_index"), resp.EncodeInteger(int(commitIndex)),
+		resp.EncodeBulkString("applied_index"), resp.EncodeInteger(int(appliedIndex)),
+		resp.EncodeBulkString("lag_entries"), resp.EncodeInteger(lagEntries),
+		resp.EncodeBulkString("lag_ms"), resp.EncodeInteger(lagMillis),
+	}, nil
+}
+
+// stayNonvoter keeps a replica out of the quorum until Shutdown: when it is made a voter it asks the leader
+// to make it a nonvoter again, and when it was elected meanwhile it hands the leadership over first
+func (ts *Server) stayNonvoter() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ts.done:
+			return
+		case <-ticker.C:
+		}
+		server, found, err := ts.clusterServer(ts.id)
+		if err != nil || !found || server.Suffrage != raft.Voter {
+			continue
+		}
+		if ts.raft.State() == raft.Leader {
+			fmt.Println("This replica is the leader, transferring the leadership")
+			if errTransfer := ts.raft.LeadershipTransfer().Error(); errTransfer != nil {
+				fmt.Println("Error occurred transferring the leadership", errTransfer)
+			}
+			continue
+		}
+		demote := resp.EncodeStringArray([]string{ClusterCommandName, clusterJoin, string(ts.id), string(server.Address), "NONVOTER"})
+		_, reply, errForward := ts.forwardRequest([]byte(demote), resp.Protocol2, false)
+		if errForward == nil && strings.HasPrefix(reply, "-") {
+			errForward = fmt.Errorf("%s", strings.TrimSpace(reply[1:]))
+		}
+		if errForward != nil {
+			fmt.Println("Error occurred making this replica a nonvoter", errForward)
+		}
+	}
+}
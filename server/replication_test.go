@@ -0,0 +1,77 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// replication returns the fields REPLICATION replies with on addr, the client reads maps as lists of fields and values
+func replication(t *testing.T, addr string) map[string]interface{} {
+	t.Helper()
+	reply := do(t, addr, "REPLICATION").([]interface{})
+	fields := make(map[string]interface{}, len(reply)/2)
+	for i := 0; i+1 < len(reply); i += 2 {
+		fields[reply[i].(string)] = reply[i+1]
+	}
+	return fields
+}
+
+func TestReplica(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	leaderAddr := cluster.ClientAddrs[leader]
+	do(t, leaderAddr, "SET", "key", "value")
+
+	readOnly, err := cluster.AddReplica(true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	forwarding, err := cluster.AddReplica(false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, replica := range []int{readOnly, forwarding} {
+		do(t, leaderAddr, "CLUSTER", "JOIN", cluster.IDs[replica], cluster.RaftAddrs[replica], "NONVOTER")
+		waitForValue(t, cluster.ClientAddrs[replica], "key", "value")
+		waitForLeaderAddress(t, cluster, replica, leader)
+	}
+
+	// Writes are rejected by the read-only replica, reads that need the leader are still forwarded
+	if _, err = doErr(cluster.ClientAddrs[readOnly], "SET", "key", "other"); err == nil || !strings.HasPrefix(err.Error(), "READONLY") {
+		t.Fatalf("expected READONLY error, got %v", err)
+	}
+	if got := do(t, cluster.ClientAddrs[readOnly], "READ", "LINEARIZABLE", "GET", "key"); got != "value" {
+		t.Fatalf("expected value, got %v", got)
+	}
+	do(t, cluster.ClientAddrs[forwarding], "SET", "key", "other")
+	waitForValue(t, cluster.ClientAddrs[readOnly], "key", "other")
+
+	fields := replication(t, cluster.ClientAddrs[readOnly])
+	if fields["role"] != "replica" || fields["suffrage"] != "nonvoter" || fields["read_only"] != "true" || fields["leader"] != leaderAddr {
+		t.Fatalf("unexpected replication fields %v", fields)
+	}
+	if lag, ok := fields["lag_ms"].(int64); !ok || lag < 0 {
+		t.Fatalf("expected the time since the leader was heard from, got %v", fields["lag_ms"])
+	}
+	if fields = replication(t, leaderAddr); fields["role"] != "leader" || fields["lag_ms"] != int64(0) {
+		t.Fatalf("unexpected replication fields %v", fields)
+	}
+
+	// A replica made a voter makes itself a nonvoter again
+	do(t, leaderAddr, "CLUSTER", "JOIN", cluster.IDs[forwarding], cluster.RaftAddrs[forwarding], "VOTER")
+	deadline := time.Now().Add(10 * time.Second)
+	for members(t, leaderAddr)[cluster.IDs[forwarding]][2] != "nonvoter" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the replica to be a nonvoter again, got %v", members(t, leaderAddr)[cluster.IDs[forwarding]])
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -10,15 +10,20 @@ import (
 
 	"github.com/hashicorp/raft"
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
+	"treds/store"
 )
 
 const RestoreCommandName = "RESTORE"
 
 func RegisterRestoreCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    RestoreCommandName,
-		Execute: executeRestore(),
+		Name:      RestoreCommandName,
+		Args:      "folder_path",
+		Execute:   executeRestore(),
+		Category:  commands.CategoryAdmin,
+		NeedsRaft: true,
 	})
 }
 
@@ -36,7 +41,7 @@ func executeRestore() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -59,38 +64,24 @@ func executeRestore() ExecutionHook {
 
 		snapshotPath := args[0]
 
-		metaFile := filepath.Join(snapshotPath, "meta.json")
-
-		// Read the file contents
-		metaData, err := os.ReadFile(metaFile)
-		if err != nil {
-			fmt.Println("Error reading file:", err)
-			ts.RespondErr(c, err)
-			return gnet.None
-		}
-
-		// Unmarshal the JSON into the SnapshotMeta struct
-		var metaSnapshot *raft.SnapshotMeta
-		err = json.Unmarshal(metaData, &metaSnapshot)
-		if err != nil {
-			fmt.Println("Error unmarshaling JSON:", err)
+		// Raft panics when the snapshot it restores cannot be applied,
+		// so the whole snapshot is checked before the live store is replaced
+		if err = verifySnapshotPath(snapshotPath); err != nil {
+			fmt.Println("Error verifying snapshot:", err)
 			ts.RespondErr(c, err)
 			return gnet.None
 		}
 
-		file, err := os.Open(filepath.Join(snapshotPath, "state.bin"))
+		metaSnapshot, state, err := OpenSnapshotPath(snapshotPath)
 		if err != nil {
-			fmt.Println("Error opening file:", err)
+			fmt.Println("Error opening snapshot:", err)
 			ts.RespondErr(c, err)
 			return gnet.None
 		}
 		// Ensure the file is closed when done
-		defer file.Close()
-
-		// Since *os.File implements io.Reader, you can directly use it as an io.Reader
-		var reader io.Reader = file
+		defer state.Close()
 
-		err = ts.GetRaft().Restore(metaSnapshot, reader, 2*time.Minute)
+		err = ts.GetRaft().Restore(metaSnapshot, state, 2*time.Minute)
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -103,3 +94,80 @@ func executeRestore() ExecutionHook {
 		return gnet.None
 	}
 }
+
+// OpenSnapshotPath returns the metadata and the state of a snapshot folder, with meta.json and state.bin, or of a backup archive.
+// The state is not verified, see store.VerifySnapshot.
+func OpenSnapshotPath(path string) (*raft.SnapshotMeta, io.ReadCloser, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		return openBackupArchive(path)
+	}
+
+	metaData, err := os.ReadFile(filepath.Join(path, "meta.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var meta *raft.SnapshotMeta
+	if err = json.Unmarshal(metaData, &meta); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(filepath.Join(path, "state.bin"))
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	if stat.Size() != meta.Size {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("%w: state.bin has %d bytes, meta.json expects %d", store.ErrSnapshotCorrupted, stat.Size(), meta.Size)
+	}
+	return meta, file, nil
+}
+
+// verifySnapshotPath checks the size recorded in the metadata and the checksums of a snapshot folder or backup archive
+func verifySnapshotPath(path string) error {
+	_, state, err := OpenSnapshotPath(path)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+	return store.VerifySnapshot(state)
+}
+
+// WriteSnapshotFolder writes view as a folder with meta.json and state.bin, the layout RESTORE reads,
+// so snapshots built outside of a cluster can be restored
+func WriteSnapshotFolder(dir, id string, view *store.PointInTimeSnapshot, compression store.SnapshotCompression) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(dir, "state.bin"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = view.Persist(file, compression); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	meta, err := json.MarshalIndent(&raft.SnapshotMeta{
+		Version: raft.SnapshotVersionMax,
+		ID:      id,
+		Size:    info.Size(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "meta.json"), meta, 0644)
+}
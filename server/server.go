@@ -2,7 +2,9 @@ package server
 
 import (
 	"bufio"
-	"encoding/hex"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -11,13 +13,13 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	wal "github.com/hashicorp/raft-wal"
 	"treds/commands"
 	"treds/datastructures/radix"
 	"treds/resp"
-	"treds/server/connPool"
 	"treds/store"
 
 	"github.com/google/uuid"
@@ -44,116 +46,191 @@ type Server struct {
 
 	connectionMap map[string]gnet.Conn
 
+	// RESP protocol version negotiated by each connection with HELLO
+	connectionProtocol map[string]int
+	// The consistency of the reads of every connection, stale when not set
+	connectionConsistency map[string]ReadConsistency
+	// Where the scan of the incomplete frame in the inbound buffer of every connection stopped
+	connectionScanner map[string]*resp.FrameScanner
+
+	// Users loaded from the ACL file, nil when authentication is disabled
+	acl *ACL
+	// User each connection authenticated as with AUTH
+	connectionUser map[string]*ACLUser
+	// Connections waiting for the reply of a command run in the background,
+	// their later commands are left in the inbound buffer until it is written
+	deferredReply map[string]struct{}
+
+	// Certificates of the client port, commands forwarded to the leader use TLS when it is set
+	clientTLS *TLSReloader
+	// Relays the TLS connections of the client port to the event loop, nil without TLS
+	tlsProxy *tlsProxy
+
+	// Snapshots taken by Raft, BACKUP archives the latest one
+	snapshotStore raft.SnapshotStore
+	// Log of the Raft node, RECOVER replays it
+	logStore raft.LogStore
+	// Where BACKUP writes its archives and how long they are kept
+	backup BackupConfig
+
 	*gnet.BuiltinEventEngine
 	fsm              *TredsFsm
 	raft             *raft.Raft
 	id               raft.ServerID
 	raftApplyTimeout time.Duration
-	connP            *connPool.ConnPool
+
+	// Address the clients reach this node at, it is published in the metadata of the cluster while this node leads
+	clientAddr string
+	// Followers reply -MOVED with the client address of the leader instead of forwarding commands to it
+	redirect bool
+	// The node is a nonvoter that serves reads, readOnly makes it reject the commands that run on the leader
+	replica  bool
+	readOnly bool
+	// The node runs without Raft, writes are applied to the store under applyLock and appended to the command log
+	standalone bool
+	applyLock  sync.Mutex
+	commandLog *commandLog
+	// Channels the commands are forwarded to the leader on by protocol, since the leader encodes its replies
+	// for the protocol of the channel. They are replaced when the leader changes.
+	forwardLock sync.Mutex
+	forward     map[int]*forwardChannel
+	// Closes the raft-wal log once Raft is shut down
+	logStoreCloser io.Closer
+	// Event loop started by Serve, stopped by Shutdown. It is set by OnBoot on the event loop
+	// and read by Shutdown on another goroutine, under engineLock
+	engineLock sync.Mutex
+	engine     gnet.Engine
+	// Closed by Shutdown to stop the background goroutines
+	done chan struct{}
 }
 
-func New(port, segmentSize int, bindAddr, advertiseAddr, serverId string, applyTimeout time.Duration, servers []BootStrapServer) (*Server, error) {
+// DefaultRaftPort is the port the Raft transport listens on when none is configured
+const DefaultRaftPort = 8300
+
+// DefaultDataDir is where the Raft log and snapshots are kept when no directory is configured
+const DefaultDataDir = "data"
+
+// Config holds the settings of a server and of its Raft node
+type Config struct {
+	// Port the clients connect to
+	Port        int
+	SegmentSize int
+	// BindAddr is the address the Raft transport listens on
+	BindAddr string
+	// AdvertiseAddr is the host the other nodes reach this node at, worked out from BindAddr when empty
+	AdvertiseAddr string
+	// ClientAdvertiseAddr is the host:port the clients and the other nodes reach the client port at,
+	// the advertised host and Port when empty. The leader publishes it so followers can forward commands to it.
+	ClientAdvertiseAddr string
+	// Redirect makes followers reply -MOVED with the client address of the leader instead of forwarding commands
+	Redirect bool
+	// Replica starts the node as a read-only replica, it does not bootstrap a cluster and is added with
+	// CLUSTER JOIN id address NONVOTER. It never counts toward the quorum, it demotes itself when it is made a voter.
+	Replica bool
+	// ReadOnly makes a replica reject writes, and the other commands that run on the leader, instead of forwarding them
+	ReadOnly bool
+	// Standalone runs a single node without Raft, writes are applied straight to the store.
+	// Nothing is written to DataDir or ServerIdFile, the commands that need Raft are not available.
+	Standalone bool
+	// CommandLog is the append-only log a standalone node keeps its writes in and replays at startup,
+	// the writes are only kept in memory when it is empty
+	CommandLog string
+	// CommandLogSync is the interval the command log is fsynced at, after every write when 0
+	CommandLogSync time.Duration
+	// RaftPort is the port of the Raft transport, DefaultRaftPort when 0
+	RaftPort int
+	// DataDir holds the Raft log, in a folder named after the server id, and the snapshots, DefaultDataDir when empty
+	DataDir string
+	// ServerIdFile is where the server id is kept, server-id next to DataDir when empty
+	ServerIdFile string
+	// ServerId must be a uuid, it is read from ServerIdFile or generated when empty
+	ServerId     string
+	ApplyTimeout time.Duration
+	// Servers are bootstrapped with this node when it has no Raft state yet
+	Servers []BootStrapServer
+	// Join starts a new node without bootstrapping it, it waits to be added with CLUSTER JOIN on the leader
+	Join bool
+	// RaftTLS enables mutual TLS on the Raft transport when it is not nil
+	RaftTLS             *TLSReloader
+	SnapshotCompression store.SnapshotCompression
+	// SnapshotRetain is the number of snapshots kept in DataDir, 3 when 0
+	SnapshotRetain int
+}
+
+// New creates the server and its Raft node
+func New(cfg Config) (*Server, error) {
+	if cfg.RaftPort == 0 {
+		cfg.RaftPort = DefaultRaftPort
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = DefaultDataDir
+	}
+	if cfg.ServerIdFile == "" {
+		cfg.ServerIdFile = filepath.Join(filepath.Dir(filepath.Clean(cfg.DataDir)), "server-id")
+	}
+	if cfg.SnapshotRetain == 0 {
+		cfg.SnapshotRetain = 3
+	}
+	if cfg.ReadOnly && !cfg.Replica {
+		return nil, fmt.Errorf("only replicas can be read only, the other nodes may have to apply writes as the leader")
+	}
+	if cfg.Standalone && (cfg.Join || cfg.Replica || len(cfg.Servers) > 0) {
+		return nil, fmt.Errorf("a standalone node runs without Raft, it cannot be part of a cluster")
+	}
+	if cfg.Replica && len(cfg.Servers) > 0 {
+		return nil, fmt.Errorf("a replica joins a running cluster, it cannot be bootstrapped with other servers")
+	}
+	if cfg.ClientAdvertiseAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.ClientAdvertiseAddr); err != nil {
+			return nil, fmt.Errorf("invalid client advertise address %s, expected host:port", cfg.ClientAdvertiseAddr)
+		}
+	}
 
 	storeCommandRegistry := commands.NewRegistry()
 	serverCommandRegistry := NewRegistry()
 	commands.RegisterCommands(storeCommandRegistry)
 	RegisterCommands(serverCommandRegistry)
 	tredsStore := store.NewTredsStore()
+	fsm := NewTredsFsm(storeCommandRegistry, tredsStore, cfg.SnapshotCompression)
+
+	if cfg.Standalone {
+		return newStandalone(cfg, newServer(cfg, storeCommandRegistry, serverCommandRegistry, fsm))
+	}
 
 	//TODO: Default config is good enough for now, but probably need to be tweaked
 	config := raft.DefaultConfig()
 
-	serverIdFileName := "server-id"
-
-	if serverId == "" {
-		// try reading from file
-		if _, err := os.Stat(serverIdFileName); err == nil {
-			// File exists, read the UUID
-			fmt.Println("File found. Reading UUID from file... If boostrap error is seen, try removing 'data' directory, " +
-				"after backup, which can be restored using RESTORE command")
-			data, readErr := os.ReadFile(serverIdFileName)
-			if readErr != nil {
-				fmt.Println("Error reading UUID from file:", err)
-			}
-			// Parse the UUID
-			id, parseErr := uuid.Parse(string(data))
-			if parseErr != nil {
-				fmt.Println("Error parsing UUID:", parseErr)
-			}
-			fmt.Println("UUID read from file:", id)
-			config.LocalID = raft.ServerID(id.String())
-
-		} else if os.IsNotExist(err) {
-			// File does not exist, generate a new UUID
-			fmt.Println("File not found. Generating a new UUID...")
-			id := uuid.New()
-
-			// Write the UUID to the file
-			err = os.WriteFile(serverIdFileName, []byte(id.String()), 0644)
-			if err != nil {
-				fmt.Println("Error writing UUID to file:", err)
-			}
-			fmt.Println("New UUID generated and written to file:", id)
-			config.LocalID = raft.ServerID(id.String())
-		} else {
-			// Other errors (e.g., permission issues)
-			fmt.Println("Error checking file:", err)
-			id := serverId
-			config.LocalID = raft.ServerID(id)
-		}
-	} else {
-		// try reading from file
-		if _, err := os.Stat(serverIdFileName); err == nil {
-			// File exists, read the UUID
-			fmt.Println("File found. Reading UUID from file...")
-			data, readErr := os.ReadFile(serverIdFileName)
-			if readErr != nil {
-				fmt.Println("Error reading UUID from file:", err)
-			}
-			// Parse the UUID
-			id, parseErr := uuid.Parse(string(data))
-			if parseErr != nil {
-				fmt.Println("Error parsing UUID:", parseErr)
-			}
-			if id.String() != serverId {
-				return nil, fmt.Errorf("UUID does not match, please fix 'server-id' file")
-			}
-			fmt.Println("UUID read from file:", id)
-			config.LocalID = raft.ServerID(id.String())
-
-		} else if os.IsNotExist(err) {
-			// File does not exist, generate a new UUID
-			fmt.Println("File not found. Generating a new UUID...")
-			id := serverId
-
-			// Write the UUID to the file
-			err = os.WriteFile(serverIdFileName, []byte(id), 0644)
-			if err != nil {
-				fmt.Println("Error writing UUID to file:", err)
-			}
-			fmt.Println("New UUID generated and written to file:", id)
-			config.LocalID = raft.ServerID(id)
-		} else {
-			// Other errors (e.g., permission issues)
-			fmt.Println("Error checking file:", err)
-			id := serverId
-			config.LocalID = raft.ServerID(id)
-		}
+	localID, err := loadServerId(cfg.ServerIdFile, cfg.ServerId)
+	if err != nil {
+		return nil, err
 	}
+	config.LocalID = localID
 
 	//This is the port used by raft for replication and such
 	// We can keep it as a separate port or do multiplexing over TCP
-	addr := fmt.Sprintf("%s:%d", bindAddr, 8300)
-
-	transport, err := raft.NewTCPTransport(addr, &net.TCPAddr{IP: net.IP(advertiseAddr), Port: port}, 10, time.Second, os.Stdout)
+	addr := net.JoinHostPort(cfg.BindAddr, strconv.Itoa(cfg.RaftPort))
 
-	//TODO: do not panic
+	advertise, err := AdvertiseAddress(cfg.BindAddr, cfg.AdvertiseAddr, cfg.RaftPort)
 	if err != nil {
 		return nil, err
 	}
+	var transport raft.Transport
+	if cfg.RaftTLS != nil {
+		stream, errStream := NewTLSStreamLayer(addr, advertise, cfg.RaftTLS)
+		if errStream != nil {
+			return nil, errStream
+		}
+		transport = raft.NewNetworkTransport(stream, 10, time.Second, os.Stdout)
+	} else {
+		tcpTransport, errTransport := raft.NewTCPTransport(addr, advertise, 10, time.Second, os.Stdout)
+		if errTransport != nil {
+			return nil, errTransport
+		}
+		transport = tcpTransport
+	}
 
 	// Use raft wal as a backend store for raft
-	dir := filepath.Join("data", string(config.LocalID))
+	dir := filepath.Join(cfg.DataDir, string(config.LocalID))
 
 	err = os.MkdirAll(dir, fs.ModeDir|fs.ModePerm)
 	if err != nil {
@@ -161,53 +238,222 @@ func New(port, segmentSize int, bindAddr, advertiseAddr, serverId string, applyT
 		return nil, err
 	}
 
-	w, err := wal.Open(dir, wal.WithSegmentSize(segmentSize))
+	w, err := wal.Open(dir, wal.WithSegmentSize(cfg.SegmentSize))
 	if err != nil {
 
 		return nil, err
 	}
 
-	snapshotStore, err := raft.NewFileSnapshotStore("data", 3, nil)
+	snapshotStore, err := raft.NewFileSnapshotStore(cfg.DataDir, cfg.SnapshotRetain, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	fsm := NewTredsFsm(storeCommandRegistry, tredsStore)
 	r, err := raft.NewRaft(config, fsm, w, w, snapshotStore, transport)
 	if err != nil {
 		return nil, err
 	}
 
-	bootStrapServers := []raft.Server{{ID: config.LocalID, Address: raft.ServerAddress(addr), Suffrage: raft.Voter}}
+	// A restarted node recovers its configuration from the log and snapshots, only a new node is bootstrapped
+	hasState, err := raft.HasExistingState(w, w, snapshotStore)
+	if err != nil {
+		return nil, err
+	}
+	if !hasState && !cfg.Join && !cfg.Replica {
+		// Every node of a new cluster is bootstrapped with the same configuration, so the addresses are the advertised ones
+		bootStrapServers := []raft.Server{{ID: config.LocalID, Address: raft.ServerAddress(advertise.String()), Suffrage: raft.Voter}}
+
+		for _, server := range cfg.Servers {
+			bootStrapServers = append(bootStrapServers, raft.Server{
+				ID:      raft.ServerID(server.ID),
+				Address: raft.ServerAddress(fmt.Sprintf("%s:%d", server.Host, server.Port)),
+			})
+		}
 
-	for _, server := range servers {
-		bootStrapServers = append(bootStrapServers, raft.Server{
-			ID:      raft.ServerID(server.ID),
-			Address: raft.ServerAddress(fmt.Sprintf("%s:%d", server.Host, server.Port)),
-		})
+		cluster := r.BootstrapCluster(raft.Configuration{Servers: bootStrapServers})
+
+		err = cluster.Error()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	cluster := r.BootstrapCluster(raft.Configuration{Servers: bootStrapServers})
+	clientAddr := cfg.ClientAdvertiseAddr
+	if clientAddr == "" {
+		clientAddr = net.JoinHostPort(advertise.IP.String(), strconv.Itoa(cfg.Port))
+	}
 
-	err = cluster.Error()
-	if err != nil {
-		return nil, err
+	ts := newServer(cfg, storeCommandRegistry, serverCommandRegistry, fsm)
+	ts.clientAddr = clientAddr
+	ts.raft = r
+	ts.snapshotStore = snapshotStore
+	ts.logStore = w
+	ts.id = config.LocalID
+	ts.logStoreCloser = w
+	go ts.publishClientAddress()
+	if ts.replica {
+		go ts.stayNonvoter()
 	}
+	return ts, nil
+}
 
+// newServer returns a server with the settings of cfg that do not depend on Raft
+func newServer(cfg Config, storeCommandRegistry commands.CommandRegistry, serverCommandRegistry ServerCommandRegistry, fsm *TredsFsm) *Server {
 	return &Server{
-		Port:                       port,
+		Port:                       cfg.Port,
+		redirect:                   cfg.Redirect,
+		replica:                    cfg.Replica,
+		readOnly:                   cfg.ReadOnly,
 		tredsCommandRegistry:       storeCommandRegistry,
 		tredsServerCommandRegistry: serverCommandRegistry,
 		fsm:                        fsm,
-		raft:                       r,
-		id:                         config.LocalID,
-		raftApplyTimeout:           applyTimeout,
+		backup:                     BackupConfig{Dir: DefaultBackupDir},
+		raftApplyTimeout:           cfg.ApplyTimeout,
+		done:                       make(chan struct{}),
 		clientTransaction:          make(map[string][]string),
-		connP:                      connPool.NewConnPool(time.Second * 5),
 		channelSubscriptionData:    radix.New(),
 		connectionSubscription:     make(map[string]map[string]struct{}),
 		connectionMap:              make(map[string]gnet.Conn),
-	}, nil
+		connectionProtocol:         make(map[string]int),
+		connectionConsistency:      make(map[string]ReadConsistency),
+		connectionScanner:          make(map[string]*resp.FrameScanner),
+		connectionUser:             make(map[string]*ACLUser),
+		deferredReply:              make(map[string]struct{}),
+	}
+}
+
+// loadServerId returns the id kept in file, or keeps serverId, or a new uuid when it is empty, in the file.
+// A given serverId has to match the one already kept.
+func loadServerId(file, serverId string) (raft.ServerID, error) {
+	data, err := os.ReadFile(file)
+	if err == nil {
+		fmt.Println("File found. Reading UUID from file... If boostrap error is seen, try removing 'data' directory, " +
+			"after backup, which can be restored using RESTORE command")
+		id, parseErr := uuid.Parse(strings.TrimSpace(string(data)))
+		if parseErr != nil {
+			return "", fmt.Errorf("parsing the UUID of %s: %v", file, parseErr)
+		}
+		if serverId != "" && id.String() != serverId {
+			return "", fmt.Errorf("UUID does not match, please fix '%s' file", file)
+		}
+		fmt.Println("UUID read from file:", id)
+		return raft.ServerID(id.String()), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("reading %s: %v", file, err)
+	}
+
+	id := serverId
+	if id == "" {
+		fmt.Println("File not found. Generating a new UUID...")
+		id = uuid.New().String()
+	}
+	if err = os.MkdirAll(filepath.Dir(file), fs.ModeDir|fs.ModePerm); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(file, []byte(id), 0644); err != nil {
+		return "", fmt.Errorf("writing the UUID to %s: %v", file, err)
+	}
+	fmt.Println("UUID written to file:", id)
+	return raft.ServerID(id), nil
+}
+
+// AdvertiseAddress returns the address the other nodes reach the Raft transport at. The host is advertiseAddr,
+// or bindAddr when it is empty, resolved to an IP. A host listening on every interface advertises its first private IP.
+func AdvertiseAddress(bindAddr, advertiseAddr string, raftPort int) (*net.TCPAddr, error) {
+	host := advertiseAddr
+	if host == "" {
+		host = bindAddr
+	}
+	ip := net.ParseIP(host)
+	if host == "" || (ip != nil && ip.IsUnspecified()) {
+		privateIP, err := privateIP()
+		if err != nil {
+			return nil, err
+		}
+		return &net.TCPAddr{IP: privateIP, Port: raftPort}, nil
+	}
+	if ip == nil {
+		// IPv4 addresses are preferred, like the ones localhost resolves to
+		resolved, err := net.ResolveIPAddr("ip4", host)
+		if err != nil {
+			if resolved, err = net.ResolveIPAddr("ip", host); err != nil {
+				return nil, fmt.Errorf("resolving the advertise address %s: %v", host, err)
+			}
+		}
+		ip = resolved.IP
+	}
+	return &net.TCPAddr{IP: ip, Port: raftPort}, nil
+}
+
+// privateIP returns the first private IPv4 address of the interfaces of the host
+func privateIP() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil && ipNet.IP.IsPrivate() {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("no private IP to advertise, please set the advertise address")
+}
+
+// Serve runs the event loop of the client port on addr until Shutdown, commands are processed by a single event loop
+func (ts *Server) Serve(addr string) error {
+	return gnet.Run(
+		ts,
+		"tcp://"+addr,
+		// Single Event loop
+		gnet.WithMulticore(false),
+		gnet.WithReusePort(false),
+		gnet.WithTCPKeepAlive(300*time.Second),
+	)
+}
+
+// ServeTLS serves the client port on addr over TLS with the certificates set by SetClientTLS until Shutdown.
+// TLS is terminated on addr and the connections are relayed to the event loop, which only listens on loopback.
+func (ts *Server) ServeTLS(addr string) error {
+	if ts.clientTLS == nil {
+		return fmt.Errorf("no certificates for the client port")
+	}
+	listener, err := tls.Listen("tcp", addr, ts.clientTLS.ServerConfig())
+	if err != nil {
+		return err
+	}
+	ts.tlsProxy = newTLSProxy(listener)
+	go func() {
+		<-ts.done
+		_ = listener.Close()
+	}()
+	go func() {
+		_ = ts.tlsProxy.serve()
+	}()
+	// The event loop binds a free port itself, the proxy looks it up in OnBoot
+	return ts.Serve("127.0.0.1:0")
+}
+
+// Shutdown stops the event loop, the background goroutines and the Raft node, and closes the log.
+// A standalone node syncs and closes its command log instead.
+func (ts *Server) Shutdown() error {
+	close(ts.done)
+	ts.engineLock.Lock()
+	engine := ts.engine
+	ts.engineLock.Unlock()
+	if engine.Validate() == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = engine.Stop(ctx)
+	}
+	ts.closeForward()
+	if ts.standalone {
+		return ts.closeCommandLog()
+	}
+	if err := ts.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	return ts.logStoreCloser.Close()
 }
 
 func (ts *Server) GetChannelSubscriptionData() *radix.Tree {
@@ -227,12 +473,72 @@ func (ts *Server) GetConnectionFromAddress(ra string) gnet.Conn {
 	return ts.connectionMap[ra]
 }
 
-func (ts *Server) OnBoot(_ gnet.Engine) gnet.Action {
+func (ts *Server) GetConnectionProtocol(ra string) int {
+	if protocol, ok := ts.connectionProtocol[ra]; ok {
+		return protocol
+	}
+	return resp.Protocol2
+}
+
+func (ts *Server) SetConnectionProtocol(ra string, protocol int) {
+	ts.connectionProtocol[ra] = protocol
+}
+
+// SetACL enables authentication, connections then have to AUTH unless a default user is defined
+func (ts *Server) SetACL(acl *ACL) {
+	ts.acl = acl
+}
+
+// SetBackupConfig sets where BACKUP writes its archives and how long they are kept
+func (ts *Server) SetBackupConfig(config BackupConfig) {
+	ts.backup = config
+}
+
+// SetClientTLS tells the server its client port uses TLS, so forwarded commands have to use it as well
+func (ts *Server) SetClientTLS(reloader *TLSReloader) {
+	ts.clientTLS = reloader
+}
+
+func (ts *Server) GetACL() *ACL {
+	return ts.acl
+}
+
+// GetConnectionUser returns the user of a connection, or the default user when it has not authenticated
+func (ts *Server) GetConnectionUser(ra string) *ACLUser {
+	if user, ok := ts.connectionUser[ra]; ok {
+		return user
+	}
+	if ts.acl == nil {
+		return nil
+	}
+	return ts.acl.DefaultUser()
+}
+
+func (ts *Server) SetConnectionUser(ra string, user *ACLUser) {
+	ts.connectionUser[ra] = user
+}
+
+func (ts *Server) OnBoot(engine gnet.Engine) gnet.Action {
 	fmt.Println("Server started on", ts.Port)
+	ts.engineLock.Lock()
+	ts.engine = engine
+	ts.engineLock.Unlock()
+	if ts.tlsProxy != nil {
+		if err := ts.tlsProxy.bind(engine); err != nil {
+			fmt.Println("Error occurred looking up the port of the event loop", err)
+			return gnet.Shutdown
+		}
+	}
 	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
 		for {
-			ts.fsm.tredsStore.CleanUpExpiredKeys()
-			time.Sleep(100 * time.Millisecond)
+			select {
+			case <-ts.done:
+				return
+			case <-ticker.C:
+				ts.fsm.cleanUpExpiredKeys()
+			}
 		}
 	}()
 	return gnet.None
@@ -252,6 +558,10 @@ func (ts *Server) executeServerCommand(command, inp string, c gnet.Conn) gnet.Ac
 		ts.RespondErr(c, err)
 		return gnet.None
 	}
+	if ts.standalone && serverCommandRegistration.NeedsRaft {
+		ts.RespondErr(c, fmt.Errorf("%s needs Raft, it is not available in standalone mode", serverCommandRegistration.Name))
+		return gnet.None
+	}
 	return serverCommandRegistration.Execute(inp, ts, c)
 }
 
@@ -259,6 +569,10 @@ func (ts *Server) GetCommandRegistry() commands.CommandRegistry {
 	return ts.tredsCommandRegistry
 }
 
+func (ts *Server) GetServerCommandRegistry() ServerCommandRegistry {
+	return ts.tredsServerCommandRegistry
+}
+
 func (ts *Server) GetClientTransaction() map[string][]string {
 	return ts.clientTransaction
 }
@@ -267,6 +581,11 @@ func (ts *Server) GetRaft() *raft.Raft {
 	return ts.raft
 }
 
+// isLeader tells whether this node applies the writes, a standalone node always does
+func (ts *Server) isLeader() bool {
+	return ts.standalone || ts.raft.State() == raft.Leader
+}
+
 func (ts *Server) GetRaftApplyTimeout() time.Duration {
 	return ts.raftApplyTimeout
 }
@@ -276,26 +595,89 @@ func (ts *Server) SetChannelSubscriptionData(data *radix.Tree) {
 }
 
 func (ts *Server) OnTraffic(c gnet.Conn) gnet.Action {
+	// Behind the TLS proxy any other connection would bypass TLS and the check of the client certificate
+	if ts.tlsProxy != nil && !ts.tlsProxy.relays(c.RemoteAddr().String()) {
+		return gnet.Close
+	}
+	// Leave partial frames in the inbound buffer, gnet keeps them around until the rest arrives.
+	// The scanner of the connection resumes where it stopped when more of a partial frame is received.
+	scanner, ok := ts.connectionScanner[c.RemoteAddr().String()]
+	if !ok {
+		scanner = &resp.FrameScanner{}
+		ts.connectionScanner[c.RemoteAddr().String()] = scanner
+	}
+	data, _ := c.Peek(-1)
+	consumed := 0
+	action := gnet.None
+	for consumed < len(data) && action == gnet.None && !ts.isReplyDeferred(c) {
+		frameLength, err := scanner.Scan(data[consumed:])
+		if errors.Is(err, resp.ErrIncompleteFrame) {
+			break
+		}
+		if err != nil {
+			// The stream cannot be re-synchronised after a protocol error
+			ts.RespondErr(c, fmt.Errorf("protocol error: %v", err))
+			consumed = len(data)
+			action = gnet.Close
+			break
+		}
+		inp := string(data[consumed : consumed+frameLength])
+		consumed += frameLength
+		// Blank inline lines, like a bare newline from a telnet session, are ignored
+		if strings.TrimSpace(inp) == "" {
+			continue
+		}
+		action = ts.processCommand(inp, c)
+	}
+	// Discard drops the whole buffer when it is given 0
+	if consumed > 0 {
+		_, _ = c.Discard(consumed)
+	}
+	return action
+}
 
-	data, _ := c.Next(-1)
-	inp := string(data)
-	if inp == "" {
-		err := fmt.Errorf("empty command")
-		respErr := fmt.Sprintf("Error Executing command - %v\n", err.Error())
-		_, errConn := c.Write([]byte(resp.EncodeError(respErr)))
+// deferReply runs reply in the background for a command that takes a while and writes what it returns.
+// The commands the connection sends meanwhile stay in its inbound buffer and are processed once the reply is written,
+// so pipelined replies keep the order of their commands while the event loop serves the other connections.
+func (ts *Server) deferReply(c gnet.Conn, reply func() string) {
+	ra := c.RemoteAddr().String()
+	ts.deferredReply[ra] = struct{}{}
+	go func() {
+		res := reply()
+		errConn := c.AsyncWrite([]byte(res), func(c gnet.Conn, err error) error {
+			// Runs on the event loop once the reply is written
+			delete(ts.deferredReply, ra)
+			if err != nil {
+				fmt.Println("Error occurred writing to connection", err)
+				return nil
+			}
+			return c.Wake(nil)
+		})
 		if errConn != nil {
 			fmt.Println("Error occurred writing to connection", errConn)
 		}
-		return gnet.None
-	}
+	}()
+}
 
+func (ts *Server) isReplyDeferred(c gnet.Conn) bool {
+	_, ok := ts.deferredReply[c.RemoteAddr().String()]
+	return ok
+}
+
+func (ts *Server) processCommand(inp string, c gnet.Conn) gnet.Action {
 	// Server Commands
-	command, _, err := parseCommand(inp)
+	command, args, err := parseCommand(inp)
 	if err != nil {
 		ts.RespondErr(c, err)
 		return gnet.None
 	}
 
+	// Permissions are checked before the command is dispatched or queued in a transaction
+	if err = ts.checkACL(ts.GetConnectionUser(c.RemoteAddr().String()), command, args); err != nil {
+		ts.RespondErr(c, err)
+		return gnet.None
+	}
+
 	if ts.isServerCommand(command) {
 		return ts.executeServerCommand(command, inp, c)
 	}
@@ -317,74 +699,183 @@ func (ts *Server) OnTraffic(c gnet.Conn) gnet.Action {
 }
 
 func (ts *Server) executeCommand(inp string, c gnet.Conn) gnet.Action {
+	ra := c.RemoteAddr().String()
+	res := ts.executeStoreCommand(inp, ts.GetConnectionProtocol(ra), ts.GetConnectionConsistency(ra))
+	_, errConn := c.Write([]byte(res))
+	if errConn != nil {
+		fmt.Println("Error occurred writing to connection", errConn)
+	}
+	return gnet.None
+}
+
+// executeStoreCommand runs a store command and returns the RESP encoded reply, failures of the cluster are error replies too
+func (ts *Server) executeStoreCommand(inp string, protocol int, consistency ReadConsistency) string {
+	res, err := ts.storeCommand(inp, protocol, consistency)
+	if err != nil {
+		return resp.EncodeError(err.Error())
+	}
+	return res
+}
+
+// UnavailableError is returned for a command the cluster could not run at the moment, because there is no leader,
+// the leader could not be reached or Raft did not apply the command. The command itself may be fine and can be retried.
+type UnavailableError struct {
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// storeCommand runs a store command and returns the RESP encoded reply.
+// Writes are forwarded to the leader, or validated and applied through Raft on the leader,
+// reads are made with the given consistency and encoded for the given protocol.
+// Errors of the command are error replies, the error is an UnavailableError when the cluster could not run it.
+func (ts *Server) storeCommand(inp string, protocol int, consistency ReadConsistency) (string, error) {
 	command, args, err := parseCommand(inp)
 	if err != nil {
-		ts.RespondErr(c, err)
-		return gnet.None
+		return resp.EncodeError(err.Error()), nil
 	}
 	commandReg, err := ts.tredsCommandRegistry.Retrieve(strings.ToUpper(command))
 	if err != nil {
-		ts.RespondErr(c, err)
-		return gnet.None
+		return resp.EncodeError(err.Error()), nil
+	}
+	if !commandReg.IsWrite {
+		if err = commandReg.Validate(args); err != nil {
+			return resp.EncodeError(err.Error()), nil
+		}
+		return ts.executeConsistentRead(commandReg, args, protocol, consistency)
 	}
-	if commandReg.IsWrite {
 
-		// Only writes need to be forwarded to leader
-		forwarded, rspFwd, forwardErr := ts.ForwardRequest([]byte(inp))
+	// Only writes need to be forwarded to leader
+	forwarded, rspFwd, forwardErr := ts.ForwardRequest([]byte(inp), protocol)
 
-		if forwardErr != nil {
-			fmt.Println("forward error:", forwardErr.Error())
-			ts.RespondErr(c, forwardErr)
-			return gnet.None
-		}
+	if forwardErr != nil {
+		fmt.Println("forward error:", forwardErr.Error())
+		return "", &UnavailableError{Err: forwardErr}
+	}
 
-		// If request is forwarded we just send back the answer from the leader to the client
-		// and stop processing
-		if forwarded {
-			_, errConn := c.Write([]byte(rspFwd))
-			if errConn != nil {
-				fmt.Println("Error occurred writing to connection", errConn)
-			}
-			return gnet.None
-		}
+	// If request is forwarded we just send back the answer from the leader
+	if forwarded {
+		return rspFwd, nil
+	}
 
-		// Validation need to be done before raft Apply so an error is returned before persisting
-		if err = commandReg.Validate(args); err != nil {
-			ts.RespondErr(c, err)
-			return gnet.None
-		}
+	// Validation need to be done before raft Apply so an error is returned before persisting
+	if err = commandReg.Validate(args); err != nil {
+		return resp.EncodeError(err.Error()), nil
+	}
 
-		future := ts.raft.Apply([]byte(inp), ts.raftApplyTimeout)
+	entry, err := ts.logEntry(commandReg, inp, args)
+	if err != nil {
+		return resp.EncodeError(err.Error()), nil
+	}
 
+	future := ts.apply(entry)
+
+	if err := future.Error(); err != nil {
+		return "", &UnavailableError{Err: err}
+	}
+
+	switch rsp := future.Response().(type) {
+	case error:
+		return resp.EncodeError(rsp.Error()), nil
+	case protocolReplies:
+		return rsp.reply(protocol), nil
+	default:
+		return rsp.(string), nil
+	}
+}
+
+// logEntry returns what is appended to the Raft log for a validated write.
+// Commands with a Prepare hook are logged with the arguments it resolved, so the FSM applies the same change on every node.
+func (ts *Server) logEntry(commandReg *commands.CommandRegistration, inp string, args []string) ([]byte, error) {
+	if commandReg.Prepare == nil {
+		return []byte(inp), nil
+	}
+	var prepared []string
+	var err error
+	ts.fsm.prepare(func(s store.Store) {
+		prepared, err = commandReg.Prepare(args, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp.EncodeStringArray(append([]string{commandReg.Name}, prepared...))), nil
+}
+
+// applyBatchSize is the number of commands applyBatch submits to Raft before it waits for them
+const applyBatchSize = 256
+
+// applyBatch applies writes through Raft without validating or preparing them, for commands built by the server itself.
+// The commands are submitted together and waited for at the end, the first error of the batch is returned.
+func (ts *Server) applyBatch(batch [][]string) error {
+	futures := make([]raft.ApplyFuture, 0, len(batch))
+	for _, args := range batch {
+		futures = append(futures, ts.apply([]byte(resp.EncodeStringArray(args))))
+	}
+	for i, future := range futures {
 		if err := future.Error(); err != nil {
-			ts.RespondErr(c, err)
-			return gnet.None
+			return err
 		}
-		rsp := future.Response()
-
-		switch rsp.(type) {
+		switch rsp := future.Response().(type) {
 		case error:
-			err := rsp.(error)
-			ts.RespondErr(c, err)
-			return gnet.None
-		default:
-			_, errConn := c.Write([]byte(rsp.(string)))
-			if errConn != nil {
-				fmt.Println("Error occurred writing to connection", errConn)
+			return fmt.Errorf("%s %s: %v", batch[i][0], batch[i][1], rsp)
+		case string:
+			if strings.HasPrefix(rsp, "-") {
+				return fmt.Errorf("%s %s: %s", batch[i][0], batch[i][1], strings.TrimSpace(rsp[1:]))
 			}
 		}
-	} else {
-		if err = commandReg.Validate(args); err != nil {
-			ts.RespondErr(c, err)
-			return gnet.None
-		}
-		res := commandReg.Execute(args, ts.fsm.tredsStore)
-		_, errConn := c.Write([]byte(res))
-		if errConn != nil {
-			fmt.Println("Error occurred writing to connection", errConn)
-		}
 	}
-	return gnet.None
+	return nil
+}
+
+// runStoreCommand runs a store command for the gateways as user and decodes its RESP3 reply, error replies are returned as errors
+func (ts *Server) runStoreCommand(user *ACLUser, args ...string) (interface{}, error) {
+	if err := ts.checkACL(user, args[0], args[1:]); err != nil {
+		return nil, err
+	}
+	reply, err := ts.storeCommand(resp.EncodeStringArray(args), resp.Protocol3, ReadConsistency{})
+	if err != nil {
+		return nil, err
+	}
+	value, err := resp.ReadReply(bufio.NewReader(strings.NewReader(reply)))
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := value.(resp.ErrorReply); ok {
+		return nil, replyErr
+	}
+	return value, nil
+}
+
+// ExecuteRead runs a read command against the local store, encoding the reply for the protocol of the connection
+func (ts *Server) ExecuteRead(commandReg *commands.CommandRegistration, args []string, c gnet.Conn) string {
+	return ts.executeRead(commandReg, args, ts.GetConnectionProtocol(c.RemoteAddr().String()))
+}
+
+func (ts *Server) executeRead(commandReg *commands.CommandRegistration, args []string, protocol int) string {
+	execute := commandReg.Execute
+	if commandReg.Resp3Execute != nil && protocol == resp.Protocol3 {
+		execute = commandReg.Resp3Execute
+	}
+	var res string
+	ts.fsm.read(func(s store.Store) {
+		res = execute(args, s)
+	})
+	return res
+}
+
+// EncodeMessage encodes a pub/sub message for the connection at the given address,
+// RESP3 connections receive it as a push so it can be told apart from command replies
+func (ts *Server) EncodeMessage(ra string, message []interface{}) string {
+	if ts.GetConnectionProtocol(ra) == resp.Protocol3 {
+		return resp.EncodePush(message)
+	}
+	return resp.EncodeArray(message)
 }
 
 func (ts *Server) RespondErr(c gnet.Conn, err error) {
@@ -395,12 +886,13 @@ func (ts *Server) RespondErr(c gnet.Conn, err error) {
 }
 
 func (ts *Server) OnClose(c gnet.Conn, _ error) gnet.Action {
-	err := ts.connP.Close()
-	if err != nil {
-		fmt.Println("Error occurred closing connection", err.Error())
-	}
 	ts.CleanUpClientTransaction(c)
 	ts.CleanUpChannelSubscriptions(c)
+	delete(ts.connectionProtocol, c.RemoteAddr().String())
+	delete(ts.connectionConsistency, c.RemoteAddr().String())
+	delete(ts.connectionScanner, c.RemoteAddr().String())
+	delete(ts.connectionUser, c.RemoteAddr().String())
+	delete(ts.deferredReply, c.RemoteAddr().String())
 	return gnet.None
 }
 
@@ -421,105 +913,3 @@ func (ts *Server) CleanUpChannelSubscriptions(c gnet.Conn) {
 		delete(ts.connectionSubscription, c.RemoteAddr().String())
 	}
 }
-
-func (ts *Server) convertRaftToTredsAddress(raftAddr string) (string, error) {
-	// Split the Raft address into host and port
-	host, _, err := net.SplitHostPort(raftAddr)
-	if err != nil {
-		return "", fmt.Errorf("invalid Raft address: %s", raftAddr)
-	}
-
-	// Replace Raft port (8300) with Treds port (7997)
-	stringPort := strconv.Itoa(ts.Port)
-	decodedAddr, err := decodeHexAddress(host)
-	if err != nil {
-		return "", fmt.Errorf("invalid Raft address: %s", raftAddr)
-	}
-	return net.JoinHostPort(decodedAddr, stringPort), nil
-}
-
-// readAllRESPData reads all RESP data from the connection as a string
-func readAllRESPData(conn net.Conn) (string, error) {
-	defer conn.Close()
-
-	var result string
-	reader := bufio.NewReader(conn)
-
-	for {
-		// Read data from the connection
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				// End of data
-				break
-			}
-			return "", err
-		}
-
-		// Append the line to the result
-		result += line
-
-		// Check for the end of RESP data
-		// If you expect specific termination (like \r\n or protocol-level signal), add logic here
-		if isEndOfRESP(result) {
-			break
-		}
-	}
-
-	return result, nil
-}
-
-// isEndOfRESP determines if the accumulated data is complete
-// Customize this function based on the RESP structure or data you expect
-func isEndOfRESP(data string) bool {
-	// Example: Customize this condition based on your protocol needs
-	// For now, assume the RESP message ends if the last line ends with \r\n
-	return strings.HasSuffix(data, "\r\n")
-}
-func decodeHexAddress(hexAddr string) (string, error) {
-	if strings.HasPrefix(hexAddr, "?") {
-		hexAddr = hexAddr[1:] // Strip the `?` prefix
-	}
-	bytes, err := hex.DecodeString(hexAddr)
-	if err != nil {
-		return "", fmt.Errorf("invalid hex address: %v", err)
-	}
-	return string(bytes), nil
-}
-
-func (ts *Server) ForwardRequest(data []byte) (bool, string, error) {
-	// create a new channel based pool with an initial capacity of 5 and maximum
-	// capacity of 30. The factory will create 5 initial connections and put it
-	// into the pool.
-
-	addr, leaderId := ts.raft.LeaderWithID()
-
-	if ts.id == leaderId {
-		return false, "", nil
-	}
-
-	tredsAddr, err := ts.convertRaftToTredsAddress(string(addr))
-
-	if err != nil {
-		fmt.Println("Error occurred converting raft to treds address", addr)
-		return false, "", err
-	}
-
-	conn, err := ts.connP.Dial("tcp", tredsAddr)
-	if err != nil {
-		fmt.Println("Error occurred connecting to treds server", tredsAddr)
-		return false, "", nil
-	}
-	defer conn.Close()
-	_, err = conn.Write(data)
-	if err != nil {
-		fmt.Println("Error occurred writing to connection", tredsAddr)
-		return false, "", nil
-	}
-	line, rerr := readAllRESPData(conn)
-	if rerr != nil {
-		fmt.Println("Error occurred reading from connection", tredsAddr)
-		return false, "", nil
-	}
-	return true, line, nil
-}
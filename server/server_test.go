@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"treds/resp"
+)
+
+func TestOnTrafficSplitFrames(t *testing.T) {
+	s, addr := startStandalone(t, "", "")
+	defer s.Shutdown()
+	rc := dialRaw(t, addr)
+
+	// Values hold CRLF so only their declared length ends them, the inline command ends with its newline
+	value := strings.Repeat("a\r\n", 100)
+	var data strings.Builder
+	data.WriteString(resp.EncodeStringArray([]string{"SET", "key", value}))
+	data.WriteString(resp.EncodeStringArray([]string{"RPUSH", "list", "x", "y\r\nz"}))
+	data.WriteString("PING\r\n")
+	data.WriteString(resp.EncodeStringArray([]string{"GET", "key"}))
+	data.WriteString(resp.EncodeStringArray([]string{"LRANGE", "list", "0", "-1"}))
+	pipelined := data.String()
+
+	// The commands are sent in pieces that split their headers, bulk strings and CRLFs
+	for sent, size := 0, 1; sent < len(pipelined); size++ {
+		end := min(sent+size%7+1, len(pipelined))
+		if _, err := rc.conn.Write([]byte(pipelined[sent:end])); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		sent = end
+		time.Sleep(time.Millisecond)
+	}
+	replies := make([]string, 5)
+	for i := range replies {
+		replies[i] = rc.read()
+	}
+	expected := []string{
+		"+OK\r\n",
+		"+OK\r\n",
+		"+PONG\r\n",
+		resp.EncodeBulkString(value),
+		resp.EncodeStringArray([]string{"x", "y\r\nz"}),
+	}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Fatalf("expected %q, got %q", expected, replies)
+	}
+}
+
+func TestOnTrafficLimits(t *testing.T) {
+	s, addr := startStandalone(t, "", "")
+	defer s.Shutdown()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "nesting", input: strings.Repeat("*1\r\n", resp.MaxNestingDepth+1)},
+		{name: "elements", input: "*2000000\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := dialRaw(t, addr)
+			if _, err := rc.conn.Write([]byte(tt.input)); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if reply := rc.read(); !strings.HasPrefix(reply, "-protocol error") {
+				t.Fatalf("expected a protocol error, got %q", reply)
+			}
+			_ = rc.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if _, err := rc.reader.ReadByte(); err != io.EOF {
+				t.Fatalf("expected the connection to be closed, got %v", err)
+			}
+		})
+	}
+}
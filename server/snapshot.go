@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/panjf2000/gnet/v2"
+	"treds/commands"
 	"treds/resp"
 )
 
@@ -11,15 +12,17 @@ const SnapshotCommandName = "SNAPSHOT"
 
 func RegisterSnapshotCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    SnapshotCommandName,
-		Execute: executeSnapshot(),
+		Name:      SnapshotCommandName,
+		Execute:   executeSnapshot(),
+		Category:  commands.CategoryAdmin,
+		NeedsRaft: true,
 	})
 }
 
 func executeSnapshot() ExecutionHook {
 	return func(inp string, ts *Server, c gnet.Conn) gnet.Action {
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -40,16 +43,14 @@ func executeSnapshot() ExecutionHook {
 			return gnet.None
 		}
 
+		// The snapshot is persisted in the background and the reply is written once it is done
 		future := ts.GetRaft().Snapshot()
-		if future.Error() != nil {
-			ts.RespondErr(c, future.Error())
-			return gnet.None
-		}
-		res := "OK"
-		_, errConn := c.Write([]byte(resp.EncodeSimpleString(res)))
-		if errConn != nil {
-			ts.RespondErr(c, errConn)
-		}
+		ts.deferReply(c, func() string {
+			if err := future.Error(); err != nil {
+				return resp.EncodeError(err.Error())
+			}
+			return resp.EncodeSimpleString("OK")
+		})
 		return gnet.None
 	}
 }
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotPipelined(t *testing.T) {
+	cluster, err := StartLocalCluster(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer cluster.Shutdown()
+	leader, err := cluster.WaitForLeader(10 * time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rc := dialRaw(t, cluster.ClientAddrs[leader])
+	if reply := rc.do("SET", "key", "value"); reply != "+OK\r\n" {
+		t.Fatalf("expected OK, got %q", reply)
+	}
+	// The commands after SNAPSHOT wait for its reply, which is written once the snapshot is persisted
+	replies := rc.pipeline(
+		[]string{"SNAPSHOT"}, []string{"PING"}, []string{"GET", "key"},
+		[]string{"SET", "key", "other"}, []string{"SNAPSHOT"}, []string{"GET", "key"},
+	)
+	expected := []string{"+OK\r\n", "+PONG\r\n", "$5\r\nvalue\r\n", "+OK\r\n", "+OK\r\n", "$5\r\nother\r\n"}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Fatalf("expected %q, got %q", expected, replies)
+	}
+	// The connection keeps serving commands sent after the pipeline
+	if reply := rc.do("PING"); reply != "+PONG\r\n" {
+		t.Fatalf("expected PONG, got %q", reply)
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+	"treds/resp"
+)
+
+// newStandalone finishes a server that runs without Raft, the command log is replayed into its store when it is set
+func newStandalone(cfg Config, ts *Server) (*Server, error) {
+	// The id is not kept, a standalone node writes nothing but its command log
+	id := cfg.ServerId
+	if id == "" {
+		id = uuid.New().String()
+	} else if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("invalid server id %s, it must be a uuid", id)
+	}
+	ts.id = raft.ServerID(id)
+	ts.standalone = true
+	ts.clientAddr = cfg.ClientAdvertiseAddr
+	if ts.clientAddr == "" {
+		ts.clientAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.Port))
+	}
+	if cfg.CommandLog == "" {
+		return ts, nil
+	}
+	cl, replayed, err := openCommandLog(cfg.CommandLog, cfg.CommandLogSync, func(entry []byte) {
+		ts.fsm.Apply(&raft.Log{Type: raft.LogCommand, Data: entry})
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Replayed", replayed, "commands from", cfg.CommandLog)
+	ts.commandLog = cl
+	return ts, nil
+}
+
+// apply applies a validated write through Raft, a standalone node appends it to its command log
+// and applies it to the store at once
+func (ts *Server) apply(entry []byte) raft.ApplyFuture {
+	if !ts.standalone {
+		return ts.raft.Apply(entry, ts.raftApplyTimeout)
+	}
+	ts.applyLock.Lock()
+	defer ts.applyLock.Unlock()
+	if ts.commandLog != nil {
+		if err := ts.commandLog.append(entry); err != nil {
+			return appliedFuture{err: err}
+		}
+	}
+	return appliedFuture{response: ts.fsm.Apply(&raft.Log{Type: raft.LogCommand, Data: entry})}
+}
+
+// closeCommandLog syncs and closes the command log of a standalone node
+func (ts *Server) closeCommandLog() error {
+	if ts.commandLog == nil {
+		return nil
+	}
+	ts.applyLock.Lock()
+	defer ts.applyLock.Unlock()
+	return ts.commandLog.close()
+}
+
+// appliedFuture is the future of a write a standalone node has already applied
+type appliedFuture struct {
+	response interface{}
+	err      error
+}
+
+func (f appliedFuture) Error() error {
+	return f.err
+}
+
+func (f appliedFuture) Index() uint64 {
+	return 0
+}
+
+func (f appliedFuture) Response() interface{} {
+	return f.response
+}
+
+// commandLog is the append-only log of the writes of a standalone node. Every write is written to the file
+// before it is applied and the file is fsynced every sync interval, so a crash of the host loses the writes
+// of the last interval at most.
+type commandLog struct {
+	lock         sync.Mutex
+	file         *os.File
+	syncInterval time.Duration
+	// Written since the last fsync
+	dirty bool
+	done  chan struct{}
+}
+
+// openCommandLog opens or creates the log at path and applies the commands it holds, in order, with apply.
+// An incomplete command at the end of the log, from a write interrupted by a crash, is dropped.
+// Any other command that cannot be read is an error, the log is left as it is rather than losing the commands after it.
+func openCommandLog(path string, syncInterval time.Duration, apply func(entry []byte)) (*commandLog, int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	replayed := 0
+	for offset < info.Size() {
+		entry, errFrame := resp.ReadFrame(reader)
+		// The log ends within the command
+		if errors.Is(errFrame, io.EOF) || errors.Is(errFrame, io.ErrUnexpectedEOF) {
+			break
+		}
+		if errFrame != nil {
+			_ = file.Close()
+			return nil, 0, fmt.Errorf("command log %s is corrupt at offset %d: %v", path, offset, errFrame)
+		}
+		apply(entry)
+		offset += int64(len(entry))
+		replayed++
+	}
+	if offset < info.Size() {
+		fmt.Println("Dropping", info.Size()-offset, "bytes of an incomplete command at the end of", path)
+		if err = file.Truncate(offset); err != nil {
+			_ = file.Close()
+			return nil, 0, err
+		}
+	}
+	if _, err = file.Seek(offset, 0); err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+
+	cl := &commandLog{file: file, syncInterval: syncInterval, done: make(chan struct{})}
+	if syncInterval > 0 {
+		go cl.run()
+	}
+	return cl, replayed, nil
+}
+
+// append writes a command to the log, as a RESP array whatever the form it was sent in
+func (cl *commandLog) append(entry []byte) error {
+	command, args, err := parseCommand(string(entry))
+	if err != nil {
+		return err
+	}
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+	if _, err = cl.file.WriteString(resp.EncodeStringArray(append([]string{command}, args...))); err != nil {
+		return err
+	}
+	if cl.syncInterval <= 0 {
+		return cl.file.Sync()
+	}
+	cl.dirty = true
+	return nil
+}
+
+// run fsyncs the log every sync interval until it is closed
+func (cl *commandLog) run() {
+	ticker := time.NewTicker(cl.syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cl.done:
+			return
+		case <-ticker.C:
+		}
+		if err := cl.sync(); err != nil {
+			fmt.Println("Error occurred syncing the command log", err)
+		}
+	}
+}
+
+func (cl *commandLog) sync() error {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+	if !cl.dirty {
+		return nil
+	}
+	cl.dirty = false
+	return cl.file.Sync()
+}
+
+func (cl *commandLog) close() error {
+	close(cl.done)
+	if err := cl.sync(); err != nil {
+		_ = cl.file.Close()
+		return err
+	}
+	return cl.file.Close()
+}
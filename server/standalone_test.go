@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"treds/resp"
+)
+
+// startStandalone serves a standalone node with the command log and returns its client address,
+// setup runs before the node starts serving
+func startStandalone(t *testing.T, commandLog, dataDir string, setup ...func(s *Server)) (*Server, string) {
+	t.Helper()
+	ports, err := freePorts(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, err := New(Config{
+		Port:           ports[0],
+		DataDir:        dataDir,
+		Standalone:     true,
+		CommandLog:     commandLog,
+		CommandLogSync: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, setupServer := range setup {
+		setupServer(s)
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0]))
+	go func() {
+		_ = s.Serve(addr)
+	}()
+	if err = waitForListener(addr, 10*time.Second); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return s, addr
+}
+
+func TestStandalone(t *testing.T) {
+	dir := t.TempDir()
+	commandLog := filepath.Join(dir, "commands.log")
+	dataDir := filepath.Join(dir, "data")
+
+	s, addr := startStandalone(t, commandLog, dataDir)
+	do(t, addr, "SET", "key", "value")
+	do(t, addr, "RPUSH", "list", "a", "b c")
+	do(t, addr, "SET", "deleted", "value")
+	do(t, addr, "DEL", "deleted")
+	if got := do(t, addr, "READ", "LINEARIZABLE", "GET", "key"); got != "value" {
+		t.Fatalf("expected value, got %v", got)
+	}
+	if _, err := doErr(addr, "CLUSTER", "MEMBERS"); err == nil || !strings.Contains(err.Error(), "standalone") {
+		t.Fatalf("expected CLUSTER to be unavailable, got %v", err)
+	}
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := os.Stat(dataDir); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected no data directory, got %v", err)
+	}
+
+	// A write cut short by a crash is dropped when the log is replayed
+	info, err := os.Stat(commandLog)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	file, err := os.OpenFile(commandLog, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, _ = file.WriteString("*3\r\n$3\r\nSET\r\n$3\r\nkey")
+	_ = file.Close()
+
+	s, addr = startStandalone(t, commandLog, dataDir)
+	defer s.Shutdown()
+	if got := do(t, addr, "GET", "key"); got != "value" {
+		t.Fatalf("expected value, got %v", got)
+	}
+	if got := do(t, addr, "LRANGE", "list", "0", "-1"); !reflect.DeepEqual(got, []interface{}{"a", "b c"}) {
+		t.Fatalf("expected the list to be replayed, got %v", got)
+	}
+	if got, errGet := doErr(addr, "GET", "deleted"); got != nil || errGet != nil {
+		t.Fatalf("expected the key to stay deleted, got %v, %v", got, errGet)
+	}
+	if replayed, _ := os.Stat(commandLog); replayed.Size() != info.Size() {
+		t.Fatalf("expected the incomplete command to be truncated, got %d bytes instead of %d", replayed.Size(), info.Size())
+	}
+}
+
+func TestStandaloneCorruptCommandLog(t *testing.T) {
+	commandLog := filepath.Join(t.TempDir(), "commands.log")
+	// The second command is not RESP, the third one would be lost if the log was truncated there
+	data := resp.EncodeStringArray([]string{"SET", "key", "value"}) + "?corrupt\r\n" + resp.EncodeStringArray([]string{"SET", "other", "value"})
+	if err := os.WriteFile(commandLog, []byte(data), 0644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := New(Config{Standalone: true, CommandLog: commandLog}); err == nil || !strings.Contains(err.Error(), "corrupt at offset 33") {
+		t.Fatalf("expected the corrupt command log to be rejected, got %v", err)
+	}
+	if kept, _ := os.ReadFile(commandLog); string(kept) != data {
+		t.Fatalf("expected the command log to be left as it is, got %q", kept)
+	}
+}
@@ -5,15 +5,18 @@ import (
 	"strings"
 
 	"github.com/panjf2000/gnet/v2"
-	"treds/resp"
+	"treds/commands"
 )
 
 const SubscribeCommandName = "SUBSCRIBE"
 
 func RegisterSubscribeCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    SubscribeCommandName,
-		Execute: executeSubscribeCommandName(),
+		Name:     SubscribeCommandName,
+		Args:     "channel [channel ...]",
+		Execute:  executeSubscribeCommandName(),
+		Category: commands.CategoryPubSub,
+		Channels: commands.KeysFrom(0, 1),
 	})
 }
 
@@ -31,7 +34,7 @@ func executeSubscribeCommandName() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -79,7 +82,7 @@ func executeSubscribeCommandName() ExecutionHook {
 			ts.GetConnectionSubscription()[c.RemoteAddr().String()][channel] = struct{}{}
 			response = append(response, indx+1)
 		}
-		_, errConn := c.Write([]byte(resp.EncodeArray(response)))
+		_, errConn := c.Write([]byte(ts.EncodeMessage(c.RemoteAddr().String(), response)))
 		if errConn != nil {
 			ts.RespondErr(c, errConn)
 		}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/panjf2000/gnet/v2"
+)
+
+// TLSFiles are the PEM files a TLS config is loaded from, CAFile is optional
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// TLSReloader holds a certificate and CA pool that can be reloaded from their files while connections are served.
+// Connections opened after Reload use the new files, open connections keep the ones they were established with.
+type TLSReloader struct {
+	files TLSFiles
+	cert  atomic.Pointer[tls.Certificate]
+	ca    atomic.Pointer[x509.CertPool]
+}
+
+func NewTLSReloader(files TLSFiles) (*TLSReloader, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, fmt.Errorf("TLS needs both a certificate and a key file")
+	}
+	r := &TLSReloader{files: files}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again, the previous certificates stay in use when they cannot be loaded
+func (r *TLSReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if r.files.CAFile != "" {
+		data, errRead := os.ReadFile(r.files.CAFile)
+		if errRead != nil {
+			return errRead
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", r.files.CAFile)
+		}
+	}
+	r.cert.Store(&cert)
+	r.ca.Store(pool)
+	return nil
+}
+
+// HasCA reports whether peers are verified against a CA file
+func (r *TLSReloader) HasCA() bool {
+	return r.files.CAFile != ""
+}
+
+// ServerConfig returns the config of a listener, clients must present a certificate signed by the CA when there is one.
+// The certificate and CA are looked up per handshake rather than copied, so the config can be cloned by HTTP and gRPC.
+func (r *TLSReloader) ServerConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.cert.Load(), nil
+		},
+	}
+	if r.HasCA() {
+		// The chain is verified in VerifyConnection against the CA pool loaded last
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return r.verifyPeer(state.PeerCertificates, "")
+		}
+	}
+	return config
+}
+
+// ClientConfig returns the config used to dial the node at host, it presents the certificate and verifies that the peer
+// holds a certificate for host, an IP address or a DNS name, signed by the CA or by the system roots when there is no CA file.
+func (r *TLSReloader) ClientConfig(host string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: host,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.cert.Load(), nil
+		},
+		// The chain and the host are verified in VerifyConnection against the CA pool loaded last
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return r.verifyPeer(state.PeerCertificates, host)
+		},
+	}
+}
+
+// verifyPeer verifies the chain of the peer, and that it is issued to host unless host is empty
+func (r *TLSReloader) verifyPeer(certs []*x509.Certificate, host string) error {
+	if len(certs) == 0 {
+		return errors.New("peer did not present a certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName: host,
+		// Without a CA file the peer is verified against the system roots
+		Roots:         r.ca.Load(),
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// TLSStreamLayer is a raft.StreamLayer that runs the Raft transport over mutual TLS
+type TLSStreamLayer struct {
+	listener  net.Listener
+	advertise net.Addr
+	reloader  *TLSReloader
+}
+
+// NewTLSStreamLayer listens on bindAddr, peers have to present a certificate signed by the CA of reloader
+func NewTLSStreamLayer(bindAddr string, advertise net.Addr, reloader *TLSReloader) (*TLSStreamLayer, error) {
+	if !reloader.HasCA() {
+		return nil, fmt.Errorf("mutual TLS for Raft needs a CA file")
+	}
+	listener, err := net.Listen("tcp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &TLSStreamLayer{
+		listener:  tls.NewListener(listener, reloader.ServerConfig()),
+		advertise: advertise,
+		reloader:  reloader,
+	}, nil
+}
+
+func (t *TLSStreamLayer) Accept() (net.Conn, error) {
+	return t.listener.Accept()
+}
+
+func (t *TLSStreamLayer) Close() error {
+	return t.listener.Close()
+}
+
+func (t *TLSStreamLayer) Addr() net.Addr {
+	if t.advertise != nil {
+		return t.advertise
+	}
+	return t.listener.Addr()
+}
+
+func (t *TLSStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(string(address))
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), t.reloader.ClientConfig(host))
+}
+
+// tlsProxy terminates the TLS connections of the client port and relays their plaintext to the event loop,
+// gnet has no TLS support. The event loop binds a loopback port itself and only serves the connections of the proxy.
+type tlsProxy struct {
+	listener net.Listener
+	// Address of the event loop, known once ready is closed
+	target string
+	ready  chan struct{}
+	// Local addresses of the connections to the event loop, the event loop sees them as their remote addresses
+	upstreams     map[string]struct{}
+	upstreamsLock sync.Mutex
+}
+
+func newTLSProxy(listener net.Listener) *tlsProxy {
+	return &tlsProxy{listener: listener, ready: make(chan struct{}), upstreams: make(map[string]struct{})}
+}
+
+// bind looks up the port the event loop listens on, connections accepted before are relayed from then on
+func (p *tlsProxy) bind(engine gnet.Engine) error {
+	fd, err := engine.Dup()
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "event loop")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return err
+	}
+	// Closing the duplicate leaves the listener of the event loop open
+	defer listener.Close()
+	p.target = listener.Addr().String()
+	close(p.ready)
+	return nil
+}
+
+func (p *tlsProxy) serve() error {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return err
+		}
+		go p.relay(conn)
+	}
+}
+
+// relays reports whether the connection of the event loop from addr was opened by the proxy
+func (p *tlsProxy) relays(addr string) bool {
+	p.upstreamsLock.Lock()
+	defer p.upstreamsLock.Unlock()
+	_, ok := p.upstreams[addr]
+	return ok
+}
+
+func (p *tlsProxy) relay(conn net.Conn) {
+	defer conn.Close()
+	<-p.ready
+	upstream, err := net.Dial("tcp", p.target)
+	if err != nil {
+		fmt.Println("Error occurred connecting to the event loop", err)
+		return
+	}
+	// The connection is known before anything is relayed, and forgotten before its address can be reused
+	local := upstream.LocalAddr().String()
+	p.upstreamsLock.Lock()
+	p.upstreams[local] = struct{}{}
+	p.upstreamsLock.Unlock()
+	defer func() {
+		p.upstreamsLock.Lock()
+		delete(p.upstreams, local)
+		p.upstreamsLock.Unlock()
+		_ = upstream.Close()
+	}()
+	// Whichever side ends first closes both connections through the defers
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"treds/resp"
+)
+
+// testCA signs the certificates of a test, its files are written to dir
+type testCA struct {
+	t    *testing.T
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	dir := t.TempDir()
+	ca := &testCA{t: t, dir: dir}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	ca.cert, ca.key = ca.sign(template, nil, nil)
+	ca.file = ca.writePEM(name+"-ca.pem", "CERTIFICATE", ca.cert.Raw)
+	return ca
+}
+
+// sign creates a certificate from template, self-signed when parent is nil
+func (ca *testCA) sign(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatalf("expected no error, got %v", err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		ca.t.Fatalf("expected no error, got %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		ca.t.Fatalf("expected no error, got %v", err)
+	}
+	return cert, key
+}
+
+func (ca *testCA) writePEM(name, blockType string, data []byte) string {
+	ca.t.Helper()
+	path := filepath.Join(ca.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600); err != nil {
+		ca.t.Fatalf("expected no error, got %v", err)
+	}
+	return path
+}
+
+// issue writes a certificate for 127.0.0.1 signed by the CA and returns its files, verified against the CA
+func (ca *testCA) issue(name string) TLSFiles {
+	ca.t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	cert, key := ca.sign(template, ca.cert, ca.key)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		ca.t.Fatalf("expected no error, got %v", err)
+	}
+	return TLSFiles{
+		CertFile: ca.writePEM(name+".pem", "CERTIFICATE", cert.Raw),
+		KeyFile:  ca.writePEM(name+"-key.pem", "EC PRIVATE KEY", keyDER),
+		CAFile:   ca.file,
+	}
+}
+
+func newTestReloader(t *testing.T, files TLSFiles) *TLSReloader {
+	t.Helper()
+	reloader, err := NewTLSReloader(files)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return reloader
+}
+
+// serveEcho accepts TLS connections with config and echoes a line back on each of them
+func serveEcho(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, errAccept := listener.Accept()
+			if errAccept != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 64)
+				n, errRead := conn.Read(buf)
+				if errRead != nil {
+					return
+				}
+				_, _ = conn.Write(buf[:n])
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// echo sends a line over a TLS connection with config and returns the error of the handshake or of the exchange.
+// With TLS 1.3 the server verifies the client certificate after the client finished its handshake,
+// so a rejected certificate only shows up when reading.
+func echo(addr string, config *tls.Config) error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err = conn.Write([]byte("ping\n")); err != nil {
+		return err
+	}
+	buf := make([]byte, 64)
+	_, err = conn.Read(buf)
+	return err
+}
+
+func TestTLSHandshake(t *testing.T) {
+	ca := newTestCA(t, "treds")
+	other := newTestCA(t, "other")
+	server := newTestReloader(t, ca.issue("server"))
+	addr := serveEcho(t, server.ServerConfig())
+
+	tests := []struct {
+		name   string
+		config *tls.Config
+		valid  bool
+	}{
+		{name: "client signed by the CA", config: newTestReloader(t, ca.issue("client")).ClientConfig("127.0.0.1"), valid: true},
+		{name: "client signed by another CA", config: newTestReloader(t, withCA(other.issue("client"), ca.file)).ClientConfig("127.0.0.1")},
+		{name: "client without a certificate", config: &tls.Config{RootCAs: certPool(t, ca.file), ServerName: "127.0.0.1"}},
+		{name: "server not signed by the trusted CA", config: newTestReloader(t, other.issue("client")).ClientConfig("127.0.0.1")},
+		{name: "server issued to another host", config: newTestReloader(t, ca.issue("client")).ClientConfig("localhost")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := echo(addr, tt.config)
+			if tt.valid && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("expected the handshake to be rejected")
+			}
+		})
+	}
+}
+
+func certPool(t *testing.T, file string) *x509.CertPool {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(data)
+	return pool
+}
+
+// withCA returns files that verify peers against caFile instead
+func withCA(files TLSFiles, caFile string) TLSFiles {
+	files.CAFile = caFile
+	return files
+}
+
+func TestTLSStreamLayer(t *testing.T) {
+	ca := newTestCA(t, "treds")
+	layer, err := NewTLSStreamLayer("127.0.0.1:0", nil, newTestReloader(t, ca.issue("node-1")))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer layer.Close()
+	go func() {
+		for {
+			conn, errAccept := layer.Accept()
+			if errAccept != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte("ok"))
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(layer.Addr().String())
+	dialer := newTestReloader(t, ca.issue("node-2"))
+
+	conn, err := (&TLSStreamLayer{reloader: dialer}).Dial(raft.ServerAddress("127.0.0.1:"+port), 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_ = conn.Close()
+	// The certificate of the node is issued to 127.0.0.1, not to the host it is dialled by
+	if conn, err = (&TLSStreamLayer{reloader: dialer}).Dial(raft.ServerAddress("localhost:"+port), 5*time.Second); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected the host of the certificate to be verified, got %v", err)
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	ca := newTestCA(t, "treds")
+	rotated := newTestCA(t, "rotated")
+	// The server trusts the CA through its own copy of the file, which is replaced when the CA is rotated
+	trusted := filepath.Join(t.TempDir(), "trusted.pem")
+	copyFile(t, ca.file, trusted)
+	server := newTestReloader(t, withCA(ca.issue("server"), trusted))
+	addr := serveEcho(t, server.ServerConfig())
+	client := newTestReloader(t, withCA(rotated.issue("client"), ca.file)).ClientConfig("127.0.0.1")
+
+	if err := echo(addr, client); err == nil {
+		t.Fatalf("expected a client of the rotated CA to be rejected before the reload")
+	}
+	copyFile(t, rotated.file, trusted)
+	if err := server.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := echo(addr, client); err != nil {
+		t.Fatalf("expected no error after the reload, got %v", err)
+	}
+
+	// A file that cannot be loaded keeps the previous certificates
+	if err := os.WriteFile(trusted, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := server.Reload(); err == nil {
+		t.Fatalf("expected an invalid CA file to fail the reload")
+	}
+	if err := echo(addr, client); err != nil {
+		t.Fatalf("expected no error with the previous CA, got %v", err)
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	data, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = os.WriteFile(to, data, 0600); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// startStandaloneTLS serves a standalone node on a TLS client port and returns its address
+func startStandaloneTLS(t *testing.T, reloader *TLSReloader) (*Server, string) {
+	t.Helper()
+	ports, err := freePorts(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, err := New(Config{Port: ports[0], Standalone: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s.SetClientTLS(reloader)
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0]))
+	go func() {
+		_ = s.ServeTLS(addr)
+	}()
+	if err = waitForListener(addr, 10*time.Second); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return s, addr
+}
+
+func TestTLSProxy(t *testing.T) {
+	ca := newTestCA(t, "treds")
+	s, addr := startStandaloneTLS(t, newTestReloader(t, ca.issue("server")))
+	defer s.Shutdown()
+	config := newTestReloader(t, ca.issue("client")).ClientConfig("127.0.0.1")
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	ping := []byte(resp.EncodeStringArray([]string{"PING"}))
+	if _, err = conn.Write(ping); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	reply := make([]byte, 7)
+	if _, err = io.ReadFull(conn, reply); err != nil || string(reply) != "+PONG\r\n" {
+		t.Fatalf("expected PONG, got %q %v", reply, err)
+	}
+
+	// The event loop closes the connections that do not come from the proxy
+	<-s.tlsProxy.ready
+	plain, err := net.DialTimeout("tcp", s.tlsProxy.target, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer plain.Close()
+	_ = plain.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err = plain.Write(ping); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if read, errRead := plain.Read(reply); errRead != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %q %v", reply[:read], errRead)
+	}
+}
+
+func TestTLSForward(t *testing.T) {
+	ca := newTestCA(t, "treds")
+	leaderTLS := newTestReloader(t, ca.issue("leader"))
+	leader, addr := startStandaloneTLS(t, leaderTLS)
+	defer leader.Shutdown()
+
+	follower, err := New(Config{Standalone: true, DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	ping := []byte(resp.EncodeStringArray([]string{"PING"}))
+
+	// Without TLS the leader does not understand the forwarded command
+	if reply, errForward := follower.forwardOnce(addr, resp.Protocol2, ping); errForward == nil && strings.HasPrefix(string(reply), "+PONG") {
+		t.Fatalf("expected a plaintext forward to fail, got %q", reply)
+	}
+
+	follower.SetClientTLS(newTestReloader(t, ca.issue("follower")))
+	reply, err := follower.forwardOnce(addr, resp.Protocol2, ping)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(reply) != "+PONG\r\n" {
+		t.Fatalf("expected PONG, got %q", reply)
+	}
+
+	// A follower whose certificate the leader does not trust is rejected
+	other := newTestCA(t, "other")
+	follower.SetClientTLS(newTestReloader(t, withCA(other.issue("follower"), ca.file)))
+	if reply, err = follower.forwardOnce(addr, resp.Protocol2, ping); err == nil {
+		t.Fatalf("expected the forward to be rejected, got %q", reply)
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/panjf2000/gnet/v2"
 	"treds/commands"
+	"treds/resp"
 	"treds/store"
 )
 
@@ -17,10 +20,22 @@ const (
 	NilStore = "error Nil Store"
 )
 
+// metadataCommandName sets a key of the metadata of the cluster. It is logged by the server itself
+// and applied by the FSM, it is not in the command registry so clients cannot run it.
+const metadataCommandName = "METADATASET"
+
 type TredsFsm struct {
 	cmdRegistry commands.CommandRegistry
+	// Held to apply commands and swap in restored stores, and read held to use the store outside of the FSM goroutine,
+	// by the event loop and the gateways
+	storeLock   sync.RWMutex
 	tredsStore  store.Store
 	conn        gnet.Conn
+	compression store.SnapshotCompression
+
+	// Metadata of the cluster, written in the snapshots with the stores and read outside of the FSM goroutine
+	metadataLock sync.RWMutex
+	metadata     map[string]string
 }
 
 func (t *TredsFsm) Apply(log *raft.Log) interface{} {
@@ -29,26 +44,63 @@ func (t *TredsFsm) Apply(log *raft.Log) interface{} {
 	if err != nil {
 		return err
 	}
+	if strings.ToUpper(command) == metadataCommandName && len(args) == 2 {
+		t.metadataLock.Lock()
+		t.metadata[args[0]] = args[1]
+		t.metadataLock.Unlock()
+		return resp.EncodeSimpleString("OK")
+	}
 	commandReg, err := t.cmdRegistry.Retrieve(strings.ToUpper(command))
 	if err != nil {
 		return err
 	}
+	t.storeLock.Lock()
+	defer t.storeLock.Unlock()
 	currentStore := t.tredsStore
-	if currentStore != nil {
-		return commandReg.Execute(args, currentStore)
+	if currentStore == nil {
+		return NilStore
+	}
+	if commandReg.ProtocolExecute != nil {
+		resp2, resp3 := commandReg.ProtocolExecute(args, currentStore)
+		return protocolReplies{resp2: resp2, resp3: resp3}
 	}
-	return NilStore
+	return commandReg.Execute(args, currentStore)
+}
+
+// protocolReplies is the response of an applied command whose reply has native RESP3 types,
+// the node that applied it for a client picks the encoding of the protocol of the client
+type protocolReplies struct {
+	resp2 string
+	resp3 string
+}
+
+func (r protocolReplies) reply(protocol int) string {
+	if protocol == resp.Protocol3 {
+		return r.resp3
+	}
+	return r.resp2
 }
 
 type snapshot struct {
-	storageSnapshot []byte
+	view        *store.PointInTimeSnapshot
+	compression store.SnapshotCompression
 }
 
+// Persist streams the snapshot to the sink, Raft calls it outside of the FSM goroutine so commands keep being applied.
+// The chunks are encoded by a goroutine while the previous ones are written to the sink.
 func (s *snapshot) Persist(sink raft.SnapshotSink) error {
-	if _, err := sink.Write(s.storageSnapshot); err != nil {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.view.Persist(writer, s.compression))
+	}()
+	_, err := io.Copy(sink, reader)
+	// Unblocks the encoding goroutine when the sink failed
+	reader.CloseWithError(err)
+	if err != nil {
+		_ = sink.Cancel()
 		return err
 	}
-	if err := sink.Close(); err != nil {
+	if err = sink.Close(); err != nil {
 		return fmt.Errorf("failed to close snapshot sink: %v", err)
 	}
 	return nil
@@ -56,37 +108,80 @@ func (s *snapshot) Persist(sink raft.SnapshotSink) error {
 
 func (s *snapshot) Release() {}
 
+// Snapshot only captures a view of the store, it is written out by Persist.
+// Commands wait for the stores that are not radix trees to be copied, see store.PointInTimeSnapshot.
 func (t *TredsFsm) Snapshot() (raft.FSMSnapshot, error) {
 	defer func(start time.Time) {
 		log.Println("snapshot created", "duration", time.Since(start).String())
 	}(time.Now())
 	fmt.Println("generating snapshot")
 
-	storageSnapshot, err := t.tredsStore.Snapshot()
+	view, err := t.view()
 	if err != nil {
 		return nil, err
 	}
-	return &snapshot{
-		storageSnapshot: storageSnapshot,
-	}, nil
+	t.metadataLock.RLock()
+	view.Metadata = maps.Clone(t.metadata)
+	t.metadataLock.RUnlock()
+	return &snapshot{view: view, compression: t.compression}, nil
 }
 
 func (t *TredsFsm) Restore(old io.ReadCloser) error {
 	fmt.Println("restoring snapshot")
 	defer old.Close()
-	data, err := io.ReadAll(old)
-	if err != nil {
-		return err
-	}
 	ts := store.NewTredsStore()
-	err = ts.Restore(data)
-	t.tredsStore = ts
+	// The current store is kept when the snapshot cannot be read
+	metadata, err := ts.RestoreMetadata(old)
 	if err != nil {
 		return err
 	}
+	t.storeLock.Lock()
+	t.tredsStore = ts
+	t.storeLock.Unlock()
+	t.metadataLock.Lock()
+	t.metadata = metadata
+	t.metadataLock.Unlock()
 	return nil
 }
 
-func NewTredsFsm(registry commands.CommandRegistry, store store.Store) *TredsFsm {
-	return &TredsFsm{cmdRegistry: registry, tredsStore: store}
+// view returns a point in time snapshot of the store, no command is applied while it is taken.
+// Taking it resets which containers the store has to copy before changing them, so it holds the lock like a write.
+func (t *TredsFsm) view() (*store.PointInTimeSnapshot, error) {
+	t.storeLock.Lock()
+	defer t.storeLock.Unlock()
+	return t.tredsStore.Snapshot()
+}
+
+// read runs read with the store, no command is applied meanwhile
+func (t *TredsFsm) read(read func(s store.Store)) {
+	t.storeLock.RLock()
+	defer t.storeLock.RUnlock()
+	read(t.tredsStore)
+}
+
+// prepare runs prepare with the store, like a command being applied, as Prepare hooks draw from the random number
+// generators of the store
+func (t *TredsFsm) prepare(prepare func(s store.Store)) {
+	t.storeLock.Lock()
+	defer t.storeLock.Unlock()
+	prepare(t.tredsStore)
+}
+
+// cleanUpExpiredKeys deletes the expired keys of the store, no command is applied meanwhile
+func (t *TredsFsm) cleanUpExpiredKeys() {
+	t.storeLock.Lock()
+	defer t.storeLock.Unlock()
+	t.tredsStore.CleanUpExpiredKeys()
+}
+
+// Metadata returns the value of a key of the metadata of the cluster
+func (t *TredsFsm) Metadata(key string) (string, bool) {
+	t.metadataLock.RLock()
+	defer t.metadataLock.RUnlock()
+	value, ok := t.metadata[key]
+	return value, ok
+}
+
+func NewTredsFsm(registry commands.CommandRegistry, store store.Store, compression store.SnapshotCompression) *TredsFsm {
+	return &TredsFsm{cmdRegistry: registry, tredsStore: store, compression: compression, metadata: make(map[string]string)}
 }
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"treds/commands"
+	"treds/resp"
+	"treds/store"
+)
+
+func TestFsmConcurrentReads(t *testing.T) {
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	tredsStore := store.NewTredsStore()
+	_ = tredsStore.Set("key", "value")
+	_ = tredsStore.RPush([]string{"list", "a"})
+	_ = tredsStore.Expire("key", time.Now().Add(-time.Second))
+	_ = tredsStore.Expire("list", time.Now().Add(-time.Second))
+	fsm := NewTredsFsm(registry, tredsStore, store.CompressionNone)
+
+	// Reads of expired keys run together under the read lock, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fsm.read(func(s store.Store) {
+					if value, _ := s.Get("key"); value != store.NilResp {
+						t.Errorf("expected nil, got %q", value)
+					}
+					_, _ = s.LRange("list", 0, -1)
+				})
+				if _, err := fsm.view(); err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFsmConcurrentPrepare(t *testing.T) {
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	tredsStore := store.NewTredsStore()
+	_ = tredsStore.VCreate([]string{"points"})
+	fsm := NewTredsFsm(registry, tredsStore, store.CompressionNone)
+	commandReg, err := registry.Retrieve(commands.VInsert)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// VINSERT draws the id and level of the node from the graph while reads run, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fsm.prepare(func(s store.Store) {
+					if _, errPrepare := commandReg.Prepare([]string{"points", "1", "2"}, s); errPrepare != nil {
+						t.Errorf("expected no error, got %v", errPrepare)
+					}
+				})
+				fsm.read(func(s store.Store) {
+					_, _ = s.VSearch([]string{"points", "1", "2", "1"})
+				})
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// countingStore counts the vector searches run on the store
+type countingStore struct {
+	store.Store
+	searches int
+}
+
+func (s *countingStore) VSearch(args []string) ([]store.VectorSearchResult, error) {
+	s.searches++
+	return s.Store.VSearch(args)
+}
+
+func TestFsmApplyVSearch(t *testing.T) {
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	tredsStore := store.NewTredsStore()
+	if err := tredsStore.VCreate([]string{"vec", "6", "0.5", "100"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	counting := &countingStore{Store: tredsStore}
+	fsm := NewTredsFsm(registry, counting, store.CompressionNone)
+
+	// The search runs once and its result is encoded for both protocols
+	applied := fsm.Apply(&raft.Log{Data: []byte(resp.EncodeStringArray([]string{"VSEARCH", "vec", "1", "2", "1"}))})
+	replies, ok := applied.(protocolReplies)
+	if !ok {
+		t.Fatalf("expected the replies of both protocols, got %v", applied)
+	}
+	if counting.searches != 1 {
+		t.Fatalf("expected 1 search, got %d", counting.searches)
+	}
+	if replies.reply(resp.Protocol2) != "*0\r\n" || replies.reply(resp.Protocol3) != "*0\r\n" {
+		t.Fatalf("expected empty arrays, got %q and %q", replies.reply(resp.Protocol2), replies.reply(resp.Protocol3))
+	}
+}
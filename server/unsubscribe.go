@@ -5,15 +5,17 @@ import (
 	"strings"
 
 	"github.com/panjf2000/gnet/v2"
-	"treds/resp"
+	"treds/commands"
 )
 
 const UnsubscribeCommandName = "UNSUBSCRIBE"
 
 func RegisterUnsubscribeCommand(r ServerCommandRegistry) {
 	r.Add(&ServerCommandRegistration{
-		Name:    UnsubscribeCommandName,
-		Execute: executeUnsubscribeCommand(),
+		Name:     UnsubscribeCommandName,
+		Args:     "channel [channel ...]",
+		Execute:  executeUnsubscribeCommand(),
+		Category: commands.CategoryPubSub,
 	})
 }
 
@@ -31,7 +33,7 @@ func executeUnsubscribeCommand() ExecutionHook {
 		}
 
 		// Process this command on leader
-		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp))
+		forwarded, rspFwd, err := ts.ForwardRequest([]byte(inp), ts.GetConnectionProtocol(c.RemoteAddr().String()))
 		if err != nil {
 			ts.RespondErr(c, err)
 			return gnet.None
@@ -70,7 +72,7 @@ func executeUnsubscribeCommand() ExecutionHook {
 			response = append(response, channel)
 			response = append(response, indx+1)
 		}
-		_, errConn := c.Write([]byte(resp.EncodeArray(response)))
+		_, errConn := c.Write([]byte(ts.EncodeMessage(c.RemoteAddr().String(), response)))
 		if errConn != nil {
 			ts.RespondErr(c, errConn)
 		}
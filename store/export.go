@@ -0,0 +1,345 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The types of the records of an export, one for every store
+const (
+	ExportTypeString     = "string"
+	ExportTypeSortedMap  = "sortedmap"
+	ExportTypeList       = "list"
+	ExportTypeSet        = "set"
+	ExportTypeHash       = "hash"
+	ExportTypeCollection = "collection"
+	ExportTypeVector     = "vector"
+)
+
+// ExportEncodingBase64 is the encoding of the records holding a string that is not UTF-8, which JSON strings cannot hold
+const ExportEncodingBase64 = "base64"
+
+// ExportRecord is a line of an export, a key with its value and the milliseconds it has left to live.
+// Value holds a string for strings, an array of ExportSortedMapMember for sorted maps, an array of strings for lists and sets,
+// an object of fields for hashes, an ExportCollection for collections and an ExportVector for vector stores.
+// When Encoding is ExportEncodingBase64 the key and the strings of the value are base64, the strings are the members,
+// elements, fields and values of the stores and the ids of documents and nodes. Schemas and documents are JSON and stay as they are.
+type ExportRecord struct {
+	Key      string          `json:"key"`
+	Type     string          `json:"type"`
+	Encoding string          `json:"encoding,omitempty"`
+	Value    json.RawMessage `json:"value"`
+	TTL      int64           `json:"ttl,omitempty"`
+}
+
+// Decode returns a string of the record as it is stored, s is the key or a string of the value
+func (r *ExportRecord) Decode(s string) (string, error) {
+	switch r.Encoding {
+	case "":
+		return s, nil
+	case ExportEncodingBase64:
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid base64 string %q: %v", s, err)
+		}
+		return string(decoded), nil
+	}
+	return "", fmt.Errorf("unknown encoding %s", r.Encoding)
+}
+
+type ExportSortedMapMember struct {
+	Member string      `json:"member"`
+	Score  ExportScore `json:"score"`
+	Value  string      `json:"value"`
+}
+
+// ExportScore is a score written as a JSON number, infinite scores, which JSON numbers cannot hold, are written as "+inf" and "-inf"
+type ExportScore float64
+
+func (s ExportScore) MarshalJSON() ([]byte, error) {
+	if math.IsInf(float64(s), 0) {
+		return json.Marshal(strconv.FormatFloat(float64(s), 'g', -1, 64))
+	}
+	return []byte(strconv.FormatFloat(float64(s), 'g', -1, 64)), nil
+}
+
+func (s *ExportScore) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	score, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid score %s", data)
+	}
+	*s = ExportScore(score)
+	return nil
+}
+
+type ExportCollection struct {
+	Schema    json.RawMessage  `json:"schema"`
+	Indices   []ExportIndex    `json:"indices"`
+	Documents []ExportDocument `json:"documents"`
+}
+
+type ExportIndex struct {
+	Fields []string `json:"fields"`
+	Type   string   `json:"type"`
+}
+
+type ExportDocument struct {
+	ID   string          `json:"id"`
+	Data json.RawMessage `json:"data"`
+}
+
+type ExportVector struct {
+	MaxNeighbors int                `json:"maxNeighbors"`
+	LayerFactor  float64            `json:"layerFactor"`
+	EfSearch     int                `json:"efSearch"`
+	Nodes        []ExportVectorNode `json:"nodes"`
+}
+
+type ExportVectorNode struct {
+	ID     string    `json:"id"`
+	Level  int       `json:"level"`
+	Vector []float64 `json:"vector"`
+}
+
+// Export writes the keys of the view starting with prefix to w as JSON Lines, one ExportRecord per key.
+// Keys are written store by store in ascending order and keys that already expired are skipped.
+func (s *PointInTimeSnapshot) Export(w io.Writer, prefix string) (int, error) {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	// Values are exported as they are stored, <, > and & included
+	encoder.SetEscapeHTML(false)
+	var valueBuf bytes.Buffer
+	valueEncoder := json.NewEncoder(&valueBuf)
+	valueEncoder.SetEscapeHTML(false)
+	now := time.Now()
+	exported := 0
+
+	// write exports the value built by value with encode applied to its strings. The strings are checked first,
+	// the value is built again with the strings in base64 when the key or one of them is not UTF-8.
+	write := func(key, recordType string, value func(encode func(string) string) interface{}) error {
+		encoded := !utf8.ValidString(key)
+		built := value(func(s string) string {
+			encoded = encoded || !utf8.ValidString(s)
+			return s
+		})
+		record := &ExportRecord{Key: key, Type: recordType}
+		if encoded {
+			record.Key = encodeBase64(key)
+			record.Encoding = ExportEncodingBase64
+			built = value(encodeBase64)
+		}
+		if expireAt, ok := s.expiry[key]; ok {
+			record.TTL = max(expireAt.Sub(now).Milliseconds(), 1)
+		}
+		valueBuf.Reset()
+		if err := valueEncoder.Encode(built); err != nil {
+			return fmt.Errorf("exporting %s: %v", key, err)
+		}
+		record.Value = bytes.TrimSuffix(valueBuf.Bytes(), []byte("\n"))
+		exported++
+		return encoder.Encode(record)
+	}
+	// included reports whether key matches the prefix and is still alive
+	included := func(key string) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return false
+		}
+		expireAt, ok := s.expiry[key]
+		return !ok || now.Before(expireAt)
+	}
+
+	var err error
+	s.tree.Root().WalkPrefix([]byte(prefix), func(k []byte, v interface{}) bool {
+		if !included(string(k)) {
+			return false
+		}
+		var value string
+		if value, err = convertToString(v); err != nil {
+			return true
+		}
+		err = write(string(k), ExportTypeString, func(encode func(string) string) interface{} {
+			return encode(value)
+		})
+		return err != nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	for _, key := range sortedKeys(s.sortedMapsKeys) {
+		if !included(key) {
+			continue
+		}
+		scores := s.sortedMapsScore[key]
+		members := make([]ExportSortedMapMember, 0, len(scores))
+		s.sortedMapsKeys[key].Root().Walk(func(k []byte, v interface{}) bool {
+			var value string
+			if value, err = convertToString(v); err != nil {
+				return true
+			}
+			members = append(members, ExportSortedMapMember{Member: string(k), Score: ExportScore(scores[string(k)]), Value: value})
+			return false
+		})
+		if err != nil {
+			return exported, err
+		}
+		if err = write(key, ExportTypeSortedMap, func(encode func(string) string) interface{} {
+			encodedMembers := make([]ExportSortedMapMember, 0, len(members))
+			for _, member := range members {
+				encodedMembers = append(encodedMembers, ExportSortedMapMember{Member: encode(member.Member), Score: member.Score, Value: encode(member.Value)})
+			}
+			return encodedMembers
+		}); err != nil {
+			return exported, err
+		}
+	}
+
+	for _, key := range sortedKeys(s.lists) {
+		if !included(key) {
+			continue
+		}
+		list := s.lists[key].Values()
+		if err = write(key, ExportTypeList, func(encode func(string) string) interface{} {
+			return encodeStrings(list, encode)
+		}); err != nil {
+			return exported, err
+		}
+	}
+
+	for _, key := range sortedKeys(s.sets) {
+		if !included(key) {
+			continue
+		}
+		members := encodeStrings(s.sets[key].Values(), func(member string) string { return member })
+		sort.Strings(members)
+		if err = write(key, ExportTypeSet, func(encode func(string) string) interface{} {
+			encodedMembers := make([]string, 0, len(members))
+			for _, member := range members {
+				encodedMembers = append(encodedMembers, encode(member))
+			}
+			return encodedMembers
+		}); err != nil {
+			return exported, err
+		}
+	}
+
+	for _, key := range sortedKeys(s.hashes) {
+		if !included(key) {
+			continue
+		}
+		fields := hashFields(s.hashes[key])
+		if err = write(key, ExportTypeHash, func(encode func(string) string) interface{} {
+			encodedFields := make(map[string]string, len(fields))
+			for field, value := range fields {
+				encodedFields[encode(field)] = encode(value)
+			}
+			return encodedFields
+		}); err != nil {
+			return exported, err
+		}
+	}
+
+	for _, name := range sortedKeys(s.collections) {
+		if !included(name) {
+			continue
+		}
+		snapshot, errCollection := snapshotCollection(name, s.collections[name])
+		if errCollection != nil {
+			return exported, errCollection
+		}
+		if err = write(name, ExportTypeCollection, func(encode func(string) string) interface{} {
+			collection := ExportCollection{
+				Schema:    json.RawMessage(snapshot.Schema),
+				Indices:   make([]ExportIndex, 0, len(snapshot.Indices)),
+				Documents: make([]ExportDocument, 0, len(snapshot.Documents)),
+			}
+			for _, index := range snapshot.Indices {
+				indexType := "normal"
+				if index.Unique {
+					indexType = Unique
+				}
+				collection.Indices = append(collection.Indices, ExportIndex{Fields: toStrings(index.Fields), Type: indexType})
+			}
+			for _, document := range snapshot.Documents {
+				collection.Documents = append(collection.Documents, ExportDocument{ID: encode(string(document.Id)), Data: json.RawMessage(document.Data)})
+			}
+			return collection
+		}); err != nil {
+			return exported, err
+		}
+	}
+
+	for _, name := range sortedKeys(s.vectors) {
+		if !included(name) {
+			continue
+		}
+		snapshot := snapshotVector(name, s.vectors[name])
+		if err = write(name, ExportTypeVector, func(encode func(string) string) interface{} {
+			vector := ExportVector{
+				MaxNeighbors: int(snapshot.MaxNeighbors),
+				LayerFactor:  snapshot.LayerFactor,
+				EfSearch:     int(snapshot.EfSearch),
+				Nodes:        make([]ExportVectorNode, 0, len(snapshot.Nodes)),
+			}
+			for _, node := range snapshot.Nodes {
+				vector.Nodes = append(vector.Nodes, ExportVectorNode{ID: encode(node.Id), Level: int(node.Layer), Vector: node.Value})
+			}
+			return vector
+		}); err != nil {
+			return exported, err
+		}
+	}
+	return exported, writer.Flush()
+}
+
+func encodeBase64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// encodeStrings returns the strings of values, the elements of a list or the members of a set, with encode applied
+func encodeStrings(values []interface{}, encode func(string) string) []string {
+	encoded := make([]string, 0, len(values))
+	for _, value := range values {
+		encoded = append(encoded, encode(value.(string)))
+	}
+	return encoded
+}
+
+// ReadExport calls fn with every record of an export read from r, blank lines are skipped.
+// The records are as they were written, their key and strings are decoded with Decode.
+func ReadExport(r io.Reader, fn func(*ExportRecord) error) error {
+	decoder := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var record ExportRecord
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %v", n, err)
+		}
+		if record.Key == "" {
+			return fmt.Errorf("record %d: missing key", n)
+		}
+		if err = fn(&record); err != nil {
+			return fmt.Errorf("record %d, key %s: %v", n, record.Key, err)
+		}
+	}
+}
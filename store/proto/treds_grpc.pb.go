@@ -0,0 +1,830 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v4.25.1
+// source: treds.proto
+
+package kvstore
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Treds_Get_FullMethodName         = "/kvstore.Treds/Get"
+	Treds_Set_FullMethodName         = "/kvstore.Treds/Set"
+	Treds_Delete_FullMethodName      = "/kvstore.Treds/Delete"
+	Treds_ScanKVS_FullMethodName     = "/kvstore.Treds/ScanKVS"
+	Treds_ZAdd_FullMethodName        = "/kvstore.Treds/ZAdd"
+	Treds_ZRem_FullMethodName        = "/kvstore.Treds/ZRem"
+	Treds_ZScore_FullMethodName      = "/kvstore.Treds/ZScore"
+	Treds_ZRangeLex_FullMethodName   = "/kvstore.Treds/ZRangeLex"
+	Treds_ZRangeScore_FullMethodName = "/kvstore.Treds/ZRangeScore"
+	Treds_DCreate_FullMethodName     = "/kvstore.Treds/DCreate"
+	Treds_DDrop_FullMethodName       = "/kvstore.Treds/DDrop"
+	Treds_DInsert_FullMethodName     = "/kvstore.Treds/DInsert"
+	Treds_DQuery_FullMethodName      = "/kvstore.Treds/DQuery"
+	Treds_VCreate_FullMethodName     = "/kvstore.Treds/VCreate"
+	Treds_VInsert_FullMethodName     = "/kvstore.Treds/VInsert"
+	Treds_VSearch_FullMethodName     = "/kvstore.Treds/VSearch"
+	Treds_VDelete_FullMethodName     = "/kvstore.Treds/VDelete"
+	Treds_Publish_FullMethodName     = "/kvstore.Treds/Publish"
+	Treds_Subscribe_FullMethodName   = "/kvstore.Treds/Subscribe"
+)
+
+// TredsClient is the client API for Treds service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Treds exposes the stores over gRPC, every RPC maps onto the RESP command of the same name
+type TredsClient interface {
+	// Key/Value store
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// ScanKVS streams every key value pair with the prefix, SCANKVS is called page by page
+	ScanKVS(ctx context.Context, in *ScanKVSRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[KeyValue], error)
+	// Sorted maps store
+	ZAdd(ctx context.Context, in *ZAddRequest, opts ...grpc.CallOption) (*ZAddResponse, error)
+	ZRem(ctx context.Context, in *ZRemRequest, opts ...grpc.CallOption) (*ZRemResponse, error)
+	ZScore(ctx context.Context, in *ZScoreRequest, opts ...grpc.CallOption) (*ZScoreResponse, error)
+	ZRangeLex(ctx context.Context, in *ZRangeLexRequest, opts ...grpc.CallOption) (*ZRangeResponse, error)
+	ZRangeScore(ctx context.Context, in *ZRangeScoreRequest, opts ...grpc.CallOption) (*ZRangeResponse, error)
+	// Collection store, schemas, indexes, documents and queries are JSON as in the RESP commands
+	DCreate(ctx context.Context, in *DCreateRequest, opts ...grpc.CallOption) (*DCreateResponse, error)
+	DDrop(ctx context.Context, in *DDropRequest, opts ...grpc.CallOption) (*DDropResponse, error)
+	DInsert(ctx context.Context, in *DInsertRequest, opts ...grpc.CallOption) (*DInsertResponse, error)
+	DQuery(ctx context.Context, in *DQueryRequest, opts ...grpc.CallOption) (*DQueryResponse, error)
+	// Vector store
+	VCreate(ctx context.Context, in *VCreateRequest, opts ...grpc.CallOption) (*VCreateResponse, error)
+	VInsert(ctx context.Context, in *VInsertRequest, opts ...grpc.CallOption) (*VInsertResponse, error)
+	VSearch(ctx context.Context, in *VSearchRequest, opts ...grpc.CallOption) (*VSearchResponse, error)
+	VDelete(ctx context.Context, in *VDeleteRequest, opts ...grpc.CallOption) (*VDeleteResponse, error)
+	// PubSub
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Subscribe streams the messages published on the channels, or on channels with the prefixes when pattern is set
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error)
+}
+
+type tredsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTredsClient(cc grpc.ClientConnInterface) TredsClient {
+	return &tredsClient{cc}
+}
+
+func (c *tredsClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, Treds_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, Treds_Set_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, Treds_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) ScanKVS(ctx context.Context, in *ScanKVSRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[KeyValue], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Treds_ServiceDesc.Streams[0], Treds_ScanKVS_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ScanKVSRequest, KeyValue]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Treds_ScanKVSClient = grpc.ServerStreamingClient[KeyValue]
+
+func (c *tredsClient) ZAdd(ctx context.Context, in *ZAddRequest, opts ...grpc.CallOption) (*ZAddResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZAddResponse)
+	err := c.cc.Invoke(ctx, Treds_ZAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) ZRem(ctx context.Context, in *ZRemRequest, opts ...grpc.CallOption) (*ZRemResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZRemResponse)
+	err := c.cc.Invoke(ctx, Treds_ZRem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) ZScore(ctx context.Context, in *ZScoreRequest, opts ...grpc.CallOption) (*ZScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZScoreResponse)
+	err := c.cc.Invoke(ctx, Treds_ZScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) ZRangeLex(ctx context.Context, in *ZRangeLexRequest, opts ...grpc.CallOption) (*ZRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZRangeResponse)
+	err := c.cc.Invoke(ctx, Treds_ZRangeLex_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) ZRangeScore(ctx context.Context, in *ZRangeScoreRequest, opts ...grpc.CallOption) (*ZRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ZRangeResponse)
+	err := c.cc.Invoke(ctx, Treds_ZRangeScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) DCreate(ctx context.Context, in *DCreateRequest, opts ...grpc.CallOption) (*DCreateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DCreateResponse)
+	err := c.cc.Invoke(ctx, Treds_DCreate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) DDrop(ctx context.Context, in *DDropRequest, opts ...grpc.CallOption) (*DDropResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DDropResponse)
+	err := c.cc.Invoke(ctx, Treds_DDrop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) DInsert(ctx context.Context, in *DInsertRequest, opts ...grpc.CallOption) (*DInsertResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DInsertResponse)
+	err := c.cc.Invoke(ctx, Treds_DInsert_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) DQuery(ctx context.Context, in *DQueryRequest, opts ...grpc.CallOption) (*DQueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DQueryResponse)
+	err := c.cc.Invoke(ctx, Treds_DQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) VCreate(ctx context.Context, in *VCreateRequest, opts ...grpc.CallOption) (*VCreateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VCreateResponse)
+	err := c.cc.Invoke(ctx, Treds_VCreate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) VInsert(ctx context.Context, in *VInsertRequest, opts ...grpc.CallOption) (*VInsertResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VInsertResponse)
+	err := c.cc.Invoke(ctx, Treds_VInsert_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) VSearch(ctx context.Context, in *VSearchRequest, opts ...grpc.CallOption) (*VSearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VSearchResponse)
+	err := c.cc.Invoke(ctx, Treds_VSearch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) VDelete(ctx context.Context, in *VDeleteRequest, opts ...grpc.CallOption) (*VDeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VDeleteResponse)
+	err := c.cc.Invoke(ctx, Treds_VDelete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, Treds_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tredsClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Treds_ServiceDesc.Streams[1], Treds_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Message]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Treds_SubscribeClient = grpc.ServerStreamingClient[Message]
+
+// TredsServer is the server API for Treds service.
+// All implementations must embed UnimplementedTredsServer
+// for forward compatibility.
+//
+// Treds exposes the stores over gRPC, every RPC maps onto the RESP command of the same name
+type TredsServer interface {
+	// Key/Value store
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// ScanKVS streams every key value pair with the prefix, SCANKVS is called page by page
+	ScanKVS(*ScanKVSRequest, grpc.ServerStreamingServer[KeyValue]) error
+	// Sorted maps store
+	ZAdd(context.Context, *ZAddRequest) (*ZAddResponse, error)
+	ZRem(context.Context, *ZRemRequest) (*ZRemResponse, error)
+	ZScore(context.Context, *ZScoreRequest) (*ZScoreResponse, error)
+	ZRangeLex(context.Context, *ZRangeLexRequest) (*ZRangeResponse, error)
+	ZRangeScore(context.Context, *ZRangeScoreRequest) (*ZRangeResponse, error)
+	// Collection store, schemas, indexes, documents and queries are JSON as in the RESP commands
+	DCreate(context.Context, *DCreateRequest) (*DCreateResponse, error)
+	DDrop(context.Context, *DDropRequest) (*DDropResponse, error)
+	DInsert(context.Context, *DInsertRequest) (*DInsertResponse, error)
+	DQuery(context.Context, *DQueryRequest) (*DQueryResponse, error)
+	// Vector store
+	VCreate(context.Context, *VCreateRequest) (*VCreateResponse, error)
+	VInsert(context.Context, *VInsertRequest) (*VInsertResponse, error)
+	VSearch(context.Context, *VSearchRequest) (*VSearchResponse, error)
+	VDelete(context.Context, *VDeleteRequest) (*VDeleteResponse, error)
+	// PubSub
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Subscribe streams the messages published on the channels, or on channels with the prefixes when pattern is set
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Message]) error
+	mustEmbedUnimplementedTredsServer()
+}
+
+// UnimplementedTredsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTredsServer struct{}
+
+func (UnimplementedTredsServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedTredsServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedTredsServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedTredsServer) ScanKVS(*ScanKVSRequest, grpc.ServerStreamingServer[KeyValue]) error {
+	return status.Errorf(codes.Unimplemented, "method ScanKVS not implemented")
+}
+func (UnimplementedTredsServer) ZAdd(context.Context, *ZAddRequest) (*ZAddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ZAdd not implemented")
+}
+func (UnimplementedTredsServer) ZRem(context.Context, *ZRemRequest) (*ZRemResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ZRem not implemented")
+}
+func (UnimplementedTredsServer) ZScore(context.Context, *ZScoreRequest) (*ZScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ZScore not implemented")
+}
+func (UnimplementedTredsServer) ZRangeLex(context.Context, *ZRangeLexRequest) (*ZRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ZRangeLex not implemented")
+}
+func (UnimplementedTredsServer) ZRangeScore(context.Context, *ZRangeScoreRequest) (*ZRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ZRangeScore not implemented")
+}
+func (UnimplementedTredsServer) DCreate(context.Context, *DCreateRequest) (*DCreateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DCreate not implemented")
+}
+func (UnimplementedTredsServer) DDrop(context.Context, *DDropRequest) (*DDropResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DDrop not implemented")
+}
+func (UnimplementedTredsServer) DInsert(context.Context, *DInsertRequest) (*DInsertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DInsert not implemented")
+}
+func (UnimplementedTredsServer) DQuery(context.Context, *DQueryRequest) (*DQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DQuery not implemented")
+}
+func (UnimplementedTredsServer) VCreate(context.Context, *VCreateRequest) (*VCreateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VCreate not implemented")
+}
+func (UnimplementedTredsServer) VInsert(context.Context, *VInsertRequest) (*VInsertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VInsert not implemented")
+}
+func (UnimplementedTredsServer) VSearch(context.Context, *VSearchRequest) (*VSearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VSearch not implemented")
+}
+func (UnimplementedTredsServer) VDelete(context.Context, *VDeleteRequest) (*VDeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VDelete not implemented")
+}
+func (UnimplementedTredsServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedTredsServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Message]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedTredsServer) mustEmbedUnimplementedTredsServer() {}
+func (UnimplementedTredsServer) testEmbeddedByValue()               {}
+
+// UnsafeTredsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TredsServer will
+// result in compilation errors.
+type UnsafeTredsServer interface {
+	mustEmbedUnimplementedTredsServer()
+}
+
+func RegisterTredsServer(s grpc.ServiceRegistrar, srv TredsServer) {
+	// If the following call pancis, it indicates UnimplementedTredsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Treds_ServiceDesc, srv)
+}
+
+func _Treds_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_ScanKVS_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanKVSRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TredsServer).ScanKVS(m, &grpc.GenericServerStream[ScanKVSRequest, KeyValue]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Treds_ScanKVSServer = grpc.ServerStreamingServer[KeyValue]
+
+func _Treds_ZAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZAddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).ZAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_ZAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).ZAdd(ctx, req.(*ZAddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_ZRem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZRemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).ZRem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_ZRem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).ZRem(ctx, req.(*ZRemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_ZScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).ZScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_ZScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).ZScore(ctx, req.(*ZScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_ZRangeLex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZRangeLexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).ZRangeLex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_ZRangeLex_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).ZRangeLex(ctx, req.(*ZRangeLexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_ZRangeScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ZRangeScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).ZRangeScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_ZRangeScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).ZRangeScore(ctx, req.(*ZRangeScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_DCreate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).DCreate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_DCreate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).DCreate(ctx, req.(*DCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_DDrop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DDropRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).DDrop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_DDrop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).DDrop(ctx, req.(*DDropRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_DInsert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DInsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).DInsert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_DInsert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).DInsert(ctx, req.(*DInsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_DQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).DQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_DQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).DQuery(ctx, req.(*DQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_VCreate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).VCreate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_VCreate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).VCreate(ctx, req.(*VCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_VInsert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VInsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).VInsert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_VInsert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).VInsert(ctx, req.(*VInsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_VSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).VSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_VSearch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).VSearch(ctx, req.(*VSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_VDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).VDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_VDelete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).VDelete(ctx, req.(*VDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TredsServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Treds_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TredsServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Treds_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TredsServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Treds_SubscribeServer = grpc.ServerStreamingServer[Message]
+
+// Treds_ServiceDesc is the grpc.ServiceDesc for Treds service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Treds_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kvstore.Treds",
+	HandlerType: (*TredsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _Treds_Get_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _Treds_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Treds_Delete_Handler,
+		},
+		{
+			MethodName: "ZAdd",
+			Handler:    _Treds_ZAdd_Handler,
+		},
+		{
+			MethodName: "ZRem",
+			Handler:    _Treds_ZRem_Handler,
+		},
+		{
+			MethodName: "ZScore",
+			Handler:    _Treds_ZScore_Handler,
+		},
+		{
+			MethodName: "ZRangeLex",
+			Handler:    _Treds_ZRangeLex_Handler,
+		},
+		{
+			MethodName: "ZRangeScore",
+			Handler:    _Treds_ZRangeScore_Handler,
+		},
+		{
+			MethodName: "DCreate",
+			Handler:    _Treds_DCreate_Handler,
+		},
+		{
+			MethodName: "DDrop",
+			Handler:    _Treds_DDrop_Handler,
+		},
+		{
+			MethodName: "DInsert",
+			Handler:    _Treds_DInsert_Handler,
+		},
+		{
+			MethodName: "DQuery",
+			Handler:    _Treds_DQuery_Handler,
+		},
+		{
+			MethodName: "VCreate",
+			Handler:    _Treds_VCreate_Handler,
+		},
+		{
+			MethodName: "VInsert",
+			Handler:    _Treds_VInsert_Handler,
+		},
+		{
+			MethodName: "VSearch",
+			Handler:    _Treds_VSearch_Handler,
+		},
+		{
+			MethodName: "VDelete",
+			Handler:    _Treds_VDelete_Handler,
+		},
+		{
+			MethodName: "Publish",
+			Handler:    _Treds_Publish_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ScanKVS",
+			Handler:       _Treds_ScanKVS_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Treds_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "treds.proto",
+}
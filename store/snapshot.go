@@ -1,5 +1,27 @@
 package store
 
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/absolutelightning/gods/lists/doublylinkedlist"
+	"github.com/absolutelightning/gods/maps/hashmap"
+	"github.com/absolutelightning/gods/maps/treemap"
+	"github.com/absolutelightning/gods/sets/hashset"
+	"github.com/absolutelightning/gods/utils"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"treds/datastructures/hnsw"
+	radix_tree "treds/datastructures/radix"
+	kvstore "treds/store/proto"
+)
+
 type Snapshot struct {
 	store     *Store
 	lastIndex uint64
@@ -8,3 +30,682 @@ type Snapshot struct {
 type Restore struct {
 	store *Store
 }
+
+// SnapshotVersion is the version of the snapshots written by PointInTimeSnapshot.Persist.
+// Version 0 snapshots only hold the key value store, version 1 snapshots hold every store in a single message,
+// version 2 snapshots are a stream of length delimited chunks and version 3 snapshots are snapshot files
+// with checksummed and optionally compressed chunks.
+const SnapshotVersion = 3
+
+// snapshotStreamMagic starts version 2 snapshots, older snapshots start with a protobuf field
+const snapshotStreamMagic = "TREDSSNP"
+
+// snapshotChunkSize is the number of entries, like keys, members or documents, written in a chunk
+var snapshotChunkSize = 10000
+
+// PointInTimeSnapshot is a view of every store at the time Snapshot was called.
+// The radix trees are immutable so only their roots are kept, that covers the key value store and the members of sorted sets.
+// The containers of the other stores, like lists, sets, hashes, the scores of sorted sets, collections and vectors,
+// are shared with the store, which copies a container before the first write to it after the snapshot, see copyOnWrite.
+// Only the maps from the keys to their containers and the expiry of the keys are copied by Snapshot.
+// Persist can run while the store keeps applying commands.
+type PointInTimeSnapshot struct {
+	tree            *radix_tree.Tree
+	sortedMapsKeys  map[string]*radix_tree.Tree
+	sortedMapsScore map[string]map[string]float64
+	lists           map[string]*doublylinkedlist.List
+	sets            map[string]*hashset.Set
+	hashes          map[string]*hashmap.Map
+	collections     map[string]*Collection
+	vectors         map[string]*hnsw.HNSW
+	expiry          map[string]time.Time
+	// Metadata is written with the stores, it holds what the caller keeps next to them, like the metadata of the cluster
+	Metadata map[string]string
+}
+
+// Snapshot captures a view of every store, it has to be called from the goroutine applying the commands
+// or with the commands held back, while the maps from the keys to their containers are copied.
+// Every container is shared with the view until it is changed.
+func (ts *TredsStore) Snapshot() (*PointInTimeSnapshot, error) {
+	view := &PointInTimeSnapshot{
+		tree:            ts.tree,
+		sortedMapsKeys:  maps.Clone(ts.sortedMapsKeys),
+		sortedMapsScore: maps.Clone(ts.sortedMapsScore),
+		lists:           maps.Clone(ts.lists),
+		sets:            maps.Clone(ts.sets),
+		hashes:          maps.Clone(ts.hashes),
+		collections:     maps.Clone(ts.collections),
+		vectors:         maps.Clone(ts.vectors),
+		expiry:          maps.Clone(ts.expiry),
+	}
+	ts.ownedScores = make(map[string]struct{})
+	ts.ownedLists = make(map[string]struct{})
+	ts.ownedSets = make(map[string]struct{})
+	ts.ownedHashes = make(map[string]struct{})
+	ts.ownedCollections = make(map[string]struct{})
+	ts.ownedVectors = make(map[string]struct{})
+	return view, nil
+}
+
+// copyOnWrite reports whether the container of key has to be copied before it is changed, owned holds the keys
+// of the store whose containers were copied or created since the last snapshot. The key is owned from then on,
+// the caller replaces the container with its copy or creates one for a key without a container.
+func copyOnWrite(owned map[string]struct{}, key string) bool {
+	// No snapshot was taken yet
+	if owned == nil {
+		return false
+	}
+	if _, ok := owned[key]; ok {
+		return false
+	}
+	owned[key] = struct{}{}
+	return true
+}
+
+// scoresForWrite returns the scores of the sorted map of key to change them, copied when a snapshot may refer to them
+func (ts *TredsStore) scoresForWrite(key string) (map[string]float64, bool) {
+	scores, ok := ts.sortedMapsScore[key]
+	if copyOnWrite(ts.ownedScores, key) && ok {
+		scores = maps.Clone(scores)
+		ts.sortedMapsScore[key] = scores
+	}
+	return scores, ok
+}
+
+// listForWrite returns the list of key to change it, copied when a snapshot may refer to it
+func (ts *TredsStore) listForWrite(key string) (*doublylinkedlist.List, bool) {
+	storedList, ok := ts.lists[key]
+	if copyOnWrite(ts.ownedLists, key) && ok {
+		storedList = doublylinkedlist.New(storedList.Values()...)
+		ts.lists[key] = storedList
+	}
+	return storedList, ok
+}
+
+// setForWrite returns the set of key to change it, copied when a snapshot may refer to it
+func (ts *TredsStore) setForWrite(key string) (*hashset.Set, bool) {
+	storedSet, ok := ts.sets[key]
+	if copyOnWrite(ts.ownedSets, key) && ok {
+		storedSet = hashset.New(storedSet.Values()...)
+		ts.sets[key] = storedSet
+	}
+	return storedSet, ok
+}
+
+// hashForWrite returns the hash of key to change it, copied when a snapshot may refer to it
+func (ts *TredsStore) hashForWrite(key string) (*hashmap.Map, bool) {
+	storedMap, ok := ts.hashes[key]
+	if copyOnWrite(ts.ownedHashes, key) && ok {
+		copied := hashmap.New()
+		for _, field := range storedMap.Keys() {
+			value, _ := storedMap.Get(field)
+			copied.Put(field, value)
+		}
+		storedMap = copied
+		ts.hashes[key] = storedMap
+	}
+	return storedMap, ok
+}
+
+// collectionForWrite returns the collection name to change it, copied when a snapshot may refer to it.
+// Documents are never changed once inserted and the copy shares them. It shares the indices too: their trees
+// are changed in place but snapshots only read their fields.
+func (ts *TredsStore) collectionForWrite(name string) (*Collection, bool) {
+	collection, ok := ts.collections[name]
+	if copyOnWrite(ts.ownedCollections, name) && ok {
+		collection = &Collection{
+			Documents:       maps.Clone(collection.Documents),
+			Indices:         maps.Clone(collection.Indices),
+			Schema:          collection.Schema,
+			DocumentIdIndex: maps.Clone(collection.DocumentIdIndex),
+		}
+		ts.collections[name] = collection
+	}
+	return collection, ok
+}
+
+// vectorForWrite returns the vector store name to change it, copied when a snapshot may refer to it
+func (ts *TredsStore) vectorForWrite(name string) (*hnsw.HNSW, bool) {
+	graph, ok := ts.vectors[name]
+	if copyOnWrite(ts.ownedVectors, name) && ok {
+		graph = graph.Clone()
+		ts.vectors[name] = graph
+	}
+	return graph, ok
+}
+
+// snapshotWriter fills a chunk and writes it in a frame once it holds snapshotChunkSize entries
+type snapshotWriter struct {
+	frames  *snapshotFrameWriter
+	chunk   *kvstore.Snapshot
+	entries int
+}
+
+// added counts an entry of the current chunk and reports whether the chunk was written,
+// the entries of a key that follow go in a new message of the next chunk
+func (sw *snapshotWriter) added() (bool, error) {
+	sw.entries++
+	if sw.entries < snapshotChunkSize {
+		return false, nil
+	}
+	return true, sw.flush()
+}
+
+// flush writes the current chunk and starts the next one, an empty store is written as a single empty chunk
+func (sw *snapshotWriter) flush() error {
+	if sw.entries == 0 && sw.frames.frames > 0 {
+		return nil
+	}
+	// Map fields are sorted, so snapshots of the same state are identical
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(sw.chunk)
+	if err != nil {
+		return err
+	}
+	if err = sw.frames.writeFrame(data); err != nil {
+		return err
+	}
+	sw.chunk = &kvstore.Snapshot{Version: SnapshotVersion}
+	sw.entries = 0
+	return nil
+}
+
+// Persist writes the view to w as a snapshot file with the chunks compressed by compression,
+// keys are written in ascending order
+func (s *PointInTimeSnapshot) Persist(w io.Writer, compression SnapshotCompression) error {
+	frames, err := newSnapshotFrameWriter(w, compression)
+	if err != nil {
+		return err
+	}
+	sw := &snapshotWriter{frames: frames, chunk: &kvstore.Snapshot{Version: SnapshotVersion, Metadata: s.Metadata}}
+
+	s.tree.Root().Walk(func(k []byte, v interface{}) bool {
+		var value string
+		if value, err = convertToString(v); err != nil {
+			return true
+		}
+		sw.chunk.Pairs = append(sw.chunk.Pairs, &kvstore.KeyValue{Key: k, Value: []byte(value)})
+		_, err = sw.added()
+		return err != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(s.sortedMapsKeys) {
+		sortedMap := &kvstore.SortedMap{Key: []byte(key)}
+		sw.chunk.SortedMaps = append(sw.chunk.SortedMaps, sortedMap)
+		scores := s.sortedMapsScore[key]
+		s.sortedMapsKeys[key].Root().Walk(func(k []byte, v interface{}) bool {
+			var value string
+			if value, err = convertToString(v); err != nil {
+				return true
+			}
+			if sortedMap == nil {
+				sortedMap = &kvstore.SortedMap{Key: []byte(key)}
+				sw.chunk.SortedMaps = append(sw.chunk.SortedMaps, sortedMap)
+			}
+			sortedMap.Members = append(sortedMap.Members, &kvstore.SortedMapMember{
+				Key:   k,
+				Value: []byte(value),
+				Score: scores[string(k)],
+			})
+			var flushed bool
+			flushed, err = sw.added()
+			if flushed {
+				sortedMap = nil
+			}
+			return err != nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range sortedKeys(s.lists) {
+		list := &kvstore.List{Key: []byte(key)}
+		sw.chunk.Lists = append(sw.chunk.Lists, list)
+		for _, value := range s.lists[key].Values() {
+			if list == nil {
+				list = &kvstore.List{Key: []byte(key)}
+				sw.chunk.Lists = append(sw.chunk.Lists, list)
+			}
+			list.Values = append(list.Values, []byte(value.(string)))
+			if flushed, errAdd := sw.added(); errAdd != nil {
+				return errAdd
+			} else if flushed {
+				list = nil
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(s.sets) {
+		members := make([]string, 0, s.sets[key].Size())
+		for _, member := range s.sets[key].Values() {
+			members = append(members, member.(string))
+		}
+		sort.Strings(members)
+		set := &kvstore.Set{Key: []byte(key)}
+		sw.chunk.Sets = append(sw.chunk.Sets, set)
+		for _, member := range members {
+			if set == nil {
+				set = &kvstore.Set{Key: []byte(key)}
+				sw.chunk.Sets = append(sw.chunk.Sets, set)
+			}
+			set.Members = append(set.Members, []byte(member))
+			if flushed, errAdd := sw.added(); errAdd != nil {
+				return errAdd
+			} else if flushed {
+				set = nil
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(s.hashes) {
+		fields := hashFields(s.hashes[key])
+		hash := &kvstore.Hash{Key: []byte(key)}
+		sw.chunk.Hashes = append(sw.chunk.Hashes, hash)
+		for _, field := range sortedKeys(fields) {
+			if hash == nil {
+				hash = &kvstore.Hash{Key: []byte(key)}
+				sw.chunk.Hashes = append(sw.chunk.Hashes, hash)
+			}
+			hash.Fields = append(hash.Fields, &kvstore.KeyValue{Key: []byte(field), Value: []byte(fields[field])})
+			if flushed, errAdd := sw.added(); errAdd != nil {
+				return errAdd
+			} else if flushed {
+				hash = nil
+			}
+		}
+	}
+
+	// The schema and indices of a collection are in its first message, the messages in the next chunks only hold documents
+	for _, name := range sortedKeys(s.collections) {
+		snapshot, errCollection := snapshotCollection(name, s.collections[name])
+		if errCollection != nil {
+			return errCollection
+		}
+		collection := &kvstore.Collection{Name: []byte(name), Schema: snapshot.Schema, Indices: snapshot.Indices}
+		sw.chunk.Collections = append(sw.chunk.Collections, collection)
+		for _, document := range snapshot.Documents {
+			if collection == nil {
+				collection = &kvstore.Collection{Name: []byte(name)}
+				sw.chunk.Collections = append(sw.chunk.Collections, collection)
+			}
+			collection.Documents = append(collection.Documents, document)
+			if flushed, errAdd := sw.added(); errAdd != nil {
+				return errAdd
+			} else if flushed {
+				collection = nil
+			}
+		}
+	}
+
+	// Like collections, the parameters of a vector store are in its first message
+	for _, name := range sortedKeys(s.vectors) {
+		snapshot := snapshotVector(name, s.vectors[name])
+		vector := &kvstore.Vector{
+			Name:          []byte(name),
+			MaxNeighbors:  snapshot.MaxNeighbors,
+			MaxNeighbors0: snapshot.MaxNeighbors0,
+			LayerFactor:   snapshot.LayerFactor,
+			EfSearch:      snapshot.EfSearch,
+			Layers:        snapshot.Layers,
+			EntryPoint:    snapshot.EntryPoint,
+		}
+		sw.chunk.Vectors = append(sw.chunk.Vectors, vector)
+		for _, node := range snapshot.Nodes {
+			if vector == nil {
+				vector = &kvstore.Vector{Name: []byte(name)}
+				sw.chunk.Vectors = append(sw.chunk.Vectors, vector)
+			}
+			vector.Nodes = append(vector.Nodes, node)
+			if flushed, errAdd := sw.added(); errAdd != nil {
+				return errAdd
+			} else if flushed {
+				vector = nil
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(s.expiry) {
+		sw.chunk.Expiry = append(sw.chunk.Expiry, &kvstore.Expiry{Key: []byte(key), UnixNano: s.expiry[key].UnixNano()})
+		if _, errAdd := sw.added(); errAdd != nil {
+			return errAdd
+		}
+	}
+	if err = sw.flush(); err != nil {
+		return err
+	}
+	return frames.close()
+}
+
+// hashFields returns the fields of a hash with their values
+func hashFields(hash *hashmap.Map) map[string]string {
+	fields := make(map[string]string, hash.Size())
+	for _, field := range hash.Keys() {
+		value, _ := hash.Get(field)
+		fields[field.(string)] = value.(string)
+	}
+	return fields
+}
+
+func snapshotCollection(name string, collection *Collection) (*kvstore.Collection, error) {
+	schema, err := json.Marshal(collection.Schema)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &kvstore.Collection{Name: []byte(name), Schema: string(schema)}
+	for _, indexName := range sortedKeys(collection.Indices) {
+		index := collection.Indices[indexName]
+		snapshot.Indices = append(snapshot.Indices, &kvstore.CollectionIndex{
+			Fields: toBytes(index.Fields.Fields),
+			Unique: index.isUnique,
+		})
+	}
+	for _, id := range sortedKeys(collection.Documents) {
+		snapshot.Documents = append(snapshot.Documents, &kvstore.Document{
+			Id:   []byte(id),
+			Data: collection.Documents[id].StringData,
+		})
+	}
+	return snapshot, nil
+}
+
+func snapshotVector(name string, graph *hnsw.HNSW) *kvstore.Vector {
+	snapshot := &kvstore.Vector{
+		Name:          []byte(name),
+		MaxNeighbors:  int32(graph.MaxNeighbors),
+		MaxNeighbors0: int32(graph.MaxNeighbors0),
+		LayerFactor:   graph.LayerFactor,
+		EfSearch:      int32(graph.EfSearch),
+		Layers:        int32(len(graph.Layers)),
+	}
+	if graph.EntryPoint != nil {
+		snapshot.EntryPoint = graph.EntryPoint.ID
+	}
+	// Every node is in layer 0, the higher layers hold the same node pointers
+	if len(graph.Layers) > 0 {
+		for _, id := range sortedKeys(graph.Layers[0].Nodes) {
+			node := graph.Layers[0].Nodes[id]
+			snapshot.Nodes = append(snapshot.Nodes, &kvstore.VectorNode{
+				Id:        node.ID,
+				Layer:     int32(node.Layer),
+				Value:     node.Value,
+				Neighbors: node.Neighbors,
+			})
+		}
+	}
+	return snapshot
+}
+
+// Restore replaces every store with the content of a snapshot read from r, the leaf lists and treemap indices are rebuilt
+// as they are filled. The store is only replaced once the whole snapshot was read and matched its checksums.
+func (ts *TredsStore) Restore(r io.Reader) error {
+	_, err := ts.RestoreMetadata(r)
+	return err
+}
+
+// RestoreMetadata restores the store like Restore and returns the metadata written with it
+func (ts *TredsStore) RestoreMetadata(r io.Reader) (map[string]string, error) {
+	restored := &snapshotRestore{store: NewTredsStore(), entryPoints: make(map[string]string), metadata: make(map[string]string)}
+	if err := readSnapshot(r, restored.chunk); err != nil {
+		return nil, err
+	}
+	for name, entryPoint := range restored.entryPoints {
+		graph := restored.store.vectors[name]
+		if len(graph.Layers) > 0 {
+			graph.EntryPoint = graph.Layers[0].Nodes[entryPoint]
+		}
+	}
+	*ts = *restored.store
+	return restored.metadata, nil
+}
+
+// VerifySnapshot reads a whole snapshot and returns an error when it is corrupted, of a newer version or cannot be decoded.
+// Snapshots written before version 3 have no checksums, they are only decoded.
+func VerifySnapshot(r io.Reader) error {
+	return readSnapshot(r, func(*kvstore.Snapshot) error { return nil })
+}
+
+// readSnapshot calls fn with every chunk of a snapshot in any of the versions
+func readSnapshot(r io.Reader, fn func(*kvstore.Snapshot) error) error {
+	reader := bufio.NewReader(r)
+	chunk := func(snapshot *kvstore.Snapshot) error {
+		if snapshot.Version > SnapshotVersion {
+			return fmt.Errorf("snapshot version %d is newer than the supported version %d", snapshot.Version, SnapshotVersion)
+		}
+		return fn(snapshot)
+	}
+
+	switch {
+	case peekSnapshotMagic(reader, snapshotMagic):
+		frames, err := newSnapshotFrameReader(reader)
+		if err != nil {
+			return err
+		}
+		defer frames.close()
+		for {
+			data, errFrame := frames.next()
+			if errors.Is(errFrame, io.EOF) {
+				return nil
+			}
+			if errFrame != nil {
+				return errFrame
+			}
+			var snapshot kvstore.Snapshot
+			if err = proto.Unmarshal(data, &snapshot); err != nil {
+				return fmt.Errorf("invalid snapshot: %v", err)
+			}
+			if err = chunk(&snapshot); err != nil {
+				return err
+			}
+		}
+
+	case peekSnapshotMagic(reader, snapshotStreamMagic):
+		if _, err := reader.Discard(len(snapshotStreamMagic)); err != nil {
+			return err
+		}
+		for {
+			var snapshot kvstore.Snapshot
+			// Chunks are bounded by their number of entries and not their size
+			err := protodelim.UnmarshalOptions{MaxSize: -1}.UnmarshalFrom(reader, &snapshot)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid snapshot: %v", err)
+			}
+			if err = chunk(&snapshot); err != nil {
+				return err
+			}
+		}
+
+	default:
+		// Snapshots written before version 2 are a single message
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		var snapshot kvstore.Snapshot
+		if err = proto.Unmarshal(data, &snapshot); err != nil {
+			return fmt.Errorf("invalid snapshot: %v", err)
+		}
+		return chunk(&snapshot)
+	}
+}
+
+// snapshotRestore fills a store from the chunks of a snapshot, the entries of a key can be spread over several chunks
+type snapshotRestore struct {
+	store *TredsStore
+	// The entry points of the vector stores are set once all their nodes are restored
+	entryPoints map[string]string
+	metadata    map[string]string
+}
+
+func (sr *snapshotRestore) chunk(snapshot *kvstore.Snapshot) error {
+	restored := sr.store
+	maps.Copy(sr.metadata, snapshot.Metadata)
+
+	for _, pair := range snapshot.Pairs {
+		restored.tree, _, _ = restored.tree.Insert([]byte(pair.Key), string(pair.Value))
+	}
+
+	for _, sortedMap := range snapshot.SortedMaps {
+		key := string(sortedMap.Key)
+		tm, ok := restored.sortedMaps[key]
+		if !ok {
+			tm = treemap.NewWith(utils.Float64Comparator)
+			restored.sortedMaps[key] = tm
+			restored.sortedMapsScore[key] = make(map[string]float64)
+			restored.sortedMapsKeys[key] = radix_tree.New()
+		}
+		sm := restored.sortedMapsScore[key]
+		sortedKeyMap := restored.sortedMapsKeys[key]
+		// Members are added in score order, like the scans walk them
+		members := sortedMap.Members
+		sort.SliceStable(members, func(i, j int) bool {
+			return members[i].Score < members[j].Score
+		})
+		for _, member := range members {
+			sortedKeyMap = addSortedMapMember(tm, sm, sortedKeyMap, member.Score, string(member.Key), string(member.Value))
+		}
+		restored.sortedMapsKeys[key] = sortedKeyMap
+	}
+
+	for _, list := range snapshot.Lists {
+		storedList, ok := restored.lists[string(list.Key)]
+		if !ok {
+			storedList = doublylinkedlist.New()
+			restored.lists[string(list.Key)] = storedList
+		}
+		for _, value := range list.Values {
+			storedList.Append(string(value))
+		}
+	}
+
+	for _, set := range snapshot.Sets {
+		storedSet, ok := restored.sets[string(set.Key)]
+		if !ok {
+			storedSet = hashset.New()
+			restored.sets[string(set.Key)] = storedSet
+		}
+		for _, member := range set.Members {
+			storedSet.Add(string(member))
+		}
+	}
+
+	for _, hash := range snapshot.Hashes {
+		storedMap, ok := restored.hashes[string(hash.Key)]
+		if !ok {
+			storedMap = hashmap.New()
+			restored.hashes[string(hash.Key)] = storedMap
+		}
+		for _, field := range hash.Fields {
+			storedMap.Put(string(field.Key), string(field.Value))
+		}
+	}
+
+	for _, collection := range snapshot.Collections {
+		if err := restored.restoreCollection(collection); err != nil {
+			return fmt.Errorf("restoring collection %s: %v", string(collection.Name), err)
+		}
+	}
+
+	for _, vector := range snapshot.Vectors {
+		graph, ok := restored.vectors[string(vector.Name)]
+		if !ok {
+			graph = newSnapshotVector(vector)
+			restored.vectors[string(vector.Name)] = graph
+			sr.entryPoints[string(vector.Name)] = vector.EntryPoint
+		}
+		restoreVectorNodes(graph, vector.Nodes)
+	}
+
+	for _, expiry := range snapshot.Expiry {
+		restored.expiry[string(expiry.Key)] = time.Unix(0, expiry.UnixNano)
+	}
+	return nil
+}
+
+// restoreCollection creates the collection when it does not exist yet and inserts the documents again, which rebuilds the indices
+func (ts *TredsStore) restoreCollection(snapshot *kvstore.Collection) error {
+	name := string(snapshot.Name)
+	if _, ok := ts.collections[name]; !ok {
+		indices := make([]map[string]interface{}, 0, len(snapshot.Indices))
+		for _, index := range snapshot.Indices {
+			indexType := "normal"
+			if index.Unique {
+				indexType = Unique
+			}
+			indices = append(indices, map[string]interface{}{"fields": toStrings(index.Fields), "type": indexType})
+		}
+		indexJson, err := json.Marshal(indices)
+		if err != nil {
+			return err
+		}
+		if err = ts.DCreateCollection([]string{name, snapshot.Schema, string(indexJson)}); err != nil {
+			return err
+		}
+	}
+	for _, document := range snapshot.Documents {
+		if _, err := ts.DInsert([]string{name, document.Data, string(document.Id)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newSnapshotVector(snapshot *kvstore.Vector) *hnsw.HNSW {
+	graph := hnsw.NewHNSW(int(snapshot.MaxNeighbors), snapshot.LayerFactor, int(snapshot.EfSearch), hnsw.EuclideanDistance)
+	graph.MaxNeighbors0 = int(snapshot.MaxNeighbors0)
+	for len(graph.Layers) < int(snapshot.Layers) {
+		graph.Layers = append(graph.Layers, &hnsw.GraphLayer{Nodes: make(map[string]*hnsw.Node)})
+	}
+	return graph
+}
+
+func restoreVectorNodes(graph *hnsw.HNSW, nodes []*kvstore.VectorNode) {
+	for _, snapshotNode := range nodes {
+		node := &hnsw.Node{
+			ID:        snapshotNode.Id,
+			Layer:     int(snapshotNode.Layer),
+			Neighbors: snapshotNode.Neighbors,
+			Value:     snapshotNode.Value,
+		}
+		if node.Neighbors == nil {
+			node.Neighbors = make(map[string]float64)
+		}
+		for layer := 0; layer <= node.Layer; layer++ {
+			for len(graph.Layers) <= layer {
+				graph.Layers = append(graph.Layers, &hnsw.GraphLayer{Nodes: make(map[string]*hnsw.Node)})
+			}
+			graph.Layers[layer].Nodes[node.ID] = node
+		}
+	}
+}
+
+func toBytes(values []string) [][]byte {
+	converted := make([][]byte, 0, len(values))
+	for _, value := range values {
+		converted = append(converted, []byte(value))
+	}
+	return converted
+}
+
+func toStrings(values [][]byte) []string {
+	converted := make([]string, 0, len(values))
+	for _, value := range values {
+		converted = append(converted, string(value))
+	}
+	return converted
+}
+
+// sortedKeys returns the keys of a map in ascending order, so snapshots of the same state are identical
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
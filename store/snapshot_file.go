@@ -0,0 +1,245 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// A snapshot file starts with a header and is followed by a frame for every chunk and a trailer.
+//
+//	header:  magic "TREDSNAP" | version uint32 | compression uint8 | CRC of the previous fields uint32
+//	frame:   length uint32 | CRC of the payload uint32 | payload, the compressed chunk
+//	trailer: length 0 uint32 | number of frames uint64
+//
+// Integers are big endian and the CRCs are CRC-32C. The trailer tells a complete file from a truncated one.
+const snapshotMagic = "TREDSNAP"
+
+const snapshotHeaderSize = len(snapshotMagic) + 4 + 1 + 4
+
+// maxSnapshotFrameSize bounds the payload of a frame and the chunk it holds once decompressed,
+// so a corrupted length cannot make the reader allocate gigabytes before the checksum is verified
+const maxSnapshotFrameSize = 1 << 30
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrSnapshotCorrupted is returned when a snapshot file does not match its checksums or is truncated
+var ErrSnapshotCorrupted = errors.New("snapshot is corrupted")
+
+// SnapshotCompression is the algorithm the chunks of a snapshot are compressed with
+type SnapshotCompression uint8
+
+const (
+	CompressionNone SnapshotCompression = iota
+	CompressionSnappy
+	CompressionZstd
+)
+
+var compressionNames = map[SnapshotCompression]string{
+	CompressionNone:   "none",
+	CompressionSnappy: "snappy",
+	CompressionZstd:   "zstd",
+}
+
+func (c SnapshotCompression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(c))
+}
+
+// ParseSnapshotCompression returns the compression named none, snappy or zstd
+func ParseSnapshotCompression(name string) (SnapshotCompression, error) {
+	for compression, compressionName := range compressionNames {
+		if strings.EqualFold(name, compressionName) {
+			return compression, nil
+		}
+	}
+	return CompressionNone, fmt.Errorf("unknown snapshot compression %s, expected none, snappy or zstd", name)
+}
+
+// snapshotFrameWriter writes the header, a frame for every chunk and the trailer of a snapshot file
+type snapshotFrameWriter struct {
+	w           io.Writer
+	compression SnapshotCompression
+	encoder     *zstd.Encoder
+	frames      uint64
+}
+
+func newSnapshotFrameWriter(w io.Writer, compression SnapshotCompression) (*snapshotFrameWriter, error) {
+	fw := &snapshotFrameWriter{w: w, compression: compression}
+	switch compression {
+	case CompressionNone, CompressionSnappy:
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		fw.encoder = encoder
+	default:
+		return nil, fmt.Errorf("unknown snapshot compression %d", compression)
+	}
+
+	header := make([]byte, 0, snapshotHeaderSize)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint32(header, SnapshotVersion)
+	header = append(header, byte(compression))
+	header = binary.BigEndian.AppendUint32(header, crc32.Checksum(header, crcTable))
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+func (fw *snapshotFrameWriter) writeFrame(chunk []byte) error {
+	if len(chunk) > maxSnapshotFrameSize {
+		return fmt.Errorf("snapshot chunk of %d bytes is larger than the maximum of %d bytes", len(chunk), maxSnapshotFrameSize)
+	}
+	var payload []byte
+	switch fw.compression {
+	case CompressionSnappy:
+		payload = snappy.Encode(nil, chunk)
+	case CompressionZstd:
+		payload = fw.encoder.EncodeAll(chunk, nil)
+	default:
+		payload = chunk
+	}
+	if len(payload) > maxSnapshotFrameSize {
+		return fmt.Errorf("snapshot frame of %d bytes is larger than the maximum of %d bytes", len(payload), maxSnapshotFrameSize)
+	}
+	frame := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	if _, err := fw.w.Write(append(frame, payload...)); err != nil {
+		return err
+	}
+	fw.frames++
+	return nil
+}
+
+// close writes the trailer, the file is only complete after it
+func (fw *snapshotFrameWriter) close() error {
+	if fw.encoder != nil {
+		_ = fw.encoder.Close()
+	}
+	trailer := make([]byte, 12)
+	binary.BigEndian.PutUint64(trailer[4:], fw.frames)
+	_, err := fw.w.Write(trailer)
+	return err
+}
+
+// snapshotFrameReader checks the header of a snapshot file and returns the chunks of its frames
+type snapshotFrameReader struct {
+	r           io.Reader
+	version     uint32
+	compression SnapshotCompression
+	decoder     *zstd.Decoder
+	frames      uint64
+}
+
+func newSnapshotFrameReader(r io.Reader) (*snapshotFrameReader, error) {
+	header := make([]byte, snapshotHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: reading the header: %v", ErrSnapshotCorrupted, err)
+	}
+	checksum := binary.BigEndian.Uint32(header[snapshotHeaderSize-4:])
+	if crc32.Checksum(header[:snapshotHeaderSize-4], crcTable) != checksum {
+		return nil, fmt.Errorf("%w: the header does not match its checksum", ErrSnapshotCorrupted)
+	}
+	fr := &snapshotFrameReader{
+		r:           r,
+		version:     binary.BigEndian.Uint32(header[len(snapshotMagic):]),
+		compression: SnapshotCompression(header[len(snapshotMagic)+4]),
+	}
+	if fr.version > SnapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is newer than the supported version %d", fr.version, SnapshotVersion)
+	}
+	switch fr.compression {
+	case CompressionNone, CompressionSnappy:
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxSnapshotFrameSize))
+		if err != nil {
+			return nil, err
+		}
+		fr.decoder = decoder
+	default:
+		return nil, fmt.Errorf("unknown snapshot compression %d", fr.compression)
+	}
+	return fr, nil
+}
+
+// next returns the chunk of the next frame, or io.EOF once the trailer was read
+func (fr *snapshotFrameReader) next() ([]byte, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(fr.r, prefix); err != nil {
+		return nil, fmt.Errorf("%w: reading frame %d: %v", ErrSnapshotCorrupted, fr.frames, err)
+	}
+	length := binary.BigEndian.Uint32(prefix[0:4])
+	if length == 0 {
+		// The trailer, the last 4 bytes of the prefix are the first half of the number of frames
+		count := make([]byte, 4)
+		if _, err := io.ReadFull(fr.r, count); err != nil {
+			return nil, fmt.Errorf("%w: reading the trailer: %v", ErrSnapshotCorrupted, err)
+		}
+		frames := binary.BigEndian.Uint64(append(prefix[4:8], count...))
+		if frames != fr.frames {
+			return nil, fmt.Errorf("%w: expected %d frames, read %d", ErrSnapshotCorrupted, frames, fr.frames)
+		}
+		return nil, io.EOF
+	}
+
+	if length > maxSnapshotFrameSize {
+		return nil, fmt.Errorf("%w: frame %d has %d bytes, more than the maximum of %d bytes", ErrSnapshotCorrupted, fr.frames, length, maxSnapshotFrameSize)
+	}
+	// The buffer grows with the bytes read rather than the length, which is not verified yet,
+	// so a truncated file fails once its bytes run out
+	var buffer bytes.Buffer
+	if _, err := io.CopyN(&buffer, fr.r, int64(length)); err != nil {
+		return nil, fmt.Errorf("%w: reading frame %d: %v", ErrSnapshotCorrupted, fr.frames, err)
+	}
+	payload := buffer.Bytes()
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(prefix[4:8]) {
+		return nil, fmt.Errorf("%w: frame %d does not match its checksum", ErrSnapshotCorrupted, fr.frames)
+	}
+	fr.frames++
+	switch fr.compression {
+	case CompressionSnappy:
+		if decodedLength, err := snappy.DecodedLen(payload); err != nil || decodedLength > maxSnapshotFrameSize {
+			return nil, fmt.Errorf("%w: frame %d does not decompress to a chunk of at most %d bytes", ErrSnapshotCorrupted, fr.frames-1, maxSnapshotFrameSize)
+		}
+		chunk, err := snappy.Decode(nil, payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decompressing frame %d: %v", ErrSnapshotCorrupted, fr.frames-1, err)
+		}
+		return chunk, nil
+	case CompressionZstd:
+		chunk, err := fr.decoder.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decompressing frame %d: %v", ErrSnapshotCorrupted, fr.frames-1, err)
+		}
+		return chunk, nil
+	default:
+		return payload, nil
+	}
+}
+
+// close releases the decoder, the reader is not closed
+func (fr *snapshotFrameReader) close() {
+	if fr.decoder != nil {
+		fr.decoder.Close()
+	}
+}
+
+// peekSnapshotMagic reports whether reader is at the start of a snapshot written in the given format
+func peekSnapshotMagic(reader *bufio.Reader, magic string) bool {
+	peeked, err := reader.Peek(len(magic))
+	return err == nil && string(peeked) == magic
+}
@@ -1,6 +1,9 @@
 package store
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 type Store interface {
 	Get(string) (string, error)
@@ -60,15 +63,16 @@ type Store interface {
 	Expire(key string, at time.Time) error
 	Ttl(key string) int
 	LongestPrefix(string) ([]string, error)
-	Snapshot() ([]byte, error)
-	Restore([]byte) error
+	Snapshot() (*PointInTimeSnapshot, error)
+	Restore(io.Reader) error
 	DCreateCollection([]string) error
 	DDropCollection([]string) error
 	DInsert([]string) (string, error)
 	DQuery([]string) ([]string, error)
 	DExplain([]string) (string, error)
 	VCreate([]string) error
+	VNextNode(string) (string, int, error)
 	VInsert([]string) (string, error)
-	VSearch([]string) ([][]string, error)
+	VSearch([]string) ([]VectorSearchResult, error)
 	VDelete([]string) (bool, error)
 }
@@ -17,13 +17,11 @@ import (
 	"github.com/absolutelightning/gods/maps/treemap"
 	"github.com/absolutelightning/gods/sets/hashset"
 	"github.com/absolutelightning/gods/utils"
-	"github.com/golang/protobuf/proto"
 	"github.com/google/uuid"
 	"github.com/tidwall/gjson"
 	"golang.org/x/sync/errgroup"
 	"treds/datastructures/hnsw"
 	radix_tree "treds/datastructures/radix"
-	kvstore "treds/store/proto"
 )
 
 const NilResp = "(nil)"
@@ -97,6 +95,13 @@ type Collection struct {
 	DocumentIdIndex map[string]map[string]struct{}
 }
 
+// VectorSearchResult is a vector found by VSearch and its distance to the searched vector
+type VectorSearchResult struct {
+	ID       string
+	Distance float64
+	Vector   []float64
+}
+
 type TredsStore struct {
 	// Key Value Store
 	tree *radix_tree.Tree
@@ -123,6 +128,15 @@ type TredsStore struct {
 
 	// Expiry
 	expiry map[string]time.Time
+
+	// Keys whose containers were copied or created since the last snapshot, by store, nil before the first snapshot.
+	// The containers of the other keys may be shared with a snapshot, they are copied before they are changed.
+	ownedScores      map[string]struct{}
+	ownedLists       map[string]struct{}
+	ownedSets        map[string]struct{}
+	ownedHashes      map[string]struct{}
+	ownedCollections map[string]struct{}
+	ownedVectors     map[string]struct{}
 }
 
 func NewTredsStore() *TredsStore {
@@ -157,7 +171,19 @@ func (ts *TredsStore) hasExpired(key string) bool {
 	return expired
 }
 
+// getKeyDetails returns the store of a key, -1 when it does not exist or has expired. Reads call it and run concurrently
+// under the read lock of the FSM, so it does not delete an expired key: its values stay in the stores until a write
+// or the cleanup deletes them, and readers treat the key as missing.
 func (ts *TredsStore) getKeyDetails(key string) Type {
+	if ts.hasExpired(key) {
+		return -1
+	}
+	return ts.getKeyStore(key)
+}
+
+// getKeyDetailsForWrite returns the store of a key like getKeyDetails, an expired key is deleted first
+// so the write replaces it. Writes call it, they are applied alone.
+func (ts *TredsStore) getKeyDetailsForWrite(key string) Type {
 	if ts.hasExpired(key) {
 		_ = ts.Delete(key)
 		return -1
@@ -198,31 +224,20 @@ func (ts *TredsStore) Get(k string) (string, error) {
 }
 
 func (ts *TredsStore) MSet(kvs []string) error {
-	args := strings.Join(kvs, " ")
-	keyValues, err := splitCommandWithQuotes(args)
-	if err != nil {
-		return err
+	if len(kvs)%2 != 0 {
+		return fmt.Errorf("wrong number of arguments for key value pairs")
 	}
-	var g errgroup.Group
-	var mu sync.Mutex
-	for itr := 0; itr < len(keyValues); itr += 2 {
-		g.Go(func() error {
-			mu.Lock()
-			validKey := validateKey(keyValues[itr])
-			if !validKey {
-				return fmt.Errorf("invalid key: %s", keyValues[itr])
-			}
-			err = ts.Set(keyValues[itr], keyValues[itr+1])
-			mu.Unlock()
-			if err != nil {
-				return err
-			}
-			return nil
-		})
+	for itr := 0; itr < len(kvs); itr += 2 {
+		validKey := validateKey(kvs[itr])
+		if !validKey {
+			return fmt.Errorf("invalid key: %s", kvs[itr])
+		}
 	}
-
-	if err := g.Wait(); err != nil {
-		return err
+	for itr := 0; itr < len(kvs); itr += 2 {
+		err := ts.Set(kvs[itr], kvs[itr+1])
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -257,19 +272,16 @@ func (ts *TredsStore) MGet(args []string) ([]string, error) {
 }
 
 func (ts *TredsStore) Set(k string, v string) error {
-	kd := ts.getKeyDetails(k)
+	kd := ts.getKeyDetailsForWrite(k)
 	if kd != -1 && kd != KeyValueStore {
 		return fmt.Errorf("not key value store")
 	}
 	validKey := validateKey(k)
 	if !validKey {
-		return fmt.Errorf("invalid key: %s", v)
-	}
-	parsedArgs, err := splitCommandWithQuotes(v)
-	if err != nil {
-		return err
+		return fmt.Errorf("invalid key: %s", k)
 	}
-	ts.tree, _, _ = ts.tree.Insert([]byte(k), parsedArgs[0])
+	// Values are binary safe and stored exactly as they were received
+	ts.tree, _, _ = ts.tree.Insert([]byte(k), v)
 	return nil
 }
 
@@ -757,14 +769,12 @@ func (ts *TredsStore) Size() (int, error) {
 }
 
 func (ts *TredsStore) ZAdd(args []string) error {
-	kd := ts.getKeyDetails(args[0])
+	kd := ts.getKeyDetailsForWrite(args[0])
 	if kd != -1 && kd != SortedMapStore {
 		return fmt.Errorf("not sorted map store")
 	}
-	parsedArgs, pErr := splitCommandWithQuotes(strings.Join(args[1:], " "))
-	if pErr != nil {
-		return pErr
-	}
+	// Every argument is an element as it was sent, values are stored byte for byte
+	parsedArgs := args[1:]
 	validKey := validateKey(args[0])
 	if !validKey {
 		return fmt.Errorf("invalid key")
@@ -774,7 +784,7 @@ func (ts *TredsStore) ZAdd(args []string) error {
 		tm = storedTm
 	}
 	sm := make(map[string]float64)
-	if storedSm, ok := ts.sortedMapsScore[args[0]]; ok {
+	if storedSm, ok := ts.scoresForWrite(args[0]); ok {
 		sm = storedSm
 	}
 	sortedKeyMap, ok := ts.sortedMapsKeys[args[0]]
@@ -792,39 +802,7 @@ func (ts *TredsStore) ZAdd(args []string) error {
 		if err != nil {
 			return err
 		}
-		sm[parsedArgs[itr+1]] = score
-		radixTree := radix_tree.New()
-		storedRadixTree, found := tm.Get(score)
-		if found {
-			radixTree = storedRadixTree.(*radix_tree.Tree)
-		}
-		sortedKeyMap, _, _ = sortedKeyMap.Insert([]byte(parsedArgs[itr+1]), parsedArgs[itr+2])
-		radixTree, _, _ = radixTree.Insert([]byte(parsedArgs[itr+1]), parsedArgs[itr+2])
-		tm.Put(score, radixTree)
-		_, radixTreeFloor := tm.Lower(score)
-		if radixTreeFloor != nil {
-			tree := radixTreeFloor.(*radix_tree.Tree)
-			maxLeaf, foundMaxLeaf := tree.Root().MaximumLeaf()
-			minLeaf, foundMinLeaf := radixTree.Root().MinimumLeaf()
-			if foundMaxLeaf {
-				maxLeaf.SetNextLeaf(minLeaf)
-			}
-			if foundMinLeaf {
-				minLeaf.SetPrevLeaf(maxLeaf)
-			}
-		}
-		_, radixTreeCeiling := tm.Greater(score)
-		if radixTreeCeiling != nil {
-			tree := radixTreeCeiling.(*radix_tree.Tree)
-			minLeaf, foundMaxLeaf := tree.Root().MinimumLeaf()
-			maxLeaf, foundMinLeaf := radixTree.Root().MaximumLeaf()
-			if foundMaxLeaf {
-				maxLeaf.SetNextLeaf(minLeaf)
-			}
-			if foundMinLeaf {
-				minLeaf.SetPrevLeaf(maxLeaf)
-			}
-		}
+		sortedKeyMap = addSortedMapMember(tm, sm, sortedKeyMap, score, parsedArgs[itr+1], parsedArgs[itr+2])
 	}
 	ts.sortedMaps[args[0]] = tm
 	ts.sortedMapsScore[args[0]] = sm
@@ -832,8 +810,47 @@ func (ts *TredsStore) ZAdd(args []string) error {
 	return nil
 }
 
+// addSortedMapMember adds member with value at score to a sorted map, linking the leaves of its score
+// to the ones of the neighbouring scores, and returns the updated tree of members
+func addSortedMapMember(tm *treemap.Map, sm map[string]float64, sortedKeyMap *radix_tree.Tree, score float64, member, value string) *radix_tree.Tree {
+	sm[member] = score
+	radixTree := radix_tree.New()
+	storedRadixTree, found := tm.Get(score)
+	if found {
+		radixTree = storedRadixTree.(*radix_tree.Tree)
+	}
+	sortedKeyMap, _, _ = sortedKeyMap.Insert([]byte(member), value)
+	radixTree, _, _ = radixTree.Insert([]byte(member), value)
+	tm.Put(score, radixTree)
+	_, radixTreeFloor := tm.Lower(score)
+	if radixTreeFloor != nil {
+		tree := radixTreeFloor.(*radix_tree.Tree)
+		maxLeaf, foundMaxLeaf := tree.Root().MaximumLeaf()
+		minLeaf, foundMinLeaf := radixTree.Root().MinimumLeaf()
+		if foundMaxLeaf {
+			maxLeaf.SetNextLeaf(minLeaf)
+		}
+		if foundMinLeaf {
+			minLeaf.SetPrevLeaf(maxLeaf)
+		}
+	}
+	_, radixTreeCeiling := tm.Greater(score)
+	if radixTreeCeiling != nil {
+		tree := radixTreeCeiling.(*radix_tree.Tree)
+		minLeaf, foundMaxLeaf := tree.Root().MinimumLeaf()
+		maxLeaf, foundMinLeaf := radixTree.Root().MaximumLeaf()
+		if foundMaxLeaf {
+			maxLeaf.SetNextLeaf(minLeaf)
+		}
+		if foundMinLeaf {
+			minLeaf.SetPrevLeaf(maxLeaf)
+		}
+	}
+	return sortedKeyMap
+}
+
 func (ts *TredsStore) ZRem(args []string) error {
-	kd := ts.getKeyDetails(args[0])
+	kd := ts.getKeyDetailsForWrite(args[0])
 	if kd != -1 && kd != SortedMapStore {
 		return fmt.Errorf("not sorted map store")
 	}
@@ -885,8 +902,9 @@ func (ts *TredsStore) ZRem(args []string) error {
 		}
 	}
 	ts.sortedMaps[args[0]] = storedTm
+	scores, _ := ts.scoresForWrite(args[0])
 	for _, arg := range args[1:] {
-		delete(ts.sortedMapsScore[args[0]], arg)
+		delete(scores, arg)
 		ts.sortedMapsKeys[args[0]], _, _ = ts.sortedMapsKeys[args[0]].Delete([]byte(arg))
 	}
 	return nil
@@ -898,7 +916,7 @@ func (ts *TredsStore) ZRangeByLexKVS(key, cursor, min, max, count string, withSc
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	radixTree, ok := ts.sortedMapsKeys[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	iterator := radixTree.Root().Iterator()
@@ -951,7 +969,7 @@ func (ts *TredsStore) ZRangeByLexKeys(key, cursor, min, max, count string, withS
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	radixTree, ok := ts.sortedMapsKeys[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	iterator := radixTree.Root().Iterator()
@@ -1002,7 +1020,7 @@ func (ts *TredsStore) ZRangeByScoreKVS(key, min, max, offset, count string, with
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	sortedMap := ts.sortedMaps[key]
-	if sortedMap == nil {
+	if sortedMap == nil || ts.hasExpired(key) {
 		return nil, nil
 	}
 	minFloat, err := strconv.ParseFloat(min, 64)
@@ -1065,7 +1083,7 @@ func (ts *TredsStore) ZRangeByScoreKeys(key, min, max, offset, count string, wit
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	sortedMap := ts.sortedMaps[key]
-	if sortedMap == nil {
+	if sortedMap == nil || ts.hasExpired(key) {
 		return nil, nil
 	}
 	minFloat, err := strconv.ParseFloat(min, 64)
@@ -1126,7 +1144,7 @@ func (ts *TredsStore) ZScore(args []string) (string, error) {
 		return "", fmt.Errorf("not sorted map store")
 	}
 	store, ok := ts.sortedMapsScore[args[0]]
-	if !ok {
+	if !ok || ts.hasExpired(args[0]) {
 		return "", nil
 	}
 	if score, found := store[args[1]]; found {
@@ -1141,7 +1159,7 @@ func (ts *TredsStore) ZCard(key string) (int, error) {
 		return 0, fmt.Errorf("not sorted map store")
 	}
 	store, ok := ts.sortedMapsKeys[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return 0, nil
 	}
 	return store.Len(), nil
@@ -1153,7 +1171,7 @@ func (ts *TredsStore) ZRevRangeByLexKVS(key, cursor, min, max, count string, wit
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	radixTree, ok := ts.sortedMapsKeys[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	iterator := radixTree.Root().ReverseIterator()
@@ -1200,7 +1218,7 @@ func (ts *TredsStore) ZRevRangeByLexKeys(key, cursor, min, max, count string, wi
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	radixTree, ok := ts.sortedMapsKeys[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	iterator := radixTree.Root().ReverseIterator()
@@ -1248,7 +1266,7 @@ func (ts *TredsStore) ZRevRangeByScoreKVS(key, min, max, offset, count string, w
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	sortedMap := ts.sortedMaps[key]
-	if sortedMap == nil {
+	if sortedMap == nil || ts.hasExpired(key) {
 		return nil, nil
 	}
 	minFloat, err := strconv.ParseFloat(min, 64)
@@ -1310,7 +1328,7 @@ func (ts *TredsStore) ZRevRangeByScoreKeys(key, min, max, offset, count string,
 		return nil, fmt.Errorf("not sorted map store")
 	}
 	sortedMap := ts.sortedMaps[key]
-	if sortedMap == nil {
+	if sortedMap == nil || ts.hasExpired(key) {
 		return nil, nil
 	}
 	minFloat, err := strconv.ParseFloat(min, 64)
@@ -1379,7 +1397,7 @@ func (ts *TredsStore) FlushAll() error {
 
 func (ts *TredsStore) LPush(args []string) error {
 	key := args[0]
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != ListStore {
 		return fmt.Errorf("not list store")
 	}
@@ -1387,14 +1405,11 @@ func (ts *TredsStore) LPush(args []string) error {
 	if !validKey {
 		return fmt.Errorf("invalid key")
 	}
-	storedList, ok := ts.lists[key]
+	storedList, ok := ts.listForWrite(key)
 	if !ok {
 		storedList = doublylinkedlist.New()
 	}
-	parsedArgs, err := splitCommandWithQuotes(strings.Join(args[1:], " "))
-	if err != nil {
-		return err
-	}
+	parsedArgs := args[1:]
 	for _, arg := range parsedArgs {
 		storedList.Prepend(arg)
 	}
@@ -1404,7 +1419,7 @@ func (ts *TredsStore) LPush(args []string) error {
 
 func (ts *TredsStore) RPush(args []string) error {
 	key := args[0]
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != ListStore {
 		return fmt.Errorf("not list store")
 	}
@@ -1412,14 +1427,11 @@ func (ts *TredsStore) RPush(args []string) error {
 	if !validKey {
 		return fmt.Errorf("invalid key")
 	}
-	storedList, ok := ts.lists[key]
+	storedList, ok := ts.listForWrite(key)
 	if !ok {
 		storedList = doublylinkedlist.New()
 	}
-	parsedArgs, err := splitCommandWithQuotes(strings.Join(args[1:], " "))
-	if err != nil {
-		return err
-	}
+	parsedArgs := args[1:]
 	for _, arg := range parsedArgs {
 		storedList.Append(arg)
 	}
@@ -1434,7 +1446,7 @@ func (ts *TredsStore) LIndex(args []string) (string, error) {
 		return "", fmt.Errorf("not list store")
 	}
 	storedList, ok := ts.lists[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return "", nil
 	}
 	index, err := strconv.Atoi(args[1])
@@ -1457,7 +1469,7 @@ func (ts *TredsStore) LLen(key string) (int, error) {
 		return 0, fmt.Errorf("not list store")
 	}
 	storedList, ok := ts.lists[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return 0, nil
 	}
 	return storedList.Size(), nil
@@ -1469,7 +1481,7 @@ func (ts *TredsStore) LRange(key string, start, stop int) ([]string, error) {
 		return nil, fmt.Errorf("not list store")
 	}
 	storedList, ok := ts.lists[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	if start < 0 {
@@ -1490,11 +1502,11 @@ func (ts *TredsStore) LRange(key string, start, stop int) ([]string, error) {
 }
 
 func (ts *TredsStore) LSet(key string, index int, element string) error {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != ListStore {
 		return fmt.Errorf("not list store")
 	}
-	storedList, ok := ts.lists[key]
+	storedList, ok := ts.listForWrite(key)
 	if !ok {
 		return nil
 	}
@@ -1506,11 +1518,11 @@ func (ts *TredsStore) LSet(key string, index int, element string) error {
 }
 
 func (ts *TredsStore) LRem(key string, index int) error {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != ListStore {
 		return fmt.Errorf("not list store")
 	}
-	storedList, ok := ts.lists[key]
+	storedList, ok := ts.listForWrite(key)
 	if !ok {
 		return nil
 	}
@@ -1522,12 +1534,12 @@ func (ts *TredsStore) LRem(key string, index int) error {
 }
 
 func (ts *TredsStore) LPop(key string, count int) ([]string, error) {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != ListStore {
 		return nil, fmt.Errorf("not list store")
 	}
 	res := make([]string, 0)
-	storedList, ok := ts.lists[key]
+	storedList, ok := ts.listForWrite(key)
 	if !ok {
 		return nil, nil
 	}
@@ -1545,12 +1557,12 @@ func (ts *TredsStore) LPop(key string, count int) ([]string, error) {
 }
 
 func (ts *TredsStore) RPop(key string, count int) ([]string, error) {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != ListStore {
 		return nil, fmt.Errorf("not list store")
 	}
 	res := make([]string, 0)
-	storedList, ok := ts.lists[key]
+	storedList, ok := ts.listForWrite(key)
 	if !ok {
 		return nil, nil
 	}
@@ -1570,7 +1582,7 @@ func (ts *TredsStore) RPop(key string, count int) ([]string, error) {
 }
 
 func (ts *TredsStore) SAdd(key string, members []string) error {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != SetStore {
 		return fmt.Errorf("not set store")
 	}
@@ -1578,11 +1590,8 @@ func (ts *TredsStore) SAdd(key string, members []string) error {
 	if !validKey {
 		return fmt.Errorf("invalid key")
 	}
-	parsedArgs, err := splitCommandWithQuotes(strings.Join(members, " "))
-	if err != nil {
-		return err
-	}
-	storedSet, ok := ts.sets[key]
+	parsedArgs := members
+	storedSet, ok := ts.setForWrite(key)
 	if !ok {
 		storedSet = hashset.New()
 		ts.sets[key] = storedSet
@@ -1594,15 +1603,12 @@ func (ts *TredsStore) SAdd(key string, members []string) error {
 }
 
 func (ts *TredsStore) SRem(key string, members []string) error {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != SetStore {
 		return fmt.Errorf("not set store")
 	}
-	parsedArgs, err := splitCommandWithQuotes(strings.Join(members, " "))
-	if err != nil {
-		return err
-	}
-	storedSet, ok := ts.sets[key]
+	parsedArgs := members
+	storedSet, ok := ts.setForWrite(key)
 	if !ok {
 		return nil
 	}
@@ -1618,7 +1624,7 @@ func (ts *TredsStore) SMembers(key string) ([]string, error) {
 		return nil, fmt.Errorf("not set store")
 	}
 	storedSet, ok := ts.sets[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	res := make([]string, 0)
@@ -1635,7 +1641,7 @@ func (ts *TredsStore) SIsMember(key string, member string) (bool, error) {
 		return false, fmt.Errorf("not set store")
 	}
 	storedSet, ok := ts.sets[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return false, nil
 	}
 	return storedSet.Contains(member), nil
@@ -1647,7 +1653,7 @@ func (ts *TredsStore) SCard(key string) (int, error) {
 		return 0, fmt.Errorf("not set store")
 	}
 	storedSet, ok := ts.sets[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return 0, nil
 	}
 	return storedSet.Size(), nil
@@ -1663,7 +1669,7 @@ func (ts *TredsStore) SUnion(keys []string) ([]string, error) {
 	unionSet := hashset.New()
 	for _, key := range keys {
 		storedSet, ok := ts.sets[key]
-		if !ok {
+		if !ok || ts.hasExpired(key) {
 			continue
 		}
 		unionSet = unionSet.Union(storedSet)
@@ -1686,7 +1692,7 @@ func (ts *TredsStore) SInter(keys []string) ([]string, error) {
 	intersectionSet := hashset.New()
 	for _, key := range keys {
 		storedSet, ok := ts.sets[key]
-		if !ok {
+		if !ok || ts.hasExpired(key) {
 			continue
 		}
 		intersectionSet = storedSet
@@ -1694,7 +1700,7 @@ func (ts *TredsStore) SInter(keys []string) ([]string, error) {
 	}
 	for _, key := range keys {
 		storedSet, ok := ts.sets[key]
-		if !ok {
+		if !ok || ts.hasExpired(key) {
 			continue
 		}
 		intersectionSet = intersectionSet.Intersection(storedSet)
@@ -1720,7 +1726,7 @@ func (ts *TredsStore) SDiff(keys []string) ([]string, error) {
 	}
 	for _, key := range keys[1:] {
 		storedSet, found := ts.sets[key]
-		if !found {
+		if !found || ts.hasExpired(key) {
 			continue
 		}
 		diffSet = diffSet.Difference(storedSet)
@@ -1734,7 +1740,7 @@ func (ts *TredsStore) SDiff(keys []string) ([]string, error) {
 }
 
 func (ts *TredsStore) HSet(key string, args []string) error {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != HashStore {
 		return fmt.Errorf("not hash store")
 	}
@@ -1742,16 +1748,13 @@ func (ts *TredsStore) HSet(key string, args []string) error {
 	if !validKey {
 		return fmt.Errorf("invalid key")
 	}
-	storedMap, ok := ts.hashes[key]
+	storedMap, ok := ts.hashForWrite(key)
 	if !ok {
 		storedMap = hashmap.New()
 		ts.hashes[key] = storedMap
 	}
-	parsedArgs, err := splitCommandWithQuotes(strings.Join(args, " "))
-	if err != nil {
-		return err
-	}
-	for iter := 0; iter < storedMap.Size(); iter += 2 {
+	parsedArgs := args
+	for iter := 0; iter < len(parsedArgs); iter += 2 {
 		validKey = validateKey(parsedArgs[iter])
 		if !validKey {
 			return fmt.Errorf("invalid key")
@@ -1769,7 +1772,7 @@ func (ts *TredsStore) HGet(key string, field string) (string, error) {
 		return "", fmt.Errorf("not hash store")
 	}
 	storedMap, ok := ts.hashes[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return NilResp, nil
 	}
 	val, found := storedMap.Get(field)
@@ -1785,7 +1788,7 @@ func (ts *TredsStore) HGetAll(key string) ([]string, error) {
 		return nil, fmt.Errorf("not hash store")
 	}
 	storedMap, ok := ts.hashes[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	res := make([]string, 0)
@@ -1802,18 +1805,18 @@ func (ts *TredsStore) HLen(key string) (int, error) {
 		return 0, fmt.Errorf("not hash store")
 	}
 	storedMap, ok := ts.hashes[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return 0, nil
 	}
 	return storedMap.Size(), nil
 }
 
 func (ts *TredsStore) HDel(key string, fields []string) error {
-	kd := ts.getKeyDetails(key)
+	kd := ts.getKeyDetailsForWrite(key)
 	if kd != -1 && kd != HashStore {
 		return fmt.Errorf("not hash store")
 	}
-	storedMap, ok := ts.hashes[key]
+	storedMap, ok := ts.hashForWrite(key)
 	if !ok {
 		return nil
 	}
@@ -1829,7 +1832,7 @@ func (ts *TredsStore) HExists(key string, field string) (bool, error) {
 		return false, fmt.Errorf("not hash store")
 	}
 	storedMap, ok := ts.hashes[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return false, nil
 	}
 	_, found := storedMap.Get(field)
@@ -1842,7 +1845,7 @@ func (ts *TredsStore) HKeys(key string) ([]string, error) {
 		return nil, fmt.Errorf("not hash store")
 	}
 	storedMap, ok := ts.hashes[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	fields := storedMap.Keys()
@@ -1859,7 +1862,7 @@ func (ts *TredsStore) HVals(key string) ([]string, error) {
 		return nil, fmt.Errorf("not hash store")
 	}
 	storedMap, ok := ts.hashes[key]
-	if !ok {
+	if !ok || ts.hasExpired(key) {
 		return nil, nil
 	}
 	fields := storedMap.Values()
@@ -1904,51 +1907,6 @@ func convertToString(value interface{}) (string, error) {
 	return str, nil
 }
 
-func (ts *TredsStore) Snapshot() ([]byte, error) {
-	// For now just persisting the root level key value store
-	// That is tree *radix_tree.Tree in the Store
-	store := &kvstore.KeyValueStore{
-		Pairs: make([]*kvstore.KeyValue, 0),
-	}
-	minLeaf, found := ts.tree.Root().MinimumLeaf()
-	if !found {
-		return []byte{}, nil
-	}
-	for minLeaf != nil {
-		value := minLeaf.Value()
-		valueString, err := convertToString(value)
-		if err != nil {
-			return nil, err
-		}
-		store.Pairs = append(store.Pairs, &kvstore.KeyValue{
-			Key:   string(minLeaf.Key()),
-			Value: valueString,
-		})
-		minLeaf = minLeaf.GetNextLeaf()
-	}
-	data, err := proto.Marshal(store)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
-}
-
-func (ts *TredsStore) Restore(data []byte) error {
-	var deserializedStore kvstore.KeyValueStore
-	err := proto.Unmarshal(data, &deserializedStore)
-	if err != nil {
-		fmt.Println("Error deserializing KeyValueStore:", err)
-		return err
-	}
-	// Print the deserialized key-value pairs
-	ts.tree = radix_tree.New()
-	fmt.Println("Deserialized KeyValueStore:")
-	for _, pair := range deserializedStore.Pairs {
-		ts.tree, _, _ = ts.tree.Insert([]byte(pair.Key), pair.Value)
-	}
-	return nil
-}
-
 func (ts *TredsStore) DCreateCollection(args []string) error {
 	collectionName := args[0]
 	_, found := ts.collections[collectionName]
@@ -2012,14 +1970,20 @@ func (ts *TredsStore) DDropCollection(args []string) error {
 	return nil
 }
 
+// DInsert inserts the json document args[1] into the collection args[0].
+// args[2] is the id of the document, a new one is generated when it is missing.
 func (ts *TredsStore) DInsert(args []string) (string, error) {
 	collectionName := args[0]
-	collection, foundCollection := ts.collections[collectionName]
+	collection, foundCollection := ts.collectionForWrite(collectionName)
 	if !foundCollection {
 		return "", fmt.Errorf("collection not found")
 	}
+	id := uuid.New().String()
+	if len(args) > 2 {
+		id = args[2]
+	}
 	document := &Document{
-		Id:         uuid.New().String(),
+		Id:         id,
 		StringData: "",
 		Fields:     make(map[string]interface{}),
 	}
@@ -2178,15 +2142,22 @@ func (ts *TredsStore) DQuery(query []string) ([]string, error) {
 	return jsonDocuments, nil
 }
 
+// Defaults of the HNSW parameters when VCREATE is called without them
+const (
+	DefaultMaxNeighbor = 6
+	DefaultLevelFactor = 0.5
+	DefaultEfSearch    = 20
+)
+
 func (ts *TredsStore) VCreate(args []string) error {
 	vectorName := args[0]
 	_, found := ts.vectors[vectorName]
 	if found {
 		return fmt.Errorf("vector already exists")
 	}
-	maxNeighbor := 6
-	levelFactor := 0.5
-	efSearch := 20
+	maxNeighbor := DefaultMaxNeighbor
+	levelFactor := DefaultLevelFactor
+	efSearch := DefaultEfSearch
 	if len(args) > 1 {
 		maxNeighbor, _ = strconv.Atoi(args[1])
 	}
@@ -2208,24 +2179,40 @@ func (ts *TredsStore) VCreate(args []string) error {
 	return nil
 }
 
+// VNextNode draws the id and the level of the next vector inserted into the vector store vectorName
+func (ts *TredsStore) VNextNode(vectorName string) (string, int, error) {
+	vector, found := ts.vectors[vectorName]
+	if !found {
+		return "", 0, fmt.Errorf("vector not found")
+	}
+	id, level := vector.NextNode()
+	return id, level, nil
+}
+
+// VInsert inserts the vector args[3:] into the vector store args[0] with the id args[1] and the level args[2],
+// both drawn with VNextNode.
 func (ts *TredsStore) VInsert(args []string) (string, error) {
 	vectorName := args[0]
-	vector, found := ts.vectors[vectorName]
+	vector, found := ts.vectorForWrite(vectorName)
 	if !found {
 		return "", fmt.Errorf("vector not found")
 	}
+	level, err := strconv.Atoi(args[2])
+	if err != nil {
+		return "", err
+	}
 	vectorData := make([]float64, 0)
-	for _, data := range args[1:] {
-		vectorDataFloat, err := strconv.ParseFloat(data, 64)
-		if err != nil {
-			return "", err
+	for _, data := range args[3:] {
+		vectorDataFloat, errParse := strconv.ParseFloat(data, 64)
+		if errParse != nil {
+			return "", errParse
 		}
 		vectorData = append(vectorData, vectorDataFloat)
 	}
-	return vector.Insert(vectorData), nil
+	return vector.InsertNode(args[1], level, vectorData), nil
 }
 
-func (ts *TredsStore) VSearch(args []string) ([][]string, error) {
+func (ts *TredsStore) VSearch(args []string) ([]VectorSearchResult, error) {
 	vectorName := args[0]
 	vector, found := ts.vectors[vectorName]
 	if !found {
@@ -2244,21 +2231,20 @@ func (ts *TredsStore) VSearch(args []string) ([][]string, error) {
 		return nil, err
 	}
 	results := vector.Search(vectorData, k)
-	res := make([][]string, 0)
+	res := make([]VectorSearchResult, 0, len(results))
 	for _, result := range results {
-		resData := make([]string, 0)
-		resData = append(resData, result.ID)
-		for _, vecData := range result.Value {
-			resData = append(resData, strconv.FormatFloat(vecData, 'f', -1, 64))
-		}
-		res = append(res, resData)
+		res = append(res, VectorSearchResult{
+			ID:       result.ID,
+			Distance: vector.DistFunc(vectorData, result.Value),
+			Vector:   result.Value,
+		})
 	}
 	return res, nil
 }
 
 func (ts *TredsStore) VDelete(args []string) (bool, error) {
 	vectorName := args[0]
-	vector, found := ts.vectors[vectorName]
+	vector, found := ts.vectorForWrite(vectorName)
 	if !found {
 		return false, fmt.Errorf("vector not found")
 	}
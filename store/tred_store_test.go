@@ -1,7 +1,21 @@
 package store
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	kvstore "treds/store/proto"
 )
 
 func TestTredsStore_Get(t *testing.T) {
@@ -197,3 +211,719 @@ func TestTredsStore_KVS(t *testing.T) {
 	//	t.Fatalf("expected %s, got %s", expected, result)
 	//}
 }
+
+func TestTredsStore_SetBinaryValue(t *testing.T) {
+	store := NewTredsStore()
+
+	values := []string{
+		"hello world",
+		"\"quoted\" 'value'",
+		"line1\r\nline2\r\n",
+		string([]byte{0x00, 0xff, 0x0d, 0x0a, 0x20}),
+	}
+
+	for _, value := range values {
+		err := store.Set("key1", value)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		got, err := store.Get("key1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got != value {
+			t.Fatalf("expected %q, got %q", value, got)
+		}
+	}
+
+	// Binary values survive a snapshot round trip
+	data := persistSnapshot(t, store)
+	restored := NewTredsStore()
+	if err := restored.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, _ := restored.Get("key1")
+	if got != values[len(values)-1] {
+		t.Fatalf("expected %q, got %q", values[len(values)-1], got)
+	}
+}
+
+func TestTredsStore_MSet(t *testing.T) {
+	store := NewTredsStore()
+
+	err := store.MSet([]string{"key1", "value with spaces", "key2", "value2"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	value, _ := store.Get("key1")
+	if value != "value with spaces" {
+		t.Fatalf("expected %q, got %q", "value with spaces", value)
+	}
+
+	err = store.MSet([]string{"key1", "value1", "key2"})
+	if err == nil {
+		t.Fatalf("expected error for odd number of arguments")
+	}
+}
+
+func TestTredsStore_DInsertWithId(t *testing.T) {
+	store := NewTredsStore()
+	if err := store.DCreateCollection([]string{"users", `{"age": {"type": "float"}}`, `[{"fields": ["age"], "type": "normal"}]`}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	id, err := store.DInsert([]string{"users", `{"age": 30}`, "user-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "user-1" {
+		t.Fatalf("expected id %q, got %q", "user-1", id)
+	}
+	documents, err := store.DQuery([]string{"users", `{"filters": [{"field": "age", "operator": "$eq", "value": 30}]}`})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(documents) != 1 || !strings.Contains(documents[0], `"_id":"user-1"`) {
+		t.Fatalf("expected the document with id user-1, got %v", documents)
+	}
+}
+
+func TestTredsStore_VInsertWithNode(t *testing.T) {
+	store := NewTredsStore()
+	if err := store.VCreate([]string{"points"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	id, level, err := store.VNextNode("points")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	inserted, err := store.VInsert([]string{"points", id, strconv.Itoa(level), "1", "2"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if inserted != id {
+		t.Fatalf("expected id %q, got %q", id, inserted)
+	}
+
+	if _, _, err = store.VNextNode("missing"); err == nil {
+		t.Fatalf("expected error for a missing vector store")
+	}
+}
+
+func persistSnapshot(t *testing.T, store *TredsStore) []byte {
+	return persistCompressedSnapshot(t, store, CompressionNone)
+}
+
+func persistCompressedSnapshot(t *testing.T, store *TredsStore, compression SnapshotCompression) []byte {
+	view, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err = view.Persist(&buf, compression); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTredsStore_SnapshotRestore(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.Set("key1", "value1")
+	if err := store.ZAdd([]string{"board", "2", "bob", "b", "1", "alice", "a", "3", "carol", "c"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_ = store.RPush([]string{"list", "x", "y", "z"})
+	_ = store.SAdd("set", []string{"m1", "m2"})
+	_ = store.HSet("hash", []string{"f1", "v1", "f2", "v2"})
+	_ = store.DCreateCollection([]string{"users", `{"age": {"type": "float"}}`, `[{"fields": ["age"], "type": "normal"}]`})
+	_, _ = store.DInsert([]string{"users", `{"age": 30}`, "user-1"})
+	_, _ = store.DInsert([]string{"users", `{"age": 40}`, "user-2"})
+	_ = store.VCreate([]string{"points"})
+	for i := 0; i < 10; i++ {
+		id, level, _ := store.VNextNode("points")
+		_, _ = store.VInsert([]string{"points", id, strconv.Itoa(level), strconv.Itoa(i), "0"})
+	}
+	expiry := time.Now().Add(time.Hour)
+	_ = store.Expire("key1", expiry)
+
+	data := persistSnapshot(t, store)
+	restored := NewTredsStore()
+	if err := restored.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if value, _ := restored.Get("key1"); value != "value1" {
+		t.Fatalf("expected %q, got %q", "value1", value)
+	}
+	// The scan walks the leaves linked across the scores
+	members, _ := restored.ZRangeByScoreKVS("board", "0", "10", "0", "10", true)
+	expectedMembers := []string{"1", "alice", "a", "2", "bob", "b", "3", "carol", "c"}
+	if !reflect.DeepEqual(members, expectedMembers) {
+		t.Fatalf("expected %v, got %v", expectedMembers, members)
+	}
+	if list, _ := restored.LRange("list", 0, -1); !reflect.DeepEqual(list, []string{"x", "y", "z"}) {
+		t.Fatalf("expected [x y z], got %v", list)
+	}
+	if isMember, _ := restored.SIsMember("set", "m2"); !isMember {
+		t.Fatalf("expected m2 to be a member of the set")
+	}
+	if value, _ := restored.HGet("hash", "f2"); value != "v2" {
+		t.Fatalf("expected %q, got %q", "v2", value)
+	}
+	documents, err := restored.DQuery([]string{"users", `{"filters": [{"field": "age", "operator": "$gt", "value": 35}]}`})
+	if err != nil || len(documents) != 1 || !strings.Contains(documents[0], "user-2") {
+		t.Fatalf("expected the document user-2, got %v %v", documents, err)
+	}
+	original, _ := store.VSearch([]string{"points", "4", "0", "3"})
+	searched, _ := restored.VSearch([]string{"points", "4", "0", "3"})
+	if !reflect.DeepEqual(original, searched) {
+		t.Fatalf("expected %v, got %v", original, searched)
+	}
+	if !restored.expiry["key1"].Equal(expiry) {
+		t.Fatalf("expected expiry %v, got %v", expiry, restored.expiry["key1"])
+	}
+
+	// Snapshots of the same state are identical
+	again := persistSnapshot(t, restored)
+	if !bytes.Equal(data, again) {
+		t.Fatalf("expected the snapshot of the restored store to match the original")
+	}
+}
+
+func TestTredsStore_SnapshotBinaryKeys(t *testing.T) {
+	// Keys, members and fields are stored as they are received and may not be UTF-8
+	binary := "\xff\xfe"
+	store := NewTredsStore()
+	for _, err := range []error{
+		store.Set(binary, "value"),
+		store.ZAdd([]string{"board" + binary, "1", "alice" + binary, "a"}),
+		store.RPush([]string{"list" + binary, "x"}),
+		store.SAdd("set"+binary, []string{"m" + binary}),
+		store.HSet("hash"+binary, []string{"f" + binary, "v"}),
+		store.DCreateCollection([]string{"users" + binary, `{"age": {"type": "float"}}`, `[{"fields": ["age"], "type": "normal"}]`}),
+		store.VCreate([]string{"points" + binary}),
+		store.Expire(binary, time.Now().Add(time.Hour)),
+	} {
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if _, err := store.DInsert([]string{"users" + binary, `{"age": 30}`, "user-1"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	id, level, _ := store.VNextNode("points" + binary)
+	if _, err := store.VInsert([]string{"points" + binary, id, strconv.Itoa(level), "1", "0"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := persistSnapshot(t, store)
+	restored := NewTredsStore()
+	if err := restored.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if value, _ := restored.Get(binary); value != "value" {
+		t.Fatalf("expected %q, got %q", "value", value)
+	}
+	if members, _ := restored.ZRangeByScoreKVS("board"+binary, "0", "10", "0", "10", true); !reflect.DeepEqual(members, []string{"1", "alice" + binary, "a"}) {
+		t.Fatalf("expected the member, got %q", members)
+	}
+	if list, _ := restored.LRange("list"+binary, 0, -1); !reflect.DeepEqual(list, []string{"x"}) {
+		t.Fatalf("expected [x], got %q", list)
+	}
+	if isMember, _ := restored.SIsMember("set"+binary, "m"+binary); !isMember {
+		t.Fatalf("expected the member to be in the set")
+	}
+	if value, _ := restored.HGet("hash"+binary, "f"+binary); value != "v" {
+		t.Fatalf("expected %q, got %q", "v", value)
+	}
+	if documents, err := restored.DQuery([]string{"users" + binary, `{"filters": [{"field": "age", "operator": "$gt", "value": 20}]}`}); err != nil || len(documents) != 1 {
+		t.Fatalf("expected the document, got %v %v", documents, err)
+	}
+	if _, ok := restored.vectors["points"+binary]; !ok {
+		t.Fatalf("expected the vector store to be restored")
+	}
+	if _, ok := restored.expiry[binary]; !ok {
+		t.Fatalf("expected the expiry to be restored")
+	}
+	if again := persistSnapshot(t, restored); !bytes.Equal(data, again) {
+		t.Fatalf("expected the snapshot of the restored store to match the original")
+	}
+}
+
+func TestTredsStore_RestoreVersion0(t *testing.T) {
+	data, err := proto.Marshal(&kvstore.KeyValueStore{
+		Pairs: []*kvstore.KeyValue{{Key: []byte("key1"), Value: []byte("value1")}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	store := NewTredsStore()
+	if err = store.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if value, _ := store.Get("key1"); value != "value1" {
+		t.Fatalf("expected %q, got %q", "value1", value)
+	}
+
+	data, _ = proto.Marshal(&kvstore.Snapshot{Version: SnapshotVersion + 1})
+	if err = store.Restore(bytes.NewReader(data)); err == nil {
+		t.Fatalf("expected error for a snapshot of a newer version")
+	}
+}
+
+func TestTredsStore_SnapshotPointInTime(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.MSet([]string{"key1", "value1", "key2", "value2"})
+	_ = store.ZAdd([]string{"board", "1", "alice", "a"})
+	_ = store.RPush([]string{"list", "x"})
+
+	view, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Writes applied while the snapshot is persisted are not part of it
+	_ = store.Set("key1", "updated")
+	_ = store.Delete("key2")
+	_ = store.Set("key3", "value3")
+	_ = store.ZAdd([]string{"board", "2", "bob", "b"})
+	_ = store.RPush([]string{"list", "y"})
+
+	var buf bytes.Buffer
+	if err = view.Persist(&buf, CompressionNone); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	restored := NewTredsStore()
+	if err = restored.Restore(&buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if values, _ := restored.MGet([]string{"key1", "key2", "key3"}); !reflect.DeepEqual(values, []string{"value1", "value2", "(nil)"}) {
+		t.Fatalf("expected [value1 value2 (nil)], got %v", values)
+	}
+	if members, _ := restored.ZRangeByScoreKVS("board", "0", "10", "0", "10", true); !reflect.DeepEqual(members, []string{"1", "alice", "a"}) {
+		t.Fatalf("expected [1 alice a], got %v", members)
+	}
+	if list, _ := restored.LRange("list", 0, -1); !reflect.DeepEqual(list, []string{"x"}) {
+		t.Fatalf("expected [x], got %v", list)
+	}
+}
+
+func TestTredsStore_SnapshotCopyOnWrite(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.ZAdd([]string{"board", "1", "alice", "a", "2", "bob", "b"})
+	_ = store.RPush([]string{"list", "x", "y", "z"})
+	_ = store.SAdd("set", []string{"m1", "m2"})
+	_ = store.HSet("hash", []string{"f1", "v1", "f2", "v2"})
+	_ = store.DCreateCollection([]string{"users", `{"age": {"type": "float"}}`, `[{"fields": ["age"], "type": "normal"}]`})
+	_, _ = store.DInsert([]string{"users", `{"age": 30}`, "user-1"})
+	_ = store.VCreate([]string{"points"})
+	ids := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		id, level, _ := store.VNextNode("points")
+		_, _ = store.VInsert([]string{"points", id, strconv.Itoa(level), strconv.Itoa(i), "0"})
+		ids = append(ids, id)
+	}
+	expected := persistSnapshot(t, store)
+
+	view, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// The view is persisted while every store is changed
+	persisted := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		if errPersist := view.Persist(&buf, CompressionNone); errPersist != nil {
+			t.Errorf("expected no error, got %v", errPersist)
+		}
+		persisted <- buf.Bytes()
+	}()
+	for i := 0; i < 2; i++ {
+		_ = store.ZAdd([]string{"board", "3", "carol" + strconv.Itoa(i), "c"})
+		_ = store.ZRem([]string{"board", "alice"})
+		_ = store.LSet("list", 0, "w")
+		_, _ = store.LPop("list", 1)
+		_ = store.RPush([]string{"list", "v"})
+		_ = store.SAdd("set", []string{"m3"})
+		_ = store.SRem("set", []string{"m1"})
+		_ = store.HSet("hash", []string{"f3", "v3"})
+		_ = store.HDel("hash", []string{"f1"})
+		_, _ = store.DInsert([]string{"users", `{"age": 40}`, "user-" + strconv.Itoa(i+2)})
+		id, level, _ := store.VNextNode("points")
+		_, _ = store.VInsert([]string{"points", id, strconv.Itoa(level), "0", "1"})
+		_, _ = store.VDelete([]string{"points", ids[i]})
+	}
+	if data := <-persisted; !bytes.Equal(data, expected) {
+		t.Fatalf("expected the view to hold the stores at the time of the snapshot")
+	}
+
+	// The containers changed after the snapshot are copied once
+	if _, ok := store.ownedLists["list"]; !ok {
+		t.Fatalf("expected the list to be owned by the store")
+	}
+	if list, _ := store.LRange("list", 0, -1); !reflect.DeepEqual(list, []string{"z", "v", "v"}) {
+		t.Fatalf("expected [z v v], got %v", list)
+	}
+	if members, _ := store.SMembers("set"); len(members) != 2 {
+		t.Fatalf("expected [m2 m3], got %v", members)
+	}
+	if fields, _ := store.HKeys("hash"); len(fields) != 2 {
+		t.Fatalf("expected [f2 f3], got %v", fields)
+	}
+	if documents, _ := store.DQuery([]string{"users", `{"filters": [{"field": "age", "operator": "$gt", "value": 35}]}`}); len(documents) != 2 {
+		t.Fatalf("expected two documents, got %v", documents)
+	}
+	if again := persistSnapshot(t, store); bytes.Equal(again, expected) {
+		t.Fatalf("expected the changes to be in the next snapshot")
+	}
+}
+
+func TestTredsStore_SnapshotChunks(t *testing.T) {
+	defer func(size int) { snapshotChunkSize = size }(snapshotChunkSize)
+	snapshotChunkSize = 2
+
+	store := NewTredsStore()
+	_ = store.MSet([]string{"key1", "value1", "key2", "value2", "key3", "value3"})
+	_ = store.ZAdd([]string{"board", "2", "bob", "b", "1", "alice", "a", "3", "carol", "c"})
+	_ = store.RPush([]string{"list", "x", "y", "z"})
+	_ = store.SAdd("set", []string{"m1", "m2", "m3"})
+	_ = store.HSet("hash", []string{"f1", "v1", "f2", "v2", "f3", "v3"})
+	_ = store.DCreateCollection([]string{"users", `{"age": {"type": "float"}}`, `[{"fields": ["age"], "type": "normal"}]`})
+	for i := 0; i < 5; i++ {
+		_, _ = store.DInsert([]string{"users", fmt.Sprintf(`{"age": %d}`, 30+i), fmt.Sprintf("user-%d", i)})
+	}
+	_ = store.VCreate([]string{"points"})
+	for i := 0; i < 10; i++ {
+		id, level, _ := store.VNextNode("points")
+		_, _ = store.VInsert([]string{"points", id, strconv.Itoa(level), strconv.Itoa(i), "0"})
+	}
+
+	data := persistSnapshot(t, store)
+	restored := NewTredsStore()
+	if err := restored.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if values, _ := restored.MGet([]string{"key1", "key2", "key3"}); !reflect.DeepEqual(values, []string{"value1", "value2", "value3"}) {
+		t.Fatalf("expected [value1 value2 value3], got %v", values)
+	}
+	members, _ := restored.ZRangeByScoreKVS("board", "0", "10", "0", "10", true)
+	expectedMembers := []string{"1", "alice", "a", "2", "bob", "b", "3", "carol", "c"}
+	if !reflect.DeepEqual(members, expectedMembers) {
+		t.Fatalf("expected %v, got %v", expectedMembers, members)
+	}
+	if list, _ := restored.LRange("list", 0, -1); !reflect.DeepEqual(list, []string{"x", "y", "z"}) {
+		t.Fatalf("expected [x y z], got %v", list)
+	}
+	if size, _ := restored.SCard("set"); size != 3 {
+		t.Fatalf("expected 3 members, got %d", size)
+	}
+	if fields, _ := restored.HLen("hash"); fields != 3 {
+		t.Fatalf("expected 3 fields, got %d", fields)
+	}
+	documents, err := restored.DQuery([]string{"users", `{"filters": [{"field": "age", "operator": "$gt", "value": 31}]}`})
+	if err != nil || len(documents) != 3 {
+		t.Fatalf("expected 3 documents, got %v %v", documents, err)
+	}
+	original, _ := store.VSearch([]string{"points", "4", "0", "3"})
+	searched, _ := restored.VSearch([]string{"points", "4", "0", "3"})
+	if !reflect.DeepEqual(original, searched) {
+		t.Fatalf("expected %v, got %v", original, searched)
+	}
+}
+
+func TestTredsStore_SnapshotCompression(t *testing.T) {
+	store := NewTredsStore()
+	for i := 0; i < 100; i++ {
+		_ = store.Set(fmt.Sprintf("key%d", i), strings.Repeat("value", 10))
+	}
+	uncompressed := persistSnapshot(t, store)
+
+	for _, compression := range []SnapshotCompression{CompressionSnappy, CompressionZstd} {
+		data := persistCompressedSnapshot(t, store, compression)
+		if len(data) >= len(uncompressed) {
+			t.Fatalf("expected the %s snapshot to be smaller than %d bytes, got %d", compression, len(uncompressed), len(data))
+		}
+		restored := NewTredsStore()
+		if err := restored.Restore(bytes.NewReader(data)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if value, _ := restored.Get("key42"); value != strings.Repeat("value", 10) {
+			t.Fatalf("expected the value of key42, got %q", value)
+		}
+	}
+
+	if _, err := ParseSnapshotCompression("lz4"); err == nil {
+		t.Fatalf("expected error for an unknown compression")
+	}
+}
+
+func TestTredsStore_SnapshotMetadata(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.Set("key", "value")
+	view, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	view.Metadata = map[string]string{"leader": "10.0.0.1:7997"}
+	var buf bytes.Buffer
+	if err = view.Persist(&buf, CompressionNone); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	restored := NewTredsStore()
+	metadata, err := restored.RestoreMetadata(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if metadata["leader"] != "10.0.0.1:7997" {
+		t.Fatalf("expected the metadata to be restored, got %v", metadata)
+	}
+	if value, _ := restored.Get("key"); value != "value" {
+		t.Fatalf("expected value, got %q", value)
+	}
+}
+
+func TestTredsStore_SnapshotCorrupted(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.MSet([]string{"key1", "value1", "key2", "value2"})
+	data := persistCompressedSnapshot(t, store, CompressionZstd)
+	if err := VerifySnapshot(bytes.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)-20] ^= 0xff
+	truncated := data[:len(data)-4]
+	for name, corrupted := range map[string][]byte{"flipped": flipped, "truncated": truncated} {
+		if err := VerifySnapshot(bytes.NewReader(corrupted)); !errors.Is(err, ErrSnapshotCorrupted) {
+			t.Fatalf("expected %s snapshot to be corrupted, got %v", name, err)
+		}
+		// The store is left untouched
+		if err := store.Restore(bytes.NewReader(corrupted)); err == nil {
+			t.Fatalf("expected error restoring the %s snapshot", name)
+		}
+		if value, _ := store.Get("key1"); value != "value1" {
+			t.Fatalf("expected %q, got %q", "value1", value)
+		}
+	}
+
+	// A corrupted frame length fails without allocating the frame it claims
+	var header bytes.Buffer
+	if _, err := newSnapshotFrameWriter(&header, CompressionNone); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, length := range []uint32{math.MaxUint32, maxSnapshotFrameSize} {
+		corrupted := binary.BigEndian.AppendUint32(bytes.Clone(header.Bytes()), length)
+		corrupted = append(corrupted, 0, 0, 0, 0, 'x')
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := VerifySnapshot(bytes.NewReader(corrupted))
+		runtime.ReadMemStats(&after)
+		if !errors.Is(err, ErrSnapshotCorrupted) {
+			t.Fatalf("expected a frame of %d bytes to be corrupted, got %v", length, err)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Fatalf("expected less than 1 MiB to be allocated for a frame of %d bytes, got %d bytes", length, allocated)
+		}
+	}
+}
+
+func TestTredsStore_CollectionValues(t *testing.T) {
+	store := NewTredsStore()
+	// Every argument is an element, spaces, quotes, binary bytes and empty values are kept
+	values := []string{"hello world", "it's", "\x00\xff\r\n", ""}
+	if err := store.RPush(append([]string{"list"}, values...)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if list, _ := store.LRange("list", 0, -1); !reflect.DeepEqual(list, values) {
+		t.Fatalf("expected %q, got %q", values, list)
+	}
+	if err := store.SAdd("set", values); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if size, _ := store.SCard("set"); size != len(values) {
+		t.Fatalf("expected %d members, got %d", len(values), size)
+	}
+	if err := store.SRem("set", []string{"hello world"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if size, _ := store.SCard("set"); size != len(values)-1 {
+		t.Fatalf("expected %d members, got %d", len(values)-1, size)
+	}
+	// Every field and value pair is stored, not only the first
+	if err := store.HSet("hash", []string{"greeting", "hello world", "binary", "\x00\xff"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if value, _ := store.HGet("hash", "greeting"); value != "hello world" {
+		t.Fatalf("expected %q, got %q", "hello world", value)
+	}
+	if value, _ := store.HGet("hash", "binary"); value != "\x00\xff" {
+		t.Fatalf("expected %q, got %q", "\x00\xff", value)
+	}
+	if err := store.ZAdd([]string{"board", "1", "alice", "", "2", "bob", "it's"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	members, _ := store.ZRangeByScoreKVS("board", "0", "10", "0", "10", false)
+	if !reflect.DeepEqual(members, []string{"alice", "", "bob", "it's"}) {
+		t.Fatalf("expected [alice  bob it's], got %q", members)
+	}
+}
+
+func TestTredsStore_Export(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.Set("user:1", "it's <alice>")
+	_ = store.Set("other", "value")
+	_ = store.ZAdd([]string{"user:board", "+inf", "bob", "", "1.5", "alice", "a"})
+	_ = store.RPush([]string{"user:list", "x", "y z"})
+	_ = store.HSet("user:hash", []string{"f1", "v1"})
+	_ = store.DCreateCollection([]string{"user:docs", `{"age": {"type": "float"}}`, `[{"fields": ["age"], "type": "unique"}]`})
+	_, _ = store.DInsert([]string{"user:docs", `{"age": 30}`, "doc-1"})
+	_ = store.Expire("user:1", time.Now().Add(time.Hour))
+	_ = store.Set("user:expired", "value")
+	_ = store.Expire("user:expired", time.Now().Add(-time.Second))
+
+	view, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	exported, err := view.Export(&buf, "user:")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if exported != 5 || len(lines) != 5 {
+		t.Fatalf("expected 5 keys, got %d in %q", exported, buf.String())
+	}
+	expected := []string{
+		`"value":"it's <alice>"`,
+		`"value":[{"member":"alice","score":1.5,"value":"a"},{"member":"bob","score":"+Inf","value":""}]`,
+		`{"key":"user:list","type":"list","value":["x","y z"]}`,
+		`{"key":"user:hash","type":"hash","value":{"f1":"v1"}}`,
+		`"documents":[{"id":"doc-1","data":{"_id":"doc-1","age":30}}]`,
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, expected[i]) {
+			t.Fatalf("expected line %d to contain %s, got %s", i, expected[i], line)
+		}
+	}
+
+	var records []*ExportRecord
+	if err = ReadExport(&buf, func(record *ExportRecord) error {
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if records[0].Key != "user:1" || records[0].TTL <= 0 || records[0].TTL > time.Hour.Milliseconds() {
+		t.Fatalf("unexpected record %+v", records[0])
+	}
+	var members []ExportSortedMapMember
+	if err = json.Unmarshal(records[1].Value, &members); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !math.IsInf(float64(members[1].Score), 1) {
+		t.Fatalf("expected an infinite score, got %v", members[1].Score)
+	}
+
+	if err = ReadExport(strings.NewReader(`{"type":"string","value":"v"}`), func(*ExportRecord) error { return nil }); err == nil {
+		t.Fatalf("expected error for a record without key")
+	}
+}
+
+func TestTredsStore_ExportBinary(t *testing.T) {
+	binary := "\b\x96\x01\xff\xfe"
+	store := NewTredsStore()
+	_ = store.Set("key", binary)
+	_ = store.Set("text", "value")
+	_ = store.RPush([]string{"list", "a", binary})
+
+	view, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err = view.Export(&buf, ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// A record holding a string that is not UTF-8 is base64, the others are left as they are
+	expected := []string{
+		`{"key":"a2V5","type":"string","encoding":"base64","value":"CJYB//4="}`,
+		`{"key":"text","type":"string","value":"value"}`,
+		`{"key":"bGlzdA==","type":"list","encoding":"base64","value":["YQ==","CJYB//4="]}`,
+	}
+	if lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected %q, got %q", expected, lines)
+	}
+
+	var values []string
+	if err = ReadExport(&buf, func(record *ExportRecord) error {
+		var value string
+		if record.Type == ExportTypeString {
+			_ = json.Unmarshal(record.Value, &value)
+		} else {
+			var list []string
+			_ = json.Unmarshal(record.Value, &list)
+			value = list[1]
+		}
+		decoded, errDecode := record.Decode(value)
+		values = append(values, decoded)
+		return errDecode
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expectedValues := []string{binary, "value", binary}; !reflect.DeepEqual(values, expectedValues) {
+		t.Fatalf("expected %q, got %q", expectedValues, values)
+	}
+
+	record := &ExportRecord{Encoding: "hex"}
+	if _, err = record.Decode("00"); err == nil {
+		t.Fatalf("expected error for an unknown encoding")
+	}
+}
+
+func TestTredsStore_ReadExpired(t *testing.T) {
+	store := NewTredsStore()
+	_ = store.Set("key", "value")
+	_ = store.RPush([]string{"list", "a", "b"})
+	_ = store.HSet("hash", []string{"f", "v"})
+	_ = store.SAdd("set", []string{"m"})
+	_ = store.ZAdd([]string{"board", "1", "alice", "a"})
+	for _, key := range []string{"key", "list", "hash", "set", "board"} {
+		_ = store.Expire(key, time.Now().Add(-time.Second))
+	}
+
+	// Reads treat expired keys as missing without deleting them, they run concurrently
+	if value, _ := store.Get("key"); value != NilResp {
+		t.Fatalf("expected nil, got %q", value)
+	}
+	if length, _ := store.LLen("list"); length != 0 {
+		t.Fatalf("expected 0, got %d", length)
+	}
+	if value, _ := store.HGet("hash", "f"); value != NilResp {
+		t.Fatalf("expected nil, got %q", value)
+	}
+	if members, _ := store.SUnion([]string{"set"}); len(members) != 0 {
+		t.Fatalf("expected no member, got %v", members)
+	}
+	if members, _ := store.ZRangeByScoreKVS("board", "0", "10", "0", "10", false); len(members) != 0 {
+		t.Fatalf("expected no member, got %v", members)
+	}
+	if score, _ := store.ZScore([]string{"board", "alice"}); score != "" {
+		t.Fatalf("expected no score, got %q", score)
+	}
+	if _, ok := store.lists["list"]; !ok {
+		t.Fatalf("expected the read to leave the expired list")
+	}
+
+	// A write replaces the expired key
+	_ = store.RPush([]string{"list", "c"})
+	if list, _ := store.LRange("list", 0, -1); !reflect.DeepEqual(list, []string{"c"}) {
+		t.Fatalf("expected [c], got %v", list)
+	}
+	store.CleanUpExpiredKeys()
+	if _, ok := store.hashes["hash"]; ok {
+		t.Fatalf("expected the cleanup to delete the expired hash")
+	}
+}
@@ -0,0 +1,238 @@
+// treds-inspect looks inside the data directory of a stopped node, without starting a server
+//
+//	treds-inspect log [-data data] [-id server-id] [-from index] [-to index]
+//	treds-inspect snapshots [-data data]
+//	treds-inspect dump [-data data] [-snapshot id|folder|archive] [-prefix prefix]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	wal "github.com/hashicorp/raft-wal"
+	"treds/resp"
+	"treds/server"
+	"treds/store"
+)
+
+// listAllSnapshots is the retention of the snapshot stores opened here, List returns at most that many snapshots
+// and nothing is written, so nothing is removed
+const listAllSnapshots = math.MaxInt32
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("expected a mode, log, snapshots or dump")
+	}
+	var err error
+	switch os.Args[1] {
+	case "log":
+		err = inspectLog(os.Args[2:], os.Stdout)
+	case "snapshots":
+		err = inspectSnapshots(os.Args[2:], os.Stdout)
+	case "dump":
+		err = dumpSnapshot(os.Args[2:], os.Stdout, os.Stderr)
+	default:
+		err = fmt.Errorf("unknown mode %s, expected log, snapshots or dump", os.Args[1])
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// inspectLog writes the Raft state and the entries of the raft-wal log of a node to out, commands are decoded
+func inspectLog(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("log", flag.ExitOnError)
+	dataDir := flags.String("data", "data", "Raft data directory of the node")
+	serverId := flags.String("id", "", "Server id of the node, read from the server-id file next to the data directory when empty")
+	from := flags.Uint64("from", 0, "First index printed, the first index of the log when 0")
+	to := flags.Uint64("to", 0, "Last index printed, the last index of the log when 0")
+	_ = flags.Parse(args)
+
+	id := *serverId
+	idFile := filepath.Join(filepath.Dir(filepath.Clean(*dataDir)), "server-id")
+	if data, err := os.ReadFile(idFile); err == nil {
+		fmt.Fprintf(out, "server-id file: %s\n", strings.TrimSpace(string(data)))
+		if id == "" {
+			id = strings.TrimSpace(string(data))
+		}
+	} else {
+		fmt.Fprintf(out, "server-id file: %v\n", err)
+	}
+	// A node that does not bootstrap often has a server-id file that does not match its log
+	fmt.Fprintf(out, "logs in %s: %s\n", *dataDir, strings.Join(logDirs(*dataDir), ", "))
+	if id == "" {
+		return fmt.Errorf("no server id, see -id")
+	}
+
+	// The log is opened for writing by raft-wal, the node must be stopped
+	logDir := filepath.Join(*dataDir, id)
+	if _, err := os.Stat(logDir); err != nil {
+		return err
+	}
+	logs, err := wal.Open(logDir)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	first, err := logs.FirstIndex()
+	if err != nil {
+		return err
+	}
+	last, err := logs.LastIndex()
+	if err != nil {
+		return err
+	}
+	currentTerm, _ := logs.GetUint64([]byte("CurrentTerm"))
+	lastVoteTerm, _ := logs.GetUint64([]byte("LastVoteTerm"))
+	lastVoteCandidate, _ := logs.Get([]byte("LastVoteCand"))
+	fmt.Fprintf(out, "current term: %d, last vote: %q in term %d\n", currentTerm, lastVoteCandidate, lastVoteTerm)
+	fmt.Fprintf(out, "log: index %d to %d\n", first, last)
+
+	start, end := max(*from, first), last
+	if *to > 0 {
+		end = min(*to, last)
+	}
+	for index := start; index <= end && index > 0; index++ {
+		var entry raft.Log
+		if err = logs.GetLog(index, &entry); err != nil {
+			fmt.Fprintf(out, "%d\t%v\n", index, err)
+			continue
+		}
+		appendedAt := "-"
+		if !entry.AppendedAt.IsZero() {
+			appendedAt = entry.AppendedAt.UTC().Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(out, "%d\t%d\t%s\t%s\t%s\n", entry.Index, entry.Term, entry.Type, appendedAt, describeEntry(&entry))
+	}
+	return nil
+}
+
+// describeEntry decodes the command of an entry, or the servers of a configuration entry
+func describeEntry(entry *raft.Log) string {
+	switch entry.Type {
+	case raft.LogCommand:
+		command, args, err := resp.Decode(string(entry.Data))
+		if err != nil {
+			return fmt.Sprintf("undecodable command %q: %v", entry.Data, err)
+		}
+		quoted := make([]string, 0, len(args)+1)
+		quoted = append(quoted, command)
+		for _, arg := range args {
+			quoted = append(quoted, strconv.Quote(arg))
+		}
+		return strings.Join(quoted, " ")
+	case raft.LogConfiguration:
+		return describeConfiguration(raft.DecodeConfiguration(entry.Data))
+	}
+	return ""
+}
+
+func describeConfiguration(configuration raft.Configuration) string {
+	servers := make([]string, 0, len(configuration.Servers))
+	for _, srv := range configuration.Servers {
+		servers = append(servers, fmt.Sprintf("%s@%s (%s)", srv.ID, srv.Address, srv.Suffrage))
+	}
+	return strings.Join(servers, ", ")
+}
+
+// logDirs returns the folders of the data directory holding a raft-wal log, named after the id of their server
+func logDirs(dataDir string) []string {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, errStat := os.Stat(filepath.Join(dataDir, entry.Name(), "wal-meta.db")); errStat == nil {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs
+}
+
+// inspectSnapshots writes the metadata of the snapshots Raft kept to out, newest first
+func inspectSnapshots(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("snapshots", flag.ExitOnError)
+	dataDir := flags.String("data", "data", "Raft data directory of the node")
+	_ = flags.Parse(args)
+
+	snapshots, err := raft.NewFileSnapshotStore(*dataDir, listAllSnapshots, io.Discard)
+	if err != nil {
+		return err
+	}
+	metas, err := snapshots.List()
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		fmt.Fprintf(out, "%s\tindex %d\tterm %d\t%d bytes\tversion %d\tservers %s\n",
+			meta.ID, meta.Index, meta.Term, meta.Size, meta.Version, describeConfiguration(meta.Configuration))
+	}
+	return nil
+}
+
+// dumpSnapshot writes the keys of a snapshot to out as JSON Lines, in the format of EXPORT, and its metadata to info
+func dumpSnapshot(args []string, out, info io.Writer) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	dataDir := flags.String("data", "data", "Raft data directory of the node")
+	snapshot := flags.String("snapshot", "", "Id of a snapshot of the data directory, a snapshot folder or a backup archive, the latest snapshot when empty")
+	prefix := flags.String("prefix", "", "Only dump the keys starting with this prefix")
+	_ = flags.Parse(args)
+
+	meta, state, err := openSnapshot(*dataDir, *snapshot)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+	header, _ := json.Marshal(map[string]interface{}{"id": meta.ID, "index": meta.Index, "term": meta.Term, "size": meta.Size})
+	fmt.Fprintln(info, string(header))
+
+	tredsStore := store.NewTredsStore()
+	if err = tredsStore.Restore(state); err != nil {
+		return err
+	}
+	view, err := tredsStore.Snapshot()
+	if err != nil {
+		return err
+	}
+	_, err = view.Export(out, *prefix)
+	return err
+}
+
+// openSnapshot opens a snapshot folder or backup archive, or the snapshot of the data directory with the given id
+func openSnapshot(dataDir, snapshot string) (*raft.SnapshotMeta, io.ReadCloser, error) {
+	if snapshot != "" {
+		if _, err := os.Stat(snapshot); err == nil {
+			return server.OpenSnapshotPath(snapshot)
+		}
+	}
+	snapshots, err := raft.NewFileSnapshotStore(dataDir, listAllSnapshots, io.Discard)
+	if err != nil {
+		return nil, nil, err
+	}
+	if snapshot == "" {
+		metas, errList := snapshots.List()
+		if errList != nil {
+			return nil, nil, errList
+		}
+		if len(metas) == 0 {
+			return nil, nil, fmt.Errorf("no snapshot in %s", dataDir)
+		}
+		snapshot = metas[0].ID
+	}
+	return snapshots.Open(snapshot)
+}
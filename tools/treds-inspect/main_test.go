@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	wal "github.com/hashicorp/raft-wal"
+	"treds/resp"
+	"treds/store"
+)
+
+// writeDataDir writes the data directory of a stopped node with the server id node-1 and returns its path.
+// The log holds a configuration, a SET and a noop in term 2, the snapshots are taken at index 1, empty, and at index 2.
+func writeDataDir(t *testing.T) (string, time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	if err := os.WriteFile(filepath.Join(dir, "server-id"), []byte("node-1\n"), 0644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	logDir := filepath.Join(dataDir, "node-1")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	logs, err := wal.Open(logDir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	configuration := raft.Configuration{Servers: []raft.Server{{Suffrage: raft.Voter, ID: "node-1", Address: "127.0.0.1:7000"}}}
+	appendedAt := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	entries := []*raft.Log{
+		{Index: 1, Term: 2, Type: raft.LogConfiguration, Data: raft.EncodeConfiguration(configuration), AppendedAt: appendedAt},
+		{Index: 2, Term: 2, Type: raft.LogCommand, Data: []byte(resp.EncodeStringArray([]string{"SET", "key", "a value"})), AppendedAt: appendedAt},
+		{Index: 3, Term: 2, Type: raft.LogNoop, AppendedAt: appendedAt},
+	}
+	if err = logs.StoreLogs(entries); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = logs.SetUint64([]byte("CurrentTerm"), 2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = logs.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(dataDir, 2, io.Discard)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// The peers of the configuration are encoded by a transport
+	_, transport := raft.NewInmemTransport("127.0.0.1:7000")
+	tredsStore := store.NewTredsStore()
+	for index := uint64(1); index <= 2; index++ {
+		if index == 2 {
+			_ = tredsStore.Set("key", "a value")
+			_ = tredsStore.Set("user:1", "alice")
+		}
+		view, errView := tredsStore.Snapshot()
+		if errView != nil {
+			t.Fatalf("expected no error, got %v", errView)
+		}
+		sink, errCreate := snapshots.Create(raft.SnapshotVersionMax, index, 2, configuration, 1, transport)
+		if errCreate != nil {
+			t.Fatalf("expected no error, got %v", errCreate)
+		}
+		if errPersist := view.Persist(sink, store.CompressionNone); errPersist != nil {
+			t.Fatalf("expected no error, got %v", errPersist)
+		}
+		if errClose := sink.Close(); errClose != nil {
+			t.Fatalf("expected no error, got %v", errClose)
+		}
+	}
+	return dataDir, appendedAt
+}
+
+func TestInspectLog(t *testing.T) {
+	dataDir, appendedAt := writeDataDir(t)
+	at := appendedAt.Format(time.RFC3339Nano)
+
+	var out bytes.Buffer
+	if err := inspectLog([]string{"-data", dataDir}, &out); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []string{
+		"server-id file: node-1",
+		"logs in " + dataDir + ": node-1",
+		`current term: 2, last vote: "" in term 0`,
+		"log: index 1 to 3",
+		"1\t2\tLogConfiguration\t" + at + "\tnode-1@127.0.0.1:7000 (Voter)",
+		"2\t2\tLogCommand\t" + at + "\tSET \"key\" \"a value\"",
+		"3\t2\tLogNoop\t" + at + "\t",
+	}
+	if lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected %q, got %q", expected, lines)
+	}
+
+	// The range of the entries is bounded by -from and -to, the id of the log by -id
+	out.Reset()
+	if err := inspectLog([]string{"-data", dataDir, "-id", "node-1", "-from", "2", "-to", "2"}, &out); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); lines[len(lines)-1] != expected[5] || len(lines) != 5 {
+		t.Fatalf("expected the entry 2 alone, got %q", lines)
+	}
+
+	if err := inspectLog([]string{"-data", dataDir, "-id", "node-2"}, io.Discard); err == nil {
+		t.Fatalf("expected an error for a server id without a log")
+	}
+}
+
+func TestInspectSnapshots(t *testing.T) {
+	dataDir, _ := writeDataDir(t)
+	var out bytes.Buffer
+	if err := inspectSnapshots([]string{"-data", dataDir}, &out); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "\tindex 2\tterm 2\t") || !strings.Contains(lines[1], "\tindex 1\tterm 2\t") ||
+		!strings.HasSuffix(lines[0], "servers node-1@127.0.0.1:7000 (Voter)") {
+		t.Fatalf("expected the snapshots at index 2 and 1, got %q", lines)
+	}
+}
+
+func TestDumpSnapshot(t *testing.T) {
+	dataDir, _ := writeDataDir(t)
+	snapshots, err := raft.NewFileSnapshotStore(dataDir, listAllSnapshots, io.Discard)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	metas, err := snapshots.List()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		index    string
+		expected string
+	}{
+		{
+			name:     "latest snapshot",
+			args:     []string{"-data", dataDir},
+			index:    `"index":2`,
+			expected: `{"key":"key","type":"string","value":"a value"}` + "\n" + `{"key":"user:1","type":"string","value":"alice"}` + "\n",
+		},
+		{
+			name:     "prefix",
+			args:     []string{"-data", dataDir, "-prefix", "user:"},
+			index:    `"index":2`,
+			expected: `{"key":"user:1","type":"string","value":"alice"}` + "\n",
+		},
+		{name: "snapshot id", args: []string{"-data", dataDir, "-snapshot", metas[1].ID}, index: `"index":1`},
+		{
+			name:     "snapshot folder",
+			args:     []string{"-snapshot", filepath.Join(dataDir, "snapshots", metas[0].ID)},
+			index:    `"index":2`,
+			expected: `{"key":"key","type":"string","value":"a value"}` + "\n" + `{"key":"user:1","type":"string","value":"alice"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, info bytes.Buffer
+			if errDump := dumpSnapshot(tt.args, &out, &info); errDump != nil {
+				t.Fatalf("expected no error, got %v", errDump)
+			}
+			if out.String() != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, out.String())
+			}
+			if !strings.Contains(info.String(), tt.index) {
+				t.Fatalf("expected the metadata with %s, got %q", tt.index, info.String())
+			}
+		})
+	}
+
+	if err = dumpSnapshot([]string{"-data", t.TempDir()}, io.Discard, io.Discard); err == nil || !strings.Contains(err.Error(), "no snapshot in") {
+		t.Fatalf("expected no snapshot, got %v", err)
+	}
+}
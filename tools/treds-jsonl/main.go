@@ -0,0 +1,123 @@
+// treds-jsonl converts between Treds snapshot folders and JSON Lines exports, the files EXPORT writes and IMPORT reads
+//
+//	treds-jsonl export -snapshot data/snapshots/2-10-1700000000000 -out keys.jsonl [-prefix user:]
+//	treds-jsonl import -in keys.jsonl -out seed [-compression zstd]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"treds/commands"
+	"treds/server"
+	"treds/store"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("expected a mode, export or import")
+	}
+	switch os.Args[1] {
+	case "export":
+		exportSnapshot(os.Args[2:])
+	case "import":
+		importExport(os.Args[2:])
+	default:
+		log.Fatalf("unknown mode %s, expected export or import", os.Args[1])
+	}
+}
+
+// exportSnapshot writes the keys of a snapshot folder as JSON Lines
+func exportSnapshot(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	snapshot := flags.String("snapshot", "", "Snapshot folder, with the state.bin written by Raft, SNAPSHOT or this tool")
+	out := flags.String("out", "", "File the JSON Lines are written to")
+	prefix := flags.String("prefix", "", "Only export the keys starting with this prefix")
+	_ = flags.Parse(args)
+
+	if *snapshot == "" || *out == "" {
+		log.Fatal("a snapshot folder and an output file are needed, see -snapshot and -out")
+	}
+	file, err := os.Open(filepath.Join(*snapshot, "state.bin"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	tredsStore := store.NewTredsStore()
+	if err = tredsStore.Restore(file); err != nil {
+		log.Fatal(err)
+	}
+	view, err := tredsStore.Snapshot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	exported, err := server.ExportFile(*out, view, *prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Exported %d keys into %s\n", exported, *out)
+}
+
+// importExport builds a snapshot folder from JSON Lines, which RESTORE loads on a cluster
+func importExport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "", "JSON Lines file to import")
+	out := flags.String("out", "", "Folder the snapshot is written to, with the meta.json and state.bin that RESTORE reads")
+	compression := flags.String("compression", "none", "Compression of the snapshot chunks, none, snappy or zstd")
+	_ = flags.Parse(args)
+
+	if *in == "" || *out == "" {
+		log.Fatal("an input file and an output folder are needed, see -in and -out")
+	}
+	snapshotCompression, err := store.ParseSnapshotCompression(*compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+	file, err := os.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	// The commands are executed like the FSM applies them, so the snapshot matches what IMPORT loads
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	tredsStore := store.NewTredsStore()
+	keys := 0
+	err = store.ReadExport(file, func(record *store.ExportRecord) error {
+		recordCommands, errRecord := server.ImportCommands(record, time.Now())
+		if errRecord != nil {
+			return errRecord
+		}
+		for _, commandArgs := range recordCommands {
+			commandReg, errCommand := registry.Retrieve(commandArgs[0])
+			if errCommand != nil {
+				return errCommand
+			}
+			if reply := commandReg.Execute(commandArgs[1:], tredsStore); strings.HasPrefix(reply, "-") {
+				return fmt.Errorf("%s %s: %s", commandArgs[0], commandArgs[1], strings.TrimSpace(reply[1:]))
+			}
+		}
+		keys++
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	view, err := tredsStore.Snapshot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	id := fmt.Sprintf("jsonl-%d", time.Now().UnixMilli())
+	if err = server.WriteSnapshotFolder(*out, id, view, snapshotCompression); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Imported %d keys into %s, load them with RESTORE %s\n", keys, *out, *out)
+}
@@ -0,0 +1,72 @@
+// treds-rdb converts a Redis RDB dump into a Treds snapshot folder, which RESTORE loads on a cluster
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"treds/commands"
+	"treds/rdb"
+	"treds/server"
+	"treds/store"
+)
+
+func main() {
+	rdbPath := flag.String("rdb", "dump.rdb", "Redis RDB dump to convert")
+	out := flag.String("out", "", "Folder the snapshot is written to, with the meta.json and state.bin that RESTORE reads")
+	compression := flag.String("compression", "none", "Compression of the snapshot chunks, none, snappy or zstd")
+	flag.Parse()
+
+	if *out == "" {
+		log.Fatal("an output folder is needed, see -out")
+	}
+	snapshotCompression, err := store.ParseSnapshotCompression(*compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(*rdbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	// The commands are executed like the FSM applies them, so the snapshot matches what LOADRDB loads
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	tredsStore := store.NewTredsStore()
+	keys := 0
+	err = rdb.Parse(file, func(entry *rdb.Entry) error {
+		entryCommands := entry.Commands()
+		for _, args := range entryCommands {
+			commandReg, errCommand := registry.Retrieve(args[0])
+			if errCommand != nil {
+				return errCommand
+			}
+			if reply := commandReg.Execute(args[1:], tredsStore); strings.HasPrefix(reply, "-") {
+				return fmt.Errorf("%s %s: %s", args[0], args[1], strings.TrimSpace(reply[1:]))
+			}
+		}
+		if len(entryCommands) > 0 {
+			keys++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	view, err := tredsStore.Snapshot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	id := fmt.Sprintf("rdb-%d", time.Now().UnixMilli())
+	if err = server.WriteSnapshotFolder(*out, id, view, snapshotCompression); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Converted %d keys into %s, load them with RESTORE %s\n", keys, *out, *out)
+}
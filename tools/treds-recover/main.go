@@ -0,0 +1,93 @@
+// treds-recover rebuilds the store of a stopped node at a Raft index or a time, from the snapshots and the raft-wal log
+// in its data directory, into a snapshot folder which RESTORE loads on a cluster
+//
+//	treds-recover -index 1234 -out recovered
+//	treds-recover -time 2026-10-17T09:30:00Z -data /var/lib/treds/data -out recovered
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	wal "github.com/hashicorp/raft-wal"
+	"treds/commands"
+	"treds/server"
+	"treds/store"
+)
+
+func main() {
+	dataDir := flag.String("data", "data", "Raft data directory of the node, with the snapshots folder and a log folder named after the server id")
+	serverId := flag.String("id", "", "Server id of the node, read from the server-id file next to the data directory when empty")
+	index := flag.Uint64("index", 0, "Raft index of the last entry replayed")
+	at := flag.String("time", "", "Time of the last entry replayed, RFC 3339 or unix milliseconds, used when -index is 0")
+	out := flag.String("out", "", "Folder the snapshot is written to, with the meta.json and state.bin that RESTORE reads")
+	compression := flag.String("compression", "none", "Compression of the snapshot chunks, none, snappy or zstd")
+	flag.Parse()
+
+	if *out == "" {
+		log.Fatal("an output folder is needed, see -out")
+	}
+	var target server.RecoveryTarget
+	var err error
+	switch {
+	case *index > 0:
+		target = server.RecoveryTarget{Index: *index}
+	case *at != "":
+		if target, err = server.ParseRecoveryTarget("TIME", *at); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatal("a target is needed, see -index and -time")
+	}
+	snapshotCompression, err := store.ParseSnapshotCompression(*compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id := *serverId
+	if id == "" {
+		data, errRead := os.ReadFile(filepath.Join(filepath.Dir(filepath.Clean(*dataDir)), "server-id"))
+		if errRead != nil {
+			log.Fatalf("reading the server id: %v, see -id", errRead)
+		}
+		id = strings.TrimSpace(string(data))
+	}
+	// The log is opened for writing by raft-wal, the node must be stopped
+	logDir := filepath.Join(*dataDir, id)
+	if _, err = os.Stat(logDir); err != nil {
+		log.Fatal(err)
+	}
+	logs, err := wal.Open(logDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer logs.Close()
+	// List returns at most as many snapshots as the retention, every kept snapshot is a candidate
+	snapshots, err := raft.NewFileSnapshotStore(*dataDir, math.MaxInt32, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	registry := commands.NewRegistry()
+	commands.RegisterCommands(registry)
+	recovered, recoveredIndex, err := server.RecoverStore(logs, snapshots, registry, target)
+	if err != nil {
+		log.Fatal(err)
+	}
+	view, err := recovered.Snapshot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	snapshotId := fmt.Sprintf("recover-%d-%d", recoveredIndex, time.Now().UnixMilli())
+	if err = server.WriteSnapshotFolder(*out, snapshotId, view, snapshotCompression); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Recovered the store at index %d into %s, load it with RESTORE %s\n", recoveredIndex, *out, *out)
+}